@@ -0,0 +1,81 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+// admissionPlugins is the output of an "admission" check. The fields are
+// available to the check's tests as paths, e.g. '{.enabled_plugins}'.
+type admissionPlugins struct {
+	Enabled  string `json:"enabled_plugins"`
+	Disabled string `json:"disabled_plugins"`
+}
+
+// auditAdmission works out the set of admission plugins enabled on the API
+// server from the flags in the output of the check's audit command. Plugins
+// enabled by default are given as a comma-separated list in the "defaults"
+// audit option, as they vary between Kubernetes versions.
+func auditAdmission(c *Check) (string, error) {
+	out, err := auditOutput(c.Audit)
+	if err != nil {
+		return "", err
+	}
+
+	var enabled []string
+	disabled := splitAndRemoveLastSeparator(flagValue(out, "--disable-admission-plugins"), defaultArraySeparator)
+
+	if legacy := flagValue(out, "--admission-control"); legacy != "" {
+		// The deprecated flag replaces the default plugins entirely.
+		enabled = splitAndRemoveLastSeparator(legacy, defaultArraySeparator)
+	} else {
+		enabled = splitAndRemoveLastSeparator(c.AuditOptions["defaults"], defaultArraySeparator)
+		enabled = append(enabled, splitAndRemoveLastSeparator(flagValue(out, "--enable-admission-plugins"), defaultArraySeparator)...)
+	}
+
+	var plugins []string
+	seen := make(map[string]bool)
+	for _, p := range enabled {
+		if seen[p] || contains(disabled, p) {
+			continue
+		}
+		seen[p] = true
+		plugins = append(plugins, p)
+	}
+
+	result, err := json.Marshal(&admissionPlugins{
+		Enabled:  strings.Join(plugins, defaultArraySeparator),
+		Disabled: strings.Join(disabled, defaultArraySeparator),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	glog.V(3).Infof("Check.ID: %s admission plugins: %s", c.ID, result)
+	return string(result), nil
+}
+
+func contains(s []string, v string) bool {
+	for _, e := range s {
+		if e == v {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,93 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"testing"
+)
+
+func TestAuditAdmission(t *testing.T) {
+	defaults := map[string]string{"defaults": "NamespaceLifecycle,LimitRanger,ServiceAccount"}
+
+	cases := []struct {
+		name     string
+		audit    string
+		opts     map[string]string
+		expected string
+	}{
+		{
+			name:     "defaults only",
+			audit:    "echo kube-apiserver --v=2",
+			opts:     defaults,
+			expected: `{"enabled_plugins":"NamespaceLifecycle,LimitRanger,ServiceAccount","disabled_plugins":""}`,
+		},
+		{
+			name:     "enabled plugins are added to the defaults",
+			audit:    "echo kube-apiserver --enable-admission-plugins=NodeRestriction,ServiceAccount",
+			opts:     defaults,
+			expected: `{"enabled_plugins":"NamespaceLifecycle,LimitRanger,ServiceAccount,NodeRestriction","disabled_plugins":""}`,
+		},
+		{
+			name:     "disabled plugins are removed",
+			audit:    "echo kube-apiserver --enable-admission-plugins=NodeRestriction --disable-admission-plugins=ServiceAccount",
+			opts:     defaults,
+			expected: `{"enabled_plugins":"NamespaceLifecycle,LimitRanger,NodeRestriction","disabled_plugins":"ServiceAccount"}`,
+		},
+		{
+			name:     "deprecated flag replaces the defaults",
+			audit:    "echo kube-apiserver --admission-control=AlwaysAdmit",
+			opts:     defaults,
+			expected: `{"enabled_plugins":"AlwaysAdmit","disabled_plugins":""}`,
+		},
+		{
+			name:     "no defaults",
+			audit:    "echo kube-apiserver --enable-admission-plugins=NodeRestriction",
+			expected: `{"enabled_plugins":"NodeRestriction","disabled_plugins":""}`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			out, err := auditAdmission(&Check{Type: ADMISSION, Audit: c.audit, AuditOptions: c.opts})
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+			if out != c.expected {
+				t.Errorf("expected %s, actual %s", c.expected, out)
+			}
+		})
+	}
+}
+
+func TestCheck_RunAdmission(t *testing.T) {
+	check := &Check{
+		Type:         ADMISSION,
+		Audit:        "echo kube-apiserver --enable-admission-plugins=NodeRestriction --disable-admission-plugins=ServiceAccount",
+		AuditOptions: map[string]string{"defaults": "NamespaceLifecycle,ServiceAccount"},
+		Scored:       true,
+		Tests: &tests{TestItems: []*testItem{
+			{Path: "{.enabled_plugins}", Set: true, Compare: compare{Op: "has_elements", Value: "NodeRestriction,NamespaceLifecycle"}},
+			{Path: "{.enabled_plugins}", Set: true, Compare: compare{Op: "nothave_elements", Value: "AlwaysAdmit"}},
+		}},
+	}
+	if state := check.run(); state != PASS {
+		t.Errorf("expected %s, actual %s (%s)", PASS, state, check.Reason)
+	}
+
+	check.Tests.TestItems[0].Compare.Value = "ServiceAccount"
+	if state := check.run(); state != FAIL {
+		t.Errorf("expected %s, actual %s (%s)", FAIL, state, check.Reason)
+	}
+}
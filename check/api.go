@@ -0,0 +1,181 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/golang/glog"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+)
+
+// apiQueryFunc queries the Kubernetes API and returns a description of each
+// object that violates a policy. The descriptions start with the object, as
+// <kind>/<namespace>/<name>, or <kind>/<name> for cluster scoped objects.
+type apiQueryFunc func(client kubernetes.Interface, opts map[string]string) ([]string, error)
+
+// apiQueries maps the names that can be given as the audit of an "api"
+// check to their query.
+var apiQueries = map[string]apiQueryFunc{
+	"cluster-admin-default-service-accounts": clusterAdminDefaultServiceAccounts,
+	"wildcard-rules":                         wildcardRules,
+	"anonymous-bindings":                     anonymousBindings,
+	"namespaces-without-network-policies":    namespacesWithoutNetworkPolicies,
+	"privileged-containers":                  privilegedContainers,
+	"host-path-volumes":                      hostPathVolumes,
+	"host-namespaces":                        hostNamespaces,
+	"automounted-service-account-tokens":     automountedServiceAccountTokens,
+	"secrets-in-environment":                 secretsInEnvironment,
+	"old-service-account-tokens":             oldServiceAccountTokens,
+	"default-namespace-workloads":            defaultNamespaceWorkloads,
+	"namespaces-without-resource-quotas":     namespacesWithoutResourceQuotas,
+	"namespaces-without-limit-ranges":        namespacesWithoutLimitRanges,
+	"untrusted-registry-images":              untrustedRegistryImages,
+	"latest-tag-images":                      latestTagImages,
+}
+
+// apiResult is the output of an "api" check. The fields are available to
+// the check's tests as paths, e.g. '{.count}'.
+type apiResult struct {
+	Count      int      `json:"count"`
+	Violations []string `json:"violations"`
+}
+
+// kubeClient returns the client used by "api" checks; it's separated into a
+// variable so we can write tests.
+var kubeClient = newKubeClient
+
+var cachedKubeClient kubernetes.Interface
+
+// KubeClient returns the Kubernetes client used by checks.
+func KubeClient() (kubernetes.Interface, error) {
+	if offline {
+		return nil, ErrOffline
+	}
+	return kubeClient()
+}
+
+// KubeDynamicClient returns a client of the custom resources of the
+// cluster.
+func KubeDynamicClient() (dynamic.Interface, error) {
+	if offline {
+		return nil, ErrOffline
+	}
+	config, err := kubeConfig()
+	if err != nil {
+		return nil, err
+	}
+	client, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %v", err)
+	}
+	return client, nil
+}
+
+// kubeConfig returns the configuration of the in-cluster service account
+// when running in a pod, and of the user's kubeconfig otherwise.
+func kubeConfig() (*rest.Config, error) {
+	config, err := rest.InClusterConfig()
+	if err != nil {
+		glog.V(2).Infof("not running in a cluster, using kubeconfig: %v", err)
+		config, err = clientcmd.NewNonInteractiveDeferredLoadingClientConfig(
+			clientcmd.NewDefaultClientConfigLoadingRules(),
+			&clientcmd.ConfigOverrides{},
+		).ClientConfig()
+		if err != nil {
+			return nil, fmt.Errorf("failed to load Kubernetes client configuration: %v", err)
+		}
+	}
+	return config, nil
+}
+
+// newKubeClient builds a client from the in-cluster service account when
+// running in a pod, and from the user's kubeconfig otherwise.
+func newKubeClient() (kubernetes.Interface, error) {
+	if cachedKubeClient != nil {
+		return cachedKubeClient, nil
+	}
+
+	config, err := kubeConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	client, err := kubernetes.NewForConfig(config)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create Kubernetes client: %v", err)
+	}
+
+	cachedKubeClient = client
+	return client, nil
+}
+
+// auditAPI runs the API query named in the check's audit field and reports
+// the objects that violate it.
+func auditAPI(c *Check) (string, error) {
+	name := strings.TrimSpace(c.Audit)
+	query, ok := apiQueries[name]
+	if !ok {
+		return "", fmt.Errorf("unknown API query %q", name)
+	}
+
+	client, err := kubeClient()
+	if err != nil {
+		return "", err
+	}
+
+	violations, err := query(client, c.AuditOptions)
+	if err != nil {
+		return "", fmt.Errorf("API query %q failed: %v", name, err)
+	}
+	scoped, err := scopedNamespaces(client)
+	if err != nil {
+		return "", err
+	}
+	violations = inScope(violations, scoped)
+	sort.Strings(violations)
+	if violations == nil {
+		violations = []string{}
+	}
+
+	out, err := json.Marshal(&apiResult{Count: len(violations), Violations: violations})
+	if err != nil {
+		return "", err
+	}
+
+	glog.V(3).Infof("Check.ID: %s API query %q: %s", c.ID, name, out)
+	return string(out), nil
+}
+
+// optionList returns the comma-separated list in an audit option.
+func optionList(opts map[string]string, name string) []string {
+	return splitAndRemoveLastSeparator(opts[name], defaultArraySeparator)
+}
+
+// hasPrefix returns true if s starts with any of the prefixes.
+func hasPrefix(s string, prefixes []string) bool {
+	for _, p := range prefixes {
+		if strings.HasPrefix(s, p) {
+			return true
+		}
+	}
+	return false
+}
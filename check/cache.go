@@ -0,0 +1,336 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// maxCachedDirEntries bounds the entries of the directories an audit looks
+// at that are fingerprinted, the checks of larger directories aren't cached.
+const maxCachedDirEntries = 1000
+
+// cacheableCommands are the commands audits may run for their results to be
+// cached: they only look at files and processes, whose changes are
+// detected. Audits running other commands, e.g. querying the API server,
+// are evaluated on every scan.
+var cacheableCommands = map[string]bool{
+	"sh": true, "bash": true, "cat": true, "grep": true, "egrep": true,
+	"awk": true, "sed": true, "cut": true, "head": true, "tail": true,
+	"sort": true, "uniq": true, "wc": true, "tr": true, "xargs": true,
+	"echo": true, "printf": true, "test": true, "[": true, "stat": true,
+	"ls": true, "find": true, "ps": true, "jq": true, "yq": true,
+	"readlink": true, "basename": true, "dirname": true, "true": true,
+	"false": true,
+}
+
+var (
+	auditWordRe  = regexp.MustCompile(`[\w.\-]+`)
+	procRootPath = regexp.MustCompile(`^/proc/(\d+)/root(/|$)`)
+)
+
+// CachedResult is the result of a check evaluated in an earlier scan.
+type CachedResult struct {
+	State          State        `json:"status"`
+	ActualValue    string       `json:"actual_value,omitempty"`
+	ExpectedResult string       `json:"expected_result,omitempty"`
+	Reason         string       `json:"reason,omitempty"`
+	TestInfo       []string     `json:"test_info,omitempty"`
+	Errors         []CheckError `json:"errors,omitempty"`
+}
+
+// ResultCache holds the results of checks, keyed by the fingerprint of the
+// check and of the inputs it was evaluated from: the files its audits look
+// at and the processes they inspect. Scheduled scans only re-evaluate the
+// checks whose inputs changed.
+type ResultCache struct {
+	mu sync.Mutex
+	// Version is the version of kube-bench the results were evaluated with.
+	Version string                  `json:"kube_bench_version"`
+	Results map[string]CachedResult `json:"results"`
+	// used are the fingerprints of the results used or added since the
+	// cache was last pruned.
+	used map[string]bool
+	// Hits and Misses count the checks taken from the cache and evaluated
+	// since the cache was last pruned.
+	Hits   int `json:"-"`
+	Misses int `json:"-"`
+}
+
+// NewResultCache returns an empty cache of the results of the given version
+// of kube-bench.
+func NewResultCache(version string) *ResultCache {
+	return &ResultCache{Version: version, Results: map[string]CachedResult{}}
+}
+
+// Prune drops the results that were not used since the cache was last
+// pruned, e.g. of checks whose inputs changed, and resets the counts.
+func (rc *ResultCache) Prune() {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	for fp := range rc.Results {
+		if !rc.used[fp] {
+			delete(rc.Results, fp)
+		}
+	}
+	rc.used = nil
+	rc.Hits, rc.Misses = 0, 0
+}
+
+func (rc *ResultCache) get(fp string) (CachedResult, bool) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	r, ok := rc.Results[fp]
+	if ok {
+		rc.markUsed(fp)
+		rc.Hits++
+	}
+	return r, ok
+}
+
+func (rc *ResultCache) put(fp string, r CachedResult) {
+	rc.mu.Lock()
+	defer rc.mu.Unlock()
+	if rc.Results == nil {
+		rc.Results = map[string]CachedResult{}
+	}
+	rc.Results[fp] = r
+	rc.markUsed(fp)
+	rc.Misses++
+}
+
+func (rc *ResultCache) markUsed(fp string) {
+	if rc.used == nil {
+		rc.used = map[string]bool{}
+	}
+	rc.used[fp] = true
+}
+
+// NewCachingRunner returns a Runner taking the results of checks from the
+// cache when their inputs haven't changed, and running the others with
+// runner.
+func NewCachingRunner(runner Runner, cache *ResultCache) Runner {
+	return &cachingRunner{runner: runner, cache: cache}
+}
+
+type cachingRunner struct {
+	runner Runner
+	cache  *ResultCache
+	inputs fingerprinter
+}
+
+func (r *cachingRunner) Run(c *Check) State {
+	fp, ok := r.inputs.fingerprint(c)
+	if !ok {
+		return r.runner.Run(c)
+	}
+
+	if cached, ok := r.cache.get(fp); ok {
+		c.State, c.ActualValue, c.ExpectedResult, c.Reason = cached.State, cached.ActualValue, cached.ExpectedResult, cached.Reason
+		c.TestInfo = append([]string{}, cached.TestInfo...)
+		c.Errors = append([]CheckError{}, cached.Errors...)
+		return c.State
+	}
+
+	state := r.runner.Run(c)
+	r.cache.put(fp, CachedResult{
+		State:          state,
+		ActualValue:    c.ActualValue,
+		ExpectedResult: c.ExpectedResult,
+		Reason:         c.Reason,
+		TestInfo:       append([]string{}, c.TestInfo...),
+		Errors:         append([]CheckError{}, c.Errors...),
+	})
+	return state
+}
+
+// fingerprinter fingerprints checks and their inputs.
+type fingerprinter struct {
+	procsOnce sync.Once
+	// procs are the "pid:start time" of the running processes, by name.
+	procs map[string][]string
+}
+
+// fingerprint returns the fingerprint of a check and of its inputs, or false
+// if its result can't be cached because its inputs are not known.
+func (r *fingerprinter) fingerprint(c *Check) (string, bool) {
+	if c.Type != "" || c.Unavailable != "" || c.Ignored != "" || replaying() {
+		return "", false
+	}
+
+	audits := []string{c.Audit, c.AuditConfig}
+	commands := map[string]bool{}
+	for _, audit := range audits {
+		for _, name := range auditCommands(audit) {
+			name = filepath.Base(name)
+			if !cacheableCommands[name] {
+				return "", false
+			}
+			commands[name] = true
+		}
+	}
+
+	tests, _ := json.Marshal(c.Tests)
+	options, _ := json.Marshal(c.AuditOptions)
+	inputs := []string{
+		c.ID, c.Audit, c.AuditConfig, c.AuditPath, c.AuditConfigPath,
+		strconv.FormatBool(c.UseSudo), strconv.FormatBool(c.Scored),
+		string(tests), string(options),
+	}
+
+	seen := map[string]bool{}
+	for _, audit := range audits {
+		for _, m := range auditPathRe.FindAllStringSubmatch(audit, -1) {
+			path := filepath.Clean(m[1])
+			if seen[path] || path == "/dev/null" {
+				continue
+			}
+			seen[path] = true
+
+			if pid := procRootPath.FindStringSubmatch(path); pid != nil {
+				// The file is seen through the root of a process, which may
+				// have been replaced.
+				start, err := processStartTime(pid[1])
+				if err != nil {
+					return "", false
+				}
+				inputs = append(inputs, "process "+pid[1]+":"+start)
+			} else if path == "/proc" || path == "/sys" || path == "/dev" ||
+				strings.HasPrefix(path, "/proc/") || strings.HasPrefix(path, "/sys/") || strings.HasPrefix(path, "/dev/") {
+				// The times of pseudo files don't tell whether they changed.
+				return "", false
+			}
+
+			files, ok := fileFingerprints(path)
+			if !ok {
+				return "", false
+			}
+			inputs = append(inputs, files...)
+		}
+
+		if commands["ps"] {
+			// The processes inspected are the ones named in the audit, other
+			// than the commands it runs.
+			for _, w := range auditWordRe.FindAllString(audit, -1) {
+				if commands[w] {
+					continue
+				}
+				if len(w) > 15 {
+					// The kernel truncates process names to 15 characters.
+					w = w[:15]
+				}
+				if seen["process "+w] {
+					continue
+				}
+				seen["process "+w] = true
+				for _, p := range r.processes()[w] {
+					inputs = append(inputs, "process "+w+" "+p)
+				}
+			}
+		}
+	}
+
+	sum := sha256.Sum256([]byte(strings.Join(inputs, "\x00")))
+	return hex.EncodeToString(sum[:]), true
+}
+
+// processes returns the running processes by name, read once per runner.
+func (r *fingerprinter) processes() map[string][]string {
+	r.procsOnce.Do(func() {
+		r.procs = map[string][]string{}
+		dirs, err := ioutil.ReadDir(procRoot)
+		if err != nil {
+			return
+		}
+		for _, d := range dirs {
+			if _, err := strconv.Atoi(d.Name()); err != nil || !d.IsDir() {
+				continue
+			}
+			comm, err := ioutil.ReadFile(filepath.Join(procRoot, d.Name(), "comm"))
+			if err != nil {
+				continue
+			}
+			start, err := processStartTime(d.Name())
+			if err != nil {
+				continue
+			}
+			name := strings.TrimSpace(string(comm))
+			r.procs[name] = append(r.procs[name], d.Name()+":"+start)
+		}
+	})
+	return r.procs
+}
+
+// processStartTime returns the start time of a process, in clock ticks
+// after boot.
+func processStartTime(pid string) (string, error) {
+	stat, err := ioutil.ReadFile(filepath.Join(procRoot, pid, "stat"))
+	if err != nil {
+		return "", err
+	}
+	// The name of the process, in parentheses, may contain spaces.
+	s := string(stat)
+	fields := strings.Fields(s[strings.LastIndex(s, ")")+1:])
+	// The start time is the 22nd field, the 20th after the name.
+	if len(fields) < 20 {
+		return "", fmt.Errorf("invalid stat of process %s", pid)
+	}
+	return fields[19], nil
+}
+
+// fileFingerprints returns the fingerprints of a file, or of a directory and
+// its entries, or false if the directory is too large.
+func fileFingerprints(path string) ([]string, bool) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return []string{path + " missing"}, true
+	}
+	if !info.IsDir() {
+		return []string{fileFingerprint(path, info)}, true
+	}
+
+	var fps []string
+	err = filepath.Walk(path, func(p string, info os.FileInfo, err error) error {
+		if err != nil {
+			fps = append(fps, p+" unreadable")
+			return nil
+		}
+		if len(fps) >= maxCachedDirEntries {
+			return filepath.SkipDir
+		}
+		fps = append(fps, fileFingerprint(p, info))
+		return nil
+	})
+	if err != nil || len(fps) >= maxCachedDirEntries {
+		return nil, false
+	}
+	sort.Strings(fps)
+	return fps, true
+}
+
+func fileFingerprint(path string, info os.FileInfo) string {
+	return fmt.Sprintf("%s %v %d %d %s", path, info.Mode(), info.Size(), info.ModTime().UnixNano(), fileOwnership(info))
+}
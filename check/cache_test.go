@@ -0,0 +1,119 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// countingRunner passes the checks, counting the ones it runs.
+type countingRunner map[string]int
+
+func (r countingRunner) Run(c *Check) State {
+	r[c.ID]++
+	c.State = PASS
+	c.ActualValue = "600"
+	return c.State
+}
+
+func writeProcessStat(t *testing.T, pid, comm, start string) {
+	stat := fmt.Sprintf("%s (%s) S 1 %s 0 0 -1 4194560 1 0 0 0 0 0 0 0 20 0 1 0 %s 0 0\n", pid, comm, pid, start)
+	if err := ioutil.WriteFile(filepath.Join(procRoot, pid, "stat"), []byte(stat), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestCachingRunner(t *testing.T) {
+	defer withProcesses(t, map[string][]string{"812": {"/usr/bin/kubelet", "--anonymous-auth=false"}})()
+	writeProcessStat(t, "812", "kubelet", "1000")
+
+	dir, err := ioutil.TempDir("", "kube-bench-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	file := filepath.Join(dir, "kubelet.conf")
+	if err := ioutil.WriteFile(file, []byte("kind: Config\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	checks := []*Check{
+		{ID: "4.1.1", Audit: "/bin/sh -c 'if test -e " + file + "; then stat -c %a " + file + " 2>/dev/null; fi'"},
+		{ID: "4.2.1", Audit: "/bin/ps -ef | grep kubelet | grep -v grep"},
+		// The results of checks querying the cluster, or looking at pseudo
+		// files, are not cached.
+		{ID: "4.2.2", Audit: "kubectl get nodes --kubeconfig " + file},
+		{ID: "4.2.3", Audit: "cat /proc/sys/net/ipv4/ip_forward"},
+		{ID: "4.2.4", Type: FILE, Audit: file},
+	}
+	cache := NewResultCache("test")
+	runner := countingRunner{}
+	scan := func() {
+		r := NewCachingRunner(runner, cache)
+		for _, c := range checks {
+			c.State, c.ActualValue = "", ""
+			if state := r.Run(c); state != PASS || c.ActualValue != "600" {
+				t.Errorf("%s: unexpected result %s %q", c.ID, state, c.ActualValue)
+			}
+		}
+	}
+
+	scan()
+	scan()
+	expected := countingRunner{"4.1.1": 1, "4.2.1": 1, "4.2.2": 2, "4.2.3": 2, "4.2.4": 2}
+	for id, n := range expected {
+		if runner[id] != n {
+			t.Errorf("%s: expected %d runs, got %d", id, n, runner[id])
+		}
+	}
+	if cache.Hits != 2 || cache.Misses != 2 {
+		t.Errorf("expected 2 hits and 2 misses, got %d and %d", cache.Hits, cache.Misses)
+	}
+
+	// Changing the permissions of the file and restarting the kubelet
+	// invalidates the results.
+	if err := os.Chmod(file, 0644); err != nil {
+		t.Fatal(err)
+	}
+	writeProcessStat(t, "812", "kubelet", "2000")
+	cache.Prune()
+	scan()
+	if runner["4.1.1"] != 2 || runner["4.2.1"] != 2 {
+		t.Errorf("expected the checks to run again, got %v", runner)
+	}
+
+	// The results of the previous inputs are dropped.
+	cache.Prune()
+	if len(cache.Results) != 2 {
+		t.Errorf("expected 2 cached results, got %d", len(cache.Results))
+	}
+}
+
+func TestCachingRunnerChangedCheck(t *testing.T) {
+	cache := NewResultCache("test")
+	runner := countingRunner{}
+	c := &Check{ID: "1.1.1", Audit: "stat -c %a /nonexistent/kube-apiserver.yaml", Tests: &tests{TestItems: []*testItem{{Flag: "644"}}}}
+
+	NewCachingRunner(runner, cache).Run(c)
+	c.Tests.TestItems[0].Flag = "600"
+	NewCachingRunner(runner, cache).Run(c)
+	if runner["1.1.1"] != 2 {
+		t.Errorf("expected the changed check to run again, got %d runs", runner["1.1.1"])
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+
+	"gopkg.in/yaml.v2"
+)
+
+// Catalog holds the messages of a controls file in another language, keyed
+// by the IDs of its groups and checks, e.g.
+//
+//	text: "Configuration de sécurité du nœud maître"
+//	groups:
+//	  1.1: "Fichiers de configuration du nœud maître"
+//	checks:
+//	  1.1.1:
+//	    text: "..."
+//	    remediation: "..."
+type Catalog struct {
+	Text   string                     `yaml:"text"`
+	Groups map[string]string          `yaml:"groups"`
+	Checks map[string]CatalogMessages `yaml:"checks"`
+}
+
+// CatalogMessages are the messages of a check in another language.
+type CatalogMessages struct {
+	Text        string `yaml:"text"`
+	Rationale   string `yaml:"rationale"`
+	Remediation string `yaml:"remediation"`
+}
+
+// NewCatalog parses a message catalog.
+func NewCatalog(in []byte) (*Catalog, error) {
+	c := new(Catalog)
+	if err := yaml.Unmarshal(in, c); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal YAML: %s", err)
+	}
+	return c, nil
+}
+
+// Translate replaces the texts, rationales and remediations of the controls
+// with the messages of the catalog. The messages missing from the catalog
+// are left untranslated.
+func (controls *Controls) Translate(catalog *Catalog) {
+	if catalog.Text != "" {
+		controls.Text = catalog.Text
+	}
+
+	for _, g := range controls.Groups {
+		if text := catalog.Groups[g.ID]; text != "" {
+			g.Text = text
+		}
+
+		for _, c := range g.Checks {
+			m, ok := catalog.Checks[c.ID]
+			if !ok {
+				continue
+			}
+			if m.Text != "" {
+				c.Text = m.Text
+			}
+			if m.Rationale != "" {
+				c.Rationale = m.Rationale
+			}
+			if m.Remediation != "" {
+				c.Remediation = m.Remediation
+			}
+		}
+	}
+}
@@ -0,0 +1,81 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"testing"
+)
+
+func TestControlsTranslate(t *testing.T) {
+	controls, err := NewControls(MASTER, []byte(`---
+controls:
+id: 1
+text: "Master Node Security Configuration"
+type: "master"
+groups:
+  - id: 1.1
+    text: "Master Node Configuration Files"
+    checks:
+      - id: 1.1.1
+        text: "Ensure that the API server pod specification file permissions are set to 644 or more restrictive (Scored)"
+        audit: "stat -c %a /etc/kubernetes/manifests/kube-apiserver.yaml"
+        remediation: "chmod 644 /etc/kubernetes/manifests/kube-apiserver.yaml"
+        scored: true
+      - id: 1.1.10
+        text: "Ensure that the Container Network Interface file ownership is set to root:root (Not Scored)"
+        remediation: "chown root:root <path/to/cni/files>"
+        scored: false
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	catalog, err := NewCatalog([]byte(`
+text: "Configuration de sécurité du nœud maître"
+groups:
+  1.1: "Fichiers de configuration du nœud maître"
+checks:
+  1.1.1:
+    text: "Vérifier que les permissions du fichier de spécification du pod API server sont 644 ou plus restrictives (Scored)"
+  1.1.10:
+    remediation: "chown root:root <chemin/des/fichiers/cni>"
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	controls.Translate(catalog)
+
+	g := controls.Groups[0]
+	if controls.ID != "1" || controls.Text != "Configuration de sécurité du nœud maître" || g.ID != "1.1" || g.Text != "Fichiers de configuration du nœud maître" {
+		t.Errorf("unexpected controls %s %q, group %s %q", controls.ID, controls.Text, g.ID, g.Text)
+	}
+
+	first, second := g.Checks[0], g.Checks[1]
+	if first.ID != "1.1.1" || first.Text != "Vérifier que les permissions du fichier de spécification du pod API server sont 644 ou plus restrictives (Scored)" {
+		t.Errorf("unexpected check %s %q", first.ID, first.Text)
+	}
+	// Messages missing from the catalog are left untranslated.
+	if first.Remediation != "chmod 644 /etc/kubernetes/manifests/kube-apiserver.yaml" {
+		t.Errorf("unexpected remediation %q", first.Remediation)
+	}
+	if second.ID != "1.1.10" || second.Text != "Ensure that the Container Network Interface file ownership is set to root:root (Not Scored)" || second.Remediation != "chown root:root <chemin/des/fichiers/cni>" {
+		t.Errorf("unexpected check %s %q %q", second.ID, second.Text, second.Remediation)
+	}
+
+	if _, err := NewCatalog([]byte("checks: [")); err == nil {
+		t.Errorf("expected an error for an invalid catalog")
+	}
+}
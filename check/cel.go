@@ -0,0 +1,104 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"math"
+	"os"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/google/cel-go/cel"
+	"github.com/google/cel-go/checker/decls"
+)
+
+// evalCEL evaluates a CEL expression against the variables of a cel test,
+// which are those of the input of a rego test.
+func evalCEL(expr string, input regoInput) (interface{}, error) {
+	env, err := cel.NewEnv(cel.Declarations(
+		decls.NewIdent("output", decls.String, nil),
+		decls.NewIdent("flags", decls.NewMapType(decls.String, decls.String), nil),
+		decls.NewIdent("config", decls.Dyn, nil),
+	))
+	if err != nil {
+		return nil, err
+	}
+	ast, issues := env.Compile(expr)
+	if issues != nil && issues.Err() != nil {
+		return nil, issues.Err()
+	}
+	prg, err := env.Program(ast)
+	if err != nil {
+		return nil, err
+	}
+	out, _, err := prg.Eval(map[string]interface{}{
+		"output": input.Output,
+		"flags":  input.Flags,
+		"config": celValue(input.Config),
+	})
+	if err != nil {
+		return nil, err
+	}
+	return out.Value(), nil
+}
+
+// celValue converts the whole numbers of a config decoded from JSON to
+// integers, so that they compare with the integer literals of CEL like
+// those of a config decoded from YAML.
+func celValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = celValue(e)
+		}
+	case []interface{}:
+		for i, e := range v {
+			v[i] = celValue(e)
+		}
+	case float64:
+		if v == math.Trunc(v) && math.Abs(v) < 1<<53 {
+			return int64(v)
+		}
+	case int:
+		return int64(v)
+	}
+	return v
+}
+
+// executeCEL passes when the CEL expression of the test item evaluates to
+// true against the output.
+func (t *testItem) executeCEL(s string) *testOutput {
+	input := regoInput{Output: s, Flags: parseFlags(s)}
+	var config interface{}
+	if err := unmarshal(s, &config); err == nil {
+		input.Config = convertYAMLMaps(config)
+	}
+
+	expr := strings.TrimSpace(t.CEL)
+	result, err := evalCEL(expr, input)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to evaluate CEL expression \"%s\": %v\n", expr, err)
+		return failTestItem("error evaluating CEL expression")
+	}
+	glog.V(3).Infof("CEL expression %q: %v", expr, result)
+
+	satisfied, ok := result.(bool)
+	if !ok {
+		fmt.Fprintf(os.Stderr, "CEL expression \"%s\" is not a boolean: %v\n", expr, result)
+		return failTestItem("CEL expression is not a boolean")
+	}
+	return &testOutput{testResult: satisfied, ExpectedResult: fmt.Sprintf("'%s' holds", expr)}
+}
@@ -0,0 +1,48 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"testing"
+)
+
+func TestExecuteCEL(t *testing.T) {
+	const config = "authentication:\n  anonymous:\n    enabled: false\n  webhook:\n    enabled: true\nreadOnlyPort: 0\n"
+
+	cases := []struct {
+		name     string
+		cel      string
+		output   string
+		expected bool
+	}{
+		{"config holds", "!config.authentication.anonymous.enabled && config.authentication.webhook.enabled", config, true},
+		{"config does not hold", "config.authentication.anonymous.enabled || !config.authentication.webhook.enabled", config, false},
+		{"integer", "config.readOnlyPort == 0", config, true},
+		{"integer in JSON", "config.readOnlyPort == 0", `{"readOnlyPort": 0}`, true},
+		{"missing key", "has(config.authorization) && config.authorization.mode == 'Webhook'", config, false},
+		{"flags", `flags["--anonymous-auth"] == "false"`, "/usr/bin/kubelet --anonymous-auth=false", true},
+		{"output", `output.contains("--anonymous-auth=false")`, "/usr/bin/kubelet --anonymous-auth=true", false},
+		{"not a boolean", "config.readOnlyPort", config, false},
+		{"parse error", "config.authentication ==", config, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ti := &testItem{CEL: c.cel}
+			if result := ti.execute(c.output); result.testResult != c.expected {
+				t.Errorf("expected %t, got %t", c.expected, result.testResult)
+			}
+		})
+	}
+}
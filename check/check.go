@@ -22,6 +22,7 @@ import (
 	"os/exec"
 	"regexp"
 	"strings"
+	"time"
 
 	"github.com/golang/glog"
 )
@@ -41,14 +42,13 @@ const (
 	WARN State = "WARN"
 	// INFO informational message
 	INFO State = "INFO"
+	// SKIP check was skipped.
+	SKIP State = "SKIP"
 
 	// MASTER a master node
 	MASTER NodeType = "master"
 	// NODE a node
 	NODE NodeType = "node"
-	// FEDERATED a federated deployment.
-	FEDERATED NodeType = "federated"
-
 	// ETCD an etcd node
 	ETCD NodeType = "etcd"
 	// CONTROLPLANE a control plane node
@@ -60,29 +60,140 @@ const (
 
 	// MANUAL Check Type
 	MANUAL string = "manual"
+	// TLS Check Type
+	TLS string = "tls"
+	// HTTP Check Type
+	HTTP string = "http"
+	// ETCDCONN Check Type
+	ETCDCONN string = "etcd"
+	// FILE Check Type
+	FILE string = "file"
+	// ADMISSION Check Type
+	ADMISSION string = "admission"
+	// API Check Type
+	API string = "api"
+	// PROCESS Check Type
+	PROCESS string = "process"
+	// CONFIGZ Check Type
+	CONFIGZ string = "configz"
+	// PERMISSIONS Check Type
+	PERMISSIONS string = "permissions"
+	// SECURITYMODULES Check Type
+	SECURITYMODULES string = "security_modules"
+	// SYSCTL Check Type
+	SYSCTL string = "sysctl"
 )
 
 // Check contains information about a recommendation in the
 // CIS Kubernetes document.
 type Check struct {
-	ID             string      `yaml:"id" json:"test_number"`
-	Text           string      `json:"test_desc"`
-	Audit          string      `json:"audit"`
-	AuditConfig    string      `yaml:"audit_config"`
-	Type           string      `json:"type"`
-	Commands       []*exec.Cmd `json:"omit"`
-	ConfigCommands []*exec.Cmd `json:"omit"`
-	Tests          *tests      `json:"omit"`
-	Set            bool        `json:"omit"`
-	Remediation    string      `json:"remediation"`
-	TestInfo       []string    `json:"test_info"`
+	ID             string            `yaml:"id" json:"test_number"`
+	Text           string            `json:"test_desc"`
+	Audit          string            `json:"audit"`
+	AuditConfig    string            `yaml:"audit_config"`
+	AuditOptions   map[string]string `yaml:"audit_options" json:"-"`
+	UseSudo        bool              `yaml:"use_sudo" json:"-"`
+	Retries        int               `yaml:"retries" json:"-"`
+	Type           string            `json:"type"`
+	Commands       []*exec.Cmd       `json:"-"`
+	ConfigCommands []*exec.Cmd       `json:"-"`
+	Tests          *tests            `json:"-"`
+	Set            bool              `json:"-"`
+	Remediation    string            `json:"remediation"`
+	Rationale      string            `json:"rationale,omitempty"`
+	Reference      string            `json:"reference,omitempty"`
+	TestInfo       []string          `json:"test_info"`
 	State          `json:"status"`
 	ActualValue    string `json:"actual_value"`
 	Scored         bool   `json:"scored"`
 	ExpectedResult string `json:"expected_result"`
-	Reason     string `json:"reason,omitempty"`
+	Reason         string `json:"reason,omitempty"`
+	// Duration is the time taken to run the check, in seconds.
+	Duration float64 `yaml:"-" json:"duration_seconds,omitempty"`
+	// Errors are what prevented the check from being carried out.
+	Errors []CheckError `yaml:"-" json:"errors,omitempty"`
+	// Remote is how the check is carried out against a remote member of
+	// its component over the network, e.g. a remote etcd member.
+	Remote *Check `yaml:"remote" json:"-"`
+	// Unavailable is why the check can't be carried out from this node,
+	// it then generates WARN.
+	Unavailable string `yaml:"-" json:"-"`
+	// AuditPath and AuditConfigPath select the value at a JSONPath in the
+	// output of the audit and audit_config, to be tested instead of it.
+	AuditPath       string `yaml:"audit_path" json:"-"`
+	AuditConfigPath string `yaml:"audit_config_path" json:"-"`
+	// RemediationSteps is the structured form of the remediation: the file
+	// edits, flag changes and commands fixing the check.
+	RemediationSteps []RemediationStep `yaml:"remediation_steps" json:"remediation_steps,omitempty"`
+	// Severity is how bad failing the check is: critical, high, medium or
+	// low.
+	Severity string `yaml:"severity" json:"severity,omitempty"`
+	// Ignored is why the check is ignored on this cluster, e.g. an accepted
+	// risk, it then generates SKIP.
+	Ignored string `yaml:"-" json:"-"`
+	// Flaky tells that the state of the check changed between scans while
+	// its inputs didn't, e.g. because its audit is nondeterministic.
+	Flaky bool `yaml:"-" json:"flaky,omitempty"`
+	// CISID is the number of the recommendation of the CIS benchmark the
+	// check implements, when it differs from its ID, e.g. after the
+	// recommendations were renumbered by a new version of the benchmark.
+	CISID string `yaml:"cis_id" json:"cis_id,omitempty"`
+}
+
+// ErrorKind is the kind of an error that prevented checks from being
+// carried out.
+type ErrorKind string
+
+const (
+	// AuditError an audit could not be run.
+	AuditError ErrorKind = "audit"
+	// MissingFileError a file used by audits does not exist.
+	MissingFileError ErrorKind = "missing_file"
+	// PermissionError kube-bench lacks the privileges to run an audit.
+	PermissionError ErrorKind = "permission"
+	// MissingDependencyError a command run by an audit is not available.
+	MissingDependencyError ErrorKind = "missing_dependency"
+	// TimeoutError an audit did not complete in time.
+	TimeoutError ErrorKind = "timeout"
+	// TruncatedOutputError the output of an audit was truncated to the
+	// output limit of audits, the tests only saw part of it.
+	TruncatedOutputError ErrorKind = "truncated_output"
+)
+
+// CheckError is an error that prevented a check, or a run of checks, from
+// being carried out. It tells checks that could not be checked from checks
+// that failed.
+type CheckError struct {
+	Kind    ErrorKind `json:"kind"`
+	Message string    `json:"message"`
 }
 
+// auditorFunc gathers the output of a check natively instead of running
+// its audit as a shell command. The output is evaluated by the check's tests.
+type auditorFunc func(c *Check) (string, error)
+
+// auditors maps check types to their native auditor.
+var auditors = map[string]auditorFunc{
+	TLS:             auditTLS,
+	HTTP:            auditHTTP,
+	ETCDCONN:        auditEtcd,
+	FILE:            auditFile,
+	ADMISSION:       auditAdmission,
+	API:             auditAPI,
+	PROCESS:         auditProcess,
+	CONFIGZ:         auditConfigz,
+	PERMISSIONS:     auditPermissions,
+	SECURITYMODULES: auditSecurityModules,
+	SYSCTL:          auditSysctl,
+}
+
+// geteuid is replaced in tests.
+var geteuid = os.Geteuid
+
+// retryBackoff is the delay before the first retry of a failed audit, it
+// doubles with each retry.
+var retryBackoff = time.Second
+
 // Runner wraps the basic Run method.
 type Runner interface {
 	// Run runs a given check and returns the execution state.
@@ -104,6 +215,20 @@ func (r *defaultRunner) Run(c *Check) State {
 // the results.
 func (c *Check) run() State {
 
+	// Checks that can't be carried out from this node generate WARN.
+	if c.Unavailable != "" {
+		c.Reason = c.Unavailable
+		c.State = WARN
+		c.AddError(AuditError, c.Unavailable)
+		return c.State
+	}
+
+	if c.Ignored != "" {
+		c.Reason = c.Ignored
+		c.State = SKIP
+		return c.State
+	}
+
 	// Since this is an Scored check
 	// without tests return a 'WARN' to alert
 	// the user that this check needs attention
@@ -113,10 +238,10 @@ func (c *Check) run() State {
 		return c.State
 	}
 
-	// If check type is skip, force result to INFO
+	// If check type is skip, force result to SKIP
 	if c.Type == "skip" {
 		c.Reason = "Test marked as skip"
-		c.State = INFO
+		c.State = SKIP
 		return c.State
 	}
 
@@ -127,13 +252,32 @@ func (c *Check) run() State {
 		return c.State
 	}
 
+	// Recorded output was gathered with the privileges of the recording.
+	if !replaying() {
+		if reason := c.privilegeIssue(); reason != "" {
+			c.Reason = reason
+			c.State = WARN
+			c.AddError(PermissionError, reason)
+			return c.State
+		}
+	}
+
+	if auditor, ok := auditors[c.Type]; ok {
+		return c.runAuditor(auditor)
+	}
+
+	if c.missingDependencies(c.Audit) {
+		return c.State
+	}
+
 	lastCommand := c.Audit
 	hasAuditConfig := c.ConfigCommands != nil
 
-	state, finalOutput, retErrmsgs := performTest(c.Audit, c.Commands, c.Tests)
+	state, finalOutput, retErrmsgs := c.performTestWithRetries(c.Audit, c.AuditPath, c.commands(c.Commands), c.Tests)
 	if len(state) > 0 {
 		c.Reason = retErrmsgs
 		c.State = state
+		c.auditFailed(c.Audit, retErrmsgs)
 		return c.State
 	}
 	errmsgs := retErrmsgs
@@ -142,6 +286,9 @@ func (c *Check) run() State {
 	// and an 'AuditConfig' command was provided, use it to
 	// execute tests
 	if (finalOutput == nil || !finalOutput.testResult) && hasAuditConfig {
+		if c.missingDependencies(c.AuditConfig) {
+			return c.State
+		}
 		lastCommand = c.AuditConfig
 
 		nItems := len(c.Tests.TestItems)
@@ -162,19 +309,147 @@ func (c *Check) run() State {
 				Path:    ti.Path,
 				Set:     ti.Set,
 				Compare: ti.Compare,
+				Rego:    ti.Rego,
+				CEL:     ti.CEL,
 			}
 			currentTests.TestItems[i] = nti
 		}
 
-		state, finalOutput, retErrmsgs = performTest(c.AuditConfig, c.ConfigCommands, currentTests)
+		state, finalOutput, retErrmsgs = c.performTestWithRetries(c.AuditConfig, c.AuditConfigPath, c.commands(c.ConfigCommands), currentTests)
 		if len(state) > 0 {
 			c.Reason = retErrmsgs
 			c.State = state
+			c.auditFailed(c.AuditConfig, retErrmsgs)
 			return c.State
 		}
 		errmsgs += retErrmsgs
 	}
 
+	c.setState(finalOutput, errmsgs)
+	if c.State == FAIL && !replaying() {
+		if reason := c.unreadableFileIssue(); reason != "" {
+			c.Reason = reason
+			c.State = WARN
+			c.AddError(PermissionError, reason)
+		}
+	}
+	if finalOutput != nil && finalOutput.truncated != "" {
+		c.outputTruncated(lastCommand, finalOutput.truncated)
+	}
+
+	if finalOutput != nil {
+		glog.V(3).Infof("Check.ID: %s Command: %q TestResult: %t State: %q \n", c.ID, lastCommand, finalOutput.testResult, c.State)
+	} else {
+		glog.V(3).Infof("Check.ID: %s Command: %q TestResult: <<EMPTY>> \n", c.ID, lastCommand)
+	}
+
+	if errmsgs != "" {
+		glog.V(2).Info(errmsgs)
+	}
+	return c.State
+}
+
+// runAuditor evaluates the check's tests against the output of a native auditor.
+func (c *Check) runAuditor(auditor auditorFunc) State {
+	out, err := runNativeAudit(c, auditor)
+	for attempt := 1; err != nil && attempt <= c.Retries; attempt++ {
+		c.waitBeforeRetry(attempt, c.Audit, err.Error())
+		out, err = runNativeAudit(c, auditor)
+	}
+	if err != nil {
+		c.Reason = err.Error()
+		if c.Retries > 0 {
+			c.Reason = fmt.Sprintf("%s (after %d attempts)", c.Reason, c.Retries+1)
+		}
+		c.State = WARN
+		c.AddError(auditErrorKind(err), c.Reason)
+		return c.State
+	}
+
+	finalOutput := c.Tests.execute(out)
+	c.setState(finalOutput, "")
+
+	glog.V(3).Infof("Check.ID: %s Type: %q Audit: %q TestResult: %t State: %q \n", c.ID, c.Type, c.Audit, finalOutput.testResult, c.State)
+	return c.State
+}
+
+// missingDependencies reports whether commands run by an audit of the
+// check are not available, in which case the check generates WARN.
+func (c *Check) missingDependencies(audit string) bool {
+	// Recorded output doesn't need the commands.
+	if replaying() {
+		return false
+	}
+	missing := missingDependencies(audit)
+	if len(missing) == 0 {
+		return false
+	}
+
+	c.Reason = "missing dependency: " + strings.Join(missing, ", ")
+	c.State = WARN
+	c.AddError(MissingDependencyError, c.Reason)
+	return true
+}
+
+// AddError records an error that prevented the check from being carried out.
+func (c *Check) AddError(kind ErrorKind, message string) {
+	c.Errors = append(c.Errors, CheckError{Kind: kind, Message: message})
+}
+
+// auditFailed records that an audit of the check could not be run.
+func (c *Check) auditFailed(audit, errmsgs string) {
+	message := strings.TrimSpace(errmsgs)
+	if message == "" {
+		message = fmt.Sprintf("failed to run %q", audit)
+	}
+	c.AddError(AuditError, message)
+}
+
+// outputTruncated records that the tests of the check only saw part of the
+// output of the audit. They may have failed on what was cut off, so the
+// check generates WARN rather than FAIL.
+func (c *Check) outputTruncated(audit, note string) {
+	message := fmt.Sprintf("%s of %q", note, audit)
+	c.AddError(TruncatedOutputError, message)
+	if c.State == FAIL {
+		c.State = WARN
+		c.Reason = message
+	}
+}
+
+// performTestWithRetries runs an audit and evaluates its output, retrying
+// with backoff while the audit fails to run, up to the check's retries. An
+// audit that still fails is reported as WARN rather than FAIL. Output that
+// doesn't pass the tests, e.g. of a grep finding no match, is not retried.
+func (c *Check) performTestWithRetries(audit, path string, commands []*exec.Cmd, tests *tests) (State, *testOutput, string) {
+	state, finalOutput, errmsgs, interrupted := performTest(audit, path, commands, tests)
+	for attempt := 1; attempt <= c.Retries && transientFailure(state, finalOutput, interrupted); attempt++ {
+		c.waitBeforeRetry(attempt, audit, errmsgs)
+		// Commands can only be run once.
+		state, finalOutput, errmsgs, interrupted = performTest(audit, path, c.commands(textToCommand(audit)), tests)
+	}
+
+	if c.Retries > 0 && transientFailure(state, finalOutput, interrupted) {
+		return WARN, nil, fmt.Sprintf("audit failed after %d attempts: %s", c.Retries+1, errmsgs)
+	}
+	return state, finalOutput, errmsgs
+}
+
+// transientFailure reports whether an audit failed because its commands
+// could not run to completion, rather than its output not passing the
+// tests. Missing commands are not transient.
+func transientFailure(state State, finalOutput *testOutput, interrupted bool) bool {
+	return len(state) == 0 && interrupted && (finalOutput == nil || !finalOutput.testResult)
+}
+
+func (c *Check) waitBeforeRetry(attempt int, audit, errmsgs string) {
+	delay := retryBackoff << uint(attempt-1)
+	glog.V(2).Infof("Check.ID: %s Audit: %q failed, retrying in %s: %s", c.ID, audit, delay, strings.TrimSpace(errmsgs))
+	time.Sleep(delay)
+}
+
+// setState sets the state of the check from the output of its tests.
+func (c *Check) setState(finalOutput *testOutput, errmsgs string) {
 	if finalOutput != nil && finalOutput.testResult {
 		c.State = PASS
 		c.ActualValue = finalOutput.actualResult
@@ -187,17 +462,27 @@ func (c *Check) run() State {
 			c.State = WARN
 		}
 	}
+}
 
-	if finalOutput != nil {
-		glog.V(3).Infof("Check.ID: %s Command: %q TestResult: %t State: %q \n", c.ID, lastCommand, finalOutput.testResult, c.State)
-	} else {
-		glog.V(3).Infof("Check.ID: %s Command: %q TestResult: <<EMPTY>> \n", c.ID, lastCommand)
+// commands returns the commands to run for an audit of the check, through
+// sudo if the check needs it and kube-bench is not running as root, and with
+// the niceness and I/O scheduling class of the audit limits.
+func (c *Check) commands(cmds []*exec.Cmd) []*exec.Cmd {
+	sudo := c.UseSudo && geteuid() != 0
+	if !sudo && auditLimits.Nice == 0 && auditLimits.IOClass == "" {
+		return cmds
 	}
 
-	if errmsgs != "" {
-		glog.V(2).Info(errmsgs)
+	wrapped := make([]*exec.Cmd, 0, len(cmds))
+	for _, cmd := range cmds {
+		args := limitArgs(cmd.Args)
+		if sudo {
+			// Never prompt for a password, the audit fails instead.
+			args = append([]string{"sudo", "-n"}, args...)
+		}
+		wrapped = append(wrapped, exec.Command(args[0], args[1:]...))
 	}
-	return c.State
+	return wrapped
 }
 
 // textToCommand transforms an input text representation of commands to be
@@ -240,6 +525,7 @@ func textToCommand(s string) []*exec.Cmd {
 			cs = strings.Split(v, " ")
 		}
 
+		cs = shellArgs(cs)
 		cmd := exec.Command(cs[0], cs[1:]...)
 		cmds = append(cmds, cmd)
 	}
@@ -248,48 +534,79 @@ func textToCommand(s string) []*exec.Cmd {
 }
 
 func isShellCommand(s string) bool {
-	cmd := exec.Command("/bin/sh", "-c", "command -v "+s)
-
-	out, err := cmd.Output()
-	if err != nil {
-		exitWithError(fmt.Errorf("failed to check if command: %q is valid %v", s, err))
-	}
-
-	if strings.Contains(string(out), s) {
-		return true
-	}
-	return false
+	return commandAvailable(s)
 }
 
-func performTest(audit string, commands []*exec.Cmd, tests *tests) (State, *testOutput, string) {
+func performTest(audit, path string, commands []*exec.Cmd, tests *tests) (State, *testOutput, string, bool) {
 	if len(strings.TrimSpace(audit)) == 0 {
-		return "", failTestItem("missing command"), "missing audit command"
+		return "", failTestItem("missing command"), "missing audit command", false
 	}
 
 	var out bytes.Buffer
-	state, retErrmsgs := runExecCommands(audit, commands, &out)
+	state, retErrmsgs, interrupted, truncated := runAudit(audit, commands, &out)
 	if len(state) > 0 {
-		return state, nil, retErrmsgs
+		return state, nil, retErrmsgs, false
 	}
 	errmsgs := retErrmsgs
 
-	finalOutput := tests.execute(out.String())
+	output := out.String()
+	if path != "" {
+		selected, err := selectOutput(output, path)
+		if err != nil {
+			return WARN, nil, fmt.Sprintf("failed to select %s in the output of %s: %v", path, audit, err), false
+		}
+		output = selected
+	}
+
+	finalOutput := tests.execute(output)
 	if finalOutput == nil {
 		errmsgs += fmt.Sprintf("Final output is <<EMPTY>>. Failed to run: %s\n", audit)
+	} else {
+		finalOutput.truncated = truncated
+	}
+
+	return "", finalOutput, errmsgs, interrupted
+}
+
+// auditOutput runs an audit command and returns its output.
+func auditOutput(audit string) (string, error) {
+	var out bytes.Buffer
+	capped := capOutput(&out)
+	state, errmsgs := runExecCommands(audit, textToCommand(audit), capped)
+	glog.V(3).Infof("Command %q - Output:\n\n %q\n - Error Messages:%q \n", audit, out.String(), errmsgs)
+	if len(state) > 0 {
+		return "", fmt.Errorf("failed to run %q: %s", audit, errmsgs)
+	}
+	if capped.truncated {
+		glog.V(1).Infof("Output of %q truncated to %d bytes", audit, auditLimits.MaxOutput)
 	}
+	return out.String(), nil
+}
 
-	return "", finalOutput, errmsgs
+func runExecCommands(audit string, commands []*exec.Cmd, out io.Writer) (State, string) {
+	state, errmsgs, _ := execCommands(audit, commands, out)
+	return state, errmsgs
 }
 
-func runExecCommands(audit string, commands []*exec.Cmd, out *bytes.Buffer) (State, string) {
+// execCommands runs the commands of an audit as a pipeline. It also reports
+// whether a command could not run to completion: it failed to start, or was
+// killed. A command exiting with a non-zero status, like grep finding no
+// match, ran to completion.
+func execCommands(audit string, commands []*exec.Cmd, out io.Writer) (State, string, bool) {
+	if name, ok := psAuditProcess(audit); ok {
+		state, errmsgs := writeProcesses(name, out)
+		return state, errmsgs, false
+	}
+
 	var err error
 	errmsgs := ""
+	interrupted := false
 
 	// Check if command exists or exit with WARN.
 	for _, cmd := range commands {
 		if !isShellCommand(cmd.Path) {
 			errmsgs += fmt.Sprintf("Command '%s' not found\n", cmd.Path)
-			return WARN, errmsgs
+			return WARN, errmsgs, false
 		}
 	}
 
@@ -297,7 +614,7 @@ func runExecCommands(audit string, commands []*exec.Cmd, out *bytes.Buffer) (Sta
 	n := len(commands)
 	if n == 0 {
 		// Likely a warning message.
-		return WARN, errmsgs
+		return WARN, errmsgs, false
 	}
 
 	// Each command runs,
@@ -313,6 +630,7 @@ func runExecCommands(audit string, commands []*exec.Cmd, out *bytes.Buffer) (Sta
 		cs[i-1].Stdout, err = cs[i].StdinPipe()
 		if err != nil {
 			errmsgs += fmt.Sprintf("failed to run: %s, command: %s, error: %s\n", audit, cs[i].Args, err)
+			interrupted = true
 		}
 		i++
 	}
@@ -323,6 +641,7 @@ func runExecCommands(audit string, commands []*exec.Cmd, out *bytes.Buffer) (Sta
 		err := cs[i].Start()
 		if err != nil {
 			errmsgs += fmt.Sprintf("failed to run: %s, command: %s, error: %s\n", audit, cs[i].Args, err)
+			interrupted = true
 		}
 		i++
 	}
@@ -333,6 +652,9 @@ func runExecCommands(audit string, commands []*exec.Cmd, out *bytes.Buffer) (Sta
 		err := cs[i].Wait()
 		if err != nil {
 			errmsgs += fmt.Sprintf("failed to run: %s, command: %s, error: %s\n", audit, cs[i].Args, err)
+			if exitErr, ok := err.(*exec.ExitError); !ok || !exitErr.Exited() {
+				interrupted = true
+			}
 		}
 
 		if i < n-1 {
@@ -341,8 +663,7 @@ func runExecCommands(audit string, commands []*exec.Cmd, out *bytes.Buffer) (Sta
 		i++
 	}
 
-	glog.V(3).Infof("Command %q - Output:\n\n %q\n - Error Messages:%q \n", audit, out.String(), errmsgs)
-	return "", errmsgs
+	return "", errmsgs, interrupted
 }
 
 func exitWithError(err error) {
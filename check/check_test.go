@@ -15,8 +15,16 @@
 package check
 
 import (
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"os"
 	"os/exec"
+	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 )
 
 func TestCheck_Run(t *testing.T) {
@@ -27,7 +35,8 @@ func TestCheck_Run(t *testing.T) {
 
 	testCases := []TestCase{
 		{check: Check{Type: MANUAL}, Expected: WARN},
-		{check: Check{Type: "skip"}, Expected: INFO},
+		{check: Check{Type: "skip"}, Expected: SKIP},
+		{check: Check{Scored: true, Tests: &tests{}, Ignored: "Ignored on this cluster"}, Expected: SKIP},
 
 		{check: Check{Scored: false}, Expected: WARN}, // Not scored checks with no type, or not scored failing tests are marked warn
 		{
@@ -111,3 +120,166 @@ func TestCheckAuditConfig(t *testing.T) {
 		}
 	}
 }
+
+func TestCheckCommandsUseSudo(t *testing.T) {
+	defer func(f func() int) { geteuid = f }(geteuid)
+
+	cmds := textToCommand("ps -ef | grep kubelet")
+	cases := []struct {
+		useSudo  bool
+		euid     int
+		expected [][]string
+	}{
+		{useSudo: false, euid: 1000, expected: [][]string{{"ps", "-ef"}, {"grep", "kubelet"}}},
+		// Already running as root.
+		{useSudo: true, euid: 0, expected: [][]string{{"ps", "-ef"}, {"grep", "kubelet"}}},
+		{useSudo: true, euid: 1000, expected: [][]string{{"sudo", "-n", "ps", "-ef"}, {"sudo", "-n", "grep", "kubelet"}}},
+	}
+
+	for _, c := range cases {
+		geteuid = func() int { return c.euid }
+		check := &Check{UseSudo: c.useSudo}
+
+		var args [][]string
+		for _, cmd := range check.commands(cmds) {
+			args = append(args, cmd.Args)
+		}
+		if !reflect.DeepEqual(args, c.expected) {
+			t.Errorf("use_sudo %t, euid %d: expected %v, got %v", c.useSudo, c.euid, c.expected, args)
+		}
+	}
+}
+
+func TestCheckRetries(t *testing.T) {
+	defer withPrivileges(processPrivileges{root: true, hostPID: true})()
+	defer func(d time.Duration) { retryBackoff = d }(retryBackoff)
+	retryBackoff = time.Millisecond
+
+	dir, err := ioutil.TempDir("", "kube-bench-retries")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// The audit is killed until it has been run the given number of times.
+	runs := func(name string) int {
+		data, _ := ioutil.ReadFile(filepath.Join(dir, name))
+		return strings.Count(string(data), "\n")
+	}
+	flaky := func(name string, runs int) string {
+		counter := filepath.Join(dir, name)
+		return fmt.Sprintf("/bin/sh -c 'echo >> %s; if [ $(wc -l < %s) -ge %d ]; then echo --anonymous-auth=false; else kill -9 $$; fi'", counter, counter, runs)
+	}
+	anonymousAuth := &tests{TestItems: []*testItem{{Flag: "--anonymous-auth", Compare: compare{Op: "eq", Value: "false"}, Set: true}}}
+
+	cases := []struct {
+		name     string
+		audit    string
+		retries  int
+		expected State
+		reason   string
+		runs     int
+	}{
+		{"none", flaky("none", 2), 0, FAIL, "", 1},
+		{"recovers", flaky("recovers", 3), 2, PASS, "", 3},
+		{"fails", flaky("fails", 10), 2, WARN, "audit failed after 3 attempts", 3},
+		// Output that doesn't pass the tests is not retried, even when the
+		// audit exits with a non-zero status like grep finding no match.
+		{"genuine", fmt.Sprintf("/bin/sh -c 'echo >> %s; echo --anonymous-auth=true' | grep -- --anonymous-auth=false", filepath.Join(dir, "genuine")), 2, FAIL, "", 1},
+	}
+
+	for _, c := range cases {
+		check := &Check{ID: c.name, Audit: c.audit, Commands: textToCommand(c.audit), Tests: anonymousAuth, Retries: c.retries, Scored: true}
+		if state := check.run(); state != c.expected || !strings.Contains(check.Reason, c.reason) {
+			t.Errorf("%s: expected %s %q, got %s %q", c.name, c.expected, c.reason, state, check.Reason)
+		}
+		if n := runs(c.name); n != c.runs {
+			t.Errorf("%s: expected %d runs, got %d", c.name, c.runs, n)
+		}
+	}
+}
+
+func TestAuditorRetries(t *testing.T) {
+	defer func(d time.Duration) { retryBackoff = d }(retryBackoff)
+	retryBackoff = time.Millisecond
+
+	calls := 0
+	auditor := func(c *Check) (string, error) {
+		calls++
+		if calls < 3 {
+			return "", errors.New("timeout")
+		}
+		return "ok", nil
+	}
+	ok := &tests{TestItems: []*testItem{{Flag: "ok", Set: true}}}
+
+	c := &Check{ID: "1", Type: API, Tests: ok, Retries: 2}
+	if state := c.runAuditor(auditor); state != PASS {
+		t.Errorf("expected PASS after retries, got %s %q", state, c.Reason)
+	}
+
+	calls = 0
+	c = &Check{ID: "2", Type: API, Tests: ok, Retries: 1}
+	if state := c.runAuditor(auditor); state != WARN || c.Reason != "timeout (after 2 attempts)" {
+		t.Errorf("expected WARN, got %s %q", state, c.Reason)
+	}
+}
+
+func TestCheckErrors(t *testing.T) {
+	defer withPrivileges(processPrivileges{root: true, hostPID: true})()
+
+	anonymousAuth := &tests{TestItems: []*testItem{{Flag: "--anonymous-auth", Compare: compare{Op: "eq", Value: "false"}, Set: true}}}
+
+	// A check that failed could be carried out.
+	failed := &Check{ID: "4.2.1", Audit: "echo --anonymous-auth=true", Commands: textToCommand("echo --anonymous-auth=true"), Tests: anonymousAuth, Scored: true}
+	if state := failed.run(); state != FAIL || len(failed.Errors) != 0 {
+		t.Errorf("expected FAIL without errors, got %s %v", state, failed.Errors)
+	}
+
+	notVisible := &Check{ID: "4.2.2", Type: PROCESS, Tests: anonymousAuth}
+	func() {
+		defer withPrivileges(processPrivileges{root: true, hostPID: false})()
+		notVisible.run()
+	}()
+	if len(notVisible.Errors) != 1 || notVisible.Errors[0].Kind != PermissionError || notVisible.Errors[0].Message != notVisible.Reason {
+		t.Errorf("expected a permission error, got %s %v", notVisible.State, notVisible.Errors)
+	}
+
+	auditor := &Check{ID: "4.2.3", Type: API, Tests: anonymousAuth}
+	auditor.runAuditor(func(c *Check) (string, error) { return "", errors.New("connection refused") })
+	if !reflect.DeepEqual(auditor.Errors, []CheckError{{Kind: AuditError, Message: "connection refused"}}) {
+		t.Errorf("expected an audit error, got %v", auditor.Errors)
+	}
+}
+
+func TestCheckAuditConfigPath(t *testing.T) {
+	defer withPrivileges(processPrivileges{root: true, hostPID: true})()
+
+	f, err := ioutil.TempFile("", "kube-proxy-configmap")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.WriteString(`{"kind":"ConfigMap","data":{"config.conf":"kind: KubeProxyConfiguration\nmetricsBindAddress: 127.0.0.1:10249\n"}}`)
+	f.Close()
+
+	for path, expected := range map[string]State{`{.data.config\.conf}`: PASS, `{.data}`: FAIL} {
+		check := &Check{
+			ID:              "4.2.14",
+			Audit:           "echo",
+			AuditConfig:     "cat " + f.Name(),
+			AuditConfigPath: path,
+			Tests: &tests{TestItems: []*testItem{{
+				Path:    "{.metricsBindAddress}",
+				Set:     true,
+				Compare: compare{Op: "eq", Value: "127.0.0.1:10249"},
+			}}},
+			Scored: true,
+		}
+		check.Commands = textToCommand(check.Audit)
+		check.ConfigCommands = textToCommand(check.AuditConfig)
+		if state := check.run(); state != expected {
+			t.Errorf("%s: expected %s, got %s %q", path, expected, state, check.Reason)
+		}
+	}
+}
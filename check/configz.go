@@ -0,0 +1,95 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+const defaultConfigzURL = "https://127.0.0.1:10250/configz"
+
+// serviceAccountTokenFile is where the token of the pod's service account is
+// mounted. It is a variable so that tests can replace it.
+var serviceAccountTokenFile = "/var/run/secrets/kubernetes.io/serviceaccount/token"
+
+// auditConfigz fetches the live configuration of the kubelet from its
+// /configz endpoint, at the URL given in the check's audit field or the local
+// kubelet by default. The request is authenticated with the token in the
+// token_file audit option, the pod's service account token by default, and
+// the certfile, keyfile and cafile options are used as for "http" checks.
+// The output is the kubelet configuration, so that tests use the same paths
+// as for the kubelet config file, e.g. '{.authentication.anonymous.enabled}'.
+func auditConfigz(c *Check) (string, error) {
+	target := strings.TrimSpace(c.Audit)
+	if target == "" {
+		target = defaultConfigzURL
+	}
+
+	tlsConfig, err := httpTLSConfig(c.AuditOptions)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(http.MethodGet, target, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid URL %q: %v", target, err)
+	}
+
+	tokenFile := c.AuditOptions["token_file"]
+	if tokenFile == "" {
+		tokenFile = serviceAccountTokenFile
+	}
+	token, err := ioutil.ReadFile(tokenFile)
+	switch {
+	case err == nil:
+		req.Header.Set("Authorization", "Bearer "+strings.TrimSpace(string(token)))
+	case os.IsNotExist(err) && len(tlsConfig.Certificates) > 0:
+		// The client certificate authenticates the request.
+	default:
+		return "", fmt.Errorf("failed to read token: %v", err)
+	}
+
+	client := &http.Client{
+		Timeout:   httpTimeout,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("failed to get kubelet configuration: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("unexpected status code %d from %s", resp.StatusCode, target)
+	}
+
+	var configz struct {
+		KubeletConfig json.RawMessage `json:"kubeletconfig"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&configz); err != nil {
+		return "", fmt.Errorf("invalid kubelet configuration from %s: %v", target, err)
+	}
+	if len(configz.KubeletConfig) == 0 {
+		return "", fmt.Errorf("no kubelet configuration in the response from %s", target)
+	}
+
+	glog.V(3).Infof("kubelet configuration from %s: %s", target, configz.KubeletConfig)
+	return string(configz.KubeletConfig), nil
+}
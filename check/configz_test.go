@@ -0,0 +1,77 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheck_RunConfigz(t *testing.T) {
+	kubelet := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer secret" {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		fmt.Fprint(w, `{"kubeletconfig":{"authentication":{"anonymous":{"enabled":false}},"readOnlyPort":0}}`)
+	}))
+	defer kubelet.Close()
+
+	dir, err := ioutil.TempDir("", "kube-bench-configz")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	token := filepath.Join(dir, "token")
+	if err := ioutil.WriteFile(token, []byte("secret\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	wrongToken := filepath.Join(dir, "wrong-token")
+	if err := ioutil.WriteFile(wrongToken, []byte("wrong"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := serviceAccountTokenFile
+	defer func() { serviceAccountTokenFile = saved }()
+	serviceAccountTokenFile = token
+
+	anonymousDisabled := []*testItem{{Path: "{.authentication.anonymous.enabled}", Set: true, Compare: compare{Op: "eq", Value: "false"}}}
+
+	cases := []struct {
+		name     string
+		options  map[string]string
+		expected State
+	}{
+		{name: "service account token", expected: PASS},
+		{name: "token file", options: map[string]string{"token_file": token}, expected: PASS},
+		{name: "unauthorized", options: map[string]string{"token_file": wrongToken}, expected: WARN},
+		{name: "missing token", options: map[string]string{"token_file": filepath.Join(dir, "missing")}, expected: WARN},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			check := &Check{Type: CONFIGZ, Audit: kubelet.URL + "/configz", AuditOptions: c.options, Scored: true, Tests: &tests{TestItems: anonymousDisabled}}
+			check.run()
+			if check.State != c.expected {
+				t.Errorf("expected %s, actual %s (%s)", c.expected, check.State, check.Reason)
+			}
+		})
+	}
+}
@@ -19,6 +19,8 @@ import (
 	"encoding/json"
 	"encoding/xml"
 	"fmt"
+	"strings"
+	"time"
 
 	"github.com/golang/glog"
 	"github.com/onsi/ginkgo/reporters"
@@ -31,10 +33,48 @@ type Controls struct {
 	Version string   `json:"version"`
 	Text    string   `json:"text"`
 	Type    NodeType `json:"node_type"`
-	Groups  []*Group `json:"tests"`
+	// Instance tells apart the controls of a target run against each of
+	// several instances of it, e.g. a kubelet or a remote etcd member.
+	Instance string   `yaml:"-" json:"instance,omitempty"`
+	Groups   []*Group `json:"tests"`
+	// UseSudo runs the audits of all checks through sudo.
+	UseSudo bool `yaml:"use_sudo" json:"-"`
+	// Metadata describes the node the checks were run on.
+	Metadata *NodeMetadata `yaml:"-" json:"metadata,omitempty"`
+	// RunID identifies the run the checks were part of, to correlate the
+	// results delivered to several destinations.
+	RunID string `yaml:"-" json:"run_id,omitempty"`
+	// ExpiresAt is when the results are stale, with a result TTL, so that
+	// consumers can tell results that are too old to be trusted.
+	ExpiresAt *time.Time `yaml:"-" json:"expires_at,omitempty"`
+	// Errors are what prevented checks of the run from being carried out,
+	// the errors of each check are in its results.
+	Errors []CheckError `yaml:"-" json:"errors,omitempty"`
 	Summary
 }
 
+// NodeMetadata describes a node and the cluster it is part of, so that
+// results aggregated from many nodes can be attributed.
+type NodeMetadata struct {
+	Hostname       string            `json:"hostname,omitempty"`
+	NodeName       string            `json:"node_name,omitempty"`
+	Labels         map[string]string `json:"labels,omitempty"`
+	OS             string            `json:"os,omitempty"`
+	KernelVersion  string            `json:"kernel_version,omitempty"`
+	KubeletVersion string            `json:"kubelet_version,omitempty"`
+	CloudProvider  string            `json:"cloud_provider,omitempty"`
+	ClusterName    string            `json:"cluster_name,omitempty"`
+	// ContainerRuntime and ContainerRuntimeVersion are the container runtime
+	// of the node, e.g. containerd 1.4.3, and CgroupDriver the cgroup driver
+	// of the kubelet, cgroupfs or systemd.
+	ContainerRuntime        string `json:"container_runtime,omitempty"`
+	ContainerRuntimeVersion string `json:"container_runtime_version,omitempty"`
+	CgroupDriver            string `json:"cgroup_driver,omitempty"`
+	// Annotations are the annotations of the Node, e.g. the checks skipped
+	// on it.
+	Annotations map[string]string `json:"-"`
+}
+
 // Group is a collection of similar checks.
 type Group struct {
 	ID     string   `yaml:"id" json:"section"`
@@ -42,6 +82,7 @@ type Group struct {
 	Fail   int      `json:"fail"`
 	Warn   int      `json:"warn"`
 	Info   int      `json:"info"`
+	Skip   int      `json:"skip"`
 	Text   string   `json:"desc"`
 	Checks []*Check `json:"results"`
 }
@@ -52,6 +93,24 @@ type Summary struct {
 	Fail int `json:"total_fail"`
 	Warn int `json:"total_warn"`
 	Info int `json:"total_info"`
+	Skip int `json:"total_skip"`
+	// StartTime and EndTime are when the checks started and finished running.
+	StartTime time.Time `json:"start_time"`
+	EndTime   time.Time `json:"end_time"`
+	// Groups summarizes the results of each group, in the order they ran.
+	// The JSON output has them in each group.
+	Groups []GroupSummary `json:"-"`
+}
+
+// GroupSummary is a summary of the results of the checks of a group.
+type GroupSummary struct {
+	ID   string
+	Text string
+	Pass int
+	Fail int
+	Warn int
+	Info int
+	Skip int
 }
 
 // Predicate a predicate on the given Group and Check arguments.
@@ -74,22 +133,48 @@ func NewControls(t NodeType, in []byte) (*Controls, error) {
 	for _, group := range c.Groups {
 		for _, check := range group.Checks {
 			glog.V(3).Infof("Check.ID %s", check.ID)
+			check.UseSudo = check.UseSudo || c.UseSudo
 			check.Commands = textToCommand(check.Audit)
 			if len(check.AuditConfig) > 0 {
 				glog.V(3).Infof("Check.ID has audit_config %s", check.ID)
 				check.ConfigCommands = textToCommand(check.AuditConfig)
 			}
+			if err := check.prepareRemediation(); err != nil {
+				return nil, err
+			}
+			if err := check.validateSeverity(); err != nil {
+				return nil, err
+			}
 		}
 	}
 
 	return c, nil
 }
 
+// RewriteAudits rewrites the audits of the checks, and the values of their
+// audit options, e.g. to remap the paths of files.
+func (controls *Controls) RewriteAudits(rewrite func(string) string) {
+	for _, group := range controls.Groups {
+		for _, check := range group.Checks {
+			check.Audit = rewrite(check.Audit)
+			check.Commands = textToCommand(check.Audit)
+			if len(check.AuditConfig) > 0 {
+				check.AuditConfig = rewrite(check.AuditConfig)
+				check.ConfigCommands = textToCommand(check.AuditConfig)
+			}
+			for k, v := range check.AuditOptions {
+				check.AuditOptions[k] = rewrite(v)
+			}
+		}
+	}
+}
+
 // RunChecks runs the checks with the given Runner. Only checks for which the filter Predicate returns `true` will run.
 func (controls *Controls) RunChecks(runner Runner, filter Predicate) Summary {
 	var g []*Group
 	m := make(map[string]*Group)
-	controls.Summary.Pass, controls.Summary.Fail, controls.Summary.Warn, controls.Info = 0, 0, 0, 0
+	controls.Summary.Pass, controls.Summary.Fail, controls.Summary.Warn, controls.Info, controls.Skip = 0, 0, 0, 0, 0
+	controls.StartTime = time.Now().UTC()
 
 	for _, group := range controls.Groups {
 		for _, check := range group.Checks {
@@ -98,7 +183,9 @@ func (controls *Controls) RunChecks(runner Runner, filter Predicate) Summary {
 				continue
 			}
 
+			start := time.Now()
 			state := runner.Run(check)
+			check.Duration = time.Since(start).Seconds()
 			check.TestInfo = append(check.TestInfo, check.Remediation)
 
 			// Check if we have already added this checks group.
@@ -127,23 +214,119 @@ func (controls *Controls) RunChecks(runner Runner, filter Predicate) Summary {
 	}
 
 	controls.Groups = g
+	controls.summarizeGroups()
+	controls.EndTime = time.Now().UTC()
 	return controls.Summary
 }
 
-// JSON encodes the results of last run to JSON.
+// RerunCheck runs a check of the controls again, e.g. after a file it audits
+// changed, and updates the summary. It returns the previous state of the
+// check.
+func (controls *Controls) RerunCheck(runner Runner, c *Check) State {
+	prev := c.State
+	c.TestInfo, c.Errors = nil, nil
+	c.ActualValue, c.ExpectedResult, c.Reason = "", "", ""
+
+	start := time.Now()
+	c.State = runner.Run(c)
+	c.Duration = time.Since(start).Seconds()
+	c.TestInfo = append(c.TestInfo, c.Remediation)
+
+	controls.Summary.Pass, controls.Summary.Fail, controls.Summary.Warn, controls.Info, controls.Skip = 0, 0, 0, 0, 0
+	for _, group := range controls.Groups {
+		group.Pass, group.Fail, group.Warn, group.Info, group.Skip = 0, 0, 0, 0, 0
+		for _, check := range group.Checks {
+			summarizeGroup(group, check.State)
+			summarize(controls, check.State)
+		}
+	}
+	controls.summarizeGroups()
+	return prev
+}
+
+// summarizeGroups sets the summaries of the groups from their counts.
+func (controls *Controls) summarizeGroups() {
+	controls.Summary.Groups = nil
+	for _, group := range controls.Groups {
+		controls.Summary.Groups = append(controls.Summary.Groups, GroupSummary{
+			ID:   group.ID,
+			Text: group.Text,
+			Pass: group.Pass,
+			Fail: group.Fail,
+			Warn: group.Warn,
+			Info: group.Info,
+			Skip: group.Skip,
+		})
+	}
+}
+
+// JSON encodes the results of last run to JSON, with the timestamps in
+// the configured timezone and format.
 func (controls *Controls) JSON() ([]byte, error) {
-	return json.Marshal(controls)
+	type results Controls
+	return json.Marshal(struct {
+		*results
+		StartTime json.RawMessage `json:"start_time"`
+		EndTime   json.RawMessage `json:"end_time"`
+		ExpiresAt json.RawMessage `json:"expires_at,omitempty"`
+	}{(*results)(controls), formatTime(controls.StartTime), formatTime(controls.EndTime), formatOptionalTime(controls.ExpiresAt)})
 }
 
-// JUnit encodes the results of last run to JUnit.
+// UnmarshalJSON decodes results, whatever the format of their timestamps.
+func (controls *Controls) UnmarshalJSON(data []byte) error {
+	type results Controls
+	aux := struct {
+		*results
+		StartTime json.RawMessage `json:"start_time"`
+		EndTime   json.RawMessage `json:"end_time"`
+		ExpiresAt json.RawMessage `json:"expires_at"`
+	}{results: (*results)(controls)}
+	if err := json.Unmarshal(data, &aux); err != nil {
+		return err
+	}
+
+	var err error
+	if controls.StartTime, err = parseTime(aux.StartTime); err != nil {
+		return err
+	}
+	if controls.EndTime, err = parseTime(aux.EndTime); err != nil {
+		return err
+	}
+	expires, err := parseTime(aux.ExpiresAt)
+	if err == nil && !expires.IsZero() {
+		controls.ExpiresAt = &expires
+	}
+	return err
+}
+
+// junitTestSuites is the JUnit report of a set of controls, with a test
+// suite for each group.
+type junitTestSuites struct {
+	XMLName    xml.Name                   `xml:"testsuites"`
+	Name       string                     `xml:"name,attr"`
+	Tests      int                        `xml:"tests,attr"`
+	Failures   int                        `xml:"failures,attr"`
+	Time       float64                    `xml:"time,attr"`
+	TestSuites []reporters.JUnitTestSuite `xml:"testsuite"`
+}
+
+// JUnit encodes the results of last run to JUnit, with a test suite for each
+// group whose failed test cases carry the remediation.
 func (controls *Controls) JUnit() ([]byte, error) {
-	suite := reporters.JUnitTestSuite{
-		Name:      controls.Text,
-		TestCases: []reporters.JUnitTestCase{},
-		Tests:     controls.Summary.Pass + controls.Summary.Fail + controls.Summary.Info + controls.Summary.Warn,
-		Failures:  controls.Summary.Fail,
+	suites := junitTestSuites{
+		Name:       controls.Text,
+		TestSuites: []reporters.JUnitTestSuite{},
+		Tests:      controls.Summary.Pass + controls.Summary.Fail + controls.Summary.Info + controls.Summary.Warn + controls.Summary.Skip,
+		Failures:   controls.Summary.Fail,
+		Time:       controls.EndTime.Sub(controls.StartTime).Seconds(),
 	}
 	for _, g := range controls.Groups {
+		suite := reporters.JUnitTestSuite{
+			Name:      strings.TrimSpace(fmt.Sprintf("%v %v", g.ID, g.Text)),
+			TestCases: []reporters.JUnitTestCase{},
+			Tests:     g.Pass + g.Fail + g.Info + g.Warn + g.Skip,
+			Failures:  g.Fail,
+		}
 		for _, check := range g.Checks {
 			jsonCheck := ""
 			jsonBytes, err := json.Marshal(check)
@@ -155,6 +338,7 @@ func (controls *Controls) JUnit() ([]byte, error) {
 			tc := reporters.JUnitTestCase{
 				Name:      fmt.Sprintf("%v %v", check.ID, check.Text),
 				ClassName: g.Text,
+				Time:      check.Duration,
 
 				// Store the entire json serialization as system out so we don't lose data in cases where deeper debugging is necessary.
 				SystemOut: jsonCheck,
@@ -163,8 +347,8 @@ func (controls *Controls) JUnit() ([]byte, error) {
 			switch check.State {
 			case FAIL:
 				tc.FailureMessage = &reporters.JUnitFailureMessage{Message: check.Remediation}
-			case WARN, INFO:
-				// WARN and INFO are two different versions of skipped tests. Either way it would be a false positive/negative to report
+			case WARN, INFO, SKIP:
+				// WARN, INFO and SKIP are different versions of skipped tests. Either way it would be a false positive/negative to report
 				// it any other way.
 				tc.Skipped = &reporters.JUnitSkipped{}
 			case PASS:
@@ -172,14 +356,16 @@ func (controls *Controls) JUnit() ([]byte, error) {
 				glog.Warningf("Unrecognized state %s", check.State)
 			}
 
+			suite.Time += check.Duration
 			suite.TestCases = append(suite.TestCases, tc)
 		}
+		suites.TestSuites = append(suites.TestSuites, suite)
 	}
 
 	var b bytes.Buffer
 	encoder := xml.NewEncoder(&b)
 	encoder.Indent("", "    ")
-	err := encoder.Encode(suite)
+	err := encoder.Encode(suites)
 	if err != nil {
 		return nil, fmt.Errorf("Failed to generate JUnit report: %s", err.Error())
 	}
@@ -197,6 +383,8 @@ func summarize(controls *Controls, state State) {
 		controls.Summary.Warn++
 	case INFO:
 		controls.Summary.Info++
+	case SKIP:
+		controls.Summary.Skip++
 	default:
 		glog.Warningf("Unrecognized state %s", state)
 	}
@@ -212,6 +400,8 @@ func summarizeGroup(group *Group, state State) {
 		group.Warn++
 	case INFO:
 		group.Info++
+	case SKIP:
+		group.Skip++
 	default:
 		glog.Warningf("Unrecognized state %s", state)
 	}
@@ -22,8 +22,8 @@ import (
 	"os"
 	"path/filepath"
 	"testing"
+	"time"
 
-	"github.com/onsi/ginkgo/reporters"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"gopkg.in/yaml.v2"
@@ -93,6 +93,27 @@ groups:
 		assert.EqualError(t, err, "failed to unmarshal YAML: yaml: unmarshal errors:\n  line 1: cannot unmarshal !!str `BOOM` into check.Controls")
 	})
 
+	t.Run("Should apply use_sudo of the controls to all checks", func(t *testing.T) {
+		// given
+		in := []byte(`
+---
+type: "master"
+use_sudo: true
+groups:
+- id: G1
+  checks:
+  - id: G1/C1
+  - id: G1/C2
+`)
+		// when
+		controls, err := NewControls(MASTER, in)
+		// then
+		assert.NoError(t, err)
+		for _, c := range controls.Groups[0].Checks {
+			assert.True(t, c.UseSudo, c.ID)
+		}
+	})
+
 }
 
 func TestControls_RunChecks(t *testing.T) {
@@ -159,9 +180,72 @@ groups:
 		assert.Equal(t, 1, controls.Summary.Fail)
 		assert.Equal(t, 0, controls.Summary.Info)
 		assert.Equal(t, 0, controls.Summary.Warn)
+		assert.Equal(t, []GroupSummary{{ID: "G1", Pass: 1}, {ID: "G2", Fail: 1}}, controls.Summary.Groups)
+		// and
+		assert.False(t, controls.Summary.StartTime.IsZero())
+		assert.False(t, controls.Summary.EndTime.Before(controls.Summary.StartTime))
 		// and
 		runner.AssertExpectations(t)
 	})
+
+	t.Run("Should record the duration of each check", func(t *testing.T) {
+		// given
+		runner := new(mockRunner)
+		controls, err := NewControls(MASTER, []byte(`
+---
+type: "master"
+groups:
+- id: G1
+  checks:
+  - id: G1/C1
+`))
+		assert.NoError(t, err)
+		runner.On("Run", controls.Groups[0].Checks[0]).Run(func(mock.Arguments) {
+			time.Sleep(10 * time.Millisecond)
+		}).Return(PASS)
+		// when
+		controls.RunChecks(runner, func(*Group, *Check) bool { return true })
+		// then
+		assert.True(t, controls.Groups[0].Checks[0].Duration >= 0.01)
+		assert.True(t, controls.EndTime.Sub(controls.StartTime).Seconds() >= 0.01)
+	})
+}
+
+func TestControls_RerunCheck(t *testing.T) {
+	// given
+	controls, err := NewControls(MASTER, []byte(`
+---
+type: "master"
+groups:
+- id: G1
+  checks:
+  - id: G1/C1
+  - id: G1/C2
+    remediation: "Fix it."
+`))
+	assert.NoError(t, err)
+	c1, c2 := controls.Groups[0].Checks[0], controls.Groups[0].Checks[1]
+	runner := new(mockRunner)
+	runner.On("Run", c1).Return(PASS)
+	runner.On("Run", c2).Return(FAIL)
+	controls.RunChecks(runner, func(*Group, *Check) bool { return true })
+	c1.State, c2.State = PASS, FAIL
+	c2.Reason = "stale"
+	// and
+	rerunner := new(mockRunner)
+	rerunner.On("Run", c2).Return(PASS)
+	// when
+	prev := controls.RerunCheck(rerunner, c2)
+	// then
+	assert.Equal(t, FAIL, prev)
+	assert.Equal(t, PASS, c2.State)
+	assert.Equal(t, "", c2.Reason)
+	assert.Equal(t, []string{"Fix it."}, c2.TestInfo)
+	assert.Equal(t, 2, controls.Summary.Pass)
+	assert.Equal(t, 0, controls.Summary.Fail)
+	assertEqualGroupSummary(t, 2, 0, 0, 0, controls.Groups[0])
+	assert.Equal(t, []GroupSummary{{ID: "G1", Pass: 2}}, controls.Summary.Groups)
+	rerunner.AssertExpectations(t)
 }
 
 func TestControls_JUnitIncludesJSON(t *testing.T) {
@@ -182,11 +266,13 @@ func TestControls_JUnitIncludesJSON(t *testing.T) {
 					},
 				},
 			},
-			expect: []byte(`<testsuite name="" tests="0" failures="0" errors="0" time="0">
-    <testcase name="check1id check1text" classname="" time="0">
-        <system-out>{&#34;test_number&#34;:&#34;check1id&#34;,&#34;test_desc&#34;:&#34;check1text&#34;,&#34;audit&#34;:&#34;&#34;,&#34;AuditConfig&#34;:&#34;&#34;,&#34;type&#34;:&#34;&#34;,&#34;remediation&#34;:&#34;&#34;,&#34;test_info&#34;:null,&#34;status&#34;:&#34;PASS&#34;,&#34;actual_value&#34;:&#34;&#34;,&#34;scored&#34;:false,&#34;expected_result&#34;:&#34;&#34;}</system-out>
-    </testcase>
-</testsuite>`),
+			expect: []byte(`<testsuites name="" tests="0" failures="0" time="0">
+    <testsuite name="g1" tests="0" failures="0" errors="0" time="0">
+        <testcase name="check1id check1text" classname="" time="0">
+            <system-out>{&#34;test_number&#34;:&#34;check1id&#34;,&#34;test_desc&#34;:&#34;check1text&#34;,&#34;audit&#34;:&#34;&#34;,&#34;AuditConfig&#34;:&#34;&#34;,&#34;type&#34;:&#34;&#34;,&#34;remediation&#34;:&#34;&#34;,&#34;test_info&#34;:null,&#34;status&#34;:&#34;PASS&#34;,&#34;actual_value&#34;:&#34;&#34;,&#34;scored&#34;:false,&#34;expected_result&#34;:&#34;&#34;}</system-out>
+        </testcase>
+    </testsuite>
+</testsuites>`),
 		}, {
 			desc: "Summary values come from summary not checks",
 			input: &Controls{
@@ -205,43 +291,52 @@ func TestControls_JUnitIncludesJSON(t *testing.T) {
 					},
 				},
 			},
-			expect: []byte(`<testsuite name="" tests="402" failures="99" errors="0" time="0">
-    <testcase name="check1id check1text" classname="" time="0">
-        <system-out>{&#34;test_number&#34;:&#34;check1id&#34;,&#34;test_desc&#34;:&#34;check1text&#34;,&#34;audit&#34;:&#34;&#34;,&#34;AuditConfig&#34;:&#34;&#34;,&#34;type&#34;:&#34;&#34;,&#34;remediation&#34;:&#34;&#34;,&#34;test_info&#34;:null,&#34;status&#34;:&#34;PASS&#34;,&#34;actual_value&#34;:&#34;&#34;,&#34;scored&#34;:false,&#34;expected_result&#34;:&#34;&#34;}</system-out>
-    </testcase>
-</testsuite>`),
+			expect: []byte(`<testsuites name="" tests="402" failures="99" time="0">
+    <testsuite name="g1" tests="0" failures="0" errors="0" time="0">
+        <testcase name="check1id check1text" classname="" time="0">
+            <system-out>{&#34;test_number&#34;:&#34;check1id&#34;,&#34;test_desc&#34;:&#34;check1text&#34;,&#34;audit&#34;:&#34;&#34;,&#34;AuditConfig&#34;:&#34;&#34;,&#34;type&#34;:&#34;&#34;,&#34;remediation&#34;:&#34;&#34;,&#34;test_info&#34;:null,&#34;status&#34;:&#34;PASS&#34;,&#34;actual_value&#34;:&#34;&#34;,&#34;scored&#34;:false,&#34;expected_result&#34;:&#34;&#34;}</system-out>
+        </testcase>
+    </testsuite>
+</testsuites>`),
 		}, {
 			desc: "Warn and Info are considered skips and failed tests properly reported",
 			input: &Controls{
 				Groups: []*Group{
 					{
-						ID: "g1",
+						ID:   "g1",
+						Text: "group1text",
+						Pass: 1,
+						Fail: 1,
+						Warn: 1,
+						Info: 1,
 						Checks: []*Check{
 							{ID: "check1id", Text: "check1text", State: PASS},
 							{ID: "check2id", Text: "check2text", State: INFO},
 							{ID: "check3id", Text: "check3text", State: WARN},
-							{ID: "check4id", Text: "check4text", State: FAIL},
+							{ID: "check4id", Text: "check4text", State: FAIL, Remediation: "fix check4"},
 						},
 					},
 				},
 			},
-			expect: []byte(`<testsuite name="" tests="0" failures="0" errors="0" time="0">
-    <testcase name="check1id check1text" classname="" time="0">
-        <system-out>{&#34;test_number&#34;:&#34;check1id&#34;,&#34;test_desc&#34;:&#34;check1text&#34;,&#34;audit&#34;:&#34;&#34;,&#34;AuditConfig&#34;:&#34;&#34;,&#34;type&#34;:&#34;&#34;,&#34;remediation&#34;:&#34;&#34;,&#34;test_info&#34;:null,&#34;status&#34;:&#34;PASS&#34;,&#34;actual_value&#34;:&#34;&#34;,&#34;scored&#34;:false,&#34;expected_result&#34;:&#34;&#34;}</system-out>
-    </testcase>
-    <testcase name="check2id check2text" classname="" time="0">
-        <skipped></skipped>
-        <system-out>{&#34;test_number&#34;:&#34;check2id&#34;,&#34;test_desc&#34;:&#34;check2text&#34;,&#34;audit&#34;:&#34;&#34;,&#34;AuditConfig&#34;:&#34;&#34;,&#34;type&#34;:&#34;&#34;,&#34;remediation&#34;:&#34;&#34;,&#34;test_info&#34;:null,&#34;status&#34;:&#34;INFO&#34;,&#34;actual_value&#34;:&#34;&#34;,&#34;scored&#34;:false,&#34;expected_result&#34;:&#34;&#34;}</system-out>
-    </testcase>
-    <testcase name="check3id check3text" classname="" time="0">
-        <skipped></skipped>
-        <system-out>{&#34;test_number&#34;:&#34;check3id&#34;,&#34;test_desc&#34;:&#34;check3text&#34;,&#34;audit&#34;:&#34;&#34;,&#34;AuditConfig&#34;:&#34;&#34;,&#34;type&#34;:&#34;&#34;,&#34;remediation&#34;:&#34;&#34;,&#34;test_info&#34;:null,&#34;status&#34;:&#34;WARN&#34;,&#34;actual_value&#34;:&#34;&#34;,&#34;scored&#34;:false,&#34;expected_result&#34;:&#34;&#34;}</system-out>
-    </testcase>
-    <testcase name="check4id check4text" classname="" time="0">
-        <failure type=""></failure>
-        <system-out>{&#34;test_number&#34;:&#34;check4id&#34;,&#34;test_desc&#34;:&#34;check4text&#34;,&#34;audit&#34;:&#34;&#34;,&#34;AuditConfig&#34;:&#34;&#34;,&#34;type&#34;:&#34;&#34;,&#34;remediation&#34;:&#34;&#34;,&#34;test_info&#34;:null,&#34;status&#34;:&#34;FAIL&#34;,&#34;actual_value&#34;:&#34;&#34;,&#34;scored&#34;:false,&#34;expected_result&#34;:&#34;&#34;}</system-out>
-    </testcase>
-</testsuite>`),
+			expect: []byte(`<testsuites name="" tests="0" failures="0" time="0">
+    <testsuite name="g1 group1text" tests="4" failures="1" errors="0" time="0">
+        <testcase name="check1id check1text" classname="group1text" time="0">
+            <system-out>{&#34;test_number&#34;:&#34;check1id&#34;,&#34;test_desc&#34;:&#34;check1text&#34;,&#34;audit&#34;:&#34;&#34;,&#34;AuditConfig&#34;:&#34;&#34;,&#34;type&#34;:&#34;&#34;,&#34;remediation&#34;:&#34;&#34;,&#34;test_info&#34;:null,&#34;status&#34;:&#34;PASS&#34;,&#34;actual_value&#34;:&#34;&#34;,&#34;scored&#34;:false,&#34;expected_result&#34;:&#34;&#34;}</system-out>
+        </testcase>
+        <testcase name="check2id check2text" classname="group1text" time="0">
+            <skipped></skipped>
+            <system-out>{&#34;test_number&#34;:&#34;check2id&#34;,&#34;test_desc&#34;:&#34;check2text&#34;,&#34;audit&#34;:&#34;&#34;,&#34;AuditConfig&#34;:&#34;&#34;,&#34;type&#34;:&#34;&#34;,&#34;remediation&#34;:&#34;&#34;,&#34;test_info&#34;:null,&#34;status&#34;:&#34;INFO&#34;,&#34;actual_value&#34;:&#34;&#34;,&#34;scored&#34;:false,&#34;expected_result&#34;:&#34;&#34;}</system-out>
+        </testcase>
+        <testcase name="check3id check3text" classname="group1text" time="0">
+            <skipped></skipped>
+            <system-out>{&#34;test_number&#34;:&#34;check3id&#34;,&#34;test_desc&#34;:&#34;check3text&#34;,&#34;audit&#34;:&#34;&#34;,&#34;AuditConfig&#34;:&#34;&#34;,&#34;type&#34;:&#34;&#34;,&#34;remediation&#34;:&#34;&#34;,&#34;test_info&#34;:null,&#34;status&#34;:&#34;WARN&#34;,&#34;actual_value&#34;:&#34;&#34;,&#34;scored&#34;:false,&#34;expected_result&#34;:&#34;&#34;}</system-out>
+        </testcase>
+        <testcase name="check4id check4text" classname="group1text" time="0">
+            <failure type="">fix check4</failure>
+            <system-out>{&#34;test_number&#34;:&#34;check4id&#34;,&#34;test_desc&#34;:&#34;check4text&#34;,&#34;audit&#34;:&#34;&#34;,&#34;AuditConfig&#34;:&#34;&#34;,&#34;type&#34;:&#34;&#34;,&#34;remediation&#34;:&#34;fix check4&#34;,&#34;test_info&#34;:null,&#34;status&#34;:&#34;FAIL&#34;,&#34;actual_value&#34;:&#34;&#34;,&#34;scored&#34;:false,&#34;expected_result&#34;:&#34;&#34;}</system-out>
+        </testcase>
+    </testsuite>
+</testsuites>`),
 		},
 	}
 	for _, tc := range testCases {
@@ -251,7 +346,7 @@ func TestControls_JUnitIncludesJSON(t *testing.T) {
 				t.Fatalf("Failed to serialize to JUnit: %v", err)
 			}
 
-			var out reporters.JUnitTestSuite
+			var out junitTestSuites
 			if err := xml.Unmarshal(junitBytes, &out); err != nil {
 				t.Fatalf("Unable to deserialize from resulting JUnit: %v", err)
 			}
@@ -264,9 +359,9 @@ func TestControls_JUnitIncludesJSON(t *testing.T) {
 						t.Fatalf("Failed to serialize to JUnit: %v", err)
 					}
 
-					if out.TestCases[iGroup*iCheck+iCheck].SystemOut != string(jsonBytes) {
+					if out.TestSuites[iGroup].TestCases[iCheck].SystemOut != string(jsonBytes) {
 						t.Errorf("Expected\n\t%v\n\tbut got\n\t%v",
-							out.TestCases[iGroup*iCheck+iCheck].SystemOut,
+							out.TestSuites[iGroup].TestCases[iCheck].SystemOut,
 							string(jsonBytes),
 						)
 					}
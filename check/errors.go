@@ -0,0 +1,89 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"errors"
+	"net"
+)
+
+// The errors that prevent checks from being carried out, one for each kind
+// of CheckError, so that integrators can tell them apart with errors.Is.
+var (
+	// ErrAuditFailed is the error of checks whose audit could not be run.
+	ErrAuditFailed = errors.New("audit failed")
+	// ErrMissingConfig is the error of checks using a config file of a
+	// component that was not found.
+	ErrMissingConfig = errors.New("missing config file")
+	// ErrPermissionDenied is the error of checks kube-bench lacks the
+	// privileges to carry out.
+	ErrPermissionDenied = errors.New("permission denied")
+	// ErrMissingDependency is the error of checks running commands that are
+	// not available.
+	ErrMissingDependency = errors.New("missing dependency")
+	// ErrTimeout is the error of checks whose audit did not complete in time.
+	ErrTimeout = errors.New("audit timed out")
+	// ErrOutputTruncated is the error of checks whose tests only saw part of
+	// the output of their audit.
+	ErrOutputTruncated = errors.New("audit output truncated")
+)
+
+var kindErrors = map[ErrorKind]error{
+	AuditError:             ErrAuditFailed,
+	MissingFileError:       ErrMissingConfig,
+	PermissionError:        ErrPermissionDenied,
+	MissingDependencyError: ErrMissingDependency,
+	TimeoutError:           ErrTimeout,
+	TruncatedOutputError:   ErrOutputTruncated,
+}
+
+func (e CheckError) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the sentinel error of the kind of the error, e.g.
+// ErrMissingConfig for a missing_file error.
+func (e CheckError) Unwrap() error {
+	return kindErrors[e.Kind]
+}
+
+// Err returns the first error that prevented the check from being carried
+// out, or nil.
+func (c *Check) Err() error {
+	if len(c.Errors) == 0 {
+		return nil
+	}
+	return c.Errors[0]
+}
+
+// Err returns the first error of the run of the checks, or nil.
+func (controls *Controls) Err() error {
+	if len(controls.Errors) == 0 {
+		return nil
+	}
+	return controls.Errors[0]
+}
+
+// auditErrorKind returns the kind of the error of a native auditor.
+func auditErrorKind(err error) ErrorKind {
+	var netErr net.Error
+	if errors.As(err, &netErr) && netErr.Timeout() {
+		return TimeoutError
+	}
+	if errors.Is(err, ErrPermissionDenied) {
+		return PermissionError
+	}
+	return AuditError
+}
@@ -0,0 +1,99 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestCheckErrorIs(t *testing.T) {
+	cases := []struct {
+		kind     ErrorKind
+		sentinel error
+	}{
+		{kind: AuditError, sentinel: ErrAuditFailed},
+		{kind: MissingFileError, sentinel: ErrMissingConfig},
+		{kind: PermissionError, sentinel: ErrPermissionDenied},
+		{kind: MissingDependencyError, sentinel: ErrMissingDependency},
+		{kind: TimeoutError, sentinel: ErrTimeout},
+	}
+
+	for _, c := range cases {
+		t.Run(string(c.kind), func(t *testing.T) {
+			check := &Check{ID: "1.1.1"}
+			check.AddError(c.kind, "something went wrong")
+			err := check.Err()
+			if !errors.Is(err, c.sentinel) {
+				t.Errorf("expected %v to be %v", err, c.sentinel)
+			}
+			if errors.Is(err, ErrOffline) {
+				t.Errorf("expected %v not to be %v", err, ErrOffline)
+			}
+			if err.Error() != "something went wrong" {
+				t.Errorf("unexpected message %q", err.Error())
+			}
+		})
+	}
+
+	if err := (&Check{}).Err(); err != nil {
+		t.Errorf("expected no error, got %v", err)
+	}
+	controls := &Controls{Errors: []CheckError{{Kind: MissingFileError, Message: "no config file of proxy found"}}}
+	if err := controls.Err(); !errors.Is(err, ErrMissingConfig) {
+		t.Errorf("expected %v to be %v", err, ErrMissingConfig)
+	}
+}
+
+func TestAuditErrorKind(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		time.Sleep(100 * time.Millisecond)
+	}))
+	defer server.Close()
+
+	client := &http.Client{Timeout: 10 * time.Millisecond}
+	_, timeout := client.Get(server.URL)
+	if timeout == nil {
+		t.Fatal("expected the request to time out")
+	}
+
+	for _, c := range []struct {
+		err      error
+		expected ErrorKind
+	}{
+		{err: timeout, expected: TimeoutError},
+		{err: fmt.Errorf("failed to get the configz of the kubelet: %w", timeout), expected: TimeoutError},
+		{err: errors.New("invalid URL"), expected: AuditError},
+		{err: fmt.Errorf("%w reading /etc/kubernetes/kubelet.conf", ErrPermissionDenied), expected: PermissionError},
+	} {
+		if kind := auditErrorKind(c.err); kind != c.expected {
+			t.Errorf("%v: expected %s, got %s", c.err, c.expected, kind)
+		}
+	}
+
+	defer delete(auditors, "slow")
+	auditors["slow"] = func(c *Check) (string, error) { return "", timeout }
+	check := &Check{ID: "1.1.1", Type: "slow"}
+	if state := check.run(); state != WARN {
+		t.Errorf("expected WARN, got %s", state)
+	}
+	if err := check.Err(); !errors.Is(err, ErrTimeout) {
+		t.Errorf("expected %v to be %v", err, ErrTimeout)
+	}
+}
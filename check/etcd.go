@@ -0,0 +1,198 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+// etcdClientPort is the default port of the client API of etcd.
+const etcdClientPort = "2379"
+
+// etcdProbe is the output of an "etcd" check. The fields are available to
+// the check's tests as paths, e.g. '{.anonymous_access}'.
+type etcdProbe struct {
+	Endpoint         string `json:"endpoint"`
+	TLS              bool   `json:"tls"`
+	AnonymousAccess  bool   `json:"anonymous_access"`
+	ClientCertAccess bool   `json:"client_cert_access"`
+	ClientCertError  string `json:"client_cert_error,omitempty"`
+}
+
+// auditEtcd requests the version of the etcd member at the URL given in the
+// check's audit field, once without a client certificate and once with the
+// certificate configured in the check's audit_options (cafile, certfile and
+// keyfile), to verify that client certificate authentication is enforced.
+func auditEtcd(c *Check) (string, error) {
+	endpoint := strings.TrimSpace(c.Audit)
+	u, err := url.Parse(endpoint)
+	if err != nil || u.Host == "" {
+		return "", fmt.Errorf("invalid etcd endpoint %q", endpoint)
+	}
+	u.Path = "/version"
+
+	probe := &etcdProbe{Endpoint: endpoint, TLS: u.Scheme == "https"}
+
+	anonymous := &tls.Config{InsecureSkipVerify: true}
+	if err := getEtcdVersion(u.String(), anonymous); err == nil {
+		probe.AnonymousAccess = true
+	} else {
+		glog.V(2).Infof("anonymous request to etcd %s rejected: %v", endpoint, err)
+	}
+
+	authenticated, err := etcdClientTLSConfig(c.AuditOptions)
+	if err == nil {
+		err = getEtcdVersion(u.String(), authenticated)
+	}
+	if err == nil {
+		probe.ClientCertAccess = true
+	} else {
+		probe.ClientCertError = err.Error()
+	}
+
+	out, err := json.Marshal(probe)
+	if err != nil {
+		return "", err
+	}
+
+	glog.V(3).Infof("etcd probe of %s: %s", endpoint, out)
+	return string(out), nil
+}
+
+// UseRemoteEtcdMember prepares the checks of etcd controls to be run against
+// the remote etcd member with the given client URL instead of a local etcd.
+// Checks with a remote definition are replaced by it. The endpoints of
+// "etcd" and "tls" checks are moved to the member, keeping their port unless
+// it's the client port 2379; the client checks use the given client
+// certificate options when set. Other checks need a local etcd and are
+// unavailable.
+func (controls *Controls) UseRemoteEtcdMember(clientURL string, clientOpts map[string]string) error {
+	member, err := url.Parse(clientURL)
+	if err != nil || member.Host == "" {
+		return fmt.Errorf("invalid etcd endpoint %q", clientURL)
+	}
+
+	for _, g := range controls.Groups {
+		for _, c := range g.Checks {
+			if c.Type == MANUAL || c.Type == "skip" {
+				continue
+			}
+
+			if r := c.Remote; r != nil {
+				c.Type, c.Audit, c.AuditOptions, c.Tests = r.Type, r.Audit, r.AuditOptions, r.Tests
+				c.AuditConfig, c.ConfigCommands = "", nil
+				c.Commands = textToCommand(c.Audit)
+			}
+
+			switch c.Type {
+			case ETCDCONN:
+				u, err := url.Parse(strings.TrimSpace(c.Audit))
+				if err != nil || u.Host == "" {
+					return fmt.Errorf("invalid etcd endpoint %q of check %s", c.Audit, c.ID)
+				}
+				u.Host = remoteEtcdHost(member, u.Port())
+				c.Audit = u.String()
+				if u.Host == member.Host {
+					c.AuditOptions = withOptions(c.AuditOptions, clientOpts)
+				}
+			case TLS:
+				_, port, err := net.SplitHostPort(strings.TrimSpace(c.Audit))
+				if err != nil {
+					return fmt.Errorf("invalid TLS endpoint %q of check %s", c.Audit, c.ID)
+				}
+				c.Audit = remoteEtcdHost(member, port)
+			default:
+				c.Unavailable = fmt.Sprintf("etcd runs on the remote member %s, this check needs a local etcd", clientURL)
+			}
+		}
+	}
+	return nil
+}
+
+// remoteEtcdHost returns the host:port of a remote etcd member for an
+// endpoint of a check with the given port.
+func remoteEtcdHost(member *url.URL, port string) string {
+	if port == "" || port == etcdClientPort {
+		return member.Host
+	}
+	return net.JoinHostPort(member.Hostname(), port)
+}
+
+// withOptions returns audit options overridden by the options that are set.
+func withOptions(opts, overrides map[string]string) map[string]string {
+	merged := make(map[string]string, len(opts))
+	for k, v := range opts {
+		merged[k] = v
+	}
+	for k, v := range overrides {
+		if v != "" {
+			merged[k] = v
+		}
+	}
+	return merged
+}
+
+func getEtcdVersion(u string, conf *tls.Config) error {
+	client := &http.Client{
+		Timeout:   httpTimeout,
+		Transport: &http.Transport{TLSClientConfig: conf},
+	}
+
+	resp, err := client.Get(u)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return fmt.Errorf("unexpected status code %d", resp.StatusCode)
+	}
+	return nil
+}
+
+func etcdClientTLSConfig(opts map[string]string) (*tls.Config, error) {
+	conf := &tls.Config{}
+
+	cert, err := tls.LoadX509KeyPair(opts["certfile"], opts["keyfile"])
+	if err != nil {
+		return nil, fmt.Errorf("failed to load client certificate: %v", err)
+	}
+	conf.Certificates = []tls.Certificate{cert}
+
+	if opts["cafile"] == "" {
+		conf.InsecureSkipVerify = true
+		return conf, nil
+	}
+
+	ca, err := ioutil.ReadFile(opts["cafile"])
+	if err != nil {
+		return nil, fmt.Errorf("failed to read CA file: %v", err)
+	}
+	conf.RootCAs = x509.NewCertPool()
+	if !conf.RootCAs.AppendCertsFromPEM(ca) {
+		return nil, fmt.Errorf("no certificates found in CA file %s", opts["cafile"])
+	}
+	return conf, nil
+}
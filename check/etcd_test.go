@@ -0,0 +1,202 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// writeClientCert writes a self-signed client certificate and key to dir.
+func writeClientCert(t *testing.T, dir string) (*x509.Certificate, string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatal(err)
+	}
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "kube-apiserver-etcd-client"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  true,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatal(err)
+	}
+	keyDer, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	certFile := filepath.Join(dir, "client.crt")
+	keyFile := filepath.Join(dir, "client.key")
+	if err := ioutil.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDer}), 0600); err != nil {
+		t.Fatal(err)
+	}
+	return cert, certFile, keyFile
+}
+
+func TestCheck_RunEtcd(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-etcd")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cert, certFile, keyFile := writeClientCert(t, dir)
+	pool := x509.NewCertPool()
+	pool.AddCert(cert)
+
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"etcdserver":"3.4.3","etcdcluster":"3.4.0"}`))
+	})
+	enforced := httptest.NewUnstartedServer(handler)
+	enforced.TLS = &tls.Config{ClientAuth: tls.RequireAndVerifyClientCert, ClientCAs: pool}
+	enforced.StartTLS()
+	defer enforced.Close()
+
+	open := httptest.NewTLSServer(handler)
+	defer open.Close()
+
+	items := []*testItem{
+		{Path: "{.anonymous_access}", Set: true, Compare: compare{Op: "eq", Value: "false"}},
+		{Path: "{.client_cert_access}", Set: true, Compare: compare{Op: "eq", Value: "true"}},
+	}
+	opts := map[string]string{"certfile": certFile, "keyfile": keyFile}
+
+	cases := []struct {
+		name     string
+		audit    string
+		opts     map[string]string
+		expected State
+	}{
+		{name: "client certificate authentication enforced", audit: enforced.URL, opts: opts, expected: PASS},
+		{name: "anonymous access allowed", audit: open.URL, opts: opts, expected: FAIL},
+		{name: "client certificate missing", audit: enforced.URL, opts: map[string]string{}, expected: FAIL},
+		{name: "invalid endpoint", audit: "", opts: opts, expected: WARN},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			check := &Check{Type: ETCDCONN, Audit: c.audit, AuditOptions: c.opts, Scored: true, Tests: &tests{TestItems: items}}
+			check.run()
+			if check.State != c.expected {
+				t.Errorf("expected %s, actual %s (%s)", c.expected, check.State, check.Reason)
+			}
+		})
+	}
+}
+
+func TestUseRemoteEtcdMember(t *testing.T) {
+	controls, err := NewControls(ETCD, []byte(`---
+controls:
+id: 2
+text: "Etcd Node Configuration"
+type: "etcd"
+groups:
+  - id: 2
+    text: "Etcd Node Configuration Files"
+    checks:
+      - id: 2.1
+        text: "Ensure that the --cert-file and --key-file arguments are set as appropriate (Scored)"
+        audit: "/bin/ps -ef | /bin/grep etcd | /bin/grep -v grep"
+        remote:
+          type: "etcd"
+          audit: "https://127.0.0.1:2379"
+          tests:
+            test_items:
+              - path: '{.tls}'
+                set: true
+        scored: true
+      - id: 2.3
+        text: "Ensure that the --auto-tls argument is not set to true (Scored)"
+        audit: "/bin/ps -ef | /bin/grep etcd | /bin/grep -v grep"
+        scored: true
+      - id: 2.7
+        text: "Ensure that a unique Certificate Authority is used for etcd (Not Scored)"
+        type: "manual"
+        scored: false
+      - id: 2.8
+        text: "Ensure that etcd only accepts client connections authenticated with a client certificate (Not Scored)"
+        type: "etcd"
+        audit: "https://127.0.0.1:2379"
+        audit_options:
+          cafile: /etc/kubernetes/pki/etcd/ca.crt
+          certfile: /etc/kubernetes/pki/apiserver-etcd-client.crt
+          keyfile: /etc/kubernetes/pki/apiserver-etcd-client.key
+        scored: false
+      - id: 2.9
+        text: "Ensure that etcd only accepts peer connections authenticated with a peer certificate (Not Scored)"
+        type: "etcd"
+        audit: "https://127.0.0.1:2380"
+        audit_options:
+          certfile: /etc/kubernetes/pki/etcd/peer.crt
+        scored: false
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := controls.UseRemoteEtcdMember("https://10.0.0.5:2379", map[string]string{"certfile": "/etc/kube-bench/etcd-client.crt", "keyfile": ""}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	checks := controls.Groups[0].Checks
+	if c := checks[0]; c.Type != ETCDCONN || c.Audit != "https://10.0.0.5:2379" || c.Tests == nil || c.Tests.TestItems[0].Path != "{.tls}" {
+		t.Errorf("expected the remote definition of 2.1, got %s %q %+v", c.Type, c.Audit, c.Tests)
+	}
+	if c := checks[1]; c.Unavailable == "" {
+		t.Errorf("expected 2.3 to be unavailable")
+	} else if state := c.run(); state != WARN || c.Reason != "etcd runs on the remote member https://10.0.0.5:2379, this check needs a local etcd" {
+		t.Errorf("expected WARN for 2.3, got %s %q", state, c.Reason)
+	}
+	if c := checks[2]; c.Unavailable != "" || c.Type != MANUAL {
+		t.Errorf("expected manual checks to be left as they are")
+	}
+	if c := checks[3]; c.Audit != "https://10.0.0.5:2379" || c.AuditOptions["certfile"] != "/etc/kube-bench/etcd-client.crt" || c.AuditOptions["keyfile"] != "/etc/kubernetes/pki/apiserver-etcd-client.key" {
+		t.Errorf("unexpected client check %q %v", c.Audit, c.AuditOptions)
+	}
+	if c := checks[4]; c.Audit != "https://10.0.0.5:2380" || c.AuditOptions["certfile"] != "/etc/kubernetes/pki/etcd/peer.crt" {
+		t.Errorf("unexpected peer check %q %v", c.Audit, c.AuditOptions)
+	}
+
+	if err := controls.UseRemoteEtcdMember("etcd-0:2379", nil); err == nil {
+		t.Errorf("expected an error for an endpoint without scheme")
+	}
+}
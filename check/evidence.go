@@ -0,0 +1,145 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"fmt"
+	"os/exec"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+)
+
+// Evidence holds the raw output of the audits run during a scan, keyed by
+// the audit that produced it.
+type Evidence map[string]string
+
+var (
+	evidenceMu sync.Mutex
+	recorded   Evidence
+	replayed   Evidence
+)
+
+// RecordEvidence makes checks save the output of their audits in e.
+// Passing nil stops recording.
+func RecordEvidence(e Evidence) {
+	evidenceMu.Lock()
+	defer evidenceMu.Unlock()
+	recorded = e
+}
+
+// ReplayEvidence makes checks take the output of their audits from e
+// instead of running them. Passing nil restores normal execution.
+func ReplayEvidence(e Evidence) {
+	evidenceMu.Lock()
+	defer evidenceMu.Unlock()
+	replayed = e
+}
+
+// auditorKey identifies the output of a native auditor in the evidence: its
+// type, its audit, e.g. the endpoint of a remote etcd member, and its audit
+// options, e.g. the flag giving the file of a file check.
+func auditorKey(c *Check) string {
+	key := c.Type + ":" + c.Audit
+	if len(c.AuditOptions) == 0 {
+		return key
+	}
+	options := make([]string, 0, len(c.AuditOptions))
+	for k, v := range c.AuditOptions {
+		options = append(options, k+"="+v)
+	}
+	sort.Strings(options)
+	return key + " [" + strings.Join(options, " ") + "]"
+}
+
+// truncatedKey notes in the evidence that the output of an audit was
+// truncated to the output limit of audits.
+func truncatedKey(audit string) string {
+	return "truncated:" + audit
+}
+
+// runAudit runs an audit command, or replays its recorded output. It also
+// reports whether the commands could not run to completion, and notes when
+// the output was truncated to the output limit of audits.
+func runAudit(audit string, commands []*exec.Cmd, out *bytes.Buffer) (State, string, bool, string) {
+	if o, ok, replaying := replayedOutput(audit); replaying {
+		if !ok {
+			return WARN, fmt.Sprintf("no recorded output for %q\n", audit), false, ""
+		}
+		out.WriteString(o)
+		truncated, _, _ := replayedOutput(truncatedKey(audit))
+		return "", "", false, truncated
+	}
+
+	capped := capOutput(out)
+	state, errmsgs, interrupted := execCommands(audit, commands, capped)
+	glog.V(3).Infof("Command %q - Output:\n\n %q\n - Error Messages:%q \n", audit, out.String(), errmsgs)
+	throttle(commands)
+	var truncated string
+	if len(state) == 0 {
+		recordOutput(audit, out.String())
+		if capped.truncated {
+			glog.V(1).Infof("Output of %q truncated to %d bytes", audit, auditLimits.MaxOutput)
+			truncated = fmt.Sprintf("output truncated to %d bytes", auditLimits.MaxOutput)
+			recordOutput(truncatedKey(audit), truncated)
+		}
+	}
+	return state, errmsgs, interrupted, truncated
+}
+
+// runNativeAudit runs a native auditor, or replays its recorded output.
+func runNativeAudit(c *Check, auditor auditorFunc) (string, error) {
+	key := auditorKey(c)
+	if o, ok, replaying := replayedOutput(key); replaying {
+		if !ok {
+			return "", fmt.Errorf("no recorded output for %q", key)
+		}
+		return o, nil
+	}
+
+	out, err := auditor(c)
+	if err == nil {
+		recordOutput(key, out)
+	}
+	return out, err
+}
+
+// replaying reports whether audit output is replayed rather than gathered.
+func replaying() bool {
+	evidenceMu.Lock()
+	defer evidenceMu.Unlock()
+	return replayed != nil
+}
+
+func replayedOutput(key string) (string, bool, bool) {
+	evidenceMu.Lock()
+	defer evidenceMu.Unlock()
+	if replayed == nil {
+		return "", false, false
+	}
+	o, ok := replayed[key]
+	return o, ok, true
+}
+
+func recordOutput(key, output string) {
+	evidenceMu.Lock()
+	defer evidenceMu.Unlock()
+	if recorded != nil {
+		recorded[key] = output
+	}
+}
@@ -0,0 +1,159 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func newEvidenceCheck(audit string) *Check {
+	return &Check{
+		ID:       "1.1",
+		Audit:    audit,
+		Commands: textToCommand(audit),
+		Scored:   true,
+		Tests: &tests{TestItems: []*testItem{
+			{Flag: "--anonymous-auth=false", Set: true},
+		}},
+	}
+}
+
+func TestRecordEvidence(t *testing.T) {
+	e := Evidence{}
+	RecordEvidence(e)
+	defer RecordEvidence(nil)
+
+	c := newEvidenceCheck("echo --anonymous-auth=false")
+	if state := c.run(); state != PASS {
+		t.Fatalf("expected PASS, got %s: %s", state, c.Reason)
+	}
+
+	if got := e[c.Audit]; got != "--anonymous-auth=false\n" {
+		t.Errorf("expected the audit output to be recorded, got %q", got)
+	}
+
+	fc := &Check{ID: "1.2", Type: FILE, Audit: "/nonexistent/file", Tests: &tests{}}
+	fc.run()
+	if _, ok := e[auditorKey(fc)]; ok {
+		t.Errorf("expected a failed auditor not to be recorded")
+	}
+}
+
+func TestReplayEvidence(t *testing.T) {
+	audit := "echo --anonymous-auth=false"
+	ReplayEvidence(Evidence{
+		audit:                       "--anonymous-auth=true\n",
+		FILE + ":/etc/kubernetes/a": "kind: Policy",
+	})
+	defer ReplayEvidence(nil)
+
+	cases := []struct {
+		check    *Check
+		expected State
+	}{
+		// The recorded output is used instead of running the audit.
+		{check: newEvidenceCheck(audit), expected: FAIL},
+		// Audits without recorded output can't be evaluated.
+		{check: newEvidenceCheck("echo missing"), expected: WARN},
+		{
+			check: &Check{ID: "1.3", Type: FILE, Audit: "/etc/kubernetes/a", Scored: true, Tests: &tests{TestItems: []*testItem{
+				{Path: "{.kind}", Set: true, Compare: compare{Op: "eq", Value: "Policy"}},
+			}}},
+			expected: PASS,
+		},
+		{check: &Check{ID: "1.4", Type: FILE, Audit: "/etc/kubernetes/b", Scored: true, Tests: &tests{}}, expected: WARN},
+	}
+
+	for _, c := range cases {
+		if state := c.check.run(); state != c.expected {
+			t.Errorf("%s: expected %s, got %s: %s", c.check.ID, c.expected, state, c.check.Reason)
+		}
+	}
+}
+
+func TestEvidenceOfFileChecksSharingAnAudit(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-evidence")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	encryption := filepath.Join(dir, "encryption.yaml")
+	policy := filepath.Join(dir, "policy.yaml")
+	if err := ioutil.WriteFile(encryption, []byte("kind: EncryptionConfiguration\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(policy, []byte("kind: Policy\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	// Like the checks of the encryption and audit policy files of the API
+	// server, both files are given by flags of the same command line.
+	audit := fmt.Sprintf("echo kube-apiserver --encryption-provider-config=%s --audit-policy-file=%s", encryption, policy)
+	fileCheck := func(id, flag, kind string) *Check {
+		return &Check{ID: id, Type: FILE, Audit: audit, AuditOptions: map[string]string{"flag": flag}, Scored: true, Tests: &tests{TestItems: []*testItem{
+			{Path: "{.kind}", Set: true, Compare: compare{Op: "eq", Value: kind}},
+		}}}
+	}
+	checks := func() []*Check {
+		return []*Check{
+			fileCheck("1.2.34", "--encryption-provider-config", "EncryptionConfiguration"),
+			fileCheck("3.2.1", "--audit-policy-file", "Policy"),
+		}
+	}
+
+	e := Evidence{}
+	RecordEvidence(e)
+	for _, c := range checks() {
+		if state := c.run(); state != PASS {
+			t.Errorf("%s: expected PASS when recording, got %s: %s", c.ID, state, c.Reason)
+		}
+	}
+	RecordEvidence(nil)
+
+	if err := os.RemoveAll(dir); err != nil {
+		t.Fatal(err)
+	}
+	ReplayEvidence(e)
+	defer ReplayEvidence(nil)
+	for _, c := range checks() {
+		if state := c.run(); state != PASS {
+			t.Errorf("%s: expected PASS when replaying, got %s: %s", c.ID, state, c.Reason)
+		}
+	}
+}
+
+func TestAuditorKey(t *testing.T) {
+	probe := &Check{Type: ETCDCONN, Audit: "https://10.0.0.2:2379"}
+	withCerts := &Check{Type: ETCDCONN, Audit: "https://10.0.0.2:2379", AuditOptions: map[string]string{
+		"keyfile":  "/etc/kubernetes/pki/apiserver-etcd-client.key",
+		"certfile": "/etc/kubernetes/pki/apiserver-etcd-client.crt",
+	}}
+	otherMember := &Check{Type: ETCDCONN, Audit: "https://10.0.0.3:2379"}
+
+	if auditorKey(probe) == auditorKey(withCerts) {
+		t.Errorf("expected the audit options to be part of the key, got %q", auditorKey(probe))
+	}
+	if auditorKey(probe) == auditorKey(otherMember) {
+		t.Errorf("expected the endpoint to be part of the key, got %q", auditorKey(probe))
+	}
+	expected := "etcd:https://10.0.0.2:2379 [certfile=/etc/kubernetes/pki/apiserver-etcd-client.crt keyfile=/etc/kubernetes/pki/apiserver-etcd-client.key]"
+	if key := auditorKey(withCerts); key != expected {
+		t.Errorf("expected %q, got %q", expected, key)
+	}
+}
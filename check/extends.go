@@ -0,0 +1,155 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"gopkg.in/yaml.v2"
+)
+
+// ResolveExtends applies a controls file declaring the controls it extends,
+// e.g.
+//
+//	extends: cis-1.6/node.yaml
+//
+// as an overlay on these controls. Its groups and checks replace the keys of
+// the groups and checks with the same id, and the others are added, so that
+// a profile of a distribution only lists what it changes. The path is
+// relative to dir, or else to its parent directory, the config directory.
+// The includes of the overlay must be resolved already.
+func ResolveExtends(in []byte, dir string) ([]byte, error) {
+	return resolveExtends(in, dir, map[string]bool{})
+}
+
+// numericIDRe matches the ids which YAML would otherwise decode as numbers,
+// turning e.g. 4.10 into 4.1.
+var numericIDRe = regexp.MustCompile(`(?m)^(\s*(?:-\s+)?id:\s*)([0-9][0-9.]*)\s*$`)
+
+func decodeControls(in []byte) (map[interface{}]interface{}, error) {
+	// Mappings are not decoded to yaml.MapSlice, which ignores merge keys.
+	var controls map[interface{}]interface{}
+	err := yaml.Unmarshal(numericIDRe.ReplaceAll(in, []byte(`$1"$2"`)), &controls)
+	if controls == nil {
+		controls = map[interface{}]interface{}{}
+	}
+	return controls, err
+}
+
+func resolveExtends(in []byte, dir string, seen map[string]bool) ([]byte, error) {
+	overlay, err := decodeControls(in)
+	if err != nil {
+		return nil, fmt.Errorf("failed to unmarshal YAML: %v", err)
+	}
+	extends, ok := overlay["extends"].(string)
+	if !ok || extends == "" {
+		return in, nil
+	}
+
+	path, err := extendedPath(extends, dir)
+	if err != nil {
+		return nil, err
+	}
+	if seen[path] {
+		return nil, fmt.Errorf("controls %s extend themselves", extends)
+	}
+	seen[path] = true
+
+	base, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extend %s: %v", extends, err)
+	}
+	base, err = ResolveIncludes(base, filepath.Dir(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to extend %s: %v", extends, err)
+	}
+	base, err = resolveExtends(base, filepath.Dir(path), seen)
+	if err != nil {
+		return nil, err
+	}
+
+	controls, err := decodeControls(base)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extend %s: %v", extends, err)
+	}
+	delete(controls, includedKey)
+	for key, value := range overlay {
+		switch key {
+		case "extends", includedKey:
+		case "groups":
+			controls[key] = mergeByID(controls[key], value, "checks")
+		default:
+			controls[key] = value
+		}
+	}
+
+	return yaml.Marshal(controls)
+}
+
+// extendedPath returns the path of the controls extended.
+func extendedPath(extends, dir string) (string, error) {
+	if filepath.IsAbs(extends) {
+		return extends, nil
+	}
+	path := filepath.Join(dir, extends)
+	if _, err := os.Stat(path); err != nil {
+		path = filepath.Join(filepath.Dir(dir), extends)
+	}
+	return filepath.Abs(path)
+}
+
+// mergeByID merges the overlay items into the base ones with the same id,
+// merging their children lists by id too, and adds the other ones.
+func mergeByID(base, overlay interface{}, children string) []interface{} {
+	items, _ := base.([]interface{})
+	merged := append([]interface{}{}, items...)
+	overlayItems, _ := overlay.([]interface{})
+	for _, o := range overlayItems {
+		om, ok := o.(map[interface{}]interface{})
+		if !ok {
+			continue
+		}
+
+		found := false
+		for i, b := range merged {
+			bm, ok := b.(map[interface{}]interface{})
+			if !ok || fmt.Sprint(bm["id"]) != fmt.Sprint(om["id"]) {
+				continue
+			}
+			item := map[interface{}]interface{}{}
+			for key, value := range bm {
+				item[key] = value
+			}
+			for key, value := range om {
+				if key == children && children != "" {
+					item[key] = mergeByID(bm[key], value, "")
+					continue
+				}
+				item[key] = value
+			}
+			merged[i] = item
+			found = true
+			break
+		}
+		if !found {
+			merged = append(merged, om)
+		}
+	}
+	return merged
+}
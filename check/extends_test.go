@@ -0,0 +1,120 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const overlayControls = `---
+controls:
+extends: cis-1.6/node.yaml
+text: "k3s Worker Node Security Configuration"
+groups:
+- id: 4.2
+  checks:
+  - id: 4.2.2
+    audit: "journalctl -u k3s | grep -m1 read-only-port"
+    scored: true
+  - id: 4.2.3
+    text: "Ensure that the --protect-kernel-defaults argument is set to true"
+    audit: "journalctl -u k3s | grep -m1 protect-kernel-defaults"
+- id: 4.10
+  text: "k3s"
+  checks:
+  - id: 4.10.1
+    text: "Ensure that the k3s token file is protected"
+`
+
+func TestResolveExtends(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-extends")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for _, d := range []string{"cis-1.6/fragments", "k3s-1.0"} {
+		if err := os.MkdirAll(filepath.Join(dir, d), 0700); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "cis-1.6", "fragments", "kubelet.yaml"), []byte(includedFragment), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "cis-1.6", "node.yaml"), []byte(includingControls), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	in, err := ResolveExtends([]byte(overlayControls), filepath.Join(dir, "k3s-1.0"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	controls, err := NewControls(NODE, in)
+	if err != nil {
+		t.Fatalf("failed to load extended controls: %v\n%s", err, in)
+	}
+
+	if controls.ID != "4" || controls.Text != "k3s Worker Node Security Configuration" {
+		t.Errorf("expected the text of the overlay on the controls, got %q %q", controls.ID, controls.Text)
+	}
+	if len(controls.Groups) != 2 || controls.Groups[0].Text != "Kubelet" || len(controls.Groups[0].Checks) != 3 {
+		t.Fatalf("unexpected groups\n%s", in)
+	}
+	c1, c2, c3 := controls.Groups[0].Checks[0], controls.Groups[0].Checks[1], controls.Groups[0].Checks[2]
+	if c1.Audit != "/bin/ps -fC kubelet" || c1.Tests == nil || c1.Tests.TestItems[0].Flag != "--anonymous-auth" {
+		t.Errorf("expected 4.2.1 to be kept, got %+v", c1)
+	}
+	if c2.Text != "Ensure that the --read-only-port argument is set to 0" || c2.Audit != "journalctl -u k3s | grep -m1 read-only-port" || !c2.Scored {
+		t.Errorf("expected 4.2.2 to be overridden, got %+v", c2)
+	}
+	if c3.ID != "4.2.3" {
+		t.Errorf("expected 4.2.3 to be added, got %+v", c3)
+	}
+	if g := controls.Groups[1]; g.ID != "4.10" || len(g.Checks) != 1 {
+		t.Errorf("expected group 4.10 to be added, got %+v", g)
+	}
+}
+
+func TestResolveExtendsWithoutDirective(t *testing.T) {
+	in := []byte("---\ncontrols:\nid: 1\n")
+	out, err := ResolveExtends(in, ".")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(out) != string(in) {
+		t.Errorf("expected controls to be unchanged, got %q", out)
+	}
+}
+
+func TestResolveExtendsErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-extends")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "loop.yaml"), []byte("extends: loop.yaml\nid: 1\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ResolveExtends([]byte("extends: loop.yaml\n"), dir); err == nil {
+		t.Errorf("expected an error for controls extending themselves")
+	}
+	if _, err := ResolveExtends([]byte("extends: missing.yaml\n"), dir); err == nil {
+		t.Errorf("expected an error for missing extended controls")
+	}
+}
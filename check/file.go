@@ -0,0 +1,88 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+// auditFile returns the contents of the file named by the check, so that
+// its tests can inspect the YAML or JSON document with paths.
+//
+// The audit field is the path of the file. If audit_options sets a flag,
+// the audit field is instead a command, e.g. "/bin/ps -ef | grep $apiserverbin",
+// and the file is the value of that flag in the command's output, read
+// under the root audit option when set.
+func auditFile(c *Check) (string, error) {
+	path, err := auditFilePath(c)
+	if err != nil {
+		return "", err
+	}
+
+	glog.V(2).Infof("Check.ID: %s reading file %s", c.ID, path)
+	data, err := ioutil.ReadFile(path)
+	if os.IsPermission(err) {
+		return "", fmt.Errorf("%w reading %s", ErrPermissionDenied, path)
+	}
+	if err != nil {
+		return "", fmt.Errorf("failed to read %s: %v", path, err)
+	}
+
+	return string(data), nil
+}
+
+// auditFilePath returns the path of the file named by a check, given by its
+// audit field or by a flag of the output of its audit command.
+func auditFilePath(c *Check) (string, error) {
+	path := strings.TrimSpace(c.Audit)
+
+	if flag := c.AuditOptions["flag"]; flag != "" {
+		out, err := auditOutput(c.Audit)
+		if err != nil {
+			return "", err
+		}
+
+		path = flagValue(out, flag)
+		if path == "" {
+			return "", fmt.Errorf("flag %s is not set", flag)
+		}
+		// The path is in the filesystem of the process, mounted at root.
+		if root := c.AuditOptions["root"]; root != "" && filepath.IsAbs(path) {
+			path = strings.TrimRight(root, "/") + path
+		}
+	}
+
+	if path == "" {
+		return "", fmt.Errorf("missing file path")
+	}
+	return path, nil
+}
+
+// flagValue returns the value of a flag given as --flag=value or --flag value.
+func flagValue(s, flag string) string {
+	re := regexp.MustCompile(regexp.QuoteMeta(flag) + `(?:=|\s+)(\S+)`)
+	vals := re.FindStringSubmatch(s)
+	if len(vals) < 2 {
+		return ""
+	}
+	return strings.Trim(vals[1], `'"`)
+}
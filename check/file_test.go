@@ -0,0 +1,299 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"gopkg.in/yaml.v2"
+)
+
+const auditPolicy = `
+apiVersion: audit.k8s.io/v1
+kind: Policy
+omitStages:
+  - "ResponseStarted"
+rules:
+  - level: None
+    resources:
+    - group: ""
+      resources: ["events"]
+  - level: Metadata
+    resources:
+    - group: ""
+      resources: ["secrets", "configmaps"]
+`
+
+func TestCheck_RunFile(t *testing.T) {
+	f, err := ioutil.TempFile("", "audit-policy")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString(auditPolicy); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+
+	notOmitted := &testItem{Path: "{.omitStages}", Set: false, Compare: compare{}}
+	secretsMetadata := &testItem{
+		Path:    `{.rules[?(@.level=="Metadata")].resources[*].resources[*]}`,
+		Set:     true,
+		Compare: compare{Op: "has", Value: "secrets"},
+	}
+	omitsRequestReceived := &testItem{Path: "{.omitStages}", Set: true, Compare: compare{Op: "nothave", Value: "RequestReceived"}}
+
+	cases := []struct {
+		name     string
+		audit    string
+		opts     map[string]string
+		items    []*testItem
+		expected State
+	}{
+		{name: "file read from path", audit: f.Name(), items: []*testItem{secretsMetadata, omitsRequestReceived}, expected: PASS},
+		{name: "test on file content fails", audit: f.Name(), items: []*testItem{notOmitted}, expected: FAIL},
+		{
+			name:     "file read from flag",
+			audit:    "echo --audit-policy-file=" + f.Name(),
+			opts:     map[string]string{"flag": "--audit-policy-file"},
+			items:    []*testItem{secretsMetadata},
+			expected: PASS,
+		},
+		{
+			name:     "file read from flag under root",
+			audit:    "echo --audit-policy-file=/" + filepath.Base(f.Name()),
+			opts:     map[string]string{"flag": "--audit-policy-file", "root": filepath.Dir(f.Name())},
+			items:    []*testItem{secretsMetadata},
+			expected: PASS,
+		},
+		{
+			name:     "flag not set",
+			audit:    "echo --audit-log-path=/var/log/audit.log",
+			opts:     map[string]string{"flag": "--audit-policy-file"},
+			items:    []*testItem{secretsMetadata},
+			expected: WARN,
+		},
+		{name: "missing file", audit: "/does/not/exist", items: []*testItem{secretsMetadata}, expected: WARN},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			check := &Check{Type: FILE, Audit: c.audit, AuditOptions: c.opts, Scored: true, Tests: &tests{TestItems: c.items}}
+			check.run()
+			if check.State != c.expected {
+				t.Errorf("expected %s, actual %s (%s)", c.expected, check.State, check.Reason)
+			}
+		})
+	}
+}
+
+func TestCheck_RunFileAuditPolicy(t *testing.T) {
+	// 3.2.2 isn't scored, so it generates WARN when it fails.
+	cases := []struct {
+		name     string
+		policy   string
+		expected State
+	}{
+		{name: "secrets logged, ResponseStarted omitted", policy: auditPolicy, expected: PASS},
+		{
+			name: "no stage omitted",
+			policy: `
+apiVersion: audit.k8s.io/v1
+kind: Policy
+rules:
+  - level: RequestResponse
+    resources:
+    - group: ""
+      resources: ["secrets"]
+`,
+			expected: PASS,
+		},
+		{
+			name: "RequestReceived omitted",
+			policy: `
+apiVersion: audit.k8s.io/v1
+kind: Policy
+omitStages:
+  - "RequestReceived"
+rules:
+  - level: Metadata
+    resources:
+    - group: ""
+      resources: ["secrets"]
+`,
+			expected: WARN,
+		},
+		{
+			name: "secrets not logged",
+			policy: `
+apiVersion: audit.k8s.io/v1
+kind: Policy
+rules:
+  - level: None
+    resources:
+    - group: ""
+      resources: ["secrets"]
+`,
+			expected: WARN,
+		},
+		{
+			name:     "not an audit policy",
+			policy:   "rules: []\n",
+			expected: WARN,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f, err := ioutil.TempFile("", "audit-policy")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(f.Name())
+			if _, err := f.WriteString(c.policy); err != nil {
+				t.Fatal(err)
+			}
+			f.Close()
+
+			check := shippedCheck(t, "cis-1.5/controlplane.yaml", "3.2.2")
+			check.Audit, check.AuditOptions = f.Name(), nil
+			check.run()
+			if check.State != c.expected {
+				t.Errorf("expected %s, actual %s (%s)", c.expected, check.State, check.Reason)
+			}
+		})
+	}
+}
+
+func TestCheck_RunFileEncryptionConfig(t *testing.T) {
+	cases := []struct {
+		name     string
+		config   string
+		expected State
+	}{
+		{
+			name: "aescbc listed before identity",
+			config: `
+kind: EncryptionConfiguration
+resources:
+  - resources:
+    - configmaps
+    - secrets
+    providers:
+    - aescbc:
+        keys:
+        - name: key1
+          secret: c2VjcmV0IGlzIHNlY3VyZQ==
+    - identity: {}
+`,
+			expected: PASS,
+		},
+		{
+			name: "identity listed before aescbc",
+			config: `
+kind: EncryptionConfiguration
+resources:
+  - resources:
+    - secrets
+    providers:
+    - identity: {}
+    - aescbc:
+        keys:
+        - name: key1
+          secret: c2VjcmV0IGlzIHNlY3VyZQ==
+`,
+			expected: FAIL,
+		},
+		{
+			name: "secrets not covered",
+			config: `
+kind: EncryptionConfiguration
+resources:
+  - resources:
+    - configmaps
+    providers:
+    - kms:
+        name: myKmsPlugin
+        endpoint: unix:///tmp/socketfile.sock
+`,
+			expected: FAIL,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			f, err := ioutil.TempFile("", "encryption-config")
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer os.Remove(f.Name())
+			if _, err := f.WriteString(c.config); err != nil {
+				t.Fatal(err)
+			}
+			f.Close()
+
+			check := shippedCheck(t, "cis-1.5/master.yaml", "1.2.34")
+			check.Audit, check.AuditOptions = f.Name(), nil
+			check.run()
+			if check.State != c.expected {
+				t.Errorf("expected %s, actual %s (%s)", c.expected, check.State, check.Reason)
+			}
+		})
+	}
+}
+
+func TestFlagValue(t *testing.T) {
+	cases := []struct {
+		s        string
+		flag     string
+		expected string
+	}{
+		{s: "kube-apiserver --audit-policy-file=/etc/policy.yaml --v=2", flag: "--audit-policy-file", expected: "/etc/policy.yaml"},
+		{s: "kube-apiserver --audit-policy-file /etc/policy.yaml", flag: "--audit-policy-file", expected: "/etc/policy.yaml"},
+		{s: `kube-apiserver --audit-policy-file="/etc/policy.yaml"`, flag: "--audit-policy-file", expected: "/etc/policy.yaml"},
+		{s: "kube-apiserver --v=2", flag: "--audit-policy-file", expected: ""},
+	}
+
+	for _, c := range cases {
+		if actual := flagValue(c.s, c.flag); actual != c.expected {
+			t.Errorf("flagValue(%q, %q): expected %q, actual %q", c.s, c.flag, c.expected, actual)
+		}
+	}
+}
+
+// shippedCheck returns a check of the controls shipped in cfgDir.
+func shippedCheck(t *testing.T, file, id string) *Check {
+	t.Helper()
+	in, err := ioutil.ReadFile(filepath.Join(cfgDir, file))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var controls Controls
+	if err := yaml.Unmarshal(in, &controls); err != nil {
+		t.Fatalf("failed to load %s: %v", file, err)
+	}
+	for _, g := range controls.Groups {
+		for _, c := range g.Checks {
+			if c.ID == id {
+				return c
+			}
+		}
+	}
+	t.Fatalf("no check %s in %s", id, file)
+	return nil
+}
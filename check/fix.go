@@ -0,0 +1,209 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// backupSuffix is the suffix of the copies of the files kept before
+// remediations first edit them.
+const backupSuffix = ".kube-bench.bak"
+
+// manifestArgRe matches the arguments of the command of a container in a
+// pod specification file, one per line, e.g. "    - --profiling=false".
+var manifestArgRe = regexp.MustCompile(`^(\s*-\s+)(--[\w-]+)(=.*)?$`)
+
+// Apply carries out the step on the node: it edits the file of file and
+// flag steps, and runs the command of command steps with the audit shell.
+// root is prepended to the paths of the files, e.g. the mount of the host.
+func (s RemediationStep) Apply(root string) error {
+	if err := s.validate(); err != nil {
+		return err
+	}
+	path := filepath.Join(root, s.File)
+	switch s.Type {
+	case FileStep:
+		return editFile(path, func(data []byte) ([]byte, error) { return setKey(path, data, s.Key, s.Value, s.Unset) })
+	case FlagStep:
+		return editFile(path, func(data []byte) ([]byte, error) { return setFlag(path, data, s.Flag, s.Value, s.Unset) })
+	}
+
+	args := append(append([]string{}, auditShell[1:]...), "-c", s.Command)
+	out, err := exec.Command(auditShell[0], args...).CombinedOutput()
+	if err != nil {
+		return fmt.Errorf("failed to run %q: %v: %s", s.Command, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// editFile edits a file in place, keeping its mode. A copy of the file is
+// kept before it's first edited.
+func editFile(path string, edit func([]byte) ([]byte, error)) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	edited, err := edit(data)
+	if err != nil {
+		return fmt.Errorf("failed to edit %s: %v", path, err)
+	}
+	if string(edited) == string(data) {
+		return nil
+	}
+
+	if _, err := os.Stat(path + backupSuffix); os.IsNotExist(err) {
+		if err := ioutil.WriteFile(path+backupSuffix, data, info.Mode().Perm()); err != nil {
+			return err
+		}
+	}
+	return ioutil.WriteFile(path, edited, info.Mode().Perm())
+}
+
+// setFlag sets a flag, or removes it. In a pod specification file, the flag
+// is an argument of its own line, added after the last argument when not
+// set. In other files, e.g. systemd drop-ins, only flags already set can be
+// changed.
+func setFlag(path string, data []byte, flag, value string, unset bool) ([]byte, error) {
+	lines := strings.Split(string(data), "\n")
+	if ext := filepath.Ext(path); ext == ".yaml" || ext == ".yml" {
+		last := -1
+		for i, l := range lines {
+			m := manifestArgRe.FindStringSubmatch(l)
+			if m == nil {
+				continue
+			}
+			if m[2] == flag {
+				if unset {
+					lines = append(lines[:i], lines[i+1:]...)
+				} else {
+					lines[i] = m[1] + flag + "=" + value
+				}
+				return []byte(strings.Join(lines, "\n")), nil
+			}
+			last = i
+		}
+		if unset {
+			return data, nil
+		}
+		if last < 0 {
+			return nil, fmt.Errorf("no command arguments to add %s to", flag)
+		}
+		prefix := manifestArgRe.FindStringSubmatch(lines[last])[1]
+		lines = append(lines[:last+1], append([]string{prefix + flag + "=" + value}, lines[last+1:]...)...)
+		return []byte(strings.Join(lines, "\n")), nil
+	}
+
+	flagRe := regexp.MustCompile(`(^|[\s"'=])` + regexp.QuoteMeta(flag) + `(=[^\s"']*)?([\s"']|$)`)
+	if !flagRe.Match(data) {
+		if unset {
+			return data, nil
+		}
+		return nil, fmt.Errorf("%s is not set, it can only be added to pod specification files", flag)
+	}
+	return flagRe.ReplaceAllFunc(data, func(match []byte) []byte {
+		m := flagRe.FindSubmatch(match)
+		if unset {
+			if strings.TrimSpace(string(m[3])) == "" {
+				return m[1]
+			}
+			return []byte(string(m[1]) + string(m[3]))
+		}
+		return []byte(string(m[1]) + flag + "=" + value + string(m[3]))
+	}), nil
+}
+
+// setKey sets a key of a YAML or JSON config file, given as a dotted path
+// such as authentication.anonymous.enabled, or removes it. The value is
+// typed as in YAML, e.g. false is a boolean. The comments of YAML files are
+// not kept.
+func setKey(path string, data []byte, key, value string, unset bool) ([]byte, error) {
+	var v interface{}
+	if err := yaml.Unmarshal([]byte(value), &v); err != nil {
+		v = value
+	}
+
+	if filepath.Ext(path) == ".json" {
+		doc := map[string]interface{}{}
+		if err := json.Unmarshal(data, &doc); err != nil {
+			return nil, err
+		}
+		parts := strings.Split(key, ".")
+		m := doc
+		for _, p := range parts[:len(parts)-1] {
+			next, ok := m[p].(map[string]interface{})
+			if !ok {
+				if unset {
+					return data, nil
+				}
+				next = map[string]interface{}{}
+				m[p] = next
+			}
+			m = next
+		}
+		if unset {
+			delete(m, parts[len(parts)-1])
+		} else {
+			m[parts[len(parts)-1]] = v
+		}
+		return json.MarshalIndent(doc, "", "  ")
+	}
+
+	var doc yaml.MapSlice
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+	doc = setMapSliceKey(doc, strings.Split(key, "."), v, unset)
+	return yaml.Marshal(doc)
+}
+
+func setMapSliceKey(m yaml.MapSlice, parts []string, v interface{}, unset bool) yaml.MapSlice {
+	for i, item := range m {
+		if fmt.Sprint(item.Key) != parts[0] {
+			continue
+		}
+		if len(parts) == 1 {
+			if unset {
+				return append(m[:i], m[i+1:]...)
+			}
+			m[i].Value = v
+			return m
+		}
+		child, _ := item.Value.(yaml.MapSlice)
+		m[i].Value = setMapSliceKey(child, parts[1:], v, unset)
+		return m
+	}
+
+	if unset {
+		return m
+	}
+	if len(parts) == 1 {
+		return append(m, yaml.MapItem{Key: parts[0], Value: v})
+	}
+	return append(m, yaml.MapItem{Key: parts[0], Value: setMapSliceKey(nil, parts[1:], v, unset)})
+}
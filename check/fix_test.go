@@ -0,0 +1,168 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSetFlag(t *testing.T) {
+	manifest := `spec:
+  containers:
+  - command:
+    - kube-apiserver
+    - --profiling=true
+    - --anonymous-auth=true
+    image: k8s.gcr.io/kube-apiserver
+`
+	service := `[Service]
+Environment="KUBELET_ARGS=--anonymous-auth=true --read-only-port=10255"
+`
+
+	cases := []struct {
+		name  string
+		path  string
+		data  string
+		flag  string
+		value string
+		unset bool
+		want  string
+		err   bool
+	}{
+		{name: "replace manifest flag", path: "kube-apiserver.yaml", data: manifest, flag: "--profiling", value: "false",
+			want: strings.Replace(manifest, "--profiling=true", "--profiling=false", 1)},
+		{name: "add manifest flag", path: "kube-apiserver.yaml", data: manifest, flag: "--audit-log-maxage", value: "30",
+			want: strings.Replace(manifest, "    - --anonymous-auth=true\n", "    - --anonymous-auth=true\n    - --audit-log-maxage=30\n", 1)},
+		{name: "unset manifest flag", path: "kube-apiserver.yaml", data: manifest, flag: "--profiling", unset: true,
+			want: strings.Replace(manifest, "    - --profiling=true\n", "", 1)},
+		{name: "replace service flag", path: "10-kubeadm.conf", data: service, flag: "--read-only-port", value: "0",
+			want: strings.Replace(service, "--read-only-port=10255", "--read-only-port=0", 1)},
+		{name: "unset service flag", path: "10-kubeadm.conf", data: service, flag: "--anonymous-auth", unset: true,
+			want: strings.Replace(service, "--anonymous-auth=true ", "", 1)},
+		{name: "add service flag", path: "10-kubeadm.conf", data: service, flag: "--rotate-certificates", value: "true", err: true},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got, err := setFlag(c.path, []byte(c.data), c.flag, c.value, c.unset)
+			if c.err {
+				if err == nil {
+					t.Fatalf("expected an error, got %q", got)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != c.want {
+				t.Errorf("got\n%s\nwant\n%s", got, c.want)
+			}
+		})
+	}
+}
+
+func TestSetKey(t *testing.T) {
+	config := `apiVersion: kubelet.config.k8s.io/v1beta1
+authentication:
+  anonymous:
+    enabled: true
+readOnlyPort: 10255
+`
+
+	got, err := setKey("config.yaml", []byte(config), "authentication.anonymous.enabled", "false", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), "  anonymous:\n    enabled: false\n") {
+		t.Errorf("key not set to a boolean:\n%s", got)
+	}
+
+	got, err = setKey("config.yaml", []byte(config), "authorization.mode", "Webhook", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(string(got), "authorization:\n  mode: Webhook\n") {
+		t.Errorf("key not added:\n%s", got)
+	}
+
+	got, err = setKey("config.yaml", []byte(config), "readOnlyPort", "", true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(got), "readOnlyPort") {
+		t.Errorf("key not removed:\n%s", got)
+	}
+
+	got, err = setKey("config.json", []byte(`{"authentication": {"anonymous": {"enabled": true}}}`), "authentication.anonymous.enabled", "false", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(got), `"enabled": false`) {
+		t.Errorf("JSON key not set:\n%s", got)
+	}
+}
+
+func TestApplyRemediationStep(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-fix")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "config.yaml")
+	if err := ioutil.WriteFile(file, []byte("readOnlyPort: 10255\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	steps := []RemediationStep{
+		{Type: FileStep, File: "/config.yaml", Key: "readOnlyPort", Value: "0"},
+		{Type: FileStep, File: "/config.yaml", Key: "protectKernelDefaults", Value: "true"},
+	}
+	for _, s := range steps {
+		if err := s.Apply(dir); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	got, _ := ioutil.ReadFile(file)
+	if string(got) != "readOnlyPort: 0\nprotectKernelDefaults: true\n" {
+		t.Errorf("unexpected edited file:\n%s", got)
+	}
+	backup, _ := ioutil.ReadFile(file + backupSuffix)
+	if string(backup) != "readOnlyPort: 10255\n" {
+		t.Errorf("backup should be the file before its first edit, got:\n%s", backup)
+	}
+	if info, _ := os.Stat(file); info.Mode().Perm() != 0600 {
+		t.Errorf("mode of the file changed to %v", info.Mode().Perm())
+	}
+
+	touched := filepath.Join(dir, "touched")
+	if err := (RemediationStep{Type: CommandStep, Command: "touch " + touched}).Apply(""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := os.Stat(touched); err != nil {
+		t.Errorf("command not run: %v", err)
+	}
+	if err := (RemediationStep{Type: CommandStep, Command: "exit 3"}).Apply(""); err == nil {
+		t.Errorf("expected an error from a failing command")
+	}
+	if err := (RemediationStep{Type: FileStep, File: "/missing.yaml", Key: "a", Value: "b"}).Apply(dir); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
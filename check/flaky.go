@@ -0,0 +1,91 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import "sync"
+
+const (
+	// flakyWindow is the number of runs of each check the tracker keeps.
+	flakyWindow = 10
+	// flakyFlaps is how many times the state of a check must change while
+	// its inputs don't, within the runs kept, for it to be flagged as flaky.
+	flakyFlaps = 2
+)
+
+// checkRun is the state of a check in a run, and the fingerprint of its
+// inputs then.
+type checkRun struct {
+	fingerprint string
+	state       State
+}
+
+// FlakinessTracker keeps the recent runs of the checks of a long-running
+// kube-bench, to find the ones whose state flaps while the files and
+// processes they look at don't change.
+type FlakinessTracker struct {
+	mu   sync.Mutex
+	runs map[string][]checkRun
+}
+
+// NewFlakinessTracker returns a tracker without any run.
+func NewFlakinessTracker() *FlakinessTracker {
+	return &FlakinessTracker{runs: map[string][]checkRun{}}
+}
+
+// record adds a run of a check, and reports whether the check is flaky.
+func (t *FlakinessTracker) record(key string, run checkRun) bool {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	runs := append(t.runs[key], run)
+	if len(runs) > flakyWindow {
+		runs = runs[len(runs)-flakyWindow:]
+	}
+	t.runs[key] = runs
+
+	flaps := 0
+	for i := 1; i < len(runs); i++ {
+		if runs[i].fingerprint == runs[i-1].fingerprint && runs[i].state != runs[i-1].state {
+			flaps++
+		}
+	}
+	return flaps >= flakyFlaps
+}
+
+// NewFlakinessRunner returns a Runner running checks with runner, and
+// flagging the ones that are flaky according to the tracker. Checks whose
+// inputs are not known, e.g. the ones querying the API server, are not
+// tracked.
+func NewFlakinessRunner(runner Runner, tracker *FlakinessTracker) Runner {
+	return &flakinessRunner{runner: runner, tracker: tracker}
+}
+
+type flakinessRunner struct {
+	runner  Runner
+	tracker *FlakinessTracker
+	inputs  fingerprinter
+}
+
+func (r *flakinessRunner) Run(c *Check) State {
+	fp, ok := r.inputs.fingerprint(c)
+	state := r.runner.Run(c)
+	if ok {
+		// Checks are told apart by their audits too, e.g. the same check run
+		// against several kubelets.
+		key := c.ID + "\x00" + c.Audit + "\x00" + c.AuditConfig
+		c.Flaky = r.tracker.record(key, checkRun{fingerprint: fp, state: state})
+	}
+	return state
+}
@@ -0,0 +1,74 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// flappingRunner alternates between passing and failing each check.
+type flappingRunner map[string]int
+
+func (r flappingRunner) Run(c *Check) State {
+	r[c.ID]++
+	c.State = PASS
+	if r[c.ID]%2 == 0 {
+		c.State = FAIL
+	}
+	return c.State
+}
+
+func TestFlakinessRunner(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-flaky")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	stable := filepath.Join(dir, "stable.conf")
+	changing := filepath.Join(dir, "changing.conf")
+	if err := ioutil.WriteFile(stable, []byte("a"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	checks := []*Check{
+		{ID: "4.1.1", Audit: "stat -c %a " + stable},
+		// The state of this check follows the changes of its file.
+		{ID: "4.1.2", Audit: "stat -c %a " + changing},
+		// Checks whose inputs are not known are not tracked.
+		{ID: "4.2.1", Audit: "kubectl get nodes"},
+	}
+	tracker := NewFlakinessTracker()
+	runner := flappingRunner{}
+	expected := []bool{false, false, true, true}
+	for scan, flaky := range expected {
+		if err := ioutil.WriteFile(changing, []byte(strings.Repeat("a", scan+1)), 0600); err != nil {
+			t.Fatal(err)
+		}
+		r := NewFlakinessRunner(runner, tracker)
+		for _, c := range checks {
+			r.Run(c)
+		}
+		if checks[0].Flaky != flaky {
+			t.Errorf("scan %d: expected flaky %v, got %v", scan, flaky, checks[0].Flaky)
+		}
+		if checks[1].Flaky || checks[2].Flaky {
+			t.Errorf("scan %d: unexpected flaky checks", scan)
+		}
+	}
+}
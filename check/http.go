@@ -0,0 +1,142 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// httpTimeout bounds every request made by an "http" check.
+var httpTimeout = 5 * time.Second
+
+// maxHTTPBodySize bounds the part of a response matched against body_regex.
+const maxHTTPBodySize = 1 << 20
+
+// httpProbe is the output of an "http" check. The fields are available to
+// the check's tests as paths, e.g. '{.authorized}'.
+type httpProbe struct {
+	URL        string `json:"url"`
+	Reachable  bool   `json:"reachable"`
+	StatusCode int    `json:"status_code"`
+	Authorized bool   `json:"authorized"`
+	// BodyMatches tells whether the body matches the body_regex audit option.
+	BodyMatches bool `json:"body_matches"`
+}
+
+// auditHTTP makes a request to the URL given in the check's audit field,
+// anonymous unless a client certificate is given in the check's
+// audit_options (certfile and keyfile). The options also set the method
+// (GET by default), the CA the server certificate is verified against
+// (cafile, not verified otherwise) and a regular expression to match the
+// response body against (body_regex). An endpoint that can't be reached is
+// not an error: it is reported as not reachable so that exposure checks
+// pass when a port is closed.
+func auditHTTP(c *Check) (string, error) {
+	target := strings.TrimSpace(c.Audit)
+	u, err := url.Parse(target)
+	if err != nil || u.Scheme == "" || u.Host == "" {
+		return "", fmt.Errorf("invalid URL %q", target)
+	}
+
+	method := c.AuditOptions["method"]
+	if method == "" {
+		method = http.MethodGet
+	}
+	var bodyRegex *regexp.Regexp
+	if expr := c.AuditOptions["body_regex"]; expr != "" {
+		if bodyRegex, err = regexp.Compile(expr); err != nil {
+			return "", fmt.Errorf("invalid body_regex: %v", err)
+		}
+	}
+	tlsConfig, err := httpTLSConfig(c.AuditOptions)
+	if err != nil {
+		return "", err
+	}
+	req, err := http.NewRequest(strings.ToUpper(method), target, nil)
+	if err != nil {
+		return "", fmt.Errorf("invalid request: %v", err)
+	}
+
+	client := &http.Client{
+		Timeout:   httpTimeout,
+		Transport: &http.Transport{TLSClientConfig: tlsConfig},
+	}
+
+	probe := &httpProbe{URL: target}
+	resp, err := client.Do(req)
+	if err != nil {
+		glog.V(2).Infof("http probe of %s failed: %v", target, err)
+	} else {
+		defer resp.Body.Close()
+		probe.Reachable = true
+		probe.StatusCode = resp.StatusCode
+		probe.Authorized = resp.StatusCode >= 200 && resp.StatusCode < 300
+		if bodyRegex != nil {
+			body, err := ioutil.ReadAll(io.LimitReader(resp.Body, maxHTTPBodySize))
+			if err != nil {
+				return "", fmt.Errorf("failed to read response from %s: %v", target, err)
+			}
+			probe.BodyMatches = bodyRegex.Match(body)
+		}
+	}
+
+	out, err := json.Marshal(probe)
+	if err != nil {
+		return "", err
+	}
+
+	glog.V(3).Infof("http probe of %s: %s", target, out)
+	return string(out), nil
+}
+
+// httpTLSConfig returns the TLS configuration of an "http" check. Without
+// cafile, we only want to know whether the endpoint answers, so the server
+// certificate is not verified.
+func httpTLSConfig(opts map[string]string) (*tls.Config, error) {
+	conf := &tls.Config{InsecureSkipVerify: true}
+
+	if opts["certfile"] != "" || opts["keyfile"] != "" {
+		cert, err := tls.LoadX509KeyPair(opts["certfile"], opts["keyfile"])
+		if err != nil {
+			return nil, fmt.Errorf("failed to load client certificate: %v", err)
+		}
+		conf.Certificates = []tls.Certificate{cert}
+	}
+
+	if opts["cafile"] != "" {
+		ca, err := ioutil.ReadFile(opts["cafile"])
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %v", err)
+		}
+		conf.InsecureSkipVerify = false
+		conf.RootCAs = x509.NewCertPool()
+		if !conf.RootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("no certificates found in CA file %s", opts["cafile"])
+		}
+	}
+	return conf, nil
+}
@@ -0,0 +1,107 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"encoding/pem"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCheck_RunHTTP(t *testing.T) {
+	open := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer open.Close()
+	closed := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusUnauthorized)
+	}))
+	defer closed.Close()
+
+	notAuthorized := []*testItem{{Path: "{.authorized}", Set: true, Compare: compare{Op: "eq", Value: "false"}}}
+
+	cases := []struct {
+		name     string
+		audit    string
+		expected State
+	}{
+		{name: "anonymous request succeeds", audit: open.URL + "/pods", expected: FAIL},
+		{name: "anonymous request is rejected", audit: closed.URL + "/pods", expected: PASS},
+		{name: "port is closed", audit: "http://127.0.0.1:1/pods", expected: PASS},
+		{name: "invalid URL", audit: "127.0.0.1:10255", expected: WARN},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			check := &Check{Type: HTTP, Audit: c.audit, Scored: true, Tests: &tests{TestItems: notAuthorized}}
+			check.run()
+			if check.State != c.expected {
+				t.Errorf("expected %s, actual %s (%s)", c.expected, check.State, check.Reason)
+			}
+		})
+	}
+}
+
+func TestCheck_RunHTTPOptions(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodHead {
+			fmt.Fprint(w, "ok")
+		}
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "kube-bench-http")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	ca := filepath.Join(dir, "ca.crt")
+	if err := ioutil.WriteFile(ca, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw}), 0644); err != nil {
+		t.Fatal(err)
+	}
+	invalidCA := filepath.Join(dir, "invalid.crt")
+	if err := ioutil.WriteFile(invalidCA, []byte("not a certificate"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	bodyMatches := []*testItem{{Path: "{.body_matches}", Set: true, Compare: compare{Op: "eq", Value: "true"}}}
+
+	cases := []struct {
+		name     string
+		options  map[string]string
+		expected State
+	}{
+		{name: "body matches", options: map[string]string{"body_regex": "^ok$"}, expected: PASS},
+		{name: "body does not match", options: map[string]string{"body_regex": "^healthy$"}, expected: FAIL},
+		{name: "method", options: map[string]string{"method": "head", "body_regex": "ok"}, expected: FAIL},
+		{name: "server verified", options: map[string]string{"cafile": ca, "body_regex": "ok"}, expected: PASS},
+		{name: "invalid CA file", options: map[string]string{"cafile": invalidCA, "body_regex": "ok"}, expected: WARN},
+		{name: "invalid body_regex", options: map[string]string{"body_regex": "("}, expected: WARN},
+		{name: "missing client certificate", options: map[string]string{"certfile": filepath.Join(dir, "missing.crt")}, expected: WARN},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			check := &Check{Type: HTTP, Audit: server.URL + "/healthz", AuditOptions: c.options, Scored: true, Tests: &tests{TestItems: bodyMatches}}
+			check.run()
+			if check.State != c.expected {
+				t.Errorf("expected %s, actual %s (%s)", c.expected, check.State, check.Reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/client-go/kubernetes"
+)
+
+// untrustedRegistryImages finds containers whose image doesn't come from
+// one of the registries in the "registries" audit option. Registries are
+// matched as leading path components of the fully qualified image name, so
+// an entry can also restrict images to a repository, e.g. "docker.io/library/".
+func untrustedRegistryImages(client kubernetes.Interface, opts map[string]string) ([]string, error) {
+	registries := optionList(opts, "registries")
+	if len(registries) == 0 {
+		return nil, fmt.Errorf("no allowed registries configured")
+	}
+
+	pods, err := listPods(client, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []string
+	for _, p := range pods {
+		for _, c := range allContainers(p.Spec) {
+			if !fromRegistry(qualifiedImageName(c.Image), registries) {
+				violations = append(violations, fmt.Sprintf("pod/%s/%s container %s uses image %s from an untrusted registry",
+					p.Namespace, p.Name, c.Name, c.Image))
+			}
+		}
+	}
+	return violations, nil
+}
+
+// latestTagImages finds containers whose image is tagged latest, or not
+// tagged at all, and so may change without the pod spec changing.
+func latestTagImages(client kubernetes.Interface, opts map[string]string) ([]string, error) {
+	pods, err := listPods(client, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []string
+	for _, p := range pods {
+		for _, c := range allContainers(p.Spec) {
+			if tag := imageTag(c.Image); tag == "" || tag == "latest" {
+				violations = append(violations, fmt.Sprintf("pod/%s/%s container %s uses image %s without a fixed tag",
+					p.Namespace, p.Name, c.Name, c.Image))
+			}
+		}
+	}
+	return violations, nil
+}
+
+// fromRegistry returns true if the image is one of the registries, or
+// repositories, given. The registry must match whole path components, so
+// that registry.example.com doesn't allow registry.example.com.evil.io.
+func fromRegistry(image string, registries []string) bool {
+	for _, r := range registries {
+		if image == r || strings.HasPrefix(image, strings.TrimSuffix(r, "/")+"/") {
+			return true
+		}
+	}
+	return false
+}
+
+// qualifiedImageName adds the registry and repository implied by the
+// container runtime to an image name, e.g. nginx is docker.io/library/nginx.
+func qualifiedImageName(image string) string {
+	parts := strings.SplitN(image, "/", 2)
+	if len(parts) == 1 {
+		return "docker.io/library/" + image
+	}
+	if !strings.ContainsAny(parts[0], ".:") && parts[0] != "localhost" {
+		return "docker.io/" + image
+	}
+	return image
+}
+
+// imageTag returns the tag of an image, or "@" if the image is pinned by digest.
+func imageTag(image string) string {
+	if strings.Contains(image, "@") {
+		return "@"
+	}
+
+	name := image
+	if i := strings.LastIndex(image, "/"); i >= 0 {
+		name = image[i+1:]
+	}
+	if i := strings.LastIndex(name, ":"); i >= 0 {
+		return name[i+1:]
+	}
+	return ""
+}
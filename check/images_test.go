@@ -0,0 +1,104 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestImageQueries(t *testing.T) {
+	defer withKubeClient(t,
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "app"},
+			Spec: corev1.PodSpec{
+				InitContainers: []corev1.Container{{Name: "init", Image: "busybox"}},
+				Containers: []corev1.Container{
+					{Name: "nginx", Image: "registry.example.com/web/nginx:1.17"},
+					{Name: "proxy", Image: "envoyproxy/envoy:latest"},
+					{Name: "cache", Image: "registry.example.com:5000/redis@sha256:0123456789abcdef"},
+				},
+			},
+		},
+	)()
+
+	cases := []struct {
+		audit    string
+		opts     map[string]string
+		expected string
+	}{
+		{
+			audit: "untrusted-registry-images",
+			opts:  map[string]string{"registries": "registry.example.com/,registry.example.com:5000/"},
+			expected: `{"count":2,"violations":["pod/app/web container init uses image busybox from an untrusted registry",` +
+				`"pod/app/web container proxy uses image envoyproxy/envoy:latest from an untrusted registry"]}`,
+		},
+		{
+			audit:    "untrusted-registry-images",
+			opts:     map[string]string{"registries": "registry.example.com/,registry.example.com:5000/,docker.io/"},
+			expected: `{"count":0,"violations":[]}`,
+		},
+		{
+			audit: "latest-tag-images",
+			expected: `{"count":2,"violations":["pod/app/web container init uses image busybox without a fixed tag",` +
+				`"pod/app/web container proxy uses image envoyproxy/envoy:latest without a fixed tag"]}`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.audit, func(t *testing.T) {
+			out, err := auditAPI(&Check{Type: API, Audit: c.audit, AuditOptions: c.opts})
+			assert.NoError(t, err)
+			assert.Equal(t, c.expected, out)
+		})
+	}
+
+	_, err := auditAPI(&Check{Type: API, Audit: "untrusted-registry-images"})
+	assert.EqualError(t, err, `API query "untrusted-registry-images" failed: no allowed registries configured`)
+}
+
+func TestQualifiedImageName(t *testing.T) {
+	cases := map[string]string{
+		"nginx":                           "docker.io/library/nginx",
+		"envoyproxy/envoy:v1.13.0":        "docker.io/envoyproxy/envoy:v1.13.0",
+		"gcr.io/google-containers/pause":  "gcr.io/google-containers/pause",
+		"localhost/app:dev":               "localhost/app:dev",
+		"registry.example.com:5000/redis": "registry.example.com:5000/redis",
+	}
+	for image, expected := range cases {
+		assert.Equal(t, expected, qualifiedImageName(image), image)
+	}
+}
+
+func TestFromRegistry(t *testing.T) {
+	registries := []string{"registry.corp.com", "docker.io/library/", "registry.corp.com:5000/"}
+	cases := map[string]bool{
+		"registry.corp.com/web/nginx:1.17":     true,
+		"registry.corp.com:5000/redis":         true,
+		"docker.io/library/nginx":              true,
+		"registry.corp.com.evil.io/x:1":        false,
+		"registry.corp.comevil/x":              false,
+		"registry.corp.com:5001/redis":         false,
+		"docker.io/library-evil/nginx":         false,
+		"docker.io/envoyproxy/envoy:v1.13.0":   false,
+		"evil.io/registry.corp.com/nginx:1.17": false,
+	}
+	for image, expected := range cases {
+		assert.Equal(t, expected, fromRegistry(image, registries), image)
+	}
+}
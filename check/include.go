@@ -0,0 +1,104 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// includedKey is the key under which included fragments are inlined.
+// It is not part of Controls, so it is ignored once anchors are resolved.
+const includedKey = "included_fragments"
+
+// ResolveIncludes inlines the fragments listed in the top-level include
+// directive of a controls file, e.g.
+//
+//	include:
+//	- kubelet-tests.yaml
+//
+// Paths are relative to dir. The fragments are YAML mappings whose anchors
+// can then be referenced anywhere in the controls file.
+func ResolveIncludes(in []byte, dir string) ([]byte, error) {
+	lines := strings.Split(string(in), "\n")
+
+	start := -1
+	for i, l := range lines {
+		if strings.HasPrefix(l, "include:") {
+			start = i
+			break
+		}
+	}
+	if start < 0 {
+		return in, nil
+	}
+
+	// The directive ends at the next top-level key.
+	end := start + 1
+	for ; end < len(lines); end++ {
+		l := lines[end]
+		if l != "" && !strings.HasPrefix(l, " ") && !strings.HasPrefix(l, "-") && !strings.HasPrefix(l, "#") {
+			break
+		}
+	}
+
+	var directive struct {
+		Include []string `yaml:"include"`
+	}
+	if err := yaml.Unmarshal([]byte(strings.Join(lines[start:end], "\n")), &directive); err != nil {
+		return nil, fmt.Errorf("invalid include directive: %v", err)
+	}
+
+	var fragments bytes.Buffer
+	fragments.WriteString(includedKey + ":\n")
+	for _, file := range directive.Include {
+		path := file
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to include %s: %v", file, err)
+		}
+
+		fragments.WriteString("-\n")
+		for _, l := range strings.Split(strings.TrimRight(string(data), "\n"), "\n") {
+			if l == "---" {
+				continue
+			}
+			fragments.WriteString("  " + l + "\n")
+		}
+	}
+
+	rest := append(append([]string{}, lines[:start]...), lines[end:]...)
+
+	// Anchors must be defined before they are referenced, so the fragments
+	// go first, after the document start marker if there is one.
+	var out bytes.Buffer
+	if len(rest) > 0 && strings.TrimSpace(rest[0]) == "---" {
+		out.WriteString(rest[0] + "\n")
+		rest = rest[1:]
+	}
+	out.Write(fragments.Bytes())
+	out.WriteString(strings.Join(rest, "\n"))
+
+	return out.Bytes(), nil
+}
@@ -0,0 +1,113 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const includedFragment = `---
+kubelet-flag-test: &kubeletFlagTest
+  audit: "/bin/ps -fC kubelet"
+  scored: true
+anonymous-auth: &anonymousAuth
+  test_items:
+  - flag: "--anonymous-auth"
+    compare:
+      op: eq
+      value: false
+    set: true
+`
+
+const includingControls = `---
+controls:
+include:
+  - fragments/kubelet.yaml
+id: 4
+text: "Worker Node Security Configuration"
+type: "node"
+groups:
+- id: 4.2
+  text: "Kubelet"
+  checks:
+  - id: 4.2.1
+    text: "Ensure that the --anonymous-auth argument is set to false"
+    <<: *kubeletFlagTest
+    tests: *anonymousAuth
+  - id: 4.2.2
+    text: "Ensure that the --read-only-port argument is set to 0"
+    <<: *kubeletFlagTest
+    scored: false
+`
+
+func TestResolveIncludes(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-include")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(filepath.Join(dir, "fragments"), 0700); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "fragments", "kubelet.yaml"), []byte(includedFragment), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	in, err := ResolveIncludes([]byte(includingControls), dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	controls, err := NewControls(NODE, in)
+	if err != nil {
+		t.Fatalf("failed to load controls with includes: %v\n%s", err, in)
+	}
+	if controls.ID != "4" || len(controls.Groups) != 1 || len(controls.Groups[0].Checks) != 2 {
+		t.Fatalf("unexpected controls %+v", controls)
+	}
+
+	c1, c2 := controls.Groups[0].Checks[0], controls.Groups[0].Checks[1]
+	if c1.Audit != "/bin/ps -fC kubelet" || !c1.Scored || c1.Tests == nil || len(c1.Tests.TestItems) != 1 {
+		t.Errorf("expected 4.2.1 to be merged from the included fragments, got %+v", c1)
+	}
+	if c1.Tests.TestItems[0].Flag != "--anonymous-auth" {
+		t.Errorf("expected the included test, got %+v", c1.Tests.TestItems[0])
+	}
+	// Keys of the check take precedence over merged ones.
+	if c2.Audit != "/bin/ps -fC kubelet" || c2.Scored {
+		t.Errorf("expected 4.2.2 to override the merged scored, got %+v", c2)
+	}
+}
+
+func TestResolveIncludesWithoutDirective(t *testing.T) {
+	in := []byte("---\ncontrols:\nid: 1\n")
+	out, err := ResolveIncludes(in, ".")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if string(out) != string(in) {
+		t.Errorf("expected controls to be unchanged, got %q", out)
+	}
+}
+
+func TestResolveIncludesMissingFile(t *testing.T) {
+	if _, err := ResolveIncludes([]byte("include: [missing.yaml]\nid: 1\n"), "."); err == nil {
+		t.Errorf("expected an error for a missing included file")
+	}
+}
@@ -0,0 +1,149 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"io"
+	"os/exec"
+	"strconv"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// AuditLimits keep audit commands from contending with the workloads of busy
+// nodes.
+type AuditLimits struct {
+	// Nice is the niceness audit commands are run with, 0 leaves it unchanged.
+	Nice int
+	// IOClass is the I/O scheduling class audit commands are run with, idle
+	// or best-effort. An empty class leaves it unchanged.
+	IOClass string
+	// MaxCPU is the percentage of a CPU audit commands may use on average.
+	// After each audit kube-bench waits long enough for the CPU time it used
+	// to stay under this share. 0 disables it.
+	MaxCPU int
+	// MaxOutput is the number of bytes of the output of each audit that are
+	// kept, the rest is discarded. 0 keeps all of it.
+	MaxOutput int
+}
+
+var ioClasses = map[string]string{
+	"idle":        "3",
+	"best-effort": "2",
+}
+
+var auditLimits AuditLimits
+
+// SetAuditLimits applies limits to the audit commands run by checks.
+func SetAuditLimits(l AuditLimits) error {
+	if l.Nice < -20 || l.Nice > 19 {
+		return fmt.Errorf("invalid niceness %d, must be between -20 and 19", l.Nice)
+	}
+	if l.MaxCPU < 0 || l.MaxCPU > 100 {
+		return fmt.Errorf("invalid CPU limit %d%%, must be between 0 and 100", l.MaxCPU)
+	}
+	if l.MaxOutput < 0 {
+		return fmt.Errorf("invalid output limit %d bytes, must be positive", l.MaxOutput)
+	}
+	if l.Nice != 0 {
+		if _, err := exec.LookPath("nice"); err != nil {
+			return fmt.Errorf("nice is required to set the niceness of audits: %v", err)
+		}
+	}
+	if l.IOClass != "" {
+		if _, ok := ioClasses[l.IOClass]; !ok {
+			return fmt.Errorf("unknown I/O scheduling class %q, must be idle or best-effort", l.IOClass)
+		}
+		if _, err := exec.LookPath("ionice"); err != nil {
+			return fmt.Errorf("ionice is required to set the I/O scheduling class of audits: %v", err)
+		}
+	}
+
+	auditLimits = l
+	return nil
+}
+
+// limitArgs returns the arguments running a command with the audit limits.
+func limitArgs(args []string) []string {
+	var limited []string
+	if auditLimits.Nice != 0 {
+		limited = append(limited, "nice", "-n", strconv.Itoa(auditLimits.Nice))
+	}
+	if auditLimits.IOClass != "" {
+		limited = append(limited, "ionice", "-c", ioClasses[auditLimits.IOClass])
+	}
+	return append(limited, args...)
+}
+
+// throttle waits after commands have run so that they use at most the
+// CPU share of the audit limits.
+func throttle(commands []*exec.Cmd) {
+	if auditLimits.MaxCPU == 0 || auditLimits.MaxCPU == 100 {
+		return
+	}
+
+	var used time.Duration
+	for _, cmd := range commands {
+		if cmd.ProcessState != nil {
+			used += cmd.ProcessState.UserTime() + cmd.ProcessState.SystemTime()
+		}
+	}
+
+	wait := used * time.Duration(100-auditLimits.MaxCPU) / time.Duration(auditLimits.MaxCPU)
+	if wait > 0 {
+		glog.V(3).Infof("Audit used %s of CPU, waiting %s", used, wait)
+		sleep(wait)
+	}
+}
+
+// sleep is replaced in tests.
+var sleep = time.Sleep
+
+// cappedWriter keeps the first bytes written to it, up to the output limit
+// of audits, and discards the rest without failing so that the commands
+// writing it run to completion.
+type cappedWriter struct {
+	w         io.Writer
+	remaining int
+	truncated bool
+}
+
+// capOutput returns a writer keeping at most the output limit of audits of
+// what is written to w.
+func capOutput(w io.Writer) *cappedWriter {
+	remaining := auditLimits.MaxOutput
+	if remaining == 0 {
+		remaining = -1
+	}
+	return &cappedWriter{w: w, remaining: remaining}
+}
+
+func (c *cappedWriter) Write(p []byte) (int, error) {
+	n := len(p)
+	if c.remaining < 0 {
+		return c.w.Write(p)
+	}
+	if len(p) > c.remaining {
+		p = p[:c.remaining]
+		c.truncated = true
+	}
+	c.remaining -= len(p)
+	if _, err := c.w.Write(p); err != nil {
+		return 0, err
+	}
+	return n, nil
+}
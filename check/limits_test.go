@@ -0,0 +1,187 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSetAuditLimits(t *testing.T) {
+	defer SetAuditLimits(AuditLimits{})
+
+	cases := []struct {
+		limits    AuditLimits
+		expectErr bool
+	}{
+		{limits: AuditLimits{}},
+		{limits: AuditLimits{Nice: 10, MaxCPU: 50}},
+		{limits: AuditLimits{Nice: 20}, expectErr: true},
+		{limits: AuditLimits{MaxCPU: 101}, expectErr: true},
+		{limits: AuditLimits{IOClass: "realtime"}, expectErr: true},
+		{limits: AuditLimits{MaxOutput: 1 << 20}},
+		{limits: AuditLimits{MaxOutput: -1}, expectErr: true},
+	}
+
+	for _, c := range cases {
+		if err := SetAuditLimits(c.limits); (err != nil) != c.expectErr {
+			t.Errorf("%+v: expected error %t, got %v", c.limits, c.expectErr, err)
+		}
+	}
+}
+
+func TestCheckCommandsAuditLimits(t *testing.T) {
+	defer func(l AuditLimits, f func() int) { auditLimits, geteuid = l, f }(auditLimits, geteuid)
+	geteuid = func() int { return 1000 }
+
+	auditLimits = AuditLimits{Nice: 10, IOClass: "idle"}
+	check := &Check{UseSudo: true}
+
+	var args [][]string
+	for _, cmd := range check.commands(textToCommand("ps -ef | grep kubelet")) {
+		args = append(args, cmd.Args)
+	}
+	expected := [][]string{
+		{"sudo", "-n", "nice", "-n", "10", "ionice", "-c", "3", "ps", "-ef"},
+		{"sudo", "-n", "nice", "-n", "10", "ionice", "-c", "3", "grep", "kubelet"},
+	}
+	if !reflect.DeepEqual(args, expected) {
+		t.Errorf("expected %v, got %v", expected, args)
+	}
+}
+
+func TestThrottle(t *testing.T) {
+	defer func(l AuditLimits, s func(time.Duration)) { auditLimits, sleep = l, s }(auditLimits, sleep)
+
+	var waited time.Duration
+	sleep = func(d time.Duration) { waited += d }
+
+	// Burn some CPU so that the command has a measurable CPU time.
+	audit := "/bin/sh -c 'i=0; while [ $i -lt 20000 ]; do i=$((i+1)); done'"
+	cmds := textToCommand(audit)
+	var out bytes.Buffer
+	if state, errmsgs := runExecCommands(audit, cmds, &out); state != "" {
+		t.Fatalf("failed to run audit: %s", errmsgs)
+	}
+	used := cmds[0].ProcessState.UserTime() + cmds[0].ProcessState.SystemTime()
+
+	auditLimits = AuditLimits{}
+	throttle(cmds)
+	if waited != 0 {
+		t.Errorf("expected no wait without a CPU limit, waited %s", waited)
+	}
+
+	auditLimits = AuditLimits{MaxCPU: 25}
+	throttle(cmds)
+	if waited != 3*used {
+		t.Errorf("expected to wait %s, waited %s", 3*used, waited)
+	}
+}
+
+func TestAuditOutputLimit(t *testing.T) {
+	defer func(l AuditLimits) { auditLimits = l }(auditLimits)
+	defer RecordEvidence(nil)
+
+	// The command writes far more than the limit, and must still complete.
+	audit := "/bin/sh -c 'i=0; while [ $i -lt 2000 ]; do echo 0123456789; i=$((i+1)); done'"
+
+	auditLimits = AuditLimits{MaxOutput: 25}
+	evidence := Evidence{}
+	RecordEvidence(evidence)
+	var out bytes.Buffer
+	if state, errmsgs, _, _ := runAudit(audit, textToCommand(audit), &out); state != "" {
+		t.Fatalf("failed to run audit: %s", errmsgs)
+	}
+	if expected := "0123456789\n0123456789\n012"; out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+	if evidence[audit] != out.String() {
+		t.Errorf("expected the truncated output in the evidence, got %q", evidence[audit])
+	}
+	if note := evidence[truncatedKey(audit)]; note != "output truncated to 25 bytes" {
+		t.Errorf("expected the truncation noted in the evidence, got %q", note)
+	}
+
+	auditLimits = AuditLimits{}
+	evidence = Evidence{}
+	RecordEvidence(evidence)
+	out.Reset()
+	if state, errmsgs, _, _ := runAudit(audit, textToCommand(audit), &out); state != "" {
+		t.Fatalf("failed to run audit: %s", errmsgs)
+	}
+	if out.Len() != 22000 {
+		t.Errorf("expected the whole output without a limit, got %d bytes", out.Len())
+	}
+	if _, ok := evidence[truncatedKey(audit)]; ok {
+		t.Errorf("expected no truncation noted without a limit")
+	}
+}
+
+func TestCheckTruncatedOutput(t *testing.T) {
+	defer func(l AuditLimits) { auditLimits = l }(auditLimits)
+	defer ReplayEvidence(nil)
+
+	// The flag is past the output limit, so the tests fail on the part of
+	// the output they saw.
+	audit := "/bin/sh -c 'seq 100; echo --anonymous-auth=false'"
+	newCheck := func() *Check {
+		return &Check{
+			ID:       "4.2.1",
+			Audit:    audit,
+			Commands: textToCommand(audit),
+			Scored:   true,
+			Tests: &tests{TestItems: []*testItem{{
+				Flag:    "--anonymous-auth",
+				Compare: compare{Op: "eq", Value: "false"},
+				Set:     true,
+			}}},
+		}
+	}
+
+	auditLimits = AuditLimits{MaxOutput: 100}
+	evidence := Evidence{}
+	RecordEvidence(evidence)
+	c := newCheck()
+	c.run()
+	RecordEvidence(nil)
+	expected := fmt.Sprintf("output truncated to 100 bytes of %q", audit)
+	if c.State != WARN || c.Reason != expected {
+		t.Errorf("expected WARN because of the truncated output, got %s: %q", c.State, c.Reason)
+	}
+	if err := c.Err(); !errors.Is(err, ErrOutputTruncated) || err.Error() != expected {
+		t.Errorf("expected the truncation in the errors, got %v", c.Errors)
+	}
+
+	// Replaying the evidence tells the same.
+	auditLimits = AuditLimits{}
+	ReplayEvidence(evidence)
+	c = newCheck()
+	c.run()
+	if c.State != WARN || !errors.Is(c.Err(), ErrOutputTruncated) {
+		t.Errorf("expected WARN because of the truncated output, got %s: %v", c.State, c.Errors)
+	}
+	ReplayEvidence(nil)
+
+	// Without a limit, the check passes.
+	c = newCheck()
+	c.run()
+	if c.State != PASS || len(c.Errors) != 0 {
+		t.Errorf("expected PASS without a limit, got %s: %v", c.State, c.Errors)
+	}
+}
@@ -0,0 +1,214 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// substitutionVariableRe matches the variables kube-bench substitutes with
+// the executables and files of components, e.g. $kubeletconf, and
+// fileVariableRe the ones of files.
+var (
+	substitutionVariableRe = regexp.MustCompile(`^\$\w+(bin|conf|config|svc|kubeconfig|cafile)$`)
+	fileVariableRe         = regexp.MustCompile(`^\$\w+(conf|config|svc|kubeconfig|cafile)$`)
+)
+
+// gnuOnlyFlags are the flags of commands that only GNU versions support, and
+// what to use instead.
+var gnuOnlyFlags = map[string]map[string]string{
+	"grep": {"-P": "use grep -E"},
+	"find": {"-printf": "use -exec stat -c"},
+	"sort": {"-V": "compare versions with awk"},
+	"date": {"-d": "compute dates with awk"},
+}
+
+// portableCommands are the commands whose long options, e.g. stat --format,
+// are GNU extensions that BusyBox and BSD don't support.
+var portableCommands = map[string]bool{
+	"grep": true, "sed": true, "stat": true, "find": true, "ps": true,
+	"sort": true, "head": true, "tail": true, "cut": true, "ls": true,
+	"date": true, "readlink": true, "xargs": true, "wc": true, "tr": true,
+	"uniq": true,
+}
+
+// fileReaders are the commands whose failure to read a file is masked when
+// they are piped into another one.
+var fileReaders = map[string]bool{
+	"cat": true, "head": true, "tail": true, "stat": true, "grep": true,
+	"egrep": true, "awk": true, "sed": true, "cut": true, "ls": true,
+	"find": true, "openssl": true, "jq": true, "yq": true,
+}
+
+// LintWarning is a problem found in the audit of a check.
+type LintWarning struct {
+	ID string
+	// Field is the field of the check the problem is in, e.g. audit_config.
+	Field   string
+	Message string
+}
+
+func (w LintWarning) String() string {
+	return fmt.Sprintf("%s %s: %s", w.ID, w.Field, w.Message)
+}
+
+// Lint analyzes the audits of the checks for problems that only show at
+// runtime: unquoted substitution variables in shell scripts, flags of
+// commands that only GNU versions support, and pipes that mask the exit
+// codes of commands. The controls should not be substituted yet.
+func (controls *Controls) Lint() []LintWarning {
+	var warnings []LintWarning
+	for _, g := range controls.Groups {
+		for _, c := range g.Checks {
+			if c.Type == MANUAL || c.Type == "skip" {
+				continue
+			}
+			if _, ok := auditors[c.Type]; ok {
+				continue
+			}
+			for _, field := range []struct{ name, audit string }{{"audit", c.Audit}, {"audit_config", c.AuditConfig}} {
+				for _, m := range lintAudit(field.audit) {
+					warnings = append(warnings, LintWarning{ID: c.ID, Field: field.name, Message: m})
+				}
+			}
+		}
+	}
+	return warnings
+}
+
+// lintAudit returns the problems found in an audit.
+func lintAudit(audit string) []string {
+	if strings.TrimSpace(audit) == "" {
+		return nil
+	}
+
+	var messages []string
+	cmds := textToCommand(audit)
+	for i, cmd := range cmds {
+		args := cmd.Args
+		if len(args) == 0 || args[0] == "" {
+			continue
+		}
+		messages = append(messages, lintCommand(args)...)
+
+		if script := shellScript(args); script != "" {
+			for _, name := range unquotedVariables(script) {
+				messages = append(messages, fmt.Sprintf("%s is not quoted in the shell script, a path with spaces would be split: quote it as \"%s\"", name, name))
+			}
+			for _, segment := range splitScript(script) {
+				messages = append(messages, lintCommand(strings.Fields(segment))...)
+			}
+		} else if i < len(cmds)-1 && readsFile(args) {
+			messages = append(messages, fmt.Sprintf("the pipe masks the exit code of %s, a missing file gives an empty output rather than an error: test that the file exists first", filepath.Base(args[0])))
+		}
+	}
+
+	// kube-bench splits audits at every pipe, whether quoted or not.
+	if i := strings.Index(audit, "-c '"); i >= 0 && strings.Contains(audit[i:], "|") {
+		messages = append(messages, "kube-bench splits audits at every |, cutting the shell script in two: pipe its output outside of the script")
+	}
+	return messages
+}
+
+// shellScript returns the script of a "sh -c '<script>'" command.
+func shellScript(args []string) string {
+	for i := 1; i < len(args)-1; i++ {
+		if args[i] == "-c" {
+			return args[i+1]
+		}
+	}
+	return ""
+}
+
+// lintCommand returns the GNU only flags a command uses.
+func lintCommand(words []string) []string {
+	for len(words) > 0 && (shellKeywords[words[0]] || assignmentRe.MatchString(words[0])) {
+		words = words[1:]
+	}
+	if len(words) == 0 {
+		return nil
+	}
+
+	var messages []string
+	name := filepath.Base(words[0])
+	for _, w := range words[1:] {
+		if w == "--" {
+			// What follows are not options.
+			break
+		}
+		if advice, ok := gnuOnlyFlags[name][w]; ok {
+			messages = append(messages, fmt.Sprintf("%s %s is only supported by GNU %s, %s", name, w, name, advice))
+		} else if portableCommands[name] && strings.HasPrefix(w, "--") && len(w) > 2 {
+			messages = append(messages, fmt.Sprintf("%s %s is a GNU long option that BusyBox doesn't support, use the short option", name, w))
+		}
+	}
+	return messages
+}
+
+// readsFile reports whether a command reads a file, given as a path or a
+// substitution variable.
+func readsFile(args []string) bool {
+	if !fileReaders[filepath.Base(args[0])] {
+		return false
+	}
+	// The first argument of grep that is not an option is its pattern.
+	pattern := strings.HasSuffix(args[0], "grep")
+	for _, a := range args[1:] {
+		if strings.HasPrefix(a, "-") {
+			continue
+		}
+		if pattern {
+			pattern = false
+			continue
+		}
+		if strings.HasPrefix(a, "/") || fileVariableRe.MatchString(a) {
+			return true
+		}
+	}
+	return false
+}
+
+// unquotedVariables returns the substitution variables of a shell script
+// that are not within double quotes.
+func unquotedVariables(script string) []string {
+	var names []string
+	var quote rune
+	for i, r := range script {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '$':
+			end := i + 1
+			for end < len(script) && (script[end] == '_' || isAlphanumeric(script[end])) {
+				end++
+			}
+			if name := script[i:end]; substitutionVariableRe.MatchString(name) {
+				names = append(names, name)
+			}
+		}
+	}
+	return names
+}
+
+func isAlphanumeric(b byte) bool {
+	return b >= 'a' && b <= 'z' || b >= 'A' && b <= 'Z' || b >= '0' && b <= '9'
+}
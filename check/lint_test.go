@@ -0,0 +1,92 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestLintAudit(t *testing.T) {
+	cases := []struct {
+		name  string
+		audit string
+		want  []string
+	}{
+		{name: "clean", audit: "/bin/ps -ef | grep $apiserverbin | grep -v grep"},
+		{name: "empty", audit: " "},
+		{
+			name:  "gnu only flag",
+			audit: "grep -P 'x\\d' $kubeletconf",
+			want:  []string{"grep -P is only supported by GNU grep, use grep -E"},
+		},
+		{
+			name:  "long option",
+			audit: "stat --format=%a $kubeletconf",
+			want:  []string{"stat --format=%a is a GNU long option that BusyBox doesn't support, use the short option"},
+		},
+		{name: "end of options", audit: "ps -ef | grep -- --data-dir"},
+		{
+			name:  "unquoted variable",
+			audit: `/bin/sh -c 'if test -e $kubeletconf; then stat -c %a $kubeletconf; fi'`,
+			want: []string{
+				`$kubeletconf is not quoted in the shell script, a path with spaces would be split: quote it as "$kubeletconf"`,
+				`$kubeletconf is not quoted in the shell script, a path with spaces would be split: quote it as "$kubeletconf"`,
+			},
+		},
+		{
+			name:  "masked exit code",
+			audit: "cat $kubeletconf | grep address",
+			want:  []string{"the pipe masks the exit code of cat, a missing file gives an empty output rather than an error: test that the file exists first"},
+		},
+		{name: "grep pattern", audit: "ps -ef | grep /usr/bin/etcd | wc -l"},
+		{
+			name:  "pipe in script",
+			audit: `/bin/sh -c 'stat -c %a "$kubeletconf" | cat'`,
+			want:  []string{"kube-bench splits audits at every |, cutting the shell script in two: pipe its output outside of the script"},
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			got := lintAudit(c.audit)
+			if !reflect.DeepEqual(got, c.want) {
+				t.Errorf("got %q, want %q", got, c.want)
+			}
+		})
+	}
+}
+
+func TestControlsLint(t *testing.T) {
+	controls := &Controls{Groups: []*Group{{Checks: []*Check{
+		{ID: "1.1", Audit: "stat --format=%a $kubeletconf"},
+		{ID: "1.2", Type: MANUAL, Audit: "grep -P x $kubeletconf"},
+		{ID: "1.3", Type: "file", Audit: "stat --format=%a $kubeletconf"},
+		{ID: "1.4", Audit: "true", AuditConfig: "date -d yesterday"},
+	}}}}
+
+	var got []string
+	for _, w := range controls.Lint() {
+		got = append(got, w.String())
+	}
+	want := []string{
+		"1.1 audit: stat --format=%a is a GNU long option that BusyBox doesn't support, use the short option",
+		"1.4 audit_config: date -d is only supported by GNU date, compute dates with awk",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("got %s, want %s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
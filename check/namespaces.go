@@ -0,0 +1,84 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// namespacesWithout finds namespaces not in covered. Namespaces named in
+// the "exclude" audit option are not checked.
+func namespacesWithout(client kubernetes.Interface, opts map[string]string, what string, covered map[string]bool) ([]string, error) {
+	exclude := optionList(opts, "exclude")
+
+	namespaces, err := client.CoreV1().Namespaces().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []string
+	for _, ns := range namespaces.Items {
+		if contains(exclude, ns.Name) || covered[ns.Name] {
+			continue
+		}
+		violations = append(violations, fmt.Sprintf("namespace/%s has no %s", ns.Name, what))
+	}
+	return violations, nil
+}
+
+// namespacesWithoutResourceQuotas finds namespaces in which no ResourceQuota is defined.
+func namespacesWithoutResourceQuotas(client kubernetes.Interface, opts map[string]string) ([]string, error) {
+	quotas, err := client.CoreV1().ResourceQuotas(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	covered := make(map[string]bool)
+	for _, q := range quotas.Items {
+		covered[q.Namespace] = true
+	}
+	return namespacesWithout(client, opts, "resource quotas", covered)
+}
+
+// namespacesWithoutLimitRanges finds namespaces in which no LimitRange is defined.
+func namespacesWithoutLimitRanges(client kubernetes.Interface, opts map[string]string) ([]string, error) {
+	ranges, err := client.CoreV1().LimitRanges(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	covered := make(map[string]bool)
+	for _, r := range ranges.Items {
+		covered[r.Namespace] = true
+	}
+	return namespacesWithout(client, opts, "limit ranges", covered)
+}
+
+// defaultNamespaceWorkloads finds pods running in the default namespace.
+func defaultNamespaceWorkloads(client kubernetes.Interface, opts map[string]string) ([]string, error) {
+	pods, err := client.CoreV1().Pods(metav1.NamespaceDefault).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []string
+	for _, p := range pods.Items {
+		violations = append(violations, fmt.Sprintf("pod/%s/%s runs in the default namespace", p.Namespace, p.Name))
+	}
+	return violations, nil
+}
@@ -0,0 +1,66 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestNamespaceQueries(t *testing.T) {
+	defer withKubeClient(t,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "kube-system"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "default"}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "app"}},
+		&corev1.ResourceQuota{ObjectMeta: metav1.ObjectMeta{Name: "compute", Namespace: "app"}},
+		&corev1.LimitRange{ObjectMeta: metav1.ObjectMeta{Name: "limits", Namespace: "default"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "nginx", Namespace: "default"}},
+		&corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "app"}},
+	)()
+
+	excludeSystem := map[string]string{"exclude": "kube-system"}
+
+	cases := []struct {
+		audit    string
+		opts     map[string]string
+		expected string
+	}{
+		{
+			audit:    "namespaces-without-resource-quotas",
+			opts:     excludeSystem,
+			expected: `{"count":1,"violations":["namespace/default has no resource quotas"]}`,
+		},
+		{
+			audit:    "namespaces-without-limit-ranges",
+			opts:     excludeSystem,
+			expected: `{"count":1,"violations":["namespace/app has no limit ranges"]}`,
+		},
+		{
+			audit:    "default-namespace-workloads",
+			expected: `{"count":1,"violations":["pod/default/nginx runs in the default namespace"]}`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.audit, func(t *testing.T) {
+			out, err := auditAPI(&Check{Type: API, Audit: c.audit, AuditOptions: c.opts})
+			assert.NoError(t, err)
+			assert.Equal(t, c.expected, out)
+		})
+	}
+}
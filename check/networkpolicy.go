@@ -0,0 +1,35 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// namespacesWithoutNetworkPolicies finds namespaces in which no
+// NetworkPolicy is defined.
+func namespacesWithoutNetworkPolicies(client kubernetes.Interface, opts map[string]string) ([]string, error) {
+	policies, err := client.NetworkingV1().NetworkPolicies(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	covered := make(map[string]bool)
+	for _, p := range policies.Items {
+		covered[p.Namespace] = true
+	}
+	return namespacesWithout(client, opts, "network policies", covered)
+}
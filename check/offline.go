@@ -0,0 +1,82 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"errors"
+	"path/filepath"
+)
+
+// ErrOffline is returned by the operations needing network access when
+// kube-bench runs offline.
+var ErrOffline = errors.New("network access is disabled by --offline")
+
+// offline disables the network operations of checks, see SetOffline.
+var offline bool
+
+// networkCheckTypes are the types of checks connecting to an endpoint or to
+// the Kubernetes API.
+var networkCheckTypes = map[string]bool{
+	TLS: true, HTTP: true, ETCDCONN: true, ADMISSION: true, API: true, CONFIGZ: true,
+}
+
+// networkCommands are the commands audits may run that connect to the
+// network.
+var networkCommands = map[string]bool{
+	"kubectl": true, "oc": true, "curl": true, "wget": true, "etcdctl": true,
+	"nc": true, "ncat": true, "dig": true, "nslookup": true,
+}
+
+// SetOffline disables the network operations of checks: the Kubernetes
+// client can't be used anymore.
+func SetOffline(disabled bool) {
+	offline = disabled
+}
+
+// needsNetwork reports whether a check needs network access to be carried
+// out.
+func (c *Check) needsNetwork() bool {
+	if c.Remote != nil || networkCheckTypes[c.Type] {
+		return true
+	}
+	if _, ok := auditors[c.Type]; ok {
+		return false
+	}
+	for _, audit := range []string{c.Audit, c.AuditConfig} {
+		for _, name := range auditCommands(audit) {
+			if networkCommands[filepath.Base(name)] {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// NetworkChecks returns the IDs of the checks selected by the filter that
+// need network access.
+func (controls *Controls) NetworkChecks(filter Predicate) []string {
+	var ids []string
+	for _, g := range controls.Groups {
+		for _, c := range g.Checks {
+			if !filter(g, c) || c.Type == MANUAL || c.Type == "skip" || c.Unavailable != "" || c.Ignored != "" {
+				continue
+			}
+			if c.needsNetwork() {
+				ids = append(ids, c.ID)
+			}
+		}
+	}
+	return ids
+}
@@ -0,0 +1,50 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNetworkChecks(t *testing.T) {
+	controls := &Controls{Groups: []*Group{{ID: "5.1", Checks: []*Check{
+		{ID: "5.1.1", Audit: "kubectl get clusterrolebindings -o json"},
+		{ID: "5.1.2", Audit: "/bin/sh -c 'stat -c %a /etc/kubernetes/admin.conf'"},
+		{ID: "5.1.3", Audit: "/bin/sh -c 'curl -sk https://localhost:10250/healthz'"},
+		{ID: "5.1.4", Type: API, Audit: "privileged_pods"},
+		{ID: "5.1.5", Type: TLS, Audit: "https://localhost:6443"},
+		{ID: "5.1.6", Type: PERMISSIONS, Audit: "/etc/kubernetes/admin.conf"},
+		{ID: "5.1.7", Remote: &Check{Type: ETCDCONN}},
+		// Checks that don't run are left out.
+		{ID: "5.1.8", Type: MANUAL, Audit: "kubectl get pods"},
+		{ID: "5.1.9", Ignored: "Ignored on this cluster", Audit: "kubectl get pods"},
+		{ID: "5.1.10", Audit: "kubectl get pods"},
+	}}}}
+
+	ids := controls.NetworkChecks(func(g *Group, c *Check) bool { return c.ID != "5.1.10" })
+	expected := []string{"5.1.1", "5.1.3", "5.1.4", "5.1.5", "5.1.7"}
+	if !reflect.DeepEqual(ids, expected) {
+		t.Errorf("expected %v, got %v", expected, ids)
+	}
+}
+
+func TestOfflineKubeClient(t *testing.T) {
+	defer SetOffline(false)
+	SetOffline(true)
+	if _, err := KubeClient(); err != ErrOffline {
+		t.Errorf("expected %v, got %v", ErrOffline, err)
+	}
+}
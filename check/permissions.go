@@ -0,0 +1,148 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+// Access rights of Windows access masks.
+const (
+	fileReadData   = 0x1
+	fileWriteData  = 0x2
+	fileAppendData = 0x4
+	fileExecute    = 0x20
+	writeDAC       = 0x40000
+	writeOwner     = 0x80000
+	genericAll     = 0x10000000
+	genericExecute = 0x20000000
+	genericWrite   = 0x40000000
+	genericRead    = 0x80000000
+
+	readAccess    = fileReadData | genericRead | genericAll
+	writeAccess   = fileWriteData | fileAppendData | writeDAC | writeOwner | genericWrite | genericAll
+	executeAccess = fileExecute | genericExecute | genericAll
+)
+
+// Well-known Windows SIDs.
+const (
+	everyoneSID       = "S-1-1-0"
+	systemSID         = "S-1-5-18"
+	administratorsSID = "S-1-5-32-544"
+	// trustedInstallerSID is the prefix of the SID of the TrustedInstaller
+	// service.
+	trustedInstallerSID = "S-1-5-80-956008885-"
+)
+
+// fileAccess is who owns a file and who can access it, as POSIX permission
+// bits.
+type fileAccess struct {
+	mode os.FileMode
+	// owner and group are their names, ownerID and groupID their numeric
+	// IDs, or SIDs on Windows.
+	owner, group     string
+	ownerID, groupID string
+}
+
+// auditPermissions returns the permissions and ownership of the file named
+// by the check, as "stat -c permissions=%a" and "stat -c %U:%G" do, so that
+// the same tests apply on every platform:
+//
+//	permissions=644
+//	owner=root:root
+//	owner_id=0:0
+//
+// The file is named as for file checks. On Windows, the permissions are
+// those granted by its ACL: to the owner, to its group, and to anyone else
+// for the "other" bits.
+func auditPermissions(c *Check) (string, error) {
+	path, err := auditFilePath(c)
+	if err != nil {
+		return "", err
+	}
+
+	glog.V(2).Infof("Check.ID: %s reading permissions of %s", c.ID, path)
+	access, err := getFileAccess(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to get the permissions of %s: %v", path, err)
+	}
+
+	return fmt.Sprintf("permissions=%o\nowner=%s:%s\nowner_id=%s:%s\n",
+		access.mode.Perm(), access.owner, access.group, access.ownerID, access.groupID), nil
+}
+
+// aclEntry is an entry of a Windows ACL, allowing or denying access to a SID.
+type aclEntry struct {
+	sid   string
+	allow bool
+	mask  uint32
+}
+
+// aclMode returns the POSIX permission bits equivalent to a Windows ACL.
+// The owner bits are the rights of the owner, the group bits the ones of
+// the group of the file, and the other bits the ones of any other account
+// but SYSTEM, the Administrators and TrustedInstaller, which can access all
+// files anyway. Denied rights are taken out of the allowed ones, as Windows
+// orders denying entries first.
+func aclMode(ownerSID, groupSID string, entries []aclEntry) os.FileMode {
+	var allowed, denied [3]uint32
+	for _, e := range entries {
+		class := 2
+		switch {
+		case e.sid == ownerSID:
+			class = 0
+		case e.sid == groupSID:
+			class = 1
+		case e.sid == systemSID || e.sid == administratorsSID || strings.HasPrefix(e.sid, trustedInstallerSID):
+			continue
+		}
+		if e.allow {
+			allowed[class] |= e.mask
+		} else {
+			denied[class] |= e.mask
+		}
+		// Everyone includes the owner and the group.
+		if e.sid == everyoneSID {
+			for i := 0; i < 2; i++ {
+				if e.allow {
+					allowed[i] |= e.mask
+				} else {
+					denied[i] |= e.mask
+				}
+			}
+		}
+	}
+
+	var mode os.FileMode
+	for i := 0; i < 3; i++ {
+		rights := allowed[i]
+		var bits os.FileMode
+		if rights&readAccess != 0 && denied[i]&readAccess == 0 {
+			bits |= 4
+		}
+		if rights&writeAccess != 0 && denied[i]&writeAccess == 0 {
+			bits |= 2
+		}
+		if rights&executeAccess != 0 && denied[i]&executeAccess == 0 {
+			bits |= 1
+		}
+		mode |= bits << uint(3*(2-i))
+	}
+	return mode
+}
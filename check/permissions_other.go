@@ -0,0 +1,49 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build !windows
+// +build !windows
+
+package check
+
+import (
+	"os"
+	"os/user"
+	"strconv"
+	"syscall"
+)
+
+// getFileAccess returns the permissions and ownership of a file.
+func getFileAccess(path string) (fileAccess, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return fileAccess{}, err
+	}
+
+	access := fileAccess{mode: info.Mode().Perm()}
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		access.ownerID = strconv.FormatUint(uint64(st.Uid), 10)
+		access.groupID = strconv.FormatUint(uint64(st.Gid), 10)
+	}
+
+	// Accounts without a name are given by their ID, as stat does.
+	access.owner, access.group = access.ownerID, access.groupID
+	if u, err := user.LookupId(access.ownerID); err == nil {
+		access.owner = u.Username
+	}
+	if g, err := user.LookupGroupId(access.groupID); err == nil {
+		access.group = g.Name
+	}
+	return access, nil
+}
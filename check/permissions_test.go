@@ -0,0 +1,125 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"io/ioutil"
+	"os"
+	"os/user"
+	"strings"
+	"testing"
+)
+
+func TestCheck_RunPermissions(t *testing.T) {
+	f, err := ioutil.TempFile("", "kube-apiserver")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	f.Close()
+
+	u, err := user.Current()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	permissions := &tests{TestItems: []*testItem{{
+		Flag: "permissions", Set: true, Compare: compare{Op: "bitmask", Value: "644"},
+	}}}
+	ownership := &tests{TestItems: []*testItem{{
+		Flag: "owner_id", Set: true, Compare: compare{Op: "eq", Value: u.Uid + ":" + u.Gid},
+	}}}
+
+	cases := []struct {
+		mode     os.FileMode
+		tests    *tests
+		expected State
+	}{
+		{0600, permissions, PASS},
+		{0644, permissions, PASS},
+		{0666, permissions, FAIL},
+		{0600, ownership, PASS},
+	}
+	for _, c := range cases {
+		if err := os.Chmod(f.Name(), c.mode); err != nil {
+			t.Fatal(err)
+		}
+		check := &Check{ID: "1.1.1", Type: PERMISSIONS, Audit: f.Name(), Tests: c.tests, Scored: true}
+		if state := check.run(); state != c.expected {
+			t.Errorf("%o: expected %s, got %s (%s)", c.mode, c.expected, state, check.Reason)
+		}
+	}
+
+	out, err := auditPermissions(&Check{Audit: f.Name()})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasPrefix(out, "permissions=600\nowner="+u.Username+":") {
+		t.Errorf("unexpected output %q", out)
+	}
+
+	check := &Check{ID: "1.1.1", Type: PERMISSIONS, Audit: f.Name() + ".missing", Tests: permissions}
+	if state := check.run(); state != WARN {
+		t.Errorf("expected WARN for a missing file, got %s", state)
+	}
+}
+
+func TestACLMode(t *testing.T) {
+	const (
+		owner = "S-1-5-21-1-2-3-1001"
+		group = "S-1-5-21-1-2-3-513"
+		users = "S-1-5-32-545"
+	)
+	cases := []struct {
+		entries  []aclEntry
+		expected os.FileMode
+	}{
+		{
+			[]aclEntry{
+				{sid: owner, allow: true, mask: fileReadData | fileWriteData},
+				{sid: group, allow: true, mask: genericRead},
+				{sid: users, allow: true, mask: fileReadData},
+				{sid: administratorsSID, allow: true, mask: genericAll},
+				{sid: systemSID, allow: true, mask: genericAll},
+			},
+			0644,
+		},
+		{
+			[]aclEntry{
+				{sid: owner, allow: true, mask: genericAll},
+				{sid: "S-1-5-80-956008885-3418522649-1831038044-1853292631-2271478464", allow: true, mask: genericAll},
+			},
+			0700,
+		},
+		{
+			// Everyone applies to the owner and the group too.
+			[]aclEntry{{sid: everyoneSID, allow: true, mask: genericRead | genericExecute}},
+			0555,
+		},
+		{
+			[]aclEntry{
+				{sid: users, allow: false, mask: fileWriteData},
+				{sid: owner, allow: true, mask: genericAll},
+				{sid: users, allow: true, mask: genericRead | genericWrite},
+			},
+			0704,
+		},
+	}
+	for i, c := range cases {
+		if mode := aclMode(owner, group, c.entries); mode != c.expected {
+			t.Errorf("case %d: expected %o, got %o", i, c.expected, mode)
+		}
+	}
+}
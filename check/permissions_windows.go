@@ -0,0 +1,133 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+//go:build windows
+// +build windows
+
+package check
+
+import (
+	"syscall"
+	"unsafe"
+)
+
+var (
+	advapi32                  = syscall.NewLazyDLL("advapi32.dll")
+	procGetNamedSecurityInfoW = advapi32.NewProc("GetNamedSecurityInfoW")
+	procGetAce                = advapi32.NewProc("GetAce")
+)
+
+const (
+	seFileObject             = 1
+	ownerSecurityInformation = 0x1
+	groupSecurityInformation = 0x2
+	daclSecurityInformation  = 0x4
+
+	accessAllowedACEType = 0
+	accessDeniedACEType  = 1
+	inheritOnlyACE       = 0x8
+)
+
+// acl is the header of an ACL.
+type acl struct {
+	aclRevision byte
+	sbz1        byte
+	aclSize     uint16
+	aceCount    uint16
+	sbz2        uint16
+}
+
+// accessACE is an ACCESS_ALLOWED_ACE or ACCESS_DENIED_ACE, the SID starting
+// at sidStart.
+type accessACE struct {
+	aceType  byte
+	aceFlags byte
+	aceSize  uint16
+	mask     uint32
+	sidStart uint32
+}
+
+// getFileAccess returns the owner and group of a file, and the permissions
+// equivalent to its ACL.
+func getFileAccess(path string) (fileAccess, error) {
+	p, err := syscall.UTF16PtrFromString(path)
+	if err != nil {
+		return fileAccess{}, err
+	}
+
+	var owner, group *syscall.SID
+	var dacl *acl
+	var sd uintptr
+	r, _, _ := procGetNamedSecurityInfoW.Call(
+		uintptr(unsafe.Pointer(p)),
+		seFileObject,
+		ownerSecurityInformation|groupSecurityInformation|daclSecurityInformation,
+		uintptr(unsafe.Pointer(&owner)),
+		uintptr(unsafe.Pointer(&group)),
+		uintptr(unsafe.Pointer(&dacl)),
+		0,
+		uintptr(unsafe.Pointer(&sd)),
+	)
+	if r != 0 {
+		return fileAccess{}, syscall.Errno(r)
+	}
+	defer syscall.LocalFree(syscall.Handle(sd))
+
+	var access fileAccess
+	if access.ownerID, err = owner.String(); err != nil {
+		return fileAccess{}, err
+	}
+	if access.groupID, err = group.String(); err != nil {
+		return fileAccess{}, err
+	}
+	access.owner = accountName(owner, access.ownerID)
+	access.group = accountName(group, access.groupID)
+
+	// A file without a DACL grants full access to everyone.
+	entries := []aclEntry{{sid: everyoneSID, allow: true, mask: genericAll}}
+	if dacl != nil {
+		entries = nil
+		for i := 0; i < int(dacl.aceCount); i++ {
+			var ace *accessACE
+			if r, _, err := procGetAce.Call(uintptr(unsafe.Pointer(dacl)), uintptr(i), uintptr(unsafe.Pointer(&ace))); r == 0 {
+				return fileAccess{}, err
+			}
+			// Only the entries applying to the file itself matter, other
+			// types of entries are about auditing or objects.
+			if ace.aceFlags&inheritOnlyACE != 0 || (ace.aceType != accessAllowedACEType && ace.aceType != accessDeniedACEType) {
+				continue
+			}
+			sid, err := (*syscall.SID)(unsafe.Pointer(&ace.sidStart)).String()
+			if err != nil {
+				return fileAccess{}, err
+			}
+			entries = append(entries, aclEntry{sid: sid, allow: ace.aceType == accessAllowedACEType, mask: ace.mask})
+		}
+	}
+	access.mode = aclMode(access.ownerID, access.groupID, entries)
+	return access, nil
+}
+
+// accountName returns the name of the account of a SID, e.g.
+// BUILTIN\Administrators, or the SID when it has none.
+func accountName(sid *syscall.SID, id string) string {
+	account, domain, _, err := sid.LookupAccount("")
+	if err != nil {
+		return id
+	}
+	if domain == "" {
+		return account
+	}
+	return domain + `\` + account
+}
@@ -0,0 +1,164 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	// remediationFileRe matches the files remediations edit, e.g. the pod
+	// specification file of the API server, or the variables left for them.
+	remediationFileRe = regexp.MustCompile(`(?:^|[\s(])(/[\w.\-/]+|\$\w+(?:conf|config|svc|kubeconfig|cafile))\b`)
+	// remediationFlagRe matches the flags remediations set, alone on their
+	// line, e.g. --anonymous-auth=false.
+	remediationFlagRe = regexp.MustCompile(`(?m)^\s*(--[\w-]+(?:=\S+)?)\s*$`)
+	// remediationCommandRe matches the commands remediations run to fix the
+	// permissions or ownership of a file, e.g. chmod 644 /etc/kubernetes/admin.conf.
+	remediationCommandRe = regexp.MustCompile(`(?m)(?:^|For example,)\s*((?:chmod|chown) (?:-R )?\S+ [/$]\S+)\s*$`)
+)
+
+// RemediationPlan groups the remediations of the failed checks of a run by
+// the file or component they touch, so that all the changes to a component,
+// e.g. the flags of the API server, are made together.
+type RemediationPlan struct {
+	Components []*ComponentRemediation `json:"components"`
+}
+
+// ComponentRemediation is the consolidated remediation of the failed checks
+// touching a file or component.
+type ComponentRemediation struct {
+	// Component is the file the remediations touch, or the group of the
+	// checks when they don't name any.
+	Component string   `json:"component"`
+	Checks    []string `json:"checks"`
+	// Flags are the flags to set, or remove, in the file, e.g.
+	// --anonymous-auth=false.
+	Flags []string `json:"flags,omitempty"`
+	// Keys are the keys of a config file to set, or remove.
+	Keys []string `json:"keys,omitempty"`
+	// Commands are the commands to run, e.g. to fix the permissions of the
+	// file.
+	Commands []string `json:"commands,omitempty"`
+	// Remediations are the remediations of the checks that can't be
+	// consolidated, as given by the benchmark.
+	Remediations []CheckRemediation `json:"remediations,omitempty"`
+}
+
+// CheckRemediation is the remediation of a check.
+type CheckRemediation struct {
+	ID          string `json:"id"`
+	Remediation string `json:"remediation"`
+}
+
+// NewRemediationPlan consolidates the remediations of the failed checks of
+// the results. Structured remediations are grouped by the files of their
+// steps, commands going with the file of the step before them; free-text ones by the first file they name, with the flags and the
+// chmod and chown commands they give consolidated.
+func NewRemediationPlan(results []*Controls) *RemediationPlan {
+	p := &RemediationPlan{Components: []*ComponentRemediation{}}
+	components := map[string]*ComponentRemediation{}
+	component := func(name, id string) *ComponentRemediation {
+		cr, ok := components[name]
+		if !ok {
+			cr = &ComponentRemediation{Component: name}
+			components[name] = cr
+			p.Components = append(p.Components, cr)
+		}
+		if len(cr.Checks) == 0 || cr.Checks[len(cr.Checks)-1] != id {
+			cr.Checks = append(cr.Checks, id)
+		}
+		return cr
+	}
+
+	for _, controls := range results {
+		for _, g := range controls.Groups {
+			for _, c := range g.Checks {
+				if c.State != FAIL {
+					continue
+				}
+				fallback := strings.TrimSpace(fmt.Sprintf("%s %s", g.ID, g.Text))
+
+				if len(c.RemediationSteps) > 0 {
+					// Commands go with the file of the step before them,
+					// e.g. restarting the kubelet after editing its config.
+					name := fallback
+					for _, s := range c.RemediationSteps {
+						if s.File != "" {
+							name = s.File
+						}
+						s.addTo(component(name, c.ID))
+					}
+					continue
+				}
+
+				remediation := strings.TrimSpace(c.Remediation)
+				if remediation == "" {
+					continue
+				}
+				name := fallback
+				if m := remediationFileRe.FindStringSubmatch(remediation); m != nil {
+					name = m[1]
+				}
+				cr := component(name, c.ID)
+
+				flags := remediationFlagRe.FindAllStringSubmatch(remediation, -1)
+				commands := remediationCommandRe.FindAllStringSubmatch(remediation, -1)
+				if len(flags) == 0 && len(commands) == 0 {
+					cr.Remediations = append(cr.Remediations, CheckRemediation{ID: c.ID, Remediation: remediation})
+					continue
+				}
+				for _, m := range flags {
+					cr.Flags = appendNew(cr.Flags, m[1])
+				}
+				for _, m := range commands {
+					cr.Commands = appendNew(cr.Commands, m[1])
+				}
+			}
+		}
+	}
+	return p
+}
+
+// addTo adds the step to the remediation of its component.
+func (s RemediationStep) addTo(cr *ComponentRemediation) {
+	switch s.Type {
+	case FlagStep:
+		if s.Unset {
+			cr.Flags = appendNew(cr.Flags, "remove "+s.Flag)
+		} else {
+			cr.Flags = appendNew(cr.Flags, fmt.Sprintf("%s=%s", s.Flag, s.Value))
+		}
+	case FileStep:
+		if s.Unset {
+			cr.Keys = appendNew(cr.Keys, "remove "+s.Key)
+		} else {
+			cr.Keys = appendNew(cr.Keys, fmt.Sprintf("%s: %s", s.Key, s.Value))
+		}
+	case CommandStep:
+		cr.Commands = appendNew(cr.Commands, s.Command)
+	}
+}
+
+func appendNew(list []string, s string) []string {
+	for _, e := range list {
+		if e == s {
+			return list
+		}
+	}
+	return append(list, s)
+}
@@ -0,0 +1,69 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNewRemediationPlan(t *testing.T) {
+	manifest := "/etc/kubernetes/manifests/kube-apiserver.yaml"
+	results := []*Controls{{Groups: []*Group{
+		{ID: "1.1", Text: "Master Node Configuration Files", Checks: []*Check{
+			{ID: "1.1.1", State: FAIL, Remediation: "Run the below command on the master node.\nFor example, chmod 644 " + manifest},
+			{ID: "1.1.2", State: PASS, Remediation: "Run the below command on the master node.\nFor example, chown root:root " + manifest},
+			{ID: "1.1.9", State: FAIL, Remediation: "For example,\nchmod 644 <path/to/cni/files>\n"},
+		}},
+		{ID: "1.2", Text: "API Server", Checks: []*Check{
+			{ID: "1.2.1", State: FAIL, Remediation: "Edit the API server pod specification file " + manifest + "\non the master node and set the below parameter.\n--anonymous-auth=false\n"},
+			{ID: "1.2.15", State: FAIL, Remediation: "Edit the API server pod specification file " + manifest + "\nand set the --disable-admission-plugins parameter."},
+			{ID: "1.2.21", State: FAIL, Remediation: "Edit the API server pod specification file " + manifest + "\n--profiling=false\n"},
+			{ID: "1.2.22", State: WARN, Remediation: "Edit the API server pod specification file " + manifest + "\n--audit-log-path=/var/log/audit.log\n"},
+		}},
+	}}, {Groups: []*Group{
+		{ID: "4.2", Text: "Kubelet", Checks: []*Check{
+			{ID: "4.2.1", State: FAIL, RemediationSteps: []RemediationStep{
+				{Type: FileStep, File: "/var/lib/kubelet/config.yaml", Key: "authentication.anonymous.enabled", Value: "false"},
+				{Type: FlagStep, File: "/etc/systemd/system/kubelet.service.d/10-kubeadm.conf", Flag: "--anonymous-auth", Value: "false"},
+				{Type: CommandStep, Command: "systemctl restart kubelet"},
+			}},
+			{ID: "4.2.6", State: FAIL, RemediationSteps: []RemediationStep{
+				{Type: FileStep, File: "/var/lib/kubelet/config.yaml", Key: "protectKernelDefaults", Unset: true},
+			}},
+		}},
+	}}}
+
+	expected := &RemediationPlan{Components: []*ComponentRemediation{
+		{Component: manifest, Checks: []string{"1.1.1", "1.2.1", "1.2.15", "1.2.21"},
+			Flags:    []string{"--anonymous-auth=false", "--profiling=false"},
+			Commands: []string{"chmod 644 " + manifest},
+			Remediations: []CheckRemediation{
+				{ID: "1.2.15", Remediation: "Edit the API server pod specification file " + manifest + "\nand set the --disable-admission-plugins parameter."},
+			}},
+		{Component: "1.1 Master Node Configuration Files", Checks: []string{"1.1.9"},
+			Remediations: []CheckRemediation{{ID: "1.1.9", Remediation: "For example,\nchmod 644 <path/to/cni/files>"}}},
+		{Component: "/var/lib/kubelet/config.yaml", Checks: []string{"4.2.1", "4.2.6"},
+			Keys: []string{"authentication.anonymous.enabled: false", "remove protectKernelDefaults"}},
+		{Component: "/etc/systemd/system/kubelet.service.d/10-kubeadm.conf", Checks: []string{"4.2.1"},
+			Flags: []string{"--anonymous-auth=false"}, Commands: []string{"systemctl restart kubelet"}},
+	}}
+	if plan := NewRemediationPlan(results); !reflect.DeepEqual(plan, expected) {
+		for i, cr := range plan.Components {
+			t.Logf("%d: %+v", i, cr)
+		}
+		t.Errorf("unexpected plan")
+	}
+}
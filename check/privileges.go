@@ -0,0 +1,195 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"regexp"
+	"strings"
+	"sync"
+
+	"github.com/golang/glog"
+)
+
+// insufficientPrivileges is the reason given for checks that can't be
+// evaluated with the privileges kube-bench is running with.
+const insufficientPrivileges = "insufficient privileges"
+
+// processPrivileges describes what kube-bench is able to audit.
+type processPrivileges struct {
+	root bool
+	// hostPID is true if the processes of the host are visible.
+	hostPID bool
+}
+
+var (
+	privilegesOnce sync.Once
+	privileges     processPrivileges
+)
+
+// detectPrivileges is replaced in tests.
+var detectPrivileges = func() processPrivileges {
+	p := processPrivileges{root: geteuid() == 0, hostPID: seesHostProcesses()}
+	glog.V(2).Infof("Running as root: %t, host processes visible: %t", p.root, p.hostPID)
+	return p
+}
+
+func currentPrivileges() processPrivileges {
+	privilegesOnce.Do(func() { privileges = detectPrivileges() })
+	return privileges
+}
+
+// seesHostProcesses reports whether kube-bench shares the PID namespace of the
+// host. It only reports false when it is sure kube-bench runs in a container
+// whose first process is not the host's init.
+func seesHostProcesses() bool {
+	if _, err := os.Stat("/.dockerenv"); err != nil {
+		cgroup, err := ioutil.ReadFile("/proc/1/cgroup")
+		if err != nil || !(bytes.Contains(cgroup, []byte("kubepods")) || bytes.Contains(cgroup, []byte("docker"))) {
+			return true
+		}
+	}
+
+	comm, err := ioutil.ReadFile("/proc/1/comm")
+	if err != nil {
+		return true
+	}
+	switch strings.TrimSpace(string(comm)) {
+	case "systemd", "init":
+		return true
+	}
+	return false
+}
+
+// statFile and openFile are replaced in tests, as root can access any file.
+var (
+	statFile = os.Stat
+	openFile = os.Open
+)
+
+var (
+	auditPathRe = regexp.MustCompile(`(?:^|[\s'"=:])(/[\w.\-/]+)`)
+	auditPsRe   = regexp.MustCompile(`(?:^|[\s/|'"])ps\s`)
+)
+
+// privilegeIssue returns why the check can't be evaluated with the privileges
+// of kube-bench, or an empty string if it can.
+func (c *Check) privilegeIssue() string {
+	p := currentPrivileges()
+
+	var audits []string
+	switch c.Type {
+	case "":
+		audits = []string{c.Audit, c.AuditConfig}
+	case FILE:
+		if c.AuditOptions["flag"] != "" {
+			// The audit is a command giving the path of the file.
+			audits = []string{c.Audit}
+		} else if !p.root {
+			return readable(strings.TrimSpace(c.Audit), false)
+		}
+	case PROCESS:
+		if !p.hostPID && processBackend != CrictlBackend {
+			return fmt.Sprintf("%s: host processes are not visible, run with hostPID", insufficientPrivileges)
+		}
+		return ""
+	default:
+		// Other native checks report their own errors.
+		return ""
+	}
+
+	for _, audit := range audits {
+		if audit == "" {
+			continue
+		}
+
+		// crictl finds the processes of containers without hostPID.
+		_, byBackend := psAuditProcess(audit)
+		if !p.hostPID && auditPsRe.MatchString(audit) && !(byBackend && processBackend == CrictlBackend) {
+			return fmt.Sprintf("%s: host processes are not visible, run with hostPID", insufficientPrivileges)
+		}
+
+		if p.root || c.UseSudo {
+			continue
+		}
+
+		if err := readableFiles(audit); err != "" {
+			return err
+		}
+	}
+
+	return ""
+}
+
+// unreadableFileIssue returns why a file read by the audits of a failed
+// check can't be read, or an empty string if they all can. Even root can be
+// denied access to files, e.g. by SELinux or a read-only host mount, which
+// would otherwise make the check fail for the wrong reason.
+func (c *Check) unreadableFileIssue() string {
+	switch c.Type {
+	case "":
+		for _, audit := range []string{c.Audit, c.AuditConfig} {
+			if err := readableFiles(audit); err != "" {
+				return err
+			}
+		}
+	case FILE:
+		if c.AuditOptions["flag"] == "" {
+			return readable(strings.TrimSpace(c.Audit), false)
+		}
+	}
+	return ""
+}
+
+// readableFiles returns why a file named in an audit can't be accessed, or
+// an empty string if they all can.
+func readableFiles(audit string) string {
+	// Only the metadata of files is needed to stat them.
+	statOnly := strings.Contains(audit, "stat ") && !strings.Contains(audit, "cat ")
+	for _, m := range auditPathRe.FindAllStringSubmatch(audit, -1) {
+		if err := readable(m[1], statOnly); err != "" {
+			return err
+		}
+	}
+	return ""
+}
+
+// readable returns why the file can't be accessed, or an empty string if it
+// can or doesn't exist.
+func readable(path string, statOnly bool) string {
+	if _, err := statFile(path); err != nil {
+		if os.IsPermission(err) {
+			return fmt.Sprintf("%s: permission denied accessing %s", insufficientPrivileges, path)
+		}
+		return ""
+	}
+
+	if statOnly {
+		return ""
+	}
+
+	f, err := openFile(path)
+	if err != nil {
+		if os.IsPermission(err) {
+			return fmt.Sprintf("%s: permission denied reading %s", insufficientPrivileges, path)
+		}
+		return ""
+	}
+	f.Close()
+	return ""
+}
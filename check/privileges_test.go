@@ -0,0 +1,158 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"errors"
+	"os"
+	"strings"
+	"testing"
+)
+
+// withPrivileges makes checks run as if kube-bench had the given privileges.
+func withPrivileges(p processPrivileges) func() {
+	currentPrivileges()
+	saved := privileges
+	privileges = p
+	return func() { privileges = saved }
+}
+
+// withDeniedFiles makes the given files inaccessible, stat is denied for
+// the ones with a true value.
+func withDeniedFiles(files map[string]bool) func() {
+	stat, open := statFile, openFile
+	statFile = func(name string) (os.FileInfo, error) {
+		if files[name] {
+			return nil, &os.PathError{Op: "stat", Path: name, Err: os.ErrPermission}
+		}
+		if _, ok := files[name]; ok {
+			return nil, nil
+		}
+		return stat(name)
+	}
+	openFile = func(name string) (*os.File, error) {
+		if _, ok := files[name]; ok {
+			return nil, &os.PathError{Op: "open", Path: name, Err: os.ErrPermission}
+		}
+		return open(name)
+	}
+	return func() { statFile, openFile = stat, open }
+}
+
+func TestPrivilegeIssue(t *testing.T) {
+	defer withDeniedFiles(map[string]bool{
+		"/etc/kubernetes/kubelet.conf": false,
+		"/etc/kubernetes/pki/ca.key":   true,
+	})()
+
+	user := processPrivileges{root: false, hostPID: true}
+	root := processPrivileges{root: true, hostPID: true}
+	noHostPID := processPrivileges{root: true, hostPID: false}
+
+	cases := []struct {
+		name       string
+		privileges processPrivileges
+		check      Check
+		expected   string
+	}{
+		{"readable files", user, Check{Audit: "/bin/cat /etc/hostname"}, ""},
+		{"unreadable file", user, Check{Audit: "/bin/cat /etc/kubernetes/kubelet.conf"}, "permission denied reading /etc/kubernetes/kubelet.conf"},
+		{"unreadable audit_config", user, Check{Audit: "/bin/ps -fC kubelet", AuditConfig: "/bin/cat /etc/kubernetes/kubelet.conf"}, "permission denied reading /etc/kubernetes/kubelet.conf"},
+		{"stat of unreadable file", user, Check{Audit: "/bin/sh -c 'if test -e /etc/kubernetes/kubelet.conf; then stat -c %a /etc/kubernetes/kubelet.conf; fi'"}, ""},
+		{"stat of inaccessible file", user, Check{Audit: "stat -c %a /etc/kubernetes/pki/ca.key"}, "permission denied accessing /etc/kubernetes/pki/ca.key"},
+		{"flag value", user, Check{Audit: "ps -ef --kubeconfig=/etc/kubernetes/kubelet.conf"}, "permission denied reading /etc/kubernetes/kubelet.conf"},
+		{"root", root, Check{Audit: "/bin/cat /etc/kubernetes/kubelet.conf"}, ""},
+		{"sudo", user, Check{Audit: "/bin/cat /etc/kubernetes/kubelet.conf", UseSudo: true}, ""},
+		{"file type", user, Check{Type: FILE, Audit: "/etc/kubernetes/kubelet.conf"}, "permission denied reading /etc/kubernetes/kubelet.conf"},
+		{"file type as root", root, Check{Type: FILE, Audit: "/etc/kubernetes/kubelet.conf"}, ""},
+		{"file type from flag", user, Check{Type: FILE, Audit: "/bin/ps -fC kube-apiserver", AuditOptions: map[string]string{"flag": "--audit-policy-file"}}, ""},
+		{"other type", user, Check{Type: TLS, Audit: "127.0.0.1:6443"}, ""},
+		{"no host processes", noHostPID, Check{Audit: "/bin/ps -fC kubelet"}, "host processes are not visible"},
+		{"no host processes in pipeline", noHostPID, Check{Audit: "ps -ef | grep kubelet"}, "host processes are not visible"},
+		{"no host processes without ps", noHostPID, Check{Audit: "/bin/cat /etc/kubernetes/kubelet.conf"}, ""},
+	}
+
+	for _, c := range cases {
+		restore := withPrivileges(c.privileges)
+		issue := c.check.privilegeIssue()
+		restore()
+
+		if c.expected == "" && issue != "" {
+			t.Errorf("%s: expected no issue, got %q", c.name, issue)
+		}
+		if c.expected != "" && (!strings.HasPrefix(issue, insufficientPrivileges) || !strings.Contains(issue, c.expected)) {
+			t.Errorf("%s: expected %q, got %q", c.name, c.expected, issue)
+		}
+	}
+}
+
+func TestPrivilegeIssueWithCrictl(t *testing.T) {
+	defer withPrivileges(processPrivileges{root: true, hostPID: false})()
+	defer SetProcessBackend(PSBackend)
+	if err := SetProcessBackend(CrictlBackend); err != nil {
+		t.Fatal(err)
+	}
+
+	// crictl answers the ps audits without hostPID, but not other ps commands.
+	if issue := (&Check{Audit: "/bin/ps -fC kubelet"}).privilegeIssue(); issue != "" {
+		t.Errorf("expected no issue, got %q", issue)
+	}
+	if issue := (&Check{Audit: "ps -ef | grep kubelet | wc -l"}).privilegeIssue(); !strings.Contains(issue, "host processes are not visible") {
+		t.Errorf("expected host processes not to be visible, got %q", issue)
+	}
+}
+
+func TestCheckRunInsufficientPrivileges(t *testing.T) {
+	defer withPrivileges(processPrivileges{root: true, hostPID: false})()
+
+	c := &Check{ID: "4.2.1", Audit: "/bin/ps -fC kubelet", Commands: textToCommand("/bin/ps -fC kubelet"), Scored: true, Tests: &tests{}}
+	if state := c.run(); state != WARN || !strings.HasPrefix(c.Reason, insufficientPrivileges) {
+		t.Errorf("expected WARN with insufficient privileges, got %s: %q", state, c.Reason)
+	}
+
+	// Recorded output was gathered with the privileges of the recording.
+	ReplayEvidence(Evidence{c.Audit: "kubelet --anonymous-auth=false"})
+	defer ReplayEvidence(nil)
+	c.Tests = &tests{TestItems: []*testItem{{Flag: "--anonymous-auth", Set: true}}}
+	if state := c.run(); state != PASS {
+		t.Errorf("expected replayed check to PASS, got %s: %q", state, c.Reason)
+	}
+}
+
+func TestCheckRunUnreadableFile(t *testing.T) {
+	defer withPrivileges(processPrivileges{root: true, hostPID: true})()
+
+	audit := "/bin/cat /etc/kubernetes/kubelet.conf"
+	newCheck := func() *Check {
+		return &Check{ID: "4.2.1", Audit: audit, Commands: textToCommand(audit), Scored: true,
+			Tests: &tests{TestItems: []*testItem{{Flag: "--anonymous-auth", Set: true}}}}
+	}
+
+	// The file is missing, the check fails.
+	c := newCheck()
+	if state := c.run(); state != FAIL {
+		t.Errorf("expected FAIL, got %s: %q", state, c.Reason)
+	}
+
+	// Even root can be denied access to it.
+	defer withDeniedFiles(map[string]bool{"/etc/kubernetes/kubelet.conf": false})()
+	c = newCheck()
+	if state := c.run(); state != WARN || !strings.Contains(c.Reason, "permission denied reading /etc/kubernetes/kubelet.conf") {
+		t.Errorf("expected WARN with permission denied, got %s: %q", state, c.Reason)
+	}
+	if err := c.Err(); !errors.Is(err, ErrPermissionDenied) {
+		t.Errorf("expected %v to be %v", err, ErrPermissionDenied)
+	}
+}
@@ -0,0 +1,371 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os/exec"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+const (
+	// PSBackend discovers processes with ps.
+	PSBackend = "ps"
+	// ProcBackend discovers processes by reading /proc. Command lines are
+	// never truncated, unlike with some ps implementations such as busybox.
+	ProcBackend = "proc"
+	// CrictlBackend discovers the processes of containers with crictl.
+	CrictlBackend = "crictl"
+)
+
+// process is a running process found by a process backend.
+type process struct {
+	pid     int
+	cmdline string
+}
+
+// processLister returns the running processes of a binary, ordered by PID.
+type processLister func(name string) ([]process, error)
+
+var processBackends = map[string]processLister{
+	PSBackend:     psProcesses,
+	ProcBackend:   procProcesses,
+	CrictlBackend: crictlProcesses,
+}
+
+var (
+	processBackend = PSBackend
+	listProcesses  = psProcesses
+)
+
+// procRoot and crictl are replaced in tests.
+var (
+	procRoot = "/proc"
+	crictl   = func(args ...string) ([]byte, error) {
+		return exec.Command("crictl", args...).Output()
+	}
+)
+
+// SetProcessBackend selects how running processes are discovered.
+func SetProcessBackend(name string) error {
+	l, ok := processBackends[name]
+	if !ok {
+		return fmt.Errorf("unknown process backend %q, must be one of %s, %s or %s", name, PSBackend, ProcBackend, CrictlBackend)
+	}
+	processBackend, listProcesses = name, l
+	return nil
+}
+
+// FindProcesses returns the command lines of the running processes of a binary.
+func FindProcesses(name string) ([]string, error) {
+	procs, err := listProcesses(name)
+	if err != nil {
+		return nil, err
+	}
+
+	cmdlines := make([]string, 0, len(procs))
+	for _, p := range procs {
+		cmdlines = append(cmdlines, p.cmdline)
+	}
+	return cmdlines, nil
+}
+
+// ProcessInstance is a running process of a binary.
+type ProcessInstance struct {
+	PID     int
+	Cmdline string
+}
+
+// FindProcessInstances returns the running processes of a binary, ordered by PID.
+func FindProcessInstances(name string) ([]ProcessInstance, error) {
+	procs, err := listProcesses(name)
+	if err != nil {
+		return nil, err
+	}
+
+	instances := make([]ProcessInstance, 0, len(procs))
+	for _, p := range procs {
+		instances = append(instances, ProcessInstance{PID: p.pid, Cmdline: p.cmdline})
+	}
+	return instances, nil
+}
+
+// auditProcess outputs the command lines of the running processes of the
+// binary given in the check's audit field, one per line. When several
+// processes match, the select audit option picks one of them:
+//   - oldest: the process started first, as PIDs wrap around.
+//   - cgroup: the process whose cgroup contains the cgroup audit option.
+//   - pidfile: the process whose PID is in the pidfile audit option.
+//
+// Without it all of them are output, and the ambiguity is reported in the
+// check's reason.
+func auditProcess(c *Check) (string, error) {
+	name := strings.TrimSpace(c.Audit)
+	if name == "" {
+		return "", fmt.Errorf("missing process name")
+	}
+
+	procs, err := listProcesses(name)
+	if err != nil {
+		return "", err
+	}
+
+	if len(procs) > 1 {
+		rule := c.AuditOptions["select"]
+		if rule == "" {
+			c.Reason = fmt.Sprintf("%d %s processes matched (PIDs %s), their command lines were all tested", len(procs), name, pidList(procs))
+		} else {
+			selected, err := selectProcess(procs, rule, c.AuditOptions)
+			if err != nil {
+				return "", err
+			}
+			glog.V(2).Infof("Check.ID %s: selected %s process %d out of %d (%s)", c.ID, name, selected.pid, len(procs), rule)
+			procs = []process{selected}
+		}
+	}
+
+	var cmdlines []string
+	for _, p := range procs {
+		cmdlines = append(cmdlines, p.cmdline)
+	}
+	return strings.Join(cmdlines, "\n"), nil
+}
+
+// psAuditRe matches the audits of controls files listing the processes of a
+// binary with ps, "/bin/ps -ef | grep <bin> | grep -v grep" and
+// "/bin/ps -fC <bin>".
+var psAuditRe = regexp.MustCompile(`^\s*(?:/bin/)?ps\s+(?:-ef\s*\|\s*(?:/bin/)?grep\s+(\S+)\s*\|\s*(?:/bin/)?grep\s+-v\s+grep|-fC\s+(\S+))\s*$`)
+
+// psAuditProcess returns the binary whose processes an audit lists with ps,
+// when another process backend than ps is selected. The audit's output is
+// then the command lines of the processes found by that backend, so that
+// flag tests don't depend on the ps of the host, which may truncate them.
+func psAuditProcess(audit string) (string, bool) {
+	if processBackend == PSBackend {
+		return "", false
+	}
+	m := psAuditRe.FindStringSubmatch(audit)
+	if m == nil {
+		return "", false
+	}
+	return m[1] + m[2], true
+}
+
+// writeProcesses writes the command lines of the running processes of a
+// binary, one per line, as the output of a ps audit.
+func writeProcesses(name string, out io.Writer) (State, string) {
+	procs, err := listProcesses(name)
+	if err != nil {
+		return WARN, fmt.Sprintf("failed to list %s processes with the %s backend: %v\n", name, processBackend, err)
+	}
+	for _, p := range procs {
+		fmt.Fprintln(out, p.cmdline)
+	}
+	return "", ""
+}
+
+// oldestProcess returns the process started first, going by the start times
+// in /proc/<pid>/stat. Processes that exited since they were listed are left
+// out.
+func oldestProcess(procs []process) (process, error) {
+	var oldest process
+	var oldestStart uint64
+	found := false
+	for _, p := range procs {
+		s, err := processStartTime(strconv.Itoa(p.pid))
+		if err != nil {
+			glog.V(2).Infof("Failed to get the start time of process %d: %v", p.pid, err)
+			continue
+		}
+		start, err := strconv.ParseUint(s, 10, 64)
+		if err != nil {
+			glog.V(2).Infof("Invalid start time of process %d: %q", p.pid, s)
+			continue
+		}
+		if !found || start < oldestStart {
+			oldest, oldestStart, found = p, start, true
+		}
+	}
+	if !found {
+		return process{}, fmt.Errorf("failed to get the start time of processes (PIDs %s)", pidList(procs))
+	}
+	return oldest, nil
+}
+
+// selectProcess picks one of several processes matching a binary.
+func selectProcess(procs []process, rule string, opts map[string]string) (process, error) {
+	switch rule {
+	case "oldest":
+		return oldestProcess(procs)
+	case "cgroup":
+		want := opts["cgroup"]
+		if want == "" {
+			return process{}, fmt.Errorf("missing cgroup audit option")
+		}
+		var matched []process
+		for _, p := range procs {
+			cgroup, err := ioutil.ReadFile(filepath.Join(procRoot, strconv.Itoa(p.pid), "cgroup"))
+			if err == nil && strings.Contains(string(cgroup), want) {
+				matched = append(matched, p)
+			}
+		}
+		if len(matched) != 1 {
+			return process{}, fmt.Errorf("%d processes (PIDs %s) are in cgroup %q, expected one", len(matched), pidList(matched), want)
+		}
+		return matched[0], nil
+	case "pidfile":
+		data, err := ioutil.ReadFile(opts["pidfile"])
+		if err != nil {
+			return process{}, fmt.Errorf("failed to read PID file: %v", err)
+		}
+		pid, err := strconv.Atoi(strings.TrimSpace(string(data)))
+		if err != nil {
+			return process{}, fmt.Errorf("invalid PID file %s: %v", opts["pidfile"], err)
+		}
+		for _, p := range procs {
+			if p.pid == pid {
+				return p, nil
+			}
+		}
+		return process{}, fmt.Errorf("process %d from %s is not running", pid, opts["pidfile"])
+	}
+	return process{}, fmt.Errorf("unknown process selection %q, must be one of oldest, cgroup or pidfile", rule)
+}
+
+func pidList(procs []process) string {
+	pids := make([]string, 0, len(procs))
+	for _, p := range procs {
+		pids = append(pids, strconv.Itoa(p.pid))
+	}
+	return strings.Join(pids, ", ")
+}
+
+func psProcesses(name string) ([]process, error) {
+	// TODO: truncate name to 15 chars
+	// See https://github.com/aquasecurity/kube-bench/issues/328#issuecomment-506813344
+	out, err := exec.Command("/bin/ps", "-C", name, "-o", "pid=,cmd=").Output()
+	if err != nil {
+		// ps exits with an error when no process matches.
+		glog.V(2).Infof("ps -C %s: %v", name, err)
+	}
+	return parsePs(string(out)), nil
+}
+
+// parsePs parses the "pid=,cmd=" output of ps.
+func parsePs(out string) []process {
+	var procs []process
+	for _, l := range nonEmptyLines(out) {
+		fields := strings.SplitN(strings.TrimSpace(l), " ", 2)
+		pid, err := strconv.Atoi(fields[0])
+		if err != nil || len(fields) < 2 {
+			continue
+		}
+		procs = append(procs, process{pid: pid, cmdline: strings.TrimSpace(fields[1])})
+	}
+	sort.Slice(procs, func(i, j int) bool { return procs[i].pid < procs[j].pid })
+	return procs
+}
+
+func procProcesses(name string) ([]process, error) {
+	dirs, err := ioutil.ReadDir(procRoot)
+	if err != nil {
+		return nil, err
+	}
+
+	// The kernel truncates process names to 15 characters.
+	comm := name
+	if len(comm) > 15 {
+		comm = comm[:15]
+	}
+
+	var procs []process
+	for _, d := range dirs {
+		pid, err := strconv.Atoi(d.Name())
+		if err != nil || !d.IsDir() {
+			continue
+		}
+
+		cmdline, err := ioutil.ReadFile(filepath.Join(procRoot, d.Name(), "cmdline"))
+		if err != nil || len(cmdline) == 0 {
+			// The process exited, or is a kernel thread.
+			continue
+		}
+		args := strings.Split(strings.TrimRight(string(cmdline), "\x00"), "\x00")
+
+		c, _ := ioutil.ReadFile(filepath.Join(procRoot, d.Name(), "comm"))
+		if strings.TrimSpace(string(c)) != comm && filepath.Base(args[0]) != name {
+			continue
+		}
+
+		procs = append(procs, process{pid: pid, cmdline: strings.Join(args, " ")})
+	}
+
+	sort.Slice(procs, func(i, j int) bool { return procs[i].pid < procs[j].pid })
+	return procs, nil
+}
+
+func crictlProcesses(name string) ([]process, error) {
+	ids, err := crictl("ps", "--quiet", "--state", "running", "--name", name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list containers with crictl: %v", err)
+	}
+
+	var procs []process
+	for _, id := range nonEmptyLines(string(ids)) {
+		data, err := crictl("inspect", "--output", "json", id)
+		if err != nil {
+			return nil, fmt.Errorf("failed to inspect container %s with crictl: %v", id, err)
+		}
+
+		var inspect struct {
+			Info struct {
+				Pid         int `json:"pid"`
+				RuntimeSpec struct {
+					Process struct {
+						Args []string `json:"args"`
+					} `json:"process"`
+				} `json:"runtimeSpec"`
+			} `json:"info"`
+		}
+		if err := json.Unmarshal(data, &inspect); err != nil {
+			return nil, fmt.Errorf("failed to parse crictl inspect output for container %s: %v", id, err)
+		}
+		if args := inspect.Info.RuntimeSpec.Process.Args; len(args) > 0 {
+			procs = append(procs, process{pid: inspect.Info.Pid, cmdline: strings.Join(args, " ")})
+		}
+	}
+
+	sort.Slice(procs, func(i, j int) bool { return procs[i].pid < procs[j].pid })
+	return procs, nil
+}
+
+func nonEmptyLines(s string) []string {
+	var lines []string
+	for _, l := range strings.Split(s, "\n") {
+		if strings.TrimSpace(l) != "" {
+			lines = append(lines, l)
+		}
+	}
+	return lines
+}
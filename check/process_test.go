@@ -0,0 +1,365 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// withProcesses fakes /proc with processes given by PID.
+func withProcesses(t *testing.T, procs map[string][]string) func() {
+	dir, err := ioutil.TempDir("", "kube-bench-proc")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for pid, args := range procs {
+		p := filepath.Join(dir, pid)
+		if err := os.Mkdir(p, 0755); err != nil {
+			t.Fatal(err)
+		}
+
+		var cmdline string
+		if len(args) > 0 {
+			cmdline = strings.Join(args, "\x00") + "\x00"
+		}
+		if err := ioutil.WriteFile(filepath.Join(p, "cmdline"), []byte(cmdline), 0644); err != nil {
+			t.Fatal(err)
+		}
+
+		comm := "kthreadd"
+		if len(args) > 0 {
+			comm = filepath.Base(args[0])
+			if len(comm) > 15 {
+				comm = comm[:15]
+			}
+		}
+		if err := ioutil.WriteFile(filepath.Join(p, "comm"), []byte(comm+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := os.Mkdir(filepath.Join(dir, "self"), 0755); err != nil {
+		t.Fatal(err)
+	}
+
+	saved := procRoot
+	procRoot = dir
+	return func() {
+		procRoot = saved
+		os.RemoveAll(dir)
+	}
+}
+
+// withStartTime sets the start time of a process created by withProcesses,
+// in clock ticks after boot.
+func withStartTime(t *testing.T, pid string, start int) {
+	stat := fmt.Sprintf("%s (kubelet) S %s%d 0 0\n", pid, strings.Repeat("0 ", 18), start)
+	if err := ioutil.WriteFile(filepath.Join(procRoot, pid, "stat"), []byte(stat), 0644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProcProcesses(t *testing.T) {
+	defer withProcesses(t, map[string][]string{
+		"1":    {"/sbin/init"},
+		"2":    nil,
+		"812":  {"/usr/bin/kubelet", "--anonymous-auth=false", "--read-only-port=0"},
+		"1200": {"kube-controller-manager", "--profiling=false"},
+		"97":   {"kubelet", "--config=/var/lib/kubelet/config.yaml"},
+	})()
+
+	cases := []struct {
+		name     string
+		expected []process
+	}{
+		{"kubelet", []process{
+			{97, "kubelet --config=/var/lib/kubelet/config.yaml"},
+			{812, "/usr/bin/kubelet --anonymous-auth=false --read-only-port=0"},
+		}},
+		// Longer than the 15 characters kept by the kernel.
+		{"kube-controller-manager", []process{{1200, "kube-controller-manager --profiling=false"}}},
+		{"kube-apiserver", nil},
+	}
+
+	for _, c := range cases {
+		got, err := procProcesses(c.name)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", c.name, err)
+		}
+		if !reflect.DeepEqual(got, c.expected) {
+			t.Errorf("%s: expected %v, got %v", c.name, c.expected, got)
+		}
+	}
+}
+
+func TestFindProcessInstances(t *testing.T) {
+	defer withProcesses(t, map[string][]string{
+		"812": {"/usr/bin/kubelet", "--hostname-override=kind-worker"},
+		"97":  {"/usr/bin/kubelet", "--hostname-override=kind-control-plane"},
+	})()
+	defer func(backend string, l processLister) { processBackend, listProcesses = backend, l }(processBackend, listProcesses)
+	if err := SetProcessBackend(ProcBackend); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := FindProcessInstances("kubelet")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []ProcessInstance{
+		{PID: 97, Cmdline: "/usr/bin/kubelet --hostname-override=kind-control-plane"},
+		{PID: 812, Cmdline: "/usr/bin/kubelet --hostname-override=kind-worker"},
+	}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestCrictlProcesses(t *testing.T) {
+	saved := crictl
+	defer func() { crictl = saved }()
+
+	crictl = func(args ...string) ([]byte, error) {
+		switch {
+		case args[0] == "ps" && args[len(args)-1] == "kube-apiserver":
+			return []byte("abc123\ndef456\n"), nil
+		case args[0] == "ps":
+			return []byte(""), nil
+		case args[0] == "inspect" && args[len(args)-1] == "abc123":
+			return []byte(`{"info":{"pid":4321,"runtimeSpec":{"process":{"args":["kube-apiserver","--anonymous-auth=false"]}}}}`), nil
+		case args[0] == "inspect" && args[len(args)-1] == "def456":
+			return []byte(`{"info":{"pid":1234,"runtimeSpec":{"process":{"args":["kube-apiserver","--anonymous-auth=true"]}}}}`), nil
+		}
+		return nil, fmt.Errorf("unexpected crictl %v", args)
+	}
+
+	got, err := crictlProcesses("kube-apiserver")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := []process{{1234, "kube-apiserver --anonymous-auth=true"}, {4321, "kube-apiserver --anonymous-auth=false"}}
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+
+	if got, err := crictlProcesses("kubelet"); err != nil || len(got) != 0 {
+		t.Errorf("expected no process, got %q, %v", got, err)
+	}
+
+	crictl = func(args ...string) ([]byte, error) { return nil, fmt.Errorf("crictl not found") }
+	if _, err := crictlProcesses("kubelet"); err == nil {
+		t.Errorf("expected an error when crictl fails")
+	}
+}
+
+func TestSetProcessBackend(t *testing.T) {
+	defer SetProcessBackend(PSBackend)
+
+	if err := SetProcessBackend("bogus"); err == nil {
+		t.Errorf("expected an error for an unknown backend")
+	}
+
+	defer withProcesses(t, map[string][]string{"812": {"kubelet", "--anonymous-auth=false"}})()
+	if err := SetProcessBackend(ProcBackend); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	c := &Check{Type: PROCESS, Audit: "kubelet"}
+	out, err := auditProcess(c)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if out != "kubelet --anonymous-auth=false" {
+		t.Errorf("unexpected output %q", out)
+	}
+
+	if _, err := auditProcess(&Check{Type: PROCESS}); err == nil {
+		t.Errorf("expected an error for a missing process name")
+	}
+}
+
+func TestParsePs(t *testing.T) {
+	out := "  812 /usr/bin/kubelet --anonymous-auth=false\n   97 kubelet --config=/var/lib/kubelet/config.yaml\n\n"
+	expected := []process{
+		{97, "kubelet --config=/var/lib/kubelet/config.yaml"},
+		{812, "/usr/bin/kubelet --anonymous-auth=false"},
+	}
+	if got := parsePs(out); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestAuditProcessSelection(t *testing.T) {
+	defer SetProcessBackend(PSBackend)
+	defer withProcesses(t, map[string][]string{
+		"97":  {"kubelet", "--anonymous-auth=true"},
+		"812": {"kubelet", "--anonymous-auth=false"},
+	})()
+	if err := SetProcessBackend(ProcBackend); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(procRoot, "97", "cgroup"), []byte("0::/kubepods/burstable/pod1/kind\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(procRoot, "812", "cgroup"), []byte("0::/system.slice/kubelet.service\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	withStartTime(t, "97", 1200)
+	withStartTime(t, "812", 4800)
+	pidfile := filepath.Join(procRoot, "kubelet.pid")
+	if err := ioutil.WriteFile(pidfile, []byte("812\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	cases := []struct {
+		opts      map[string]string
+		expected  string
+		ambiguous bool
+		expectErr bool
+	}{
+		{opts: nil, expected: "kubelet --anonymous-auth=true\nkubelet --anonymous-auth=false", ambiguous: true},
+		{opts: map[string]string{"select": "oldest"}, expected: "kubelet --anonymous-auth=true"},
+		{opts: map[string]string{"select": "cgroup", "cgroup": "kubelet.service"}, expected: "kubelet --anonymous-auth=false"},
+		{opts: map[string]string{"select": "cgroup", "cgroup": "kubepods"}, expected: "kubelet --anonymous-auth=true"},
+		{opts: map[string]string{"select": "pidfile", "pidfile": pidfile}, expected: "kubelet --anonymous-auth=false"},
+		{opts: map[string]string{"select": "cgroup", "cgroup": "/"}, expectErr: true},
+		{opts: map[string]string{"select": "cgroup"}, expectErr: true},
+		{opts: map[string]string{"select": "pidfile", "pidfile": "/nonexistent"}, expectErr: true},
+		{opts: map[string]string{"select": "newest"}, expectErr: true},
+	}
+
+	for _, c := range cases {
+		check := &Check{ID: "4.2.1", Type: PROCESS, Audit: "kubelet", AuditOptions: c.opts}
+		out, err := auditProcess(check)
+		if c.expectErr {
+			if err == nil {
+				t.Errorf("%v: expected an error", c.opts)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%v: unexpected error: %v", c.opts, err)
+			continue
+		}
+		if out != c.expected {
+			t.Errorf("%v: expected %q, got %q", c.opts, c.expected, out)
+		}
+		if ambiguous := strings.Contains(check.Reason, "2 kubelet processes matched (PIDs 97, 812)"); ambiguous != c.ambiguous {
+			t.Errorf("%v: expected ambiguity to be reported: %t, got reason %q", c.opts, c.ambiguous, check.Reason)
+		}
+	}
+}
+
+func TestSelectOldestProcess(t *testing.T) {
+	// PIDs wrapped around after the first kubelet started: the kubelet with
+	// the lowest PID started last.
+	defer withProcesses(t, map[string][]string{
+		"4190000": {"kubelet", "--anonymous-auth=false"},
+		"300":     {"kubelet", "--anonymous-auth=true"},
+		"12":      {"kubelet", "--anonymous-auth=true"},
+	})()
+	withStartTime(t, "4190000", 1200)
+	withStartTime(t, "300", 98000)
+	// 12 exited since the processes were listed.
+	procs := []process{{12, "kubelet --anonymous-auth=true"}, {300, "kubelet --anonymous-auth=true"}, {4190000, "kubelet --anonymous-auth=false"}}
+
+	selected, err := selectProcess(procs, "oldest", nil)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if selected.pid != 4190000 {
+		t.Errorf("expected the kubelet started first, got PID %d", selected.pid)
+	}
+
+	if _, err := selectProcess(procs[:1], "oldest", nil); err == nil {
+		t.Errorf("expected an error without start times")
+	}
+}
+
+func TestPsAuditProcess(t *testing.T) {
+	defer func(backend string, l processLister) { processBackend, listProcesses = backend, l }(processBackend, listProcesses)
+
+	cases := map[string]string{
+		"/bin/ps -ef | grep kube-apiserver | grep -v grep":           "kube-apiserver",
+		"/bin/ps -ef | /bin/grep etcd | /bin/grep -v grep":           "etcd",
+		"/bin/ps -fC kubelet":                                        "kubelet",
+		"/bin/ps -fC kubelet ":                                       "kubelet",
+		"ps -ef | grep etcd | grep -- --data-dir | sed 's%.*%%'":     "",
+		"/bin/ps -ef | grep kube-apiserver | grep -v grep | wc -l":   "",
+		"/bin/sh -c 'ps -fC kubelet'":                                "",
+		"/bin/cat /var/lib/kubelet/config.yaml":                      "",
+		"/bin/ps -ef | grep kube-apiserver | grep -v grep; echo foo": "",
+	}
+
+	processBackend, listProcesses = PSBackend, psProcesses
+	for audit := range cases {
+		if _, ok := psAuditProcess(audit); ok {
+			t.Errorf("%q: expected ps to run with the ps backend", audit)
+		}
+	}
+
+	if err := SetProcessBackend(ProcBackend); err != nil {
+		t.Fatal(err)
+	}
+	for audit, expected := range cases {
+		name, ok := psAuditProcess(audit)
+		if name != expected || ok != (expected != "") {
+			t.Errorf("%q: expected %q, got %q, %t", audit, expected, name, ok)
+		}
+	}
+}
+
+func TestPsAuditsUseProcessBackend(t *testing.T) {
+	defer func(backend string, l processLister) { processBackend, listProcesses = backend, l }(processBackend, listProcesses)
+	defer withPrivileges(processPrivileges{root: true, hostPID: true})()
+
+	// Longer than the command lines kept by busybox ps.
+	args := []string{"/usr/bin/kubelet"}
+	for i := 0; i < 200; i++ {
+		args = append(args, fmt.Sprintf("--node-labels=label%d=value", i))
+	}
+
+	cases := []struct {
+		anonymousAuth string
+		expected      State
+	}{
+		{"--anonymous-auth=false", PASS},
+		{"--anonymous-auth=true", FAIL},
+	}
+	for _, c := range cases {
+		t.Run(c.anonymousAuth, func(t *testing.T) {
+			defer withProcesses(t, map[string][]string{"812": append(args, c.anonymousAuth)})()
+			if err := SetProcessBackend(ProcBackend); err != nil {
+				t.Fatal(err)
+			}
+
+			check := shippedCheck(t, "cis-1.5/node.yaml", "4.2.1")
+			check.Audit = strings.Replace(check.Audit, "$kubeletbin", "kubelet", -1)
+			check.Commands = textToCommand(check.Audit)
+			check.AuditConfig = ""
+			check.run()
+			if check.State != c.expected {
+				t.Errorf("expected %s, actual %s (%s)", c.expected, check.State, check.Reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,144 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// binding is the part of a RoleBinding or ClusterRoleBinding we check.
+type binding struct {
+	kind     string
+	name     string
+	roleRef  rbacv1.RoleRef
+	subjects []rbacv1.Subject
+}
+
+func (b binding) String() string {
+	return fmt.Sprintf("%s/%s", b.kind, b.name)
+}
+
+func listBindings(client kubernetes.Interface) ([]binding, error) {
+	var bindings []binding
+
+	crbs, err := client.RbacV1().ClusterRoleBindings().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, crb := range crbs.Items {
+		bindings = append(bindings, binding{kind: "clusterrolebinding", name: crb.Name, roleRef: crb.RoleRef, subjects: crb.Subjects})
+	}
+
+	rbs, err := client.RbacV1().RoleBindings(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, rb := range rbs.Items {
+		bindings = append(bindings, binding{kind: "rolebinding", name: rb.Namespace + "/" + rb.Name, roleRef: rb.RoleRef, subjects: rb.Subjects})
+	}
+
+	return bindings, nil
+}
+
+// clusterAdminDefaultServiceAccounts finds bindings of the cluster-admin
+// role to the default service account of a namespace.
+func clusterAdminDefaultServiceAccounts(client kubernetes.Interface, opts map[string]string) ([]string, error) {
+	bindings, err := listBindings(client)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []string
+	for _, b := range bindings {
+		if b.roleRef.Kind != "ClusterRole" || b.roleRef.Name != "cluster-admin" {
+			continue
+		}
+		for _, s := range b.subjects {
+			if s.Kind == rbacv1.ServiceAccountKind && s.Name == "default" {
+				violations = append(violations, fmt.Sprintf("%s binds cluster-admin to serviceaccount %s/%s", b, s.Namespace, s.Name))
+			}
+		}
+	}
+	return violations, nil
+}
+
+// wildcardRules finds Roles and ClusterRoles with a wildcard in the API
+// groups, resources or verbs of a rule. Roles whose name starts with one
+// of the prefixes in the "exclude" audit option are not checked.
+func wildcardRules(client kubernetes.Interface, opts map[string]string) ([]string, error) {
+	exclude := optionList(opts, "exclude")
+	var violations []string
+
+	crs, err := client.RbacV1().ClusterRoles().List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, cr := range crs.Items {
+		if !hasPrefix(cr.Name, exclude) && hasWildcardRule(cr.Rules) {
+			violations = append(violations, fmt.Sprintf("clusterrole/%s has a wildcard rule", cr.Name))
+		}
+	}
+
+	roles, err := client.RbacV1().Roles(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range roles.Items {
+		if !hasPrefix(r.Name, exclude) && hasWildcardRule(r.Rules) {
+			violations = append(violations, fmt.Sprintf("role/%s/%s has a wildcard rule", r.Namespace, r.Name))
+		}
+	}
+
+	return violations, nil
+}
+
+func hasWildcardRule(rules []rbacv1.PolicyRule) bool {
+	for _, rule := range rules {
+		for _, l := range [][]string{rule.APIGroups, rule.Resources, rule.Verbs} {
+			if contains(l, rbacv1.ResourceAll) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// anonymousBindings finds bindings granting a role to anonymous or
+// unauthenticated users. Bindings named in the "exclude" audit option,
+// such as the default system:public-info-viewer, are not checked.
+func anonymousBindings(client kubernetes.Interface, opts map[string]string) ([]string, error) {
+	bindings, err := listBindings(client)
+	if err != nil {
+		return nil, err
+	}
+
+	exclude := optionList(opts, "exclude")
+	var violations []string
+	for _, b := range bindings {
+		if contains(exclude, b.name) {
+			continue
+		}
+		for _, s := range b.subjects {
+			if s.Name == "system:anonymous" || s.Name == "system:unauthenticated" {
+				violations = append(violations, fmt.Sprintf("%s binds %s/%s to %s", b, b.roleRef.Kind, b.roleRef.Name, s.Name))
+			}
+		}
+	}
+	return violations, nil
+}
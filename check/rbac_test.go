@@ -0,0 +1,111 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// withKubeClient makes "api" checks use a fake client holding objects.
+func withKubeClient(t *testing.T, objects ...runtime.Object) func() {
+	client := fake.NewSimpleClientset(objects...)
+	kubeClient = func() (kubernetes.Interface, error) { return client, nil }
+	return func() { kubeClient = newKubeClient }
+}
+
+func TestRBACQueries(t *testing.T) {
+	defer withKubeClient(t,
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "default-admin"},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: "cluster-admin"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "default", Namespace: "kube-system"}},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-admin", Namespace: "app"},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: "cluster-admin"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.ServiceAccountKind, Name: "app", Namespace: "app"}},
+		},
+		&rbacv1.ClusterRoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "system:public-info-viewer"},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: "system:public-info-viewer"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.GroupKind, Name: "system:unauthenticated"}},
+		},
+		&rbacv1.RoleBinding{
+			ObjectMeta: metav1.ObjectMeta{Name: "anonymous-view", Namespace: "app"},
+			RoleRef:    rbacv1.RoleRef{Kind: "ClusterRole", Name: "view"},
+			Subjects:   []rbacv1.Subject{{Kind: rbacv1.UserKind, Name: "system:anonymous"}},
+		},
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "cluster-admin"},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{"*"}, Resources: []string{"*"}, Verbs: []string{"*"}}},
+		},
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "view"},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"pods"}, Verbs: []string{"get", "list"}}},
+		},
+		&rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{Name: "app-all", Namespace: "app"},
+			Rules:      []rbacv1.PolicyRule{{APIGroups: []string{""}, Resources: []string{"secrets"}, Verbs: []string{"*"}}},
+		},
+	)()
+
+	cases := []struct {
+		audit    string
+		opts     map[string]string
+		expected string
+	}{
+		{
+			audit:    "cluster-admin-default-service-accounts",
+			expected: `{"count":1,"violations":["clusterrolebinding/default-admin binds cluster-admin to serviceaccount kube-system/default"]}`,
+		},
+		{
+			audit:    "wildcard-rules",
+			opts:     map[string]string{"exclude": "cluster-admin,system:"},
+			expected: `{"count":1,"violations":["role/app/app-all has a wildcard rule"]}`,
+		},
+		{
+			audit:    "anonymous-bindings",
+			opts:     map[string]string{"exclude": "system:public-info-viewer"},
+			expected: `{"count":1,"violations":["rolebinding/app/anonymous-view binds ClusterRole/view to system:anonymous"]}`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.audit, func(t *testing.T) {
+			out, err := auditAPI(&Check{Type: API, Audit: c.audit, AuditOptions: c.opts})
+			assert.NoError(t, err)
+			assert.Equal(t, c.expected, out)
+		})
+	}
+}
+
+func TestCheck_RunAPI(t *testing.T) {
+	defer withKubeClient(t)()
+
+	noViolations := []*testItem{{Path: "{.count}", Set: true, Compare: compare{Op: "eq", Value: "0"}}}
+
+	check := &Check{Type: API, Audit: "wildcard-rules", Scored: true, Tests: &tests{TestItems: noViolations}}
+	assert.Equal(t, PASS, check.run())
+
+	check = &Check{Type: API, Audit: "no-such-query", Scored: true, Tests: &tests{TestItems: noViolations}}
+	assert.Equal(t, WARN, check.run())
+}
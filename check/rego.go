@@ -0,0 +1,147 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/golang/glog"
+	"github.com/open-policy-agent/opa/rego"
+)
+
+// regoInput is the document a rego test is evaluated against.
+type regoInput struct {
+	// Output is the output of the audit.
+	Output string `json:"output"`
+	// Flags holds the flags found in the output, e.g. input.flags["--anonymous-auth"].
+	Flags map[string]string `json:"flags"`
+	// Config is the output parsed as YAML or JSON, e.g. a config file or
+	// an API object, if it could be.
+	Config interface{} `json:"config"`
+}
+
+// EvalRego evaluates a rego query against an input document, with the rego
+// modules given by file name, and returns the values of the expressions of
+// each of its results. A query that is undefined has no result.
+func EvalRego(query string, input interface{}, modules map[string]string) ([][]interface{}, error) {
+	// The input is made of JSON values, like the input given to opa.
+	data, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode rego input: %v", err)
+	}
+	var doc interface{}
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("failed to encode rego input: %v", err)
+	}
+
+	options := []func(*rego.Rego){rego.Query(query), rego.Input(doc)}
+	for name, module := range modules {
+		options = append(options, rego.Module(name, module))
+	}
+	rs, err := rego.New(options...).Eval(context.Background())
+	if err != nil {
+		return nil, err
+	}
+
+	results := make([][]interface{}, 0, len(rs))
+	for _, r := range rs {
+		values := make([]interface{}, 0, len(r.Expressions))
+		for _, e := range r.Expressions {
+			values = append(values, e.Value)
+		}
+		results = append(results, values)
+	}
+	return results, nil
+}
+
+// executeRego passes when the rego query of the test item is satisfied by
+// the output: all its expressions, one per line, must hold.
+func (t *testItem) executeRego(s string) *testOutput {
+	input := regoInput{Output: s, Flags: parseFlags(s)}
+	var config interface{}
+	if err := unmarshal(s, &config); err == nil {
+		input.Config = convertYAMLMaps(config)
+	}
+
+	query := strings.TrimSpace(t.Rego)
+	results, err := EvalRego(query, input, nil)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "unable to evaluate rego expression \"%s\": %v\n", query, err)
+		return failTestItem("error evaluating rego expression")
+	}
+	glog.V(3).Infof("rego expression %q: %v", query, results)
+
+	// An expression that does not hold leaves the query undefined, with no
+	// result, or evaluates to false.
+	satisfied := len(results) > 0
+	if satisfied {
+		for _, v := range results[0] {
+			if b, ok := v.(bool); ok && !b {
+				satisfied = false
+			}
+		}
+	}
+	return &testOutput{testResult: satisfied, ExpectedResult: fmt.Sprintf("'%s' holds", query)}
+}
+
+// parseFlags returns the flags in the output of a command line: --flag=value
+// and --flag value give value, and a flag on its own gives "true".
+func parseFlags(s string) map[string]string {
+	flags := map[string]string{}
+	fields := strings.Fields(s)
+	for i := 0; i < len(fields); i++ {
+		f := fields[i]
+		if !strings.HasPrefix(f, "-") || f == "-" || f == "--" {
+			continue
+		}
+		if kv := strings.SplitN(f, "=", 2); len(kv) == 2 {
+			flags[kv[0]] = kv[1]
+		} else if i+1 < len(fields) && !strings.HasPrefix(fields[i+1], "-") {
+			flags[f] = fields[i+1]
+			i++
+		} else {
+			flags[f] = "true"
+		}
+	}
+	return flags
+}
+
+// convertYAMLMaps converts the maps decoded from YAML, whose keys need not
+// be strings, so that they can be encoded as JSON.
+func convertYAMLMaps(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[interface{}]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[fmt.Sprint(k)] = convertYAMLMaps(e)
+		}
+		return m
+	case map[string]interface{}:
+		for k, e := range v {
+			v[k] = convertYAMLMaps(e)
+		}
+		return v
+	case []interface{}:
+		for i, e := range v {
+			v[i] = convertYAMLMaps(e)
+		}
+		return v
+	}
+	return v
+}
@@ -0,0 +1,76 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseFlags(t *testing.T) {
+	flags := parseFlags("/usr/bin/kubelet --anonymous-auth=false --read-only-port 0 --rotate-certificates -v 2 --config=/var/lib/kubelet/config.yaml")
+	expected := map[string]string{
+		"--anonymous-auth":      "false",
+		"--read-only-port":      "0",
+		"--rotate-certificates": "true",
+		"-v":                    "2",
+		"--config":              "/var/lib/kubelet/config.yaml",
+	}
+	if !reflect.DeepEqual(flags, expected) {
+		t.Errorf("expected %v, got %v", expected, flags)
+	}
+}
+
+func TestExecuteRego(t *testing.T) {
+	const config = "authentication:\n  anonymous:\n    enabled: false\n  webhook:\n    enabled: true\n"
+
+	cases := []struct {
+		name     string
+		rego     string
+		output   string
+		expected bool
+	}{
+		{"config holds", "input.config.authentication.anonymous.enabled == false\ninput.config.authentication.webhook.enabled == true\n", config, true},
+		{"config does not hold", "input.config.authentication.anonymous.enabled == false\ninput.config.authentication.webhook.enabled == false\n", config, false},
+		{"undefined", "input.config.authorization.mode == \"Webhook\"", config, false},
+		{"flags", `input.flags["--anonymous-auth"] == "false"`, "/usr/bin/kubelet --anonymous-auth=false", true},
+		{"output", `contains(input.output, "--anonymous-auth=false")`, "/usr/bin/kubelet --anonymous-auth=true", false},
+		{"parse error", "input.config.authentication ==", config, false},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			ti := &testItem{Rego: c.rego}
+			if result := ti.execute(c.output); result.testResult != c.expected {
+				t.Errorf("expected %t, got %t", c.expected, result.testResult)
+			}
+		})
+	}
+}
+
+func TestEvalRego(t *testing.T) {
+	module := "package test\n\ndeny[msg] {\n  input.port != 0\n  msg := sprintf(\"port %v is open\", [input.port])\n}\n"
+	results, err := EvalRego("data.test.deny", map[string]int{"port": 10255}, map[string]string{"test.rego": module})
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := [][]interface{}{{[]interface{}{"port 10255 is open"}}}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("expected %v, got %v", expected, results)
+	}
+
+	if _, err := EvalRego("data.test.deny", nil, map[string]string{"test.rego": "package"}); err == nil {
+		t.Errorf("expected an error for an invalid module")
+	}
+}
@@ -0,0 +1,104 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"strings"
+)
+
+const (
+	// FileStep sets a key of a config file, e.g. of the kubelet config file.
+	FileStep = "file"
+	// FlagStep sets a flag of a component in its pod specification file or
+	// service file.
+	FlagStep = "flag"
+	// CommandStep runs a command.
+	CommandStep = "command"
+)
+
+// RemediationStep is a step of the structured remediation of a check: a file
+// edit, a flag change or a command.
+type RemediationStep struct {
+	Type string `yaml:"type" json:"type"`
+	// File is the file to edit, for file and flag steps.
+	File string `yaml:"file" json:"file,omitempty"`
+	// Key is the key to set in the file, e.g. authentication.anonymous.enabled,
+	// for file steps.
+	Key string `yaml:"key" json:"key,omitempty"`
+	// Flag is the flag to set, e.g. --anonymous-auth, for flag steps.
+	Flag string `yaml:"flag" json:"flag,omitempty"`
+	// Value is the value to set the key or flag to. The key or flag is
+	// removed instead when Unset.
+	Value string `yaml:"value" json:"value,omitempty"`
+	Unset bool   `yaml:"unset" json:"unset,omitempty"`
+	// Command is the command to run, for command steps.
+	Command string `yaml:"command" json:"command,omitempty"`
+}
+
+func (s RemediationStep) validate() error {
+	switch s.Type {
+	case FileStep:
+		if s.File == "" || s.Key == "" {
+			return fmt.Errorf("file step needs a file and a key")
+		}
+	case FlagStep:
+		if s.File == "" || s.Flag == "" {
+			return fmt.Errorf("flag step needs a file and a flag")
+		}
+	case CommandStep:
+		if s.Command == "" {
+			return fmt.Errorf("command step needs a command")
+		}
+	default:
+		return fmt.Errorf("unknown step type %q", s.Type)
+	}
+	return nil
+}
+
+// Text returns the step as the free-text remediation of the benchmarks.
+func (s RemediationStep) Text() string {
+	switch s.Type {
+	case FileStep:
+		if s.Unset {
+			return fmt.Sprintf("Edit the file %s and remove %s.", s.File, s.Key)
+		}
+		return fmt.Sprintf("Edit the file %s and set %s to %s.", s.File, s.Key, s.Value)
+	case FlagStep:
+		if s.Unset {
+			return fmt.Sprintf("Edit the file %s and remove the %s parameter.", s.File, s.Flag)
+		}
+		return fmt.Sprintf("Edit the file %s and set the parameter below.\n%s=%s", s.File, s.Flag, s.Value)
+	default:
+		return fmt.Sprintf("Run the command below.\n%s", s.Command)
+	}
+}
+
+// prepareRemediation checks the structured remediation of a check, and
+// generates its free-text remediation from it when not given.
+func (c *Check) prepareRemediation() error {
+	texts := make([]string, 0, len(c.RemediationSteps))
+	for i, s := range c.RemediationSteps {
+		if err := s.validate(); err != nil {
+			return fmt.Errorf("check %s: invalid remediation step %d: %v", c.ID, i+1, err)
+		}
+		texts = append(texts, s.Text())
+	}
+
+	if strings.TrimSpace(c.Remediation) == "" && len(texts) > 0 {
+		c.Remediation = strings.Join(texts, "\n")
+	}
+	return nil
+}
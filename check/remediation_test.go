@@ -0,0 +1,93 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestStructuredRemediation(t *testing.T) {
+	in := []byte(`---
+controls:
+id: 4
+text: "Worker Node Security Configuration"
+type: "node"
+groups:
+- id: 4.2
+  text: "Kubelet"
+  checks:
+  - id: 4.2.1
+    text: "Ensure that the anonymous-auth argument is set to false"
+    remediation_steps:
+    - type: file
+      file: /var/lib/kubelet/config.yaml
+      key: authentication.anonymous.enabled
+      value: "false"
+    - type: flag
+      file: /etc/systemd/system/kubelet.service.d/10-kubeadm.conf
+      flag: --anonymous-auth
+      unset: true
+    - type: command
+      command: systemctl daemon-reload && systemctl restart kubelet.service
+  - id: 4.2.2
+    text: "Ensure that the --authorization-mode argument is not set to AlwaysAllow"
+    remediation: "Set authorization: mode to Webhook."
+    remediation_steps:
+    - type: file
+      file: /var/lib/kubelet/config.yaml
+      key: authorization.mode
+      value: Webhook
+`)
+
+	controls, err := NewControls(NODE, in)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	c := controls.Groups[0].Checks[0]
+	expected := strings.Join([]string{
+		"Edit the file /var/lib/kubelet/config.yaml and set authentication.anonymous.enabled to false.",
+		"Edit the file /etc/systemd/system/kubelet.service.d/10-kubeadm.conf and remove the --anonymous-auth parameter.",
+		"Run the command below.",
+		"systemctl daemon-reload && systemctl restart kubelet.service",
+	}, "\n")
+	if c.Remediation != expected {
+		t.Errorf("expected the remediation\n%s\ngot\n%s", expected, c.Remediation)
+	}
+	if len(c.RemediationSteps) != 3 || c.RemediationSteps[1].Flag != "--anonymous-auth" || !c.RemediationSteps[1].Unset {
+		t.Errorf("unexpected remediation steps %+v", c.RemediationSteps)
+	}
+
+	// A free-text remediation is kept.
+	if c := controls.Groups[0].Checks[1]; c.Remediation != "Set authorization: mode to Webhook." {
+		t.Errorf("unexpected remediation %q", c.Remediation)
+	}
+}
+
+func TestInvalidRemediationStep(t *testing.T) {
+	steps := []string{
+		"- type: file\n      file: /var/lib/kubelet/config.yaml",
+		"- type: flag\n      flag: --anonymous-auth",
+		"- type: command",
+		"- type: reboot",
+	}
+	for _, s := range steps {
+		in := []byte("controls:\ntype: node\ngroups:\n- id: 4.2\n  checks:\n  - id: 4.2.1\n    remediation_steps:\n    " + s + "\n")
+		if _, err := NewControls(NODE, in); err == nil || !strings.Contains(err.Error(), "check 4.2.1: invalid remediation step 1") {
+			t.Errorf("%s: expected an error, got %v", s, err)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"strings"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/client-go/kubernetes"
+)
+
+// namespaceScope limits the "api" checks to the objects of some namespaces,
+// e.g. the ones of a tenant. The zero value doesn't limit them.
+var namespaceScope struct {
+	names    []string
+	selector string
+}
+
+// clusterScopedKinds are the kinds of the cluster scoped objects reported as
+// violations, which are left out of the checks limited to namespaces.
+var clusterScopedKinds = map[string]bool{
+	"clusterrole":        true,
+	"clusterrolebinding": true,
+}
+
+// SetNamespaceScope limits the "api" checks to the objects of the given
+// namespaces and of the namespaces whose labels match the selector. When
+// both are given, the namespaces must be in the list and match the
+// selector. Violations of cluster scoped objects are then left out.
+func SetNamespaceScope(names []string, selector string) error {
+	if _, err := labels.Parse(selector); err != nil {
+		return fmt.Errorf("invalid namespace selector %q: %v", selector, err)
+	}
+	namespaceScope.names = names
+	namespaceScope.selector = selector
+	return nil
+}
+
+// scopedNamespaces returns the namespaces the "api" checks are limited to,
+// or nil when they are not.
+func scopedNamespaces(client kubernetes.Interface) (map[string]bool, error) {
+	if len(namespaceScope.names) == 0 && namespaceScope.selector == "" {
+		return nil, nil
+	}
+
+	scoped := map[string]bool{}
+	if namespaceScope.selector == "" {
+		for _, ns := range namespaceScope.names {
+			scoped[ns] = true
+		}
+		return scoped, nil
+	}
+
+	namespaces, err := client.CoreV1().Namespaces().List(metav1.ListOptions{LabelSelector: namespaceScope.selector})
+	if err != nil {
+		return nil, fmt.Errorf("failed to list the namespaces matching %q: %v", namespaceScope.selector, err)
+	}
+	for _, ns := range namespaces.Items {
+		if len(namespaceScope.names) == 0 || contains(namespaceScope.names, ns.Name) {
+			scoped[ns.Name] = true
+		}
+	}
+	return scoped, nil
+}
+
+// violationNamespace returns the namespace of the object a violation is
+// about, from its description: namespace/<namespace> for namespaces, and
+// <kind>/<namespace>/<name> for namespaced objects. Cluster scoped objects
+// have none.
+func violationNamespace(violation string) (string, bool) {
+	if i := strings.IndexByte(violation, ' '); i >= 0 {
+		violation = violation[:i]
+	}
+	object := strings.SplitN(violation, "/", 3)
+	switch {
+	case len(object) < 2 || clusterScopedKinds[object[0]]:
+		return "", false
+	case object[0] == "namespace":
+		return object[1], true
+	case len(object) == 3:
+		return object[1], true
+	}
+	return "", false
+}
+
+// inScope returns the violations about the objects of the scoped
+// namespaces.
+func inScope(violations []string, scoped map[string]bool) []string {
+	if scoped == nil {
+		return violations
+	}
+
+	var kept []string
+	for _, v := range violations {
+		if ns, ok := violationNamespace(v); ok && scoped[ns] {
+			kept = append(kept, v)
+		}
+	}
+	return kept
+}
@@ -0,0 +1,115 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	rbacv1 "k8s.io/api/rbac/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSetNamespaceScope(t *testing.T) {
+	defer SetNamespaceScope(nil, "")
+
+	assert.NoError(t, SetNamespaceScope([]string{"team-a"}, "tenant in (a, b)"))
+	assert.Error(t, SetNamespaceScope(nil, "tenant in a"))
+}
+
+func TestViolationNamespace(t *testing.T) {
+	cases := []struct {
+		violation  string
+		namespace  string
+		namespaced bool
+	}{
+		{violation: "pod/team-a/web container nginx is privileged", namespace: "team-a", namespaced: true},
+		{violation: "namespace/team-a has no network policies", namespace: "team-a", namespaced: true},
+		{violation: "rolebinding/team-a/admins binds ClusterRole/admin to system:anonymous", namespace: "team-a", namespaced: true},
+		{violation: "clusterrole/ops has a wildcard rule"},
+		{violation: "clusterrolebinding/ops binds cluster-admin to serviceaccount team-a/default"},
+		{violation: ""},
+	}
+	for _, c := range cases {
+		namespace, namespaced := violationNamespace(c.violation)
+		if namespace != c.namespace || namespaced != c.namespaced {
+			t.Errorf("%q: expected %q %t, got %q %t", c.violation, c.namespace, c.namespaced, namespace, namespaced)
+		}
+	}
+}
+
+func TestAuditAPINamespaceScope(t *testing.T) {
+	defer withKubeClient(t,
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a", Labels: map[string]string{"tenant": "a"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-a-staging", Labels: map[string]string{"tenant": "a"}}},
+		&corev1.Namespace{ObjectMeta: metav1.ObjectMeta{Name: "team-b", Labels: map[string]string{"tenant": "b"}}},
+		&rbacv1.ClusterRole{
+			ObjectMeta: metav1.ObjectMeta{Name: "ops"},
+			Rules:      []rbacv1.PolicyRule{{Verbs: []string{"*"}, Resources: []string{"*"}, APIGroups: []string{"*"}}},
+		},
+		&rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{Name: "deployer", Namespace: "team-a"},
+			Rules:      []rbacv1.PolicyRule{{Verbs: []string{"*"}, Resources: []string{"deployments"}, APIGroups: []string{"apps"}}},
+		},
+		&rbacv1.Role{
+			ObjectMeta: metav1.ObjectMeta{Name: "deployer", Namespace: "team-b"},
+			Rules:      []rbacv1.PolicyRule{{Verbs: []string{"*"}, Resources: []string{"deployments"}, APIGroups: []string{"apps"}}},
+		},
+	)()
+	defer SetNamespaceScope(nil, "")
+
+	cases := []struct {
+		name       string
+		namespaces []string
+		selector   string
+		audit      string
+		expected   string
+	}{
+		{
+			name:     "not scoped",
+			audit:    "wildcard-rules",
+			expected: `{"count":3,"violations":["clusterrole/ops has a wildcard rule","role/team-a/deployer has a wildcard rule","role/team-b/deployer has a wildcard rule"]}`,
+		},
+		{
+			name:       "namespaces",
+			namespaces: []string{"team-b"},
+			audit:      "wildcard-rules",
+			expected:   `{"count":1,"violations":["role/team-b/deployer has a wildcard rule"]}`,
+		},
+		{
+			name:     "selector",
+			selector: "tenant=a",
+			audit:    "namespaces-without-network-policies",
+			expected: `{"count":2,"violations":["namespace/team-a has no network policies","namespace/team-a-staging has no network policies"]}`,
+		},
+		{
+			name:       "namespaces and selector",
+			namespaces: []string{"team-a", "team-b"},
+			selector:   "tenant=a",
+			audit:      "namespaces-without-network-policies",
+			expected:   `{"count":1,"violations":["namespace/team-a has no network policies"]}`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			assert.NoError(t, SetNamespaceScope(c.namespaces, c.selector))
+			out, err := auditAPI(&Check{Type: API, Audit: c.audit})
+			assert.NoError(t, err)
+			assert.Equal(t, c.expected, out)
+		})
+	}
+}
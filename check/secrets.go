@@ -0,0 +1,87 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"strconv"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// defaultTokenMaxAgeDays is used when a check doesn't set the "max_age_days" audit option.
+const defaultTokenMaxAgeDays = 90
+
+// secretsInEnvironment finds containers which read a secret into an
+// environment variable.
+func secretsInEnvironment(client kubernetes.Interface, opts map[string]string) ([]string, error) {
+	pods, err := listPods(client, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []string
+	for _, p := range pods {
+		for _, c := range allContainers(p.Spec) {
+			for _, env := range c.Env {
+				if env.ValueFrom != nil && env.ValueFrom.SecretKeyRef != nil {
+					violations = append(violations, fmt.Sprintf("pod/%s/%s container %s reads secret %s into $%s",
+						p.Namespace, p.Name, c.Name, env.ValueFrom.SecretKeyRef.Name, env.Name))
+				}
+			}
+			for _, envFrom := range c.EnvFrom {
+				if envFrom.SecretRef != nil {
+					violations = append(violations, fmt.Sprintf("pod/%s/%s container %s reads secret %s into its environment",
+						p.Namespace, p.Name, c.Name, envFrom.SecretRef.Name))
+				}
+			}
+		}
+	}
+	return violations, nil
+}
+
+// oldServiceAccountTokens finds service account token secrets older than
+// the number of days in the "max_age_days" audit option.
+func oldServiceAccountTokens(client kubernetes.Interface, opts map[string]string) ([]string, error) {
+	maxAge := defaultTokenMaxAgeDays
+	if v, ok := opts["max_age_days"]; ok {
+		var err error
+		if maxAge, err = strconv.Atoi(v); err != nil {
+			return nil, fmt.Errorf("invalid max_age_days %q: %v", v, err)
+		}
+	}
+	exclude := optionList(opts, "exclude")
+
+	secrets, err := client.CoreV1().Secrets(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []string
+	for _, s := range secrets.Items {
+		if s.Type != corev1.SecretTypeServiceAccountToken || contains(exclude, s.Namespace) {
+			continue
+		}
+		age := int(time.Since(s.CreationTimestamp.Time).Hours() / 24)
+		if age > maxAge {
+			violations = append(violations, fmt.Sprintf("secret/%s/%s of serviceaccount %s is %d days old",
+				s.Namespace, s.Name, s.Annotations[corev1.ServiceAccountNameKey], age))
+		}
+	}
+	return violations, nil
+}
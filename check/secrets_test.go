@@ -0,0 +1,103 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestSecretsQueries(t *testing.T) {
+	defer withKubeClient(t,
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "app"},
+			Spec: corev1.PodSpec{
+				Containers: []corev1.Container{{
+					Name: "nginx",
+					Env: []corev1.EnvVar{
+						{Name: "MODE", Value: "production"},
+						{Name: "DB_PASSWORD", ValueFrom: &corev1.EnvVarSource{SecretKeyRef: &corev1.SecretKeySelector{
+							LocalObjectReference: corev1.LocalObjectReference{Name: "db"}, Key: "password",
+						}}},
+					},
+					EnvFrom: []corev1.EnvFromSource{{SecretRef: &corev1.SecretEnvSource{LocalObjectReference: corev1.LocalObjectReference{Name: "api-keys"}}}},
+				}},
+			},
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "default-token-abcde",
+				Namespace:         "app",
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-200 * 24 * time.Hour)),
+				Annotations:       map[string]string{corev1.ServiceAccountNameKey: "default"},
+			},
+			Type: corev1.SecretTypeServiceAccountToken,
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "web-token-fghij",
+				Namespace:         "app",
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-10 * 24 * time.Hour)),
+				Annotations:       map[string]string{corev1.ServiceAccountNameKey: "web"},
+			},
+			Type: corev1.SecretTypeServiceAccountToken,
+		},
+		&corev1.Secret{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:              "db",
+				Namespace:         "app",
+				CreationTimestamp: metav1.NewTime(time.Now().Add(-200 * 24 * time.Hour)),
+			},
+			Type: corev1.SecretTypeOpaque,
+		},
+	)()
+
+	cases := []struct {
+		audit    string
+		opts     map[string]string
+		expected string
+	}{
+		{
+			audit: "secrets-in-environment",
+			expected: `{"count":2,"violations":["pod/app/web container nginx reads secret api-keys into its environment",` +
+				`"pod/app/web container nginx reads secret db into $DB_PASSWORD"]}`,
+		},
+		{
+			audit:    "old-service-account-tokens",
+			expected: `{"count":1,"violations":["secret/app/default-token-abcde of serviceaccount default is 200 days old"]}`,
+		},
+		{
+			audit: "old-service-account-tokens",
+			opts:  map[string]string{"max_age_days": "7"},
+			expected: `{"count":2,"violations":["secret/app/default-token-abcde of serviceaccount default is 200 days old",` +
+				`"secret/app/web-token-fghij of serviceaccount web is 10 days old"]}`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.audit, func(t *testing.T) {
+			out, err := auditAPI(&Check{Type: API, Audit: c.audit, AuditOptions: c.opts})
+			assert.NoError(t, err)
+			assert.Equal(t, c.expected, out)
+		})
+	}
+
+	_, err := auditAPI(&Check{Type: API, Audit: "old-service-account-tokens", AuditOptions: map[string]string{"max_age_days": "ninety"}})
+	assert.Error(t, err)
+}
@@ -0,0 +1,94 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+// The files of the kernel telling about its security modules, replaced in
+// tests.
+var (
+	selinuxEnforceFile   = "/sys/fs/selinux/enforce"
+	apparmorEnabledFile  = "/sys/module/apparmor/parameters/enabled"
+	apparmorProfilesFile = "/sys/kernel/security/apparmor/profiles"
+)
+
+// auditSecurityModules returns the state of the SELinux and AppArmor
+// security modules of the host, read from the kernel rather than with
+// commands that may not be installed:
+//
+//	selinux=enforcing
+//	apparmor=enabled
+//	apparmor_profiles=docker-default,cri-containerd.apparmor.d
+//
+// selinux is enforcing, permissive or disabled, and apparmor enabled or
+// disabled. The loaded AppArmor profiles can only be listed as root. When
+// the audit field names a process, e.g. $kubeletbin, the security context
+// it runs in is given too, e.g. context=system_u:system_r:kubelet_t:s0.
+func auditSecurityModules(c *Check) (string, error) {
+	var out strings.Builder
+
+	selinux := "disabled"
+	if enforce, err := ioutil.ReadFile(selinuxEnforceFile); err == nil {
+		selinux = "permissive"
+		if strings.TrimSpace(string(enforce)) == "1" {
+			selinux = "enforcing"
+		}
+	}
+	fmt.Fprintf(&out, "selinux=%s\n", selinux)
+
+	apparmor := "disabled"
+	if enabled, err := ioutil.ReadFile(apparmorEnabledFile); err == nil && strings.TrimSpace(string(enabled)) == "Y" {
+		apparmor = "enabled"
+	}
+	fmt.Fprintf(&out, "apparmor=%s\n", apparmor)
+
+	if profiles, err := ioutil.ReadFile(apparmorProfilesFile); err == nil {
+		var names []string
+		for _, line := range strings.Split(strings.TrimSpace(string(profiles)), "\n") {
+			// Profiles are listed as "name (mode)".
+			if i := strings.LastIndex(line, " ("); i > 0 {
+				names = append(names, line[:i])
+			}
+		}
+		fmt.Fprintf(&out, "apparmor_profiles=%s\n", strings.Join(names, ","))
+	} else {
+		glog.V(2).Infof("Check.ID: %s can't list the AppArmor profiles: %v", c.ID, err)
+	}
+
+	if name := strings.TrimSpace(c.Audit); name != "" {
+		procs, err := procProcesses(name)
+		if err != nil {
+			return "", err
+		}
+		if len(procs) == 0 {
+			return "", fmt.Errorf("%s is not running", name)
+		}
+		context, err := ioutil.ReadFile(filepath.Join(procRoot, strconv.Itoa(procs[0].pid), "attr", "current"))
+		if err != nil {
+			return "", fmt.Errorf("failed to read the security context of %s: %v", name, err)
+		}
+		fmt.Fprintf(&out, "context=%s\n", strings.TrimRight(string(context), "\x00\n"))
+	}
+
+	return out.String(), nil
+}
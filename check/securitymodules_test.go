@@ -0,0 +1,82 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAuditSecurityModules(t *testing.T) {
+	defer withProcesses(t, map[string][]string{"812": {"/usr/bin/kubelet", "--anonymous-auth=false"}})()
+	if err := os.MkdirAll(filepath.Join(procRoot, "812", "attr"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(procRoot, "812", "attr", "current"), []byte("system_u:system_r:kubelet_t:s0\x00"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := ioutil.TempDir("", "kube-bench-lsm")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(enforce, enabled, profiles string) {
+		selinuxEnforceFile, apparmorEnabledFile, apparmorProfilesFile = enforce, enabled, profiles
+	}(selinuxEnforceFile, apparmorEnabledFile, apparmorProfilesFile)
+	selinuxEnforceFile = filepath.Join(dir, "enforce")
+	apparmorEnabledFile = filepath.Join(dir, "enabled")
+	apparmorProfilesFile = filepath.Join(dir, "profiles")
+
+	write := func(file, content string) {
+		if err := ioutil.WriteFile(file, []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Neither module is there.
+	out, err := auditSecurityModules(&Check{})
+	if err != nil || out != "selinux=disabled\napparmor=disabled\n" {
+		t.Errorf("unexpected output %q, %v", out, err)
+	}
+
+	write(selinuxEnforceFile, "0")
+	write(apparmorEnabledFile, "Y\n")
+	write(apparmorProfilesFile, "docker-default (enforce)\n/usr/sbin/ntpd (enforce)\ncri-containerd.apparmor.d (enforce)\n")
+	out, err = auditSecurityModules(&Check{})
+	expected := "selinux=permissive\napparmor=enabled\napparmor_profiles=docker-default,/usr/sbin/ntpd,cri-containerd.apparmor.d\n"
+	if err != nil || out != expected {
+		t.Errorf("expected %q, got %q, %v", expected, out, err)
+	}
+
+	write(selinuxEnforceFile, "1")
+	check := &Check{ID: "H.1.1", Type: SECURITYMODULES, Audit: "kubelet", Scored: true, Tests: &tests{
+		BinOp: and,
+		TestItems: []*testItem{
+			{Flag: "selinux", Set: true, Compare: compare{Op: "eq", Value: "enforcing"}},
+			{Flag: "context", Set: true, Compare: compare{Op: "has", Value: "kubelet_t"}},
+		},
+	}}
+	if state := check.run(); state != PASS {
+		t.Errorf("expected PASS, got %s (%s)", state, check.Reason)
+	}
+
+	check = &Check{ID: "H.1.2", Type: SECURITYMODULES, Audit: "containerd", Scored: true, Tests: &tests{}}
+	if state := check.run(); state != WARN {
+		t.Errorf("expected WARN for a process that isn't running, got %s", state)
+	}
+}
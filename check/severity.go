@@ -0,0 +1,45 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import "fmt"
+
+// Severities of checks, from the most severe.
+const (
+	SeverityCritical = "critical"
+	SeverityHigh     = "high"
+	SeverityMedium   = "medium"
+	SeverityLow      = "low"
+)
+
+// CheckSeverity returns the severity of a check. Checks without a severity
+// are high when scored, medium otherwise.
+func (c *Check) CheckSeverity() string {
+	if c.Severity != "" {
+		return c.Severity
+	}
+	if c.Scored {
+		return SeverityHigh
+	}
+	return SeverityMedium
+}
+
+func (c *Check) validateSeverity() error {
+	switch c.Severity {
+	case "", SeverityCritical, SeverityHigh, SeverityMedium, SeverityLow:
+		return nil
+	}
+	return fmt.Errorf("check %s: unknown severity %q", c.ID, c.Severity)
+}
@@ -0,0 +1,42 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCheckSeverity(t *testing.T) {
+	cases := []struct {
+		check    *Check
+		expected string
+	}{
+		{&Check{Severity: SeverityCritical, Scored: true}, SeverityCritical},
+		{&Check{Severity: SeverityLow}, SeverityLow},
+		{&Check{Scored: true}, SeverityHigh},
+		{&Check{}, SeverityMedium},
+	}
+	for i, c := range cases {
+		if got := c.check.CheckSeverity(); got != c.expected {
+			t.Errorf("case %d: expected %s, got %s", i, c.expected, got)
+		}
+	}
+
+	in := []byte("controls:\ntype: node\ngroups:\n- id: 4.2\n  checks:\n  - id: 4.2.1\n    severity: severe\n")
+	if _, err := NewControls(NODE, in); err == nil || !strings.Contains(err.Error(), `check 4.2.1: unknown severity "severe"`) {
+		t.Errorf("expected an error for an unknown severity, got %v", err)
+	}
+}
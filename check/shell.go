@@ -0,0 +1,220 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"os/exec"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// auditShell is the shell running the scripts of audits, given as
+// "/bin/sh -c '<script>'" in controls files.
+var auditShell = []string{"/bin/sh"}
+
+var (
+	commandsMu sync.Mutex
+	// availableCommands caches whether the commands audits run are
+	// available.
+	availableCommands = map[string]bool{}
+)
+
+// shellKeywords are the reserved words of the shell that may start a
+// command of a script.
+var shellKeywords = map[string]bool{
+	"if": true, "then": true, "else": true, "elif": true, "fi": true,
+	"while": true, "until": true, "do": true, "done": true,
+	"!": true, "{": true, "}": true, "esac": true,
+}
+
+var (
+	assignmentRe  = regexp.MustCompile(`^\w+=`)
+	commandNameRe = regexp.MustCompile(`^([A-Za-z_./][\w./+-]*|\[)$`)
+)
+
+// SetAuditShell selects the shell running the scripts of audits, e.g.
+// /bin/bash or "busybox sh".
+func SetAuditShell(shell string) error {
+	args := strings.Fields(shell)
+	if len(args) == 0 {
+		return fmt.Errorf("no shell given")
+	}
+	if _, err := exec.LookPath(args[0]); err != nil {
+		return fmt.Errorf("shell %s not found: %v", args[0], err)
+	}
+	auditShell = args
+	return nil
+}
+
+// shellArgs runs the scripts of audits with the audit shell.
+func shellArgs(args []string) []string {
+	if len(args) < 2 || (args[0] != "/bin/sh" && args[0] != "sh") || args[1] != "-c" {
+		return args
+	}
+	return append(append([]string{}, auditShell...), args[1:]...)
+}
+
+// commandAvailable reports whether the audit shell finds a command.
+func commandAvailable(name string) bool {
+	commandsMu.Lock()
+	defer commandsMu.Unlock()
+	if available, ok := availableCommands[name]; ok {
+		return available
+	}
+
+	args := append(append([]string{}, auditShell[1:]...), "-c", "command -v "+name)
+	out, err := exec.Command(auditShell[0], args...).Output()
+	available := err == nil && strings.Contains(string(out), name)
+	availableCommands[name] = available
+	return available
+}
+
+// auditCommands returns the names of the commands an audit runs, including
+// the ones run by its shell scripts.
+func auditCommands(audit string) []string {
+	if strings.TrimSpace(audit) == "" {
+		return nil
+	}
+
+	var names []string
+	for _, cmd := range textToCommand(audit) {
+		args := cmd.Args
+		if len(args) == 0 || args[0] == "" {
+			continue
+		}
+		names = append(names, args[0])
+		for i := 1; i < len(args)-1; i++ {
+			if args[i] == "-c" {
+				names = append(names, scriptCommands(args[i+1])...)
+				break
+			}
+		}
+	}
+	return names
+}
+
+// scriptCommands returns the names of the commands a shell script runs.
+// Commands run within double quotes, e.g. "$(cat file)", are not found.
+func scriptCommands(script string) []string {
+	var names []string
+	for _, segment := range splitScript(script) {
+		words := strings.Fields(segment)
+		if len(words) > 0 && (words[0] == "for" || words[0] == "case") {
+			continue
+		}
+		for len(words) > 0 && (shellKeywords[words[0]] || assignmentRe.MatchString(words[0])) {
+			words = words[1:]
+		}
+		if len(words) > 0 && commandNameRe.MatchString(words[0]) {
+			names = append(names, words[0])
+		}
+	}
+	return names
+}
+
+// splitScript splits a shell script into the commands it runs, at the
+// separators and substitutions that are not quoted. What follows the end of
+// a substitution is not a command, but the rest of the arguments of the
+// command the substitution is in.
+func splitScript(script string) []string {
+	var segments []string
+	var current strings.Builder
+	command := true
+	var quote rune
+	inBackquotes := false
+	split := func(next bool) {
+		if command {
+			segments = append(segments, current.String())
+		}
+		current.Reset()
+		command = next
+	}
+	for _, r := range script {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			}
+		case r == '\'' || r == '"':
+			quote = r
+		case r == '`':
+			inBackquotes = !inBackquotes
+			split(inBackquotes)
+			continue
+		case r == ')':
+			split(false)
+			continue
+		case strings.ContainsRune(";|&\n(", r):
+			split(true)
+			continue
+		}
+		current.WriteRune(r)
+	}
+	split(false)
+	return segments
+}
+
+// missingDependencies returns the commands an audit runs that are not
+// available, sorted.
+func missingDependencies(audit string) []string {
+	// ps audits are answered by the process backend.
+	if _, ok := psAuditProcess(audit); ok {
+		return nil
+	}
+	seen := map[string]bool{}
+	var missing []string
+	for _, name := range auditCommands(audit) {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		if !commandAvailable(name) {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
+// MissingDependencies returns the commands run by the audits of the checks
+// selected by the filter that are not available, with the IDs of the checks
+// running them.
+func (controls *Controls) MissingDependencies(filter Predicate) map[string][]string {
+	missing := map[string][]string{}
+	for _, g := range controls.Groups {
+		for _, c := range g.Checks {
+			if !filter(g, c) || c.Type == MANUAL || c.Type == "skip" || c.Unavailable != "" || c.Ignored != "" {
+				continue
+			}
+			if _, ok := auditors[c.Type]; ok {
+				continue
+			}
+
+			seen := map[string]bool{}
+			for _, audit := range []string{c.Audit, c.AuditConfig} {
+				for _, name := range missingDependencies(audit) {
+					if !seen[name] {
+						seen[name] = true
+						missing[name] = append(missing[name], c.ID)
+					}
+				}
+			}
+		}
+	}
+	return missing
+}
@@ -0,0 +1,107 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestAuditCommands(t *testing.T) {
+	cases := []struct {
+		audit    string
+		expected []string
+	}{
+		{"/bin/ps -ef | grep kube-apiserver | grep -v grep", []string{"/bin/ps", "grep", "grep"}},
+		{
+			"/bin/sh -c 'if test -e /etc/kubernetes/admin.conf; then stat -c permissions=%a /etc/kubernetes/admin.conf; fi'",
+			[]string{"/bin/sh", "test", "stat"},
+		},
+		{
+			"/bin/sh -c 'KUBECONFIG=/etc/kubernetes/admin.conf kubectl get nodes -o json 2>&1 && jq .items[0]; echo none'",
+			[]string{"/bin/sh", "kubectl", "jq", "echo"},
+		},
+		{
+			"/bin/sh -c 'for f in $(find /etc/kubernetes/pki -name *.key); do openssl rsa -in $f -noout; done'",
+			[]string{"/bin/sh", "find", "openssl"},
+		},
+		{
+			"/bin/sh -c 'if [ $(wc -l < /etc/passwd) -ge 2 ]; then echo `date +%s` ok; fi'",
+			[]string{"/bin/sh", "[", "wc", "echo", "date"},
+		},
+		{"", nil},
+	}
+	for _, c := range cases {
+		if got := auditCommands(c.audit); !reflect.DeepEqual(got, c.expected) {
+			t.Errorf("%s: expected %v, got %v", c.audit, c.expected, got)
+		}
+	}
+}
+
+func TestAuditShell(t *testing.T) {
+	defer func(shell []string) { auditShell = shell }(auditShell)
+
+	if err := SetAuditShell("/nonexistent/bash"); err == nil {
+		t.Errorf("expected an error for a missing shell")
+	}
+	if err := SetAuditShell("sh -e"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	cmds := textToCommand("/bin/sh -c 'stat -c %a /etc/kubernetes/admin.conf'")
+	if expected := []string{"sh", "-e", "-c", "stat -c %a /etc/kubernetes/admin.conf"}; !reflect.DeepEqual(cmds[0].Args, expected) {
+		t.Errorf("expected %v, got %v", expected, cmds[0].Args)
+	}
+	// Other commands are left alone.
+	if cmds := textToCommand("/bin/bash -c 'echo ok'"); cmds[0].Args[0] != "/bin/bash" {
+		t.Errorf("unexpected command %v", cmds[0].Args)
+	}
+}
+
+func TestCheckMissingDependencies(t *testing.T) {
+	defer withPrivileges(processPrivileges{root: true, hostPID: true})()
+
+	audit := "/bin/sh -c 'kube-bench-missing-tool --version && kube-bench-other-tool; echo done'"
+	check := &Check{ID: "1.1.1", Audit: audit, Commands: textToCommand(audit), Tests: &tests{}, Scored: true}
+	if state := check.run(); state != WARN {
+		t.Errorf("expected WARN, got %s", state)
+	}
+	if check.Reason != "missing dependency: kube-bench-missing-tool, kube-bench-other-tool" {
+		t.Errorf("unexpected reason %q", check.Reason)
+	}
+	if len(check.Errors) != 1 || check.Errors[0].Kind != MissingDependencyError {
+		t.Errorf("unexpected errors %v", check.Errors)
+	}
+}
+
+func TestControlsMissingDependencies(t *testing.T) {
+	controls := &Controls{Groups: []*Group{{ID: "1.1", Checks: []*Check{
+		{ID: "1.1.1", Audit: "/bin/sh -c 'kube-bench-missing-tool -a'", AuditConfig: "kube-bench-missing-tool -b"},
+		{ID: "1.1.2", Audit: "echo ok", AuditConfig: "/bin/sh -c 'kube-bench-other-tool; kube-bench-missing-tool'"},
+		// Checks that don't run their audit as a command are left out.
+		{ID: "1.1.3", Type: MANUAL, Audit: "kube-bench-missing-tool"},
+		{ID: "1.1.4", Type: FILE, Audit: "kube-bench-missing-tool"},
+		{ID: "1.1.5", Audit: "kube-bench-excluded-tool"},
+	}}}}
+
+	missing := controls.MissingDependencies(func(g *Group, c *Check) bool { return c.ID != "1.1.5" })
+	expected := map[string][]string{
+		"kube-bench-missing-tool": {"1.1.1", "1.1.2"},
+		"kube-bench-other-tool":   {"1.1.2"},
+	}
+	if !reflect.DeepEqual(missing, expected) {
+		t.Errorf("expected %v, got %v", expected, missing)
+	}
+}
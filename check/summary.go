@@ -0,0 +1,133 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"encoding/json"
+	"time"
+)
+
+// Score is the percentage of passing checks, out of the checks that passed,
+// failed or need attention.
+func (s Summary) Score() float64 {
+	total := s.Pass + s.Fail + s.Warn
+	if total == 0 {
+		return 0
+	}
+	return 100 * float64(s.Pass) / float64(total)
+}
+
+// RunSummary sums up a run of the checks of one or more targets: their
+// totals, score and duration, without the results of the checks, for CI
+// gates and dashboards.
+type RunSummary struct {
+	// RunID identifies the run.
+	RunID string  `json:"run_id,omitempty"`
+	Pass  int     `json:"total_pass"`
+	Fail  int     `json:"total_fail"`
+	Warn  int     `json:"total_warn"`
+	Info  int     `json:"total_info"`
+	Skip  int     `json:"total_skip"`
+	Score float64 `json:"score"`
+	// StartTime and EndTime are when the first target started and the last
+	// one finished running.
+	StartTime time.Time `json:"-"`
+	EndTime   time.Time `json:"-"`
+	Duration  float64   `json:"duration_seconds"`
+	// ExpiresAt is when the results of the first target to expire are stale.
+	ExpiresAt *time.Time `json:"expires_at,omitempty"`
+	// Metadata describes the node the checks were run on.
+	Metadata *NodeMetadata   `json:"metadata,omitempty"`
+	Targets  []TargetSummary `json:"targets"`
+}
+
+// TargetSummary sums up the results of the checks of a target.
+type TargetSummary struct {
+	ID       string   `json:"id"`
+	Text     string   `json:"text"`
+	Type     NodeType `json:"node_type"`
+	Pass     int      `json:"total_pass"`
+	Fail     int      `json:"total_fail"`
+	Warn     int      `json:"total_warn"`
+	Info     int      `json:"total_info"`
+	Skip     int      `json:"total_skip"`
+	Score    float64  `json:"score"`
+	Duration float64  `json:"duration_seconds"`
+}
+
+// NewRunSummary sums up the results of the targets of a run.
+func NewRunSummary(results []*Controls) *RunSummary {
+	s := &RunSummary{Targets: []TargetSummary{}}
+	var totals Summary
+	for _, controls := range results {
+		totals.Pass += controls.Pass
+		totals.Fail += controls.Fail
+		totals.Warn += controls.Warn
+		totals.Info += controls.Info
+		totals.Skip += controls.Skip
+		if !controls.StartTime.IsZero() && (s.StartTime.IsZero() || controls.StartTime.Before(s.StartTime)) {
+			s.StartTime = controls.StartTime
+		}
+		if controls.EndTime.After(s.EndTime) {
+			s.EndTime = controls.EndTime
+		}
+		if s.Metadata == nil {
+			s.Metadata = controls.Metadata
+		}
+		if s.RunID == "" {
+			s.RunID = controls.RunID
+		}
+		if controls.ExpiresAt != nil && (s.ExpiresAt == nil || controls.ExpiresAt.Before(*s.ExpiresAt)) {
+			s.ExpiresAt = controls.ExpiresAt
+		}
+
+		s.Targets = append(s.Targets, TargetSummary{
+			ID:       controls.ID,
+			Text:     controls.Text,
+			Type:     controls.Type,
+			Pass:     controls.Pass,
+			Fail:     controls.Fail,
+			Warn:     controls.Warn,
+			Info:     controls.Info,
+			Skip:     controls.Skip,
+			Score:    controls.Summary.Score(),
+			Duration: duration(controls.StartTime, controls.EndTime),
+		})
+	}
+
+	s.Pass, s.Fail, s.Warn, s.Info, s.Skip = totals.Pass, totals.Fail, totals.Warn, totals.Info, totals.Skip
+	s.Score = totals.Score()
+	s.Duration = duration(s.StartTime, s.EndTime)
+	return s
+}
+
+// JSON encodes the summary, with its timestamps in the configured timezone
+// and format.
+func (s *RunSummary) JSON() ([]byte, error) {
+	type summary RunSummary
+	return json.Marshal(struct {
+		*summary
+		StartTime json.RawMessage `json:"start_time"`
+		EndTime   json.RawMessage `json:"end_time"`
+		ExpiresAt json.RawMessage `json:"expires_at,omitempty"`
+	}{(*summary)(s), formatTime(s.StartTime), formatTime(s.EndTime), formatOptionalTime(s.ExpiresAt)})
+}
+
+func duration(start, end time.Time) float64 {
+	if start.IsZero() || end.Before(start) {
+		return 0
+	}
+	return end.Sub(start).Seconds()
+}
@@ -0,0 +1,97 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestSummaryScore(t *testing.T) {
+	cases := []struct {
+		summary  Summary
+		expected float64
+	}{
+		{summary: Summary{}, expected: 0},
+		{summary: Summary{Pass: 3, Fail: 1}, expected: 75},
+		{summary: Summary{Pass: 1, Warn: 1, Info: 5, Skip: 2}, expected: 50},
+	}
+	for _, c := range cases {
+		if score := c.summary.Score(); score != c.expected {
+			t.Errorf("%+v: expected %v, got %v", c.summary, c.expected, score)
+		}
+	}
+}
+
+func TestNewRunSummary(t *testing.T) {
+	start := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	metadata := &NodeMetadata{NodeName: "node-1"}
+	expires := start.Add(time.Hour)
+	results := []*Controls{
+		{ID: "1", Text: "Master Node Security Configuration", Type: MASTER, Metadata: metadata, RunID: "run-1", Summary: Summary{
+			Pass: 6, Fail: 2, Warn: 2, StartTime: start, EndTime: start.Add(3 * time.Second),
+		}},
+		{ID: "4", Text: "Worker Node Security Configuration", Type: NODE, Metadata: metadata, RunID: "run-1", ExpiresAt: &expires, Summary: Summary{
+			Pass: 4, Fail: 4, Info: 1, Skip: 1, StartTime: start.Add(4 * time.Second), EndTime: start.Add(5 * time.Second),
+		}},
+	}
+
+	s := NewRunSummary(results)
+	expected := &RunSummary{
+		Pass: 10, Fail: 6, Warn: 2, Info: 1, Skip: 1, Score: 100 * 10.0 / 18,
+		StartTime: start, EndTime: start.Add(5 * time.Second), Duration: 5,
+		Metadata: metadata, RunID: "run-1", ExpiresAt: &expires,
+		Targets: []TargetSummary{
+			{ID: "1", Text: "Master Node Security Configuration", Type: MASTER, Pass: 6, Fail: 2, Warn: 2, Score: 60, Duration: 3},
+			{ID: "4", Text: "Worker Node Security Configuration", Type: NODE, Pass: 4, Fail: 4, Info: 1, Skip: 1, Score: 50, Duration: 1},
+		},
+	}
+	if !reflect.DeepEqual(s, expected) {
+		t.Errorf("expected %+v, got %+v", expected, s)
+	}
+
+	defer func(loc *time.Location, format string) { timeLocation, timeFormat = loc, format }(timeLocation, timeFormat)
+	if err := SetTimeFormat("", TimeEpoch); err != nil {
+		t.Fatal(err)
+	}
+	out, err := s.JSON()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	var decoded map[string]interface{}
+	if err := json.Unmarshal(out, &decoded); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	for key, value := range map[string]interface{}{
+		"total_fail":       6.0,
+		"duration_seconds": 5.0,
+		"start_time":       1591012800.0,
+		"end_time":         1591012805.0,
+		"expires_at":       1591016400.0,
+	} {
+		if decoded[key] != value {
+			t.Errorf("%s: expected %v, got %v", key, value, decoded[key])
+		}
+	}
+	if _, ok := decoded["tests"]; ok {
+		t.Errorf("expected no results of checks in %s", out)
+	}
+
+	if out, err := NewRunSummary(nil).JSON(); err != nil || string(out) == "" {
+		t.Errorf("unexpected summary of no results %s: %v", out, err)
+	}
+}
@@ -0,0 +1,59 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/glog"
+)
+
+// sysctlRoot is where the kernel parameters are read from, replaced in tests.
+var sysctlRoot = "/proc/sys"
+
+// auditSysctl returns the values of the kernel parameters listed in the
+// audit field, separated by spaces or commas, read from /proc/sys rather
+// than with the sysctl command:
+//
+//	vm.overcommit_memory=1
+//	kernel.panic=10
+//
+// so that tests can compare them, e.g. numerically with gte. Values made of
+// several fields, like kernel.printk, are given with their fields separated
+// by commas.
+func auditSysctl(c *Check) (string, error) {
+	names := strings.FieldsFunc(c.Audit, func(r rune) bool { return r == ',' || r == ' ' || r == '\n' })
+	if len(names) == 0 {
+		return "", fmt.Errorf("missing kernel parameters")
+	}
+
+	var out strings.Builder
+	for _, name := range names {
+		if strings.Contains(name, "..") {
+			return "", fmt.Errorf("invalid kernel parameter %s", name)
+		}
+		file := filepath.Join(sysctlRoot, strings.Replace(name, ".", "/", -1))
+		glog.V(2).Infof("Check.ID: %s reading kernel parameter %s", c.ID, file)
+		value, err := ioutil.ReadFile(file)
+		if err != nil {
+			return "", fmt.Errorf("failed to read kernel parameter %s: %v", name, err)
+		}
+		fmt.Fprintf(&out, "%s=%s\n", name, strings.Join(strings.Fields(string(value)), ","))
+	}
+	return out.String(), nil
+}
@@ -0,0 +1,83 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestAuditSysctl(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-sysctl")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(root string) { sysctlRoot = root }(sysctlRoot)
+	sysctlRoot = dir
+
+	params := map[string]string{
+		"kernel/panic":         "10\n",
+		"kernel/panic_on_oops": "1\n",
+		"kernel/printk":        "4\t4\t1\t7\n",
+		"vm/overcommit_memory": "0\n",
+	}
+	for name, value := range params {
+		file := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(file, []byte(value), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out, err := auditSysctl(&Check{Audit: "kernel.panic, kernel.panic_on_oops kernel.printk"})
+	expected := "kernel.panic=10\nkernel.panic_on_oops=1\nkernel.printk=4,4,1,7\n"
+	if err != nil || out != expected {
+		t.Errorf("expected %q, got %q, %v", expected, out, err)
+	}
+
+	cases := []struct {
+		audit    string
+		tests    *tests
+		expected State
+	}{
+		{
+			"kernel.panic kernel.panic_on_oops",
+			&tests{BinOp: and, TestItems: []*testItem{
+				{Flag: "kernel.panic", Set: true, Compare: compare{Op: "gte", Value: "10"}},
+				{Flag: "kernel.panic_on_oops", Set: true, Compare: compare{Op: "eq", Value: "1"}},
+			}},
+			PASS,
+		},
+		{
+			"vm.overcommit_memory",
+			&tests{TestItems: []*testItem{{Flag: "vm.overcommit_memory", Set: true, Compare: compare{Op: "eq", Value: "1"}}}},
+			FAIL,
+		},
+		{"vm.panic_on_oom", &tests{}, WARN},
+		{"../../etc/passwd", &tests{}, WARN},
+		{"", &tests{}, WARN},
+	}
+	for _, c := range cases {
+		check := &Check{ID: "H.2.1", Type: SYSCTL, Audit: c.audit, Tests: c.tests, Scored: true}
+		if state := check.run(); state != c.expected {
+			t.Errorf("%q: expected %s, got %s (%s)", c.audit, c.expected, state, check.Reason)
+		}
+	}
+}
@@ -49,6 +49,10 @@ type testItem struct {
 	Value   string
 	Set     bool
 	Compare compare
+	// Rego is a rego query the output must satisfy, instead of the above.
+	Rego string
+	// CEL is a CEL expression the output must satisfy, instead of the above.
+	CEL string
 }
 
 type compare struct {
@@ -60,6 +64,8 @@ type testOutput struct {
 	testResult     bool
 	actualResult   string
 	ExpectedResult string
+	// truncated notes that the tests were run against truncated output.
+	truncated string
 }
 
 func failTestItem(s string) *testOutput {
@@ -67,6 +73,13 @@ func failTestItem(s string) *testOutput {
 }
 
 func (t *testItem) execute(s string) *testOutput {
+	if t.Rego != "" {
+		return t.executeRego(s)
+	}
+	if t.CEL != "" {
+		return t.executeCEL(s)
+	}
+
 	result := &testOutput{}
 	var match bool
 	var flagVal string
@@ -210,6 +223,18 @@ func compareOp(tCompareOp string, flagVal string, tCompareValue string) (string,
 		target := splitAndRemoveLastSeparator(tCompareValue, defaultArraySeparator)
 		testResult = allElementsValid(s, target)
 
+	case "has_elements":
+		expectedResultPattern = "'%s' has all elements of '%s'"
+		s := splitAndRemoveLastSeparator(flagVal, defaultArraySeparator)
+		target := splitAndRemoveLastSeparator(tCompareValue, defaultArraySeparator)
+		testResult = allElementsValid(target, s)
+
+	case "nothave_elements":
+		expectedResultPattern = "'%s' has no elements of '%s'"
+		s := splitAndRemoveLastSeparator(flagVal, defaultArraySeparator)
+		target := splitAndRemoveLastSeparator(tCompareValue, defaultArraySeparator)
+		testResult = noElementsValid(target, s)
+
 	case "bitmask":
 		expectedResultPattern = "bitmask '%s' AND '%s'"
 		requested, err := strconv.ParseInt(flagVal, 8, 64)
@@ -286,6 +311,18 @@ func allElementsValid(s, t []string) bool {
 	return true
 }
 
+// noElementsValid returns true if none of the elements of s are in t.
+func noElementsValid(s, t []string) bool {
+	for _, sv := range s {
+		for _, tv := range t {
+			if sv == tv {
+				return false
+			}
+		}
+	}
+	return true
+}
+
 func splitAndRemoveLastSeparator(s, sep string) []string {
 	cleanS := strings.TrimRight(strings.TrimSpace(s), sep)
 	if len(cleanS) == 0 {
@@ -371,3 +408,46 @@ func toNumeric(a, b string) (c, d int, err error) {
 
 	return c, d, nil
 }
+
+// selectOutput replaces the output of an audit, a JSON or YAML document, with
+// the value at a JSONPath, e.g. {.data.config\.conf} for the configuration
+// of kube-proxy in its ConfigMap. Strings are selected as they are, so that
+// the documents they hold can be tested; other values are selected as JSON.
+func selectOutput(out, path string) (string, error) {
+	if strings.TrimSpace(out) == "" {
+		return out, nil
+	}
+
+	var doc interface{}
+	if err := unmarshal(out, &doc); err != nil {
+		return "", fmt.Errorf("failed to load YAML or JSON: %v", err)
+	}
+	j := jsonpath.New("jsonpath")
+	j.AllowMissingKeys(true)
+	if err := j.Parse(path); err != nil {
+		return "", err
+	}
+	results, err := j.FindResults(convertYAMLMaps(doc))
+	if err != nil {
+		return "", err
+	}
+
+	var selected []string
+	for _, r := range results {
+		for _, v := range r {
+			if !v.IsValid() || !v.CanInterface() {
+				continue
+			}
+			if s, ok := v.Interface().(string); ok {
+				selected = append(selected, s)
+				continue
+			}
+			data, err := json.Marshal(v.Interface())
+			if err != nil {
+				return "", err
+			}
+			selected = append(selected, string(data))
+		}
+	}
+	return strings.Join(selected, "\n"), nil
+}
@@ -666,6 +666,28 @@ func TestCompareOp(t *testing.T) {
 		{label: "op=valid_elements, valid_elements expectedResultPattern empty", op: "valid_elements", flagVal: "a,b",
 			compareValue: "", expectedResultPattern: "'a,b' contains valid elements from ''",
 			testResult: false},
+		// Test Op "has_elements"
+		{label: "op=has_elements, all elements present", op: "has_elements", flagVal: "NodeRestriction,PodSecurityPolicy",
+			compareValue: "PodSecurityPolicy", expectedResultPattern: "'NodeRestriction,PodSecurityPolicy' has all elements of 'PodSecurityPolicy'",
+			testResult: true},
+		{label: "op=has_elements, element is a substring only", op: "has_elements", flagVal: "NodeRestrictionX",
+			compareValue: "NodeRestriction", expectedResultPattern: "'NodeRestrictionX' has all elements of 'NodeRestriction'",
+			testResult: false},
+		{label: "op=has_elements, flagVal empty", op: "has_elements", flagVal: "",
+			compareValue: "NodeRestriction", expectedResultPattern: "'' has all elements of 'NodeRestriction'",
+			testResult: false},
+
+		// Test Op "nothave_elements"
+		{label: "op=nothave_elements, no elements present", op: "nothave_elements", flagVal: "NodeRestriction,PodSecurityPolicy",
+			compareValue: "AlwaysAdmit", expectedResultPattern: "'NodeRestriction,PodSecurityPolicy' has no elements of 'AlwaysAdmit'",
+			testResult: true},
+		{label: "op=nothave_elements, element present", op: "nothave_elements", flagVal: "AlwaysAdmit,NodeRestriction",
+			compareValue: "AlwaysAdmit", expectedResultPattern: "'AlwaysAdmit,NodeRestriction' has no elements of 'AlwaysAdmit'",
+			testResult: false},
+		{label: "op=nothave_elements, flagVal empty", op: "nothave_elements", flagVal: "",
+			compareValue: "AlwaysAdmit", expectedResultPattern: "'' has no elements of 'AlwaysAdmit'",
+			testResult: true},
+
 		// Test Op "bitmask"
 		{label: "op=bitmask, 644 AND 640", op: "bitmask", flagVal: "640",
 			compareValue: "644", expectedResultPattern: "bitmask '640' AND '644'",
@@ -728,3 +750,47 @@ func TestToNumeric(t *testing.T) {
 		}
 	}
 }
+
+func TestSelectOutput(t *testing.T) {
+	configMap := `apiVersion: v1
+kind: ConfigMap
+data:
+  config.conf: |-
+    kind: KubeProxyConfiguration
+    metricsBindAddress: 127.0.0.1:10249
+metadata:
+  name: kube-proxy
+  labels:
+    app: kube-proxy
+`
+	cases := []struct {
+		out      string
+		path     string
+		expected string
+		fail     bool
+	}{
+		// The document held by a string can be tested in turn.
+		{configMap, `{.data.config\.conf}`, "kind: KubeProxyConfiguration\nmetricsBindAddress: 127.0.0.1:10249", false},
+		{configMap, `{.metadata.labels}`, `{"app":"kube-proxy"}`, false},
+		{`{"items":[{"name":"a"},{"name":"b"}]}`, `{.items[*].name}`, "a\nb", false},
+		{configMap, `{.data.missing}`, "", false},
+		{"", `{.data}`, "", false},
+		{"not: [valid", `{.data}`, "", true},
+		{configMap, `{.data`, "", true},
+	}
+	for _, c := range cases {
+		got, err := selectOutput(c.out, c.path)
+		if c.fail {
+			if err == nil {
+				t.Errorf("%s: expected an error", c.path)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.path, err)
+		}
+		if got != c.expected {
+			t.Errorf("%s: expected %q, got %q", c.path, c.expected, got)
+		}
+	}
+}
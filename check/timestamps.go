@@ -0,0 +1,107 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// The formats of the timestamps of the JSON output.
+const (
+	// TimeRFC3339 formats timestamps as RFC 3339 strings, with nanoseconds.
+	TimeRFC3339 = "rfc3339"
+	// TimeEpoch formats timestamps as the number of seconds since the epoch.
+	TimeEpoch = "epoch"
+	// TimeEpochMillis formats timestamps as the number of milliseconds since
+	// the epoch.
+	TimeEpochMillis = "epoch-millis"
+)
+
+var (
+	timeLocation = time.UTC
+	timeFormat   = TimeRFC3339
+)
+
+// SetTimeFormat sets the timezone, e.g. Europe/Paris or Local, and the
+// format of the timestamps of the JSON output, so that they follow the
+// conventions of the systems the results are sent to.
+func SetTimeFormat(zone, format string) error {
+	loc := time.UTC
+	if zone != "" {
+		var err error
+		if loc, err = time.LoadLocation(zone); err != nil {
+			return fmt.Errorf("unknown timezone %q: %v", zone, err)
+		}
+	}
+
+	switch format {
+	case "":
+		format = TimeRFC3339
+	case TimeRFC3339, TimeEpoch, TimeEpochMillis:
+	default:
+		return fmt.Errorf("unknown time format %q, expected %s, %s or %s", format, TimeRFC3339, TimeEpoch, TimeEpochMillis)
+	}
+
+	timeLocation, timeFormat = loc, format
+	return nil
+}
+
+// formatTime returns the JSON encoding of a timestamp in the configured
+// timezone and format.
+func formatTime(t time.Time) json.RawMessage {
+	switch timeFormat {
+	case TimeEpoch:
+		return json.RawMessage(strconv.FormatInt(t.Unix(), 10))
+	case TimeEpochMillis:
+		return json.RawMessage(strconv.FormatInt(t.UnixNano()/int64(time.Millisecond), 10))
+	}
+	return json.RawMessage(strconv.Quote(t.In(timeLocation).Format(time.RFC3339Nano)))
+}
+
+// formatOptionalTime returns the JSON encoding of a timestamp that may not
+// be set, nil when it isn't.
+func formatOptionalTime(t *time.Time) json.RawMessage {
+	if t == nil {
+		return nil
+	}
+	return formatTime(*t)
+}
+
+// parseTime decodes a timestamp in any of the formats of the JSON output.
+// Epoch timestamps of more than 11 digits are in milliseconds.
+func parseTime(data json.RawMessage) (time.Time, error) {
+	s := strings.TrimSpace(string(data))
+	if s == "" || s == "null" {
+		return time.Time{}, nil
+	}
+	if strings.HasPrefix(s, `"`) {
+		var t time.Time
+		err := json.Unmarshal(data, &t)
+		return t, err
+	}
+
+	n, err := strconv.ParseInt(s, 10, 64)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid timestamp %s", s)
+	}
+	if len(strings.TrimPrefix(s, "-")) > 11 {
+		return time.Unix(0, n*int64(time.Millisecond)).UTC(), nil
+	}
+	return time.Unix(n, 0).UTC(), nil
+}
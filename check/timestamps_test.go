@@ -0,0 +1,71 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestSetTimeFormat(t *testing.T) {
+	defer func(loc *time.Location, format string) { timeLocation, timeFormat = loc, format }(timeLocation, timeFormat)
+
+	if err := SetTimeFormat("Mars/Olympus_Mons", ""); err == nil {
+		t.Errorf("expected an error for an unknown timezone")
+	}
+	if err := SetTimeFormat("", "unix"); err == nil {
+		t.Errorf("expected an error for an unknown format")
+	}
+
+	expires := time.Date(2020, 6, 2, 12, 0, 2, 0, time.UTC)
+	controls := &Controls{ID: "4", ExpiresAt: &expires, Summary: Summary{
+		StartTime: time.Date(2020, 6, 1, 12, 0, 0, 500000000, time.UTC),
+		EndTime:   time.Date(2020, 6, 1, 12, 0, 2, 0, time.UTC),
+	}}
+	cases := []struct {
+		zone, format string
+		expected     string
+	}{
+		{"", "", `"start_time":"2020-06-01T12:00:00.5Z","end_time":"2020-06-01T12:00:02Z"`},
+		{"Asia/Tokyo", TimeRFC3339, `"start_time":"2020-06-01T21:00:00.5+09:00","end_time":"2020-06-01T21:00:02+09:00"`},
+		{"", TimeEpoch, `"start_time":1591012800,"end_time":1591012802`},
+		{"", TimeEpochMillis, `"start_time":1591012800500,"end_time":1591012802000`},
+	}
+	for _, c := range cases {
+		if err := SetTimeFormat(c.zone, c.format); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		out, err := controls.JSON()
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if !strings.Contains(string(out), c.expected) {
+			t.Errorf("%s %s: expected %s in %s", c.zone, c.format, c.expected, out)
+		}
+
+		// The results can be read back whatever the format.
+		decoded := new(Controls)
+		if err := decoded.UnmarshalJSON(out); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if decoded.ID != "4" || !decoded.EndTime.Equal(controls.EndTime) {
+			t.Errorf("%s %s: unexpected results %s end time %v", c.zone, c.format, decoded.ID, decoded.EndTime)
+		}
+		if decoded.ExpiresAt == nil || !decoded.ExpiresAt.Equal(expires) {
+			t.Errorf("%s %s: unexpected expiry %v", c.zone, c.format, decoded.ExpiresAt)
+		}
+	}
+}
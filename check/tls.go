@@ -0,0 +1,156 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"crypto/ecdsa"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// tlsDialTimeout bounds every connection attempt made while probing an endpoint.
+var tlsDialTimeout = 5 * time.Second
+
+var tlsVersions = []struct {
+	version uint16
+	name    string
+}{
+	{tls.VersionTLS10, "TLS1.0"},
+	{tls.VersionTLS11, "TLS1.1"},
+	{tls.VersionTLS12, "TLS1.2"},
+	{tls.VersionTLS13, "TLS1.3"},
+}
+
+// tlsProbe is the output of a "tls" check. The fields are available to
+// the check's tests as paths, e.g. '{.key_size}'.
+type tlsProbe struct {
+	Endpoint         string `json:"endpoint"`
+	Version          string `json:"version"`
+	MinVersion       string `json:"min_version"`
+	AcceptedVersions string `json:"accepted_versions"`
+	CipherSuites     string `json:"cipher_suites"`
+	KeyType          string `json:"key_type"`
+	KeySize          int    `json:"key_size"`
+	NotAfter         string `json:"not_after"`
+	DaysUntilExpiry  int    `json:"days_until_expiry"`
+}
+
+// auditTLS connects to the endpoint given in the check's audit field
+// (host:port) and reports the negotiated TLS parameters and the serving
+// certificate.
+func auditTLS(c *Check) (string, error) {
+	endpoint := strings.TrimSpace(c.Audit)
+	if endpoint == "" {
+		return "", fmt.Errorf("missing TLS endpoint")
+	}
+
+	// We are inspecting the endpoint's certificate, not trusting it.
+	conn, err := dialTLS(endpoint, &tls.Config{InsecureSkipVerify: true, MinVersion: tls.VersionTLS10})
+	if err != nil {
+		return "", fmt.Errorf("failed to connect to %s: %w", endpoint, err)
+	}
+	state := conn.ConnectionState()
+	conn.Close()
+
+	if len(state.PeerCertificates) == 0 {
+		return "", fmt.Errorf("no certificate presented by %s", endpoint)
+	}
+	cert := state.PeerCertificates[0]
+
+	probe := &tlsProbe{
+		Endpoint:        endpoint,
+		Version:         tlsVersionName(state.Version),
+		NotAfter:        cert.NotAfter.UTC().Format(time.RFC3339),
+		DaysUntilExpiry: int(time.Until(cert.NotAfter).Hours() / 24),
+	}
+	probe.KeyType, probe.KeySize = publicKeySize(cert)
+
+	var accepted []string
+	for _, v := range tlsVersions {
+		if acceptsTLS(endpoint, &tls.Config{InsecureSkipVerify: true, MinVersion: v.version, MaxVersion: v.version}) {
+			if probe.MinVersion == "" {
+				probe.MinVersion = v.name
+			}
+			accepted = append(accepted, v.name)
+		}
+	}
+	probe.AcceptedVersions = strings.Join(accepted, ",")
+
+	// Cipher suites are only negotiable up to TLS 1.2.
+	var suites []string
+	for _, cs := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		if acceptsTLS(endpoint, &tls.Config{
+			InsecureSkipVerify: true,
+			MinVersion:         tls.VersionTLS10,
+			MaxVersion:         tls.VersionTLS12,
+			CipherSuites:       []uint16{cs.ID},
+		}) {
+			suites = append(suites, cs.Name)
+		}
+	}
+	probe.CipherSuites = strings.Join(suites, ",")
+
+	out, err := json.Marshal(probe)
+	if err != nil {
+		return "", err
+	}
+
+	glog.V(3).Infof("TLS probe of %s: %s", endpoint, out)
+	return string(out), nil
+}
+
+func dialTLS(endpoint string, conf *tls.Config) (*tls.Conn, error) {
+	return tls.DialWithDialer(&net.Dialer{Timeout: tlsDialTimeout}, "tcp", endpoint, conf)
+}
+
+func acceptsTLS(endpoint string, conf *tls.Config) bool {
+	conn, err := dialTLS(endpoint, conf)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+func tlsVersionName(version uint16) string {
+	for _, v := range tlsVersions {
+		if v.version == version {
+			return v.name
+		}
+	}
+	return fmt.Sprintf("0x%04x", version)
+}
+
+func publicKeySize(cert *x509.Certificate) (string, int) {
+	switch k := cert.PublicKey.(type) {
+	case *rsa.PublicKey:
+		return "RSA", k.N.BitLen()
+	case *ecdsa.PublicKey:
+		return "ECDSA", k.Curve.Params().BitSize
+	case ed25519.PublicKey:
+		return "Ed25519", 256
+	default:
+		return cert.PublicKeyAlgorithm.String(), 0
+	}
+}
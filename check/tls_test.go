@@ -0,0 +1,73 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"crypto/tls"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheck_RunTLS(t *testing.T) {
+	srv := httptest.NewUnstartedServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	srv.TLS = &tls.Config{MinVersion: tls.VersionTLS12}
+	srv.StartTLS()
+	defer srv.Close()
+	endpoint := strings.TrimPrefix(srv.URL, "https://")
+
+	cases := []struct {
+		name     string
+		audit    string
+		items    []*testItem
+		expected State
+	}{
+		{
+			name:     "minimum TLS version is enforced",
+			audit:    endpoint,
+			items:    []*testItem{{Path: "{.accepted_versions}", Set: true, Compare: compare{Op: "valid_elements", Value: "TLS1.2,TLS1.3"}}},
+			expected: PASS,
+		},
+		{
+			name:     "key size is large enough",
+			audit:    endpoint,
+			items:    []*testItem{{Path: "{.key_size}", Set: true, Compare: compare{Op: "gte", Value: "4096"}}},
+			expected: FAIL,
+		},
+		{
+			name:     "certificate is not about to expire",
+			audit:    endpoint,
+			items:    []*testItem{{Path: "{.days_until_expiry}", Set: true, Compare: compare{Op: "gt", Value: "30"}}},
+			expected: PASS,
+		},
+		{
+			name:     "unreachable endpoint",
+			audit:    "127.0.0.1:1",
+			items:    []*testItem{{Path: "{.key_size}", Set: true}},
+			expected: WARN,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			check := &Check{Type: TLS, Audit: c.audit, Scored: true, Tests: &tests{TestItems: c.items}}
+			check.run()
+			if check.State != c.expected {
+				t.Errorf("expected %s, actual %s (%s)", c.expected, check.State, check.Reason)
+			}
+		})
+	}
+}
@@ -0,0 +1,143 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"fmt"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// listPods returns the pods in all namespaces except those named in the
+// "exclude" audit option.
+func listPods(client kubernetes.Interface, opts map[string]string) ([]corev1.Pod, error) {
+	exclude := optionList(opts, "exclude")
+
+	pods, err := client.CoreV1().Pods(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+
+	var result []corev1.Pod
+	for _, p := range pods.Items {
+		if !contains(exclude, p.Namespace) {
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+func allContainers(spec corev1.PodSpec) []corev1.Container {
+	return append(append([]corev1.Container{}, spec.InitContainers...), spec.Containers...)
+}
+
+// privilegedContainers finds containers running in privileged mode.
+func privilegedContainers(client kubernetes.Interface, opts map[string]string) ([]string, error) {
+	pods, err := listPods(client, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []string
+	for _, p := range pods {
+		for _, c := range allContainers(p.Spec) {
+			if c.SecurityContext != nil && c.SecurityContext.Privileged != nil && *c.SecurityContext.Privileged {
+				violations = append(violations, fmt.Sprintf("pod/%s/%s container %s is privileged", p.Namespace, p.Name, c.Name))
+			}
+		}
+	}
+	return violations, nil
+}
+
+// hostPathVolumes finds pods mounting a path from the host.
+func hostPathVolumes(client kubernetes.Interface, opts map[string]string) ([]string, error) {
+	pods, err := listPods(client, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []string
+	for _, p := range pods {
+		for _, v := range p.Spec.Volumes {
+			if v.HostPath != nil {
+				violations = append(violations, fmt.Sprintf("pod/%s/%s mounts host path %s", p.Namespace, p.Name, v.HostPath.Path))
+			}
+		}
+	}
+	return violations, nil
+}
+
+// hostNamespaces finds pods sharing the host's network, PID or IPC namespace.
+func hostNamespaces(client kubernetes.Interface, opts map[string]string) ([]string, error) {
+	pods, err := listPods(client, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	var violations []string
+	for _, p := range pods {
+		for _, ns := range []struct {
+			name   string
+			shared bool
+		}{
+			{"network", p.Spec.HostNetwork},
+			{"PID", p.Spec.HostPID},
+			{"IPC", p.Spec.HostIPC},
+		} {
+			if ns.shared {
+				violations = append(violations, fmt.Sprintf("pod/%s/%s shares the host %s namespace", p.Namespace, p.Name, ns.name))
+			}
+		}
+	}
+	return violations, nil
+}
+
+// automountedServiceAccountTokens finds pods which have a service account
+// token mounted, either explicitly or because neither the pod nor its
+// service account disables it.
+func automountedServiceAccountTokens(client kubernetes.Interface, opts map[string]string) ([]string, error) {
+	pods, err := listPods(client, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	sas, err := client.CoreV1().ServiceAccounts(metav1.NamespaceAll).List(metav1.ListOptions{})
+	if err != nil {
+		return nil, err
+	}
+	saAutomount := make(map[string]*bool)
+	for _, sa := range sas.Items {
+		saAutomount[sa.Namespace+"/"+sa.Name] = sa.AutomountServiceAccountToken
+	}
+
+	var violations []string
+	for _, p := range pods {
+		sa := p.Spec.ServiceAccountName
+		if sa == "" {
+			sa = "default"
+		}
+
+		automount := p.Spec.AutomountServiceAccountToken
+		if automount == nil {
+			automount = saAutomount[p.Namespace+"/"+sa]
+		}
+		if automount == nil || *automount {
+			violations = append(violations, fmt.Sprintf("pod/%s/%s mounts the token of serviceaccount %s", p.Namespace, p.Name, sa))
+		}
+	}
+	return violations, nil
+}
@@ -0,0 +1,106 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package check
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func TestWorkloadQueries(t *testing.T) {
+	yes, no := true, false
+
+	defer withKubeClient(t,
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "kube-proxy", Namespace: "kube-system"},
+			Spec: corev1.PodSpec{
+				HostNetwork: true,
+				Containers:  []corev1.Container{{Name: "kube-proxy", SecurityContext: &corev1.SecurityContext{Privileged: &yes}}},
+				Volumes:     []corev1.Volume{{Name: "modules", VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/lib/modules"}}}},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "debug", Namespace: "app"},
+			Spec: corev1.PodSpec{
+				HostPID:        true,
+				InitContainers: []corev1.Container{{Name: "setup", SecurityContext: &corev1.SecurityContext{Privileged: &yes}}},
+				Containers:     []corev1.Container{{Name: "shell", SecurityContext: &corev1.SecurityContext{Privileged: &no}}},
+				Volumes:        []corev1.Volume{{Name: "root", VolumeSource: corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/"}}}},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "web", Namespace: "app"},
+			Spec: corev1.PodSpec{
+				ServiceAccountName: "web",
+				Containers:         []corev1.Container{{Name: "nginx"}},
+			},
+		},
+		&corev1.Pod{
+			ObjectMeta: metav1.ObjectMeta{Name: "api", Namespace: "app"},
+			Spec: corev1.PodSpec{
+				ServiceAccountName:           "web",
+				AutomountServiceAccountToken: &yes,
+				Containers:                   []corev1.Container{{Name: "api"}},
+			},
+		},
+		&corev1.ServiceAccount{
+			ObjectMeta:                   metav1.ObjectMeta{Name: "web", Namespace: "app"},
+			AutomountServiceAccountToken: &no,
+		},
+	)()
+
+	excludeSystem := map[string]string{"exclude": "kube-system"}
+
+	cases := []struct {
+		audit    string
+		opts     map[string]string
+		expected string
+	}{
+		{
+			audit:    "privileged-containers",
+			opts:     excludeSystem,
+			expected: `{"count":1,"violations":["pod/app/debug container setup is privileged"]}`,
+		},
+		{
+			audit:    "privileged-containers",
+			expected: `{"count":2,"violations":["pod/app/debug container setup is privileged","pod/kube-system/kube-proxy container kube-proxy is privileged"]}`,
+		},
+		{
+			audit:    "host-path-volumes",
+			opts:     excludeSystem,
+			expected: `{"count":1,"violations":["pod/app/debug mounts host path /"]}`,
+		},
+		{
+			audit:    "host-namespaces",
+			expected: `{"count":2,"violations":["pod/app/debug shares the host PID namespace","pod/kube-system/kube-proxy shares the host network namespace"]}`,
+		},
+		{
+			audit:    "automounted-service-account-tokens",
+			opts:     excludeSystem,
+			expected: `{"count":2,"violations":["pod/app/api mounts the token of serviceaccount web","pod/app/debug mounts the token of serviceaccount default"]}`,
+		},
+	}
+
+	for _, c := range cases {
+		t.Run(c.audit, func(t *testing.T) {
+			out, err := auditAPI(&Check{Type: API, Audit: c.audit, AuditOptions: c.opts})
+			assert.NoError(t, err)
+			assert.Equal(t, c.expected, out)
+		})
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+	"github.com/spf13/cobra"
+)
+
+// analyzeCmd represents the analyze command
+var analyzeCmd = &cobra.Command{
+	Use:   "analyze <bundle.tar.gz>",
+	Short: "Run checks against a recorded evidence bundle",
+	Long: `Run checks against an evidence bundle recorded with --record instead of the live node.
+The audits are not run again: every check is evaluated against the output recorded in the bundle.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		b, err := readBundle(args[0])
+		if err != nil {
+			exitWithError(fmt.Errorf("failed to read evidence bundle %s: %v", args[0], err))
+		}
+
+		glog.V(1).Infof("Analyzing %s bundle recorded on %s at %s", b.Metadata.Benchmark, b.Metadata.Hostname, b.Metadata.Time)
+		if err := analyze(b); err != nil {
+			exitWithError(err)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(analyzeCmd)
+}
+
+// analyze runs the recorded controls of every target in the bundle against
+// the recorded audit output.
+func analyze(b *evidenceBundle) error {
+	check.ReplayEvidence(b.Evidence)
+	defer check.ReplayEvidence(nil)
+
+	filter, err := NewRunFilter(filterOpts)
+	if err != nil {
+		return fmt.Errorf("error setting up run filter: %v", err)
+	}
+
+	for _, target := range b.Metadata.Targets {
+		in, ok := b.Controls[target]
+		if !ok {
+			return fmt.Errorf("no controls recorded for %s", target)
+		}
+
+		controls, err := check.NewControls(check.NodeType(target), []byte(in))
+		if err != nil {
+			return fmt.Errorf("error setting up %s controls: %v", target, err)
+		}
+		controls.Metadata = b.Metadata.Node
+
+		glog.V(1).Infof("== Analyzing %s checks ==\n", target)
+		summary := controls.RunChecks(check.NewRunner(), filter)
+		writeOutput(controls, summary)
+	}
+
+	return nil
+}
@@ -0,0 +1,133 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+const analyzeControls = `---
+controls:
+id: 4
+text: "Worker Node Security Configuration"
+type: "node"
+groups:
+- id: 4.2
+  text: "Kubelet"
+  checks:
+  - id: 4.2.1
+    text: "Ensure that the --anonymous-auth argument is set to false"
+    audit: "/bin/ps -fC kubelet"
+    tests:
+      test_items:
+      - flag: "--anonymous-auth"
+        compare:
+          op: eq
+          value: false
+        set: true
+    scored: true
+  - id: 4.2.2
+    text: "Ensure that the --read-only-port argument is set to 0"
+    audit: "/bin/ps -fC kubelet --read-only-port"
+    tests:
+      test_items:
+      - flag: "--read-only-port"
+        compare:
+          op: eq
+          value: 0
+        set: true
+    scored: true
+`
+
+func TestAnalyze(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-analyze")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	b := newEvidenceBundle()
+	b.addControls(check.NODE, "cfg/cis-1.5/node.yaml", analyzeControls)
+	b.Evidence["/bin/ps -fC kubelet"] = "kubelet --anonymous-auth=false"
+
+	bundle := filepath.Join(dir, "bundle.tar.gz")
+	if err := b.write(bundle); err != nil {
+		t.Fatal(err)
+	}
+
+	rb, err := readBundle(bundle)
+	if err != nil {
+		t.Fatalf("unexpected error reading bundle: %v", err)
+	}
+	if rb.Metadata.Benchmark != "cis-1.5" || rb.Evidence["/bin/ps -fC kubelet"] != "kubelet --anonymous-auth=false" {
+		t.Fatalf("bundle was not read back: %+v", rb)
+	}
+
+	defer func(j bool, o string) { jsonFmt, outputFile = j, o }(jsonFmt, outputFile)
+	jsonFmt = true
+	outputFile = filepath.Join(dir, "results.json")
+
+	if err := analyze(rb); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	out, err := ioutil.ReadFile(outputFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var controls check.Controls
+	if err := json.Unmarshal(out, &controls); err != nil {
+		t.Fatalf("invalid output %s: %v", out, err)
+	}
+
+	states := map[string]check.State{}
+	for _, g := range controls.Groups {
+		for _, c := range g.Checks {
+			states[c.ID] = c.State
+		}
+	}
+	// 4.2.2 has no recorded output, so it can't be evaluated.
+	expected := map[string]check.State{"4.2.1": check.PASS, "4.2.2": check.WARN}
+	for id, state := range expected {
+		if states[id] != state {
+			t.Errorf("expected %s to be %s, got %s", id, state, states[id])
+		}
+	}
+}
+
+func TestReadBundleInvalid(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-analyze")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	notGzip := filepath.Join(dir, "bundle.tar.gz")
+	if err := ioutil.WriteFile(notGzip, []byte("not a bundle"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	for _, file := range []string{notGzip, filepath.Join(dir, "missing.tar.gz")} {
+		if _, err := readBundle(file); err == nil {
+			t.Errorf("expected an error reading %s", file)
+		}
+	}
+}
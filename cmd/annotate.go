@@ -0,0 +1,104 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+// The annotations of the Node summarizing the latest scan.
+const (
+	scoreAnnotation    = "kube-bench.aquasec.com/score"
+	failAnnotation     = "kube-bench.aquasec.com/fail"
+	lastScanAnnotation = "kube-bench.aquasec.com/last-scan"
+	runIDAnnotation    = "kube-bench.aquasec.com/run-id"
+)
+
+// nodeAnnotationResults collects the results of this run when
+// --annotate-node is given.
+var nodeAnnotationResults []*check.Controls
+
+// addToNodeAnnotation adds the results of a target to the ones summarized
+// on the Node.
+func addToNodeAnnotation(controls *check.Controls) {
+	if annotateNode {
+		nodeAnnotationResults = append(nodeAnnotationResults, controls)
+	}
+}
+
+// nodeAnnotations summarizes the results in annotations of the Node.
+func nodeAnnotations(controls []*check.Controls, now time.Time) map[string]string {
+	var s check.Summary
+	var runID string
+	for _, c := range controls {
+		s.Pass += c.Pass
+		s.Fail += c.Fail
+		s.Warn += c.Warn
+		s.Info += c.Info
+		if runID == "" {
+			runID = c.RunID
+		}
+	}
+	annotations := map[string]string{
+		scoreAnnotation:    fmt.Sprintf("%.1f", s.Score()),
+		failAnnotation:     strconv.Itoa(s.Fail),
+		lastScanAnnotation: now.UTC().Format(time.RFC3339),
+	}
+	if runID != "" {
+		annotations[runIDAnnotation] = runID
+	}
+	return annotations
+}
+
+// annotateNodeWithResults patches the Node kube-bench runs on with
+// annotations summarizing the results, when --annotate-node is given.
+func annotateNodeWithResults() error {
+	if !annotateNode || len(nodeAnnotationResults) == 0 {
+		return nil
+	}
+	// Mock results are not the ones of the node.
+	if mockMode != "" {
+		glog.V(1).Info("Node not annotated with mock results")
+		return nil
+	}
+
+	patch := map[string]interface{}{
+		"metadata": map[string]interface{}{
+			"annotations": nodeAnnotations(nodeAnnotationResults, time.Now()),
+		},
+	}
+	data, err := json.Marshal(patch)
+	if err != nil {
+		return err
+	}
+
+	client, err := kubeClient()
+	if err != nil {
+		return fmt.Errorf("failed to annotate node: %v", err)
+	}
+	name := currentNodeMetadata().NodeName
+	if _, err := client.CoreV1().Nodes().Patch(name, types.MergePatchType, data); err != nil {
+		return fmt.Errorf("failed to annotate node %s: %v", name, err)
+	}
+	glog.V(1).Infof("Node %s annotated with the results", name)
+	return nil
+}
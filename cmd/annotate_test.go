@@ -0,0 +1,77 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestNodeAnnotations(t *testing.T) {
+	controls := []*check.Controls{
+		{RunID: "run-1", Summary: check.Summary{Pass: 10, Fail: 2, Warn: 4, Info: 1}},
+		{RunID: "run-1", Summary: check.Summary{Pass: 2, Fail: 1, Warn: 1, Skip: 3}},
+	}
+	expected := map[string]string{
+		scoreAnnotation:    "60.0",
+		failAnnotation:     "3",
+		lastScanAnnotation: "2020-03-09T10:00:00Z",
+		runIDAnnotation:    "run-1",
+	}
+	now := time.Date(2020, 3, 9, 11, 0, 0, 0, time.FixedZone("CET", 3600))
+	if got := nodeAnnotations(controls, now); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %v, got %v", expected, got)
+	}
+}
+
+func TestAnnotateNodeWithResults(t *testing.T) {
+	savedClient, savedMetadata, savedResults := kubeClient, nodeMetadata, nodeAnnotationResults
+	defer func() {
+		kubeClient, nodeMetadata, nodeAnnotationResults, annotateNode = savedClient, savedMetadata, savedResults, false
+	}()
+
+	client := fake.NewSimpleClientset(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "gpu-1", Annotations: map[string]string{"team": "ml"}},
+	})
+	kubeClient = func() (kubernetes.Interface, error) { return client, nil }
+	nodeMetadata = &check.NodeMetadata{NodeName: "gpu-1"}
+
+	annotateNode = true
+	addToNodeAnnotation(&check.Controls{Summary: check.Summary{Pass: 3, Fail: 1}})
+	if err := annotateNodeWithResults(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	node, err := client.CoreV1().Nodes().Get("gpu-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	a := node.Annotations
+	if a[scoreAnnotation] != "75.0" || a[failAnnotation] != "1" || a[lastScanAnnotation] == "" || a["team"] != "ml" {
+		t.Errorf("unexpected annotations %v", a)
+	}
+
+	nodeMetadata = &check.NodeMetadata{NodeName: "unknown"}
+	if err := annotateNodeWithResults(); err == nil {
+		t.Errorf("expected an error annotating an unknown node")
+	}
+}
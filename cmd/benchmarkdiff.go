@@ -0,0 +1,227 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/spf13/cobra"
+)
+
+// benchmarkCheck is a check of a benchmark, along with its target.
+type benchmarkCheck struct {
+	Target string `json:"target"`
+	ID     string `json:"id"`
+	CISID  string `json:"cis_id,omitempty"`
+	Text   string `json:"text"`
+	check  *check.Check
+}
+
+// changedCheck is a check whose definition differs between two benchmarks.
+type changedCheck struct {
+	benchmarkCheck
+	// Fields lists what changed, e.g. audit or tests.
+	Fields []string `json:"fields"`
+}
+
+// benchmarkDiff lists the differences between the checks of two benchmarks.
+type benchmarkDiff struct {
+	From    string           `json:"from"`
+	To      string           `json:"to"`
+	Added   []benchmarkCheck `json:"added"`
+	Removed []benchmarkCheck `json:"removed"`
+	Changed []changedCheck   `json:"changed"`
+}
+
+// benchmarkDiffCmd represents the benchmark-diff command
+var benchmarkDiffCmd = &cobra.Command{
+	Use:   "benchmark-diff <from> <to>",
+	Short: "List the checks that differ between two benchmark versions",
+	Long: `List the checks that were added, removed or changed between two benchmark versions
+found in the config directory, e.g. kube-bench benchmark-diff cis-1.4 cis-1.5.
+Checks are matched by ID.`,
+	Args: cobra.ExactArgs(2),
+	Run: func(cmd *cobra.Command, args []string) {
+		diff, err := diffBenchmarks(args[0], args[1])
+		if err != nil {
+			exitWithError(err)
+		}
+
+		if jsonFmt {
+			out, err := json.MarshalIndent(diff, "", "  ")
+			if err != nil {
+				exitWithError(fmt.Errorf("failed to output in JSON format: %v", err))
+			}
+			PrintOutput(string(out), outputFile)
+			return
+		}
+		printBenchmarkDiff(os.Stdout, diff)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(benchmarkDiffCmd)
+}
+
+func diffBenchmarks(from, to string) (*benchmarkDiff, error) {
+	fromChecks, err := loadBenchmarkChecks(from)
+	if err != nil {
+		return nil, err
+	}
+	toChecks, err := loadBenchmarkChecks(to)
+	if err != nil {
+		return nil, err
+	}
+
+	diff := &benchmarkDiff{From: from, To: to}
+	for key, c := range toChecks {
+		old, ok := fromChecks[key]
+		if !ok {
+			diff.Added = append(diff.Added, c)
+			continue
+		}
+		if fields := changedFields(old.check, c.check); len(fields) > 0 {
+			diff.Changed = append(diff.Changed, changedCheck{benchmarkCheck: c, Fields: fields})
+		}
+	}
+	for key, c := range fromChecks {
+		if _, ok := toChecks[key]; !ok {
+			diff.Removed = append(diff.Removed, c)
+		}
+	}
+
+	sortBenchmarkChecks(diff.Added)
+	sortBenchmarkChecks(diff.Removed)
+	sort.Slice(diff.Changed, func(i, j int) bool {
+		return benchmarkCheckLess(diff.Changed[i].benchmarkCheck, diff.Changed[j].benchmarkCheck)
+	})
+	return diff, nil
+}
+
+// loadBenchmarkChecks returns the checks of a benchmark keyed by target
+// and ID.
+func loadBenchmarkChecks(benchmark string) (map[string]benchmarkCheck, error) {
+	dir := filepath.Join(cfgDir, benchmark)
+	if _, err := os.Stat(dir); err != nil {
+		return nil, fmt.Errorf("unknown benchmark %s: %v", benchmark, err)
+	}
+	files, err := getYamlFilesFromDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	checks := map[string]benchmarkCheck{}
+	for _, file := range files {
+		target := strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+		in, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		in, err = check.ResolveIncludes(in, dir)
+		if err == nil {
+			in, err = check.ResolveExtends(in, dir)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %v", file, err)
+		}
+		controls, err := check.NewControls(check.NodeType(target), in)
+		if err != nil {
+			return nil, fmt.Errorf("error reading %s: %v", file, err)
+		}
+
+		for _, g := range controls.Groups {
+			for _, c := range g.Checks {
+				checks[target+"/"+c.ID] = benchmarkCheck{Target: target, ID: c.ID, CISID: c.CISID, Text: strings.TrimSpace(c.Text), check: c}
+			}
+		}
+	}
+	return checks, nil
+}
+
+// changedFields lists the fields of the definition of a check that differ.
+func changedFields(a, b *check.Check) []string {
+	var fields []string
+	for _, f := range []struct {
+		name  string
+		equal bool
+	}{
+		{"text", strings.TrimSpace(a.Text) == strings.TrimSpace(b.Text)},
+		{"type", a.Type == b.Type},
+		{"audit", a.Audit == b.Audit},
+		{"audit_config", a.AuditConfig == b.AuditConfig},
+		{"audit_options", reflect.DeepEqual(a.AuditOptions, b.AuditOptions)},
+		{"tests", reflect.DeepEqual(a.Tests, b.Tests)},
+		{"remediation", strings.TrimSpace(a.Remediation) == strings.TrimSpace(b.Remediation)},
+		{"rationale", strings.TrimSpace(a.Rationale) == strings.TrimSpace(b.Rationale)},
+		{"reference", a.Reference == b.Reference},
+		{"scored", a.Scored == b.Scored},
+		{"cis_id", a.CISID == b.CISID},
+	} {
+		if !f.equal {
+			fields = append(fields, f.name)
+		}
+	}
+	return fields
+}
+
+func sortBenchmarkChecks(checks []benchmarkCheck) {
+	sort.Slice(checks, func(i, j int) bool { return benchmarkCheckLess(checks[i], checks[j]) })
+}
+
+// benchmarkCheckLess orders checks by target, then by ID, comparing the
+// numbers of IDs numerically so that 1.2.10 comes after 1.2.9.
+func benchmarkCheckLess(a, b benchmarkCheck) bool {
+	if a.Target != b.Target {
+		return a.Target < b.Target
+	}
+
+	as, bs := strings.Split(a.ID, "."), strings.Split(b.ID, ".")
+	for i := 0; i < len(as) && i < len(bs); i++ {
+		if as[i] == bs[i] {
+			continue
+		}
+		if len(as[i]) != len(bs[i]) {
+			return len(as[i]) < len(bs[i])
+		}
+		return as[i] < bs[i]
+	}
+	return len(as) < len(bs)
+}
+
+func printBenchmarkDiff(w io.Writer, d *benchmarkDiff) {
+	fmt.Fprintf(w, "Checks added in %s (%d):\n", d.To, len(d.Added))
+	for _, c := range d.Added {
+		fmt.Fprintf(w, "  [%s] %s %s\n", c.Target, c.ID, c.Text)
+	}
+
+	fmt.Fprintf(w, "\nChecks removed from %s (%d):\n", d.From, len(d.Removed))
+	for _, c := range d.Removed {
+		fmt.Fprintf(w, "  [%s] %s %s\n", c.Target, c.ID, c.Text)
+	}
+
+	fmt.Fprintf(w, "\nChecks changed (%d):\n", len(d.Changed))
+	for _, c := range d.Changed {
+		fmt.Fprintf(w, "  [%s] %s %s (%s)\n", c.Target, c.ID, c.Text, strings.Join(c.Fields, ", "))
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+const diffFromControls = `---
+controls:
+id: 1
+text: "Master Node Security Configuration"
+type: "master"
+groups:
+  - id: 1.1
+    text: "API Server"
+    checks:
+      - id: 1.1.1
+        text: "Ensure that the --anonymous-auth argument is set to false (Scored)"
+        audit: "ps -ef | grep kube-apiserver"
+        tests:
+          test_items:
+            - flag: "--anonymous-auth"
+              compare:
+                op: eq
+                value: false
+              set: true
+        scored: true
+      - id: 1.1.2
+        text: "Ensure that the --basic-auth-file argument is not set (Scored)"
+        audit: "ps -ef | grep kube-apiserver"
+        scored: true
+      - id: 1.1.9
+        text: "Ensure that the --profiling argument is set to false (Scored)"
+        audit: "ps -ef | grep kube-apiserver"
+        scored: true
+`
+
+const diffToControls = `---
+controls:
+id: 1
+text: "Master Node Security Configuration"
+type: "master"
+groups:
+  - id: 1.1
+    text: "API Server"
+    checks:
+      - id: 1.1.1
+        text: "Ensure that the --anonymous-auth argument is set to false (Not Scored)"
+        audit: "ps -ef | grep kube-apiserver"
+        tests:
+          test_items:
+            - flag: "--anonymous-auth"
+              compare:
+                op: eq
+                value: false
+              set: true
+        scored: false
+      - id: 1.1.9
+        text: "Ensure that the --profiling argument is set to false (Scored)"
+        audit: "ps -ef | grep kube-apiserver"
+        scored: true
+      - id: 1.1.10
+        text: "Ensure that the --token-auth-file parameter is not set (Scored)"
+        audit: "ps -ef | grep kube-apiserver"
+        scored: true
+`
+
+func TestDiffBenchmarks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-diff")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	for benchmark, controls := range map[string]string{"cis-1.0": diffFromControls, "cis-1.1": diffToControls} {
+		if err := os.Mkdir(filepath.Join(dir, benchmark), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(filepath.Join(dir, benchmark, "master.yaml"), []byte(controls), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	defer func(d string) { cfgDir = d }(cfgDir)
+	cfgDir = dir
+
+	diff, err := diffBenchmarks("cis-1.0", "cis-1.1")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	ids := func(checks []benchmarkCheck) []string {
+		var ids []string
+		for _, c := range checks {
+			ids = append(ids, c.Target+"/"+c.ID)
+		}
+		return ids
+	}
+	if got := ids(diff.Added); !reflect.DeepEqual(got, []string{"master/1.1.10"}) {
+		t.Errorf("unexpected added checks %v", got)
+	}
+	if got := ids(diff.Removed); !reflect.DeepEqual(got, []string{"master/1.1.2"}) {
+		t.Errorf("unexpected removed checks %v", got)
+	}
+	if len(diff.Changed) != 1 || diff.Changed[0].ID != "1.1.1" || !reflect.DeepEqual(diff.Changed[0].Fields, []string{"text", "scored"}) {
+		t.Errorf("unexpected changed checks %+v", diff.Changed)
+	}
+
+	var out bytes.Buffer
+	printBenchmarkDiff(&out, diff)
+	if !strings.Contains(out.String(), "  [master] 1.1.1 Ensure that the --anonymous-auth argument is set to false (Not Scored) (text, scored)\n") {
+		t.Errorf("unexpected output:\n%s", out.String())
+	}
+
+	if _, err := diffBenchmarks("cis-1.0", "cis-9.9"); err == nil {
+		t.Errorf("expected an error for an unknown benchmark")
+	}
+}
+
+func TestBenchmarkCheckLess(t *testing.T) {
+	checks := []benchmarkCheck{{Target: "node", ID: "4.1"}, {Target: "master", ID: "1.2.10"}, {Target: "master", ID: "1.2.9"}, {Target: "master", ID: "1.2"}}
+	sortBenchmarkChecks(checks)
+	if got := checkIDs(checks); !reflect.DeepEqual(got, []string{"1.2", "1.2.9", "1.2.10", "4.1"}) {
+		t.Errorf("unexpected order %v", got)
+	}
+}
+
+func checkIDs(checks []benchmarkCheck) []string {
+	var ids []string
+	for _, c := range checks {
+		ids = append(ids, c.ID)
+	}
+	return ids
+}
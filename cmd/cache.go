@@ -0,0 +1,78 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+)
+
+// resultCache is the cache of the results of checks given with --cache-file,
+// loaded when the first target runs and kept across the scans of serve.
+var resultCache *check.ResultCache
+
+// currentResultCache returns the cache of results, loading it from
+// --cache-file the first time. Results of another version of kube-bench are
+// dropped, and so is a cache that can't be read.
+func currentResultCache() (*check.ResultCache, error) {
+	if resultCache != nil {
+		return resultCache, nil
+	}
+
+	resultCache = check.NewResultCache(KubeBenchVersion)
+	data, err := ioutil.ReadFile(cacheFile)
+	if os.IsNotExist(err) {
+		return resultCache, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read result cache %s: %v", cacheFile, err)
+	}
+
+	cache := &check.ResultCache{}
+	if err := json.Unmarshal(data, cache); err != nil {
+		continueWithError(err, fmt.Sprintf("Ignoring invalid result cache %s", cacheFile))
+		return resultCache, nil
+	}
+	if cache.Version != KubeBenchVersion {
+		glog.V(1).Infof("Ignoring result cache of kube-bench %s", cache.Version)
+		return resultCache, nil
+	}
+	resultCache = cache
+	return resultCache, nil
+}
+
+// saveResultCache writes the results of the checks of the run to
+// --cache-file, dropping the ones of the checks whose inputs changed.
+func saveResultCache() error {
+	if resultCache == nil {
+		return nil
+	}
+
+	glog.V(1).Infof("%d checks taken from the result cache, %d evaluated", resultCache.Hits, resultCache.Misses)
+	resultCache.Prune()
+	data, err := json.Marshal(resultCache)
+	if err != nil {
+		return err
+	}
+	if err := ioutil.WriteFile(cacheFile, data, 0600); err != nil {
+		return fmt.Errorf("failed to save result cache %s: %v", cacheFile, err)
+	}
+	return nil
+}
@@ -0,0 +1,71 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+func TestResultCacheFile(t *testing.T) {
+	defer func(file string, cache *check.ResultCache) { cacheFile, resultCache = file, cache }(cacheFile, resultCache)
+
+	dir, err := ioutil.TempDir("", "kube-bench-cache")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	cacheFile, resultCache = filepath.Join(dir, "cache.json"), nil
+
+	// A missing cache is empty.
+	cache, err := currentResultCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(cache.Results) != 0 {
+		t.Errorf("expected an empty cache, got %v", cache.Results)
+	}
+
+	audit := "stat -c %a " + filepath.Join(dir, "kubelet.conf")
+	runner := check.NewCachingRunner(stateRunner{"4.1.1": check.FAIL}, cache)
+	runner.Run(&check.Check{ID: "4.1.1", Audit: audit})
+	if err := saveResultCache(); err != nil {
+		t.Fatal(err)
+	}
+
+	resultCache = nil
+	cache, err = currentResultCache()
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := &check.Check{ID: "4.1.1", Audit: audit}
+	if state := check.NewCachingRunner(stateRunner{}, cache).Run(c); state != check.FAIL {
+		t.Errorf("expected the cached FAIL, got %s", state)
+	}
+
+	// The results of another version are dropped.
+	cache.Version = "v0.0.1"
+	if err := saveResultCache(); err != nil {
+		t.Fatal(err)
+	}
+	resultCache = nil
+	if cache, err = currentResultCache(); err != nil || len(cache.Results) != 0 {
+		t.Errorf("expected an empty cache, got %v, %v", cache, err)
+	}
+}
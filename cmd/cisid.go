@@ -0,0 +1,64 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+// The IDs checks can be reported by with --report-ids. The numbers of the
+// CIS recommendations drift between versions of the benchmark, while the
+// internal IDs of the checks stay the same.
+const (
+	internalIDs = "internal"
+	cisIDs      = "cis"
+	bothIDs     = "both"
+)
+
+// checkReportIDs verifies the IDs given with --report-ids.
+func checkReportIDs(ids string) error {
+	switch ids {
+	case internalIDs, cisIDs, bothIDs:
+		return nil
+	}
+	return fmt.Errorf("%q is not one of %s, %s or %s", ids, internalIDs, cisIDs, bothIDs)
+}
+
+// reportedID returns the ID the check is reported by in the text output. A
+// check without a CIS ID is always reported by its ID.
+func reportedID(c *check.Check) string {
+	if c.CISID == "" || c.CISID == c.ID {
+		return c.ID
+	}
+	switch reportIDs {
+	case cisIDs:
+		return c.CISID
+	case bothIDs:
+		return fmt.Sprintf("%s (CIS %s)", c.ID, c.CISID)
+	}
+	return c.ID
+}
+
+// cisIDPrefix selects checks by their CIS ID rather than by their ID, e.g.
+// cis:1.2.15, since the CIS ID of a check can be the ID of another one.
+const cisIDPrefix = "cis:"
+
+// hasID reports whether the check is in ids, given by its ID or by its CIS
+// ID with the cis: prefix.
+func hasID(ids map[string]bool, c *check.Check) bool {
+	return ids[c.ID] || c.CISID != "" && ids[cisIDPrefix+c.CISID]
+}
@@ -0,0 +1,89 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+func TestCheckReportIDs(t *testing.T) {
+	for _, ids := range []string{internalIDs, cisIDs, bothIDs} {
+		if err := checkReportIDs(ids); err != nil {
+			t.Errorf("%s: unexpected error: %v", ids, err)
+		}
+	}
+	if err := checkReportIDs("cis-1.5"); err == nil {
+		t.Errorf("expected an error")
+	}
+}
+
+func TestReportedID(t *testing.T) {
+	defer func(ids string) { reportIDs = ids }(reportIDs)
+
+	renumbered := &check.Check{ID: "1.2.16", CISID: "1.2.15"}
+	same := &check.Check{ID: "1.2.1", CISID: "1.2.1"}
+	internal := &check.Check{ID: "1.2.2"}
+	for _, c := range []struct {
+		ids      string
+		check    *check.Check
+		expected string
+	}{
+		{internalIDs, renumbered, "1.2.16"},
+		{cisIDs, renumbered, "1.2.15"},
+		{bothIDs, renumbered, "1.2.16 (CIS 1.2.15)"},
+		{bothIDs, same, "1.2.1"},
+		{cisIDs, internal, "1.2.2"},
+	} {
+		reportIDs = c.ids
+		if id := reportedID(c.check); id != c.expected {
+			t.Errorf("%s %s: expected %s, got %s", c.ids, c.check.ID, c.expected, id)
+		}
+	}
+}
+
+func TestRunFilterByCISID(t *testing.T) {
+	group := &check.Group{ID: "1.2"}
+	renumbered := &check.Check{ID: "1.2.16", CISID: "1.2.15", Scored: true}
+	other := &check.Check{ID: "1.2.15", Scored: true}
+
+	filter, err := NewRunFilter(FilterOpts{CheckList: "1.2.15", Scored: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filter(group, renumbered) || !filter(group, other) {
+		t.Errorf("expected the check to be selected by its ID only")
+	}
+
+	filter, err = NewRunFilter(FilterOpts{CheckList: "cis:1.2.15", Scored: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !filter(group, renumbered) || filter(group, other) {
+		t.Errorf("expected the check to be selected by its CIS ID only")
+	}
+
+	filter, err = NewRunFilter(FilterOpts{SkipCheckList: "cis:1.2.15", Scored: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if filter(group, renumbered) || !filter(group, other) {
+		t.Errorf("expected the check to be skipped by its CIS ID")
+	}
+	if !filter(group, &check.Check{ID: "1.2.1", Scored: true}) {
+		t.Errorf("expected a check without CIS ID to be run")
+	}
+}
@@ -0,0 +1,222 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+const (
+	// clusterSummaryName is the name of the single ClusterComplianceSummary
+	// of the cluster.
+	clusterSummaryName = "cluster"
+	// worstOffenders is the number of nodes and checks listed as the worst
+	// offenders of the fleet.
+	worstOffenders = 10
+)
+
+// clusterSummaryResource is the ClusterComplianceSummary custom resource,
+// defined by cluster-compliance-summary-crd.yaml.
+var clusterSummaryResource = schema.GroupVersionResource{
+	Group:    "kube-bench.aquasec.com",
+	Version:  "v1alpha1",
+	Resource: "clustercompliancesummaries",
+}
+
+// dynamicClient returns the client of custom resources; it's separated into
+// a variable so we can write tests.
+var dynamicClient = check.KubeDynamicClient
+
+// clusterCompliance is the status of the ClusterComplianceSummary: the
+// compliance of the fleet and its worst offenders.
+type clusterCompliance struct {
+	Nodes       int            `json:"nodes"`
+	Cohorts     int            `json:"cohorts"`
+	Score       float64        `json:"score"`
+	Totals      fleetTotals    `json:"totals"`
+	WorstNodes  []nodeScore    `json:"worstNodes"`
+	WorstChecks []failingCheck `json:"worstChecks"`
+	// StaleNodes is the number of nodes whose results are stale, that
+	// haven't been scanned lately.
+	StaleNodes  int    `json:"staleNodes"`
+	LastUpdated string `json:"lastUpdated"`
+}
+
+// fleetTotals are the numbers of results of the checks of all the nodes.
+type fleetTotals struct {
+	Pass int `json:"pass"`
+	Fail int `json:"fail"`
+	Warn int `json:"warn"`
+	Info int `json:"info"`
+	Skip int `json:"skip"`
+}
+
+// nodeScore is the compliance of a node.
+type nodeScore struct {
+	Node  string  `json:"node"`
+	Score float64 `json:"score"`
+	Fail  int     `json:"fail"`
+	// LastScan is when the results of the node were produced.
+	LastScan string `json:"lastScan,omitempty"`
+	Stale    bool   `json:"stale,omitempty"`
+}
+
+// failingCheck is a check failing on nodes of the fleet.
+type failingCheck struct {
+	ID           string `json:"id"`
+	Text         string `json:"text"`
+	Target       string `json:"target"`
+	FailingNodes int    `json:"failingNodes"`
+}
+
+// summarizeFleet sums up the merged results of the nodes of the fleet: the
+// totals and score of all their checks, the nodes with the lowest scores,
+// and the checks failing on the most nodes.
+func summarizeFleet(m *mergedResults, now time.Time) *clusterCompliance {
+	s := &clusterCompliance{
+		Nodes:       m.Nodes,
+		Cohorts:     len(m.Cohorts),
+		WorstNodes:  []nodeScore{},
+		WorstChecks: []failingCheck{},
+		LastUpdated: now.UTC().Format(time.RFC3339),
+	}
+
+	var fleet check.Summary
+	failing := map[string]*failingCheck{}
+	var checks []*failingCheck
+	for _, cohort := range m.Cohorts {
+		var totals check.Summary
+		for _, controls := range cohort.Results {
+			totals.Pass += controls.Pass
+			totals.Fail += controls.Fail
+			totals.Warn += controls.Warn
+			totals.Info += controls.Info
+			totals.Skip += controls.Skip
+
+			for _, g := range controls.Groups {
+				for _, c := range g.Checks {
+					if c.State != check.FAIL {
+						continue
+					}
+					key := string(controls.Type) + " " + c.ID
+					f, ok := failing[key]
+					if !ok {
+						f = &failingCheck{ID: c.ID, Text: c.Text, Target: string(controls.Type)}
+						failing[key] = f
+						checks = append(checks, f)
+					}
+					f.FailingNodes += len(cohort.Nodes)
+				}
+			}
+		}
+
+		n := len(cohort.Nodes)
+		fleet.Pass += n * totals.Pass
+		fleet.Fail += n * totals.Fail
+		fleet.Warn += n * totals.Warn
+		fleet.Info += n * totals.Info
+		fleet.Skip += n * totals.Skip
+		for i, node := range cohort.Nodes {
+			score := nodeScore{Node: node, Score: totals.Score(), Fail: totals.Fail}
+			if i < len(cohort.scans) {
+				if scan := cohort.scans[i]; !scan.End.IsZero() {
+					score.LastScan = scan.End.UTC().Format(time.RFC3339)
+					score.Stale = scan.stale(now)
+				}
+			}
+			if score.Stale {
+				s.StaleNodes++
+			}
+			s.WorstNodes = append(s.WorstNodes, score)
+		}
+	}
+	s.Score = fleet.Score()
+	s.Totals = fleetTotals{Pass: fleet.Pass, Fail: fleet.Fail, Warn: fleet.Warn, Info: fleet.Info, Skip: fleet.Skip}
+
+	sort.SliceStable(s.WorstNodes, func(i, j int) bool {
+		if s.WorstNodes[i].Score != s.WorstNodes[j].Score {
+			return s.WorstNodes[i].Score < s.WorstNodes[j].Score
+		}
+		return s.WorstNodes[i].Node < s.WorstNodes[j].Node
+	})
+	if len(s.WorstNodes) > worstOffenders {
+		s.WorstNodes = s.WorstNodes[:worstOffenders]
+	}
+
+	sort.SliceStable(checks, func(i, j int) bool {
+		return checks[i].FailingNodes > checks[j].FailingNodes
+	})
+	for i, f := range checks {
+		if i == worstOffenders {
+			break
+		}
+		s.WorstChecks = append(s.WorstChecks, *f)
+	}
+	return s
+}
+
+// writeClusterSummary creates or updates the ClusterComplianceSummary of
+// the cluster with the compliance of the fleet.
+func writeClusterSummary(s *clusterCompliance) error {
+	client, err := dynamicClient()
+	if err != nil {
+		return fmt.Errorf("failed to write the cluster compliance summary: %v", err)
+	}
+
+	data, err := json.Marshal(s)
+	if err != nil {
+		return err
+	}
+	var status map[string]interface{}
+	if err := json.Unmarshal(data, &status); err != nil {
+		return err
+	}
+
+	resource := client.Resource(clusterSummaryResource)
+	summary, err := resource.Get(clusterSummaryName, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		summary = &unstructured.Unstructured{Object: map[string]interface{}{
+			"apiVersion": clusterSummaryResource.GroupVersion().String(),
+			"kind":       "ClusterComplianceSummary",
+			"metadata":   map[string]interface{}{"name": clusterSummaryName},
+			"status":     status,
+		}}
+		if _, err := resource.Create(summary, metav1.CreateOptions{}); err != nil {
+			return fmt.Errorf("failed to create the cluster compliance summary: %v", err)
+		}
+		glog.V(1).Infof("ClusterComplianceSummary %s created", clusterSummaryName)
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("failed to get the cluster compliance summary: %v", err)
+	}
+
+	summary.Object["status"] = status
+	if _, err := resource.Update(summary, metav1.UpdateOptions{}); err != nil {
+		return fmt.Errorf("failed to update the cluster compliance summary: %v", err)
+	}
+	glog.V(1).Infof("ClusterComplianceSummary %s updated", clusterSummaryName)
+	return nil
+}
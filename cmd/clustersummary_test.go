@@ -0,0 +1,113 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/dynamic/fake"
+)
+
+func TestSummarizeFleet(t *testing.T) {
+	merged := &mergedResults{Nodes: 4, Cohorts: []*resultsCohort{
+		{Nodes: []string{"node-1", "node-3", "node-4"}, Results: []*check.Controls{historyControls(check.NODE, check.PASS, check.FAIL, check.PASS)}},
+		{Nodes: []string{"node-2"}, Results: []*check.Controls{historyControls(check.NODE, check.FAIL, check.FAIL, check.WARN)}},
+	}}
+
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	s := summarizeFleet(merged, now)
+	expected := &clusterCompliance{
+		Nodes:   4,
+		Cohorts: 2,
+		Score:   100 * 6.0 / 12,
+		Totals:  fleetTotals{Pass: 6, Fail: 5, Warn: 1},
+		WorstNodes: []nodeScore{
+			{Node: "node-2", Score: 0, Fail: 2},
+			{Node: "node-1", Score: 100 * 2.0 / 3, Fail: 1},
+			{Node: "node-3", Score: 100 * 2.0 / 3, Fail: 1},
+			{Node: "node-4", Score: 100 * 2.0 / 3, Fail: 1},
+		},
+		WorstChecks: []failingCheck{
+			{ID: "4.2.2", Text: "Check 2", Target: "node", FailingNodes: 4},
+			{ID: "4.2.1", Text: "Check 1", Target: "node", FailingNodes: 1},
+		},
+		LastUpdated: "2020-06-01T12:00:00Z",
+	}
+	if !reflect.DeepEqual(s, expected) {
+		t.Errorf("expected %+v, got %+v", expected, s)
+	}
+}
+
+func TestSummarizeFleetStaleNodes(t *testing.T) {
+	now := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	expired := now.Add(-time.Minute)
+	merged := mergeNodeResults([]*nodeResults{
+		{Node: "node-1", Results: []*check.Controls{{Summary: check.Summary{EndTime: now.Add(-time.Hour)}}}},
+		{Node: "node-2", Results: []*check.Controls{{Summary: check.Summary{EndTime: now.Add(-2 * time.Hour)}, ExpiresAt: &expired}}},
+		{Node: "node-3"},
+	})
+
+	s := summarizeFleet(merged, now)
+	if s.StaleNodes != 1 {
+		t.Errorf("expected 1 stale node, got %d", s.StaleNodes)
+	}
+	expected := []nodeScore{
+		{Node: "node-1", Score: 0, LastScan: "2020-06-01T11:00:00Z"},
+		{Node: "node-2", Score: 0, LastScan: "2020-06-01T10:00:00Z", Stale: true},
+		{Node: "node-3", Score: 0},
+	}
+	if !reflect.DeepEqual(s.WorstNodes, expected) {
+		t.Errorf("expected %+v, got %+v", expected, s.WorstNodes)
+	}
+
+	defer func(ttl time.Duration) { resultTTL = ttl }(resultTTL)
+	resultTTL = 30 * time.Minute
+	if s := summarizeFleet(merged, now); s.StaleNodes != 2 {
+		t.Errorf("expected 2 stale nodes older than the TTL, got %d", s.StaleNodes)
+	}
+}
+
+func TestWriteClusterSummary(t *testing.T) {
+	defer func(f func() (dynamic.Interface, error)) { dynamicClient = f }(dynamicClient)
+	client := fake.NewSimpleDynamicClient(runtime.NewScheme())
+	dynamicClient = func() (dynamic.Interface, error) { return client, nil }
+
+	status := func() map[string]interface{} {
+		summary, err := client.Resource(clusterSummaryResource).Get(clusterSummaryName, metav1.GetOptions{})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		s, _, _ := unstructured.NestedMap(summary.Object, "status")
+		return s
+	}
+
+	// The summary is created by the first merge, and updated by the next.
+	for _, nodes := range []int{3, 5} {
+		if err := writeClusterSummary(&clusterCompliance{Nodes: nodes, Score: 50, Totals: fleetTotals{Pass: 1, Fail: 1}}); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		s := status()
+		if s["nodes"] != float64(nodes) || s["score"] != 50.0 {
+			t.Errorf("unexpected status %v", s)
+		}
+	}
+}
@@ -17,7 +17,6 @@ package cmd
 import (
 	"bufio"
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"strings"
@@ -30,10 +29,6 @@ import (
 // NewRunFilter constructs a Predicate based on FilterOpts which determines whether tested Checks should be run or not.
 func NewRunFilter(opts FilterOpts) (check.Predicate, error) {
 
-	if opts.CheckList != "" && opts.GroupList != "" {
-		return nil, fmt.Errorf("group option and check option can't be used together")
-	}
-
 	var groupIDs map[string]bool
 	if opts.GroupList != "" {
 		groupIDs = cleanIDs(opts.GroupList)
@@ -44,82 +39,265 @@ func NewRunFilter(opts FilterOpts) (check.Predicate, error) {
 		checkIDs = cleanIDs(opts.CheckList)
 	}
 
+	var skippedGroupIDs map[string]bool
+	if opts.SkipGroupList != "" {
+		skippedGroupIDs = cleanIDs(opts.SkipGroupList)
+	}
+
+	var skippedCheckIDs map[string]bool
+	if opts.SkipCheckList != "" {
+		skippedCheckIDs = cleanIDs(opts.SkipCheckList)
+	}
+
 	return func(g *check.Group, c *check.Check) bool {
 		var test = true
-		if len(groupIDs) > 0 {
-			_, ok := groupIDs[g.ID]
-			test = test && ok
+		// The checks listed are run along with the ones of the groups
+		// listed.
+		if len(groupIDs) > 0 || len(checkIDs) > 0 {
+			test = groupIDs[g.ID] || hasID(checkIDs, c)
 		}
 
-		if len(checkIDs) > 0 {
-			_, ok := checkIDs[c.ID]
-			test = test && ok
-		}
+		test = test && !skippedGroupIDs[g.ID] && !hasID(skippedCheckIDs, c)
 
 		test = test && (opts.Scored && c.Scored || opts.Unscored && !c.Scored)
 
+		if opts.Samples > 1 {
+			test = test && checkSample(c, opts.Samples) == opts.Sample
+		}
+
 		return test
 	}, nil
 }
 
+// target is the controls of a target set up to run on this node, along
+// with the files found for its components.
+type target struct {
+	controls *check.Controls
+	runner   check.Runner
+	filter   check.Predicate
+	files    []map[string]string
+	summary  check.Summary
+	// critical holds the critical checks of the controls, whether they run
+	// or not, with --quarantine-node.
+	critical []*check.Check
+}
+
 func runChecks(nodetype check.NodeType, testYamlFile string) {
-	var summary check.Summary
+	t := prepareChecks(nodetype, testYamlFile)
+	if t == nil {
+		return
+	}
+	if batching {
+		batch = append(batch, t)
+		return
+	}
+	t.run()
+	t.report()
+}
 
+// prepareChecks sets up the controls of a target, ready to run. It returns
+// nil when the checks are not run on this node.
+func prepareChecks(nodetype check.NodeType, testYamlFile string) *target {
 	// Verify config file was loaded into Viper during Cobra sub-command initialization.
 	if configFileError != nil {
 		colorPrint(check.FAIL, fmt.Sprintf("Failed to read config file: %v\n", configFileError))
-		os.Exit(1)
+		exitWithError(fmt.Errorf("failed to read config file: %v", configFileError))
+	}
+
+	metadata := currentNodeMetadata()
+	if skipUnselectedNode(metadata) {
+		return nil
 	}
 
-	in, err := ioutil.ReadFile(testYamlFile)
+	in, err := readControls(testYamlFile)
 	if err != nil {
 		exitWithError(fmt.Errorf("error opening %s test file: %v", testYamlFile, err))
 	}
 
 	glog.V(1).Info(fmt.Sprintf("Using test file: %s\n", testYamlFile))
 
+	in, err = check.ResolveIncludes(in, filepath.Dir(testYamlFile))
+	if err == nil {
+		in, err = check.ResolveExtends(in, filepath.Dir(testYamlFile))
+	}
+	if err != nil {
+		exitWithError(fmt.Errorf("error reading %s test file: %v", testYamlFile, err))
+	}
+
 	// Get the viper config for this section of tests
 	typeConf := viper.Sub(string(nodetype))
+	if typeConf == nil && !builtinTarget(nodetype) {
+		// Extra targets need no config settings unless their checks use
+		// the binaries or files of components.
+		typeConf = viper.New()
+	}
 	if typeConf == nil {
 		colorPrint(check.FAIL, fmt.Sprintf("No config settings for %s\n", string(nodetype)))
-		os.Exit(1)
+		exitWithError(fmt.Errorf("no config settings for %s", nodetype))
 	}
 
-	// Get the set of executables we need for this section of the tests
-	binmap, err := getBinaries(typeConf, nodetype)
+	// Get the set of executables we need for this section of the tests.
+	// Mock results don't need any executable to be running, nor do checks
+	// of remote etcd members.
+	remote := nodetype == check.ETCD && etcdMember != ""
+	var binmap map[string]string
+	if mockMode == "" && !remote {
+		binmap, err = getBinaries(typeConf, nodetype)
 
-	// Checks that the executables we need for the section are running.
-	if err != nil {
-		exitWithError(fmt.Errorf("failed to get a set of executables needed for tests: %v", err))
+		// Checks that the executables we need for the section are running.
+		if err != nil {
+			exitWithError(fmt.Errorf("failed to get a set of executables needed for tests: %v", err))
+		}
+	}
+
+	if nodetype == check.NODE {
+		in, err = addDockerChecks(in, binmap)
+		if err != nil {
+			exitWithError(fmt.Errorf("error adding the Docker checks: %v", err))
+		}
 	}
 
 	confmap := getFiles(typeConf, "config")
+	componentconfmap := getFiles(typeConf, "componentconfig")
 	svcmap := getFiles(typeConf, "service")
 	kubeconfmap := getFiles(typeConf, "kubeconfig")
 	cafilemap := getFiles(typeConf, "ca")
+	if nodetype == check.NODE {
+		useKubeProxyPodFiles(confmap, kubeconfmap)
+	}
+	instance := nodetype == check.NODE && kubeletInstance != nil
+	if instance {
+		useKubeletInstanceFiles(confmap, svcmap, kubeconfmap, cafilemap)
+	}
 
 	// Variable substitutions. Replace all occurrences of variables in controls files.
 	s := string(in)
+	if instance {
+		s = useKubeletInstanceAudits(s)
+	}
 	s = makeSubstitutions(s, "bin", binmap)
+	// $<component>config must be substituted before $<component>conf.
+	s = makeSubstitutions(s, "config", componentconfmap)
 	s = makeSubstitutions(s, "conf", confmap)
 	s = makeSubstitutions(s, "svc", svcmap)
 	s = makeSubstitutions(s, "kubeconfig", kubeconfmap)
 	s = makeSubstitutions(s, "cafile", cafilemap)
+	s = makeSubstitutions(s, "", nodeVariables(metadata))
+
+	if recording != nil {
+		recording.Metadata.Node = metadata
+		recording.addControls(nodetype, testYamlFile, s, confmap, componentconfmap, svcmap, kubeconfmap, cafilemap)
+	}
 
 	controls, err := check.NewControls(nodetype, []byte(s))
 	if err != nil {
 		exitWithError(fmt.Errorf("error setting up %s controls: %v", nodetype, err))
 	}
+	controls.Metadata = resultMetadata(metadata)
+	controls.RunID = currentRunID()
+	ignoreChecks(controls, metadata)
+
+	if remote {
+		if err := useRemoteEtcdMember(controls); err != nil {
+			exitWithError(fmt.Errorf("error setting up %s controls: %v", nodetype, err))
+		}
+	}
+	if instance {
+		controls.Instance = kubeletInstanceName()
+		controls.Text = fmt.Sprintf("%s (%s)", controls.Text, kubeletInstanceLabel())
+	}
+	if label := namespaceScopeLabel(namespaces, namespaceSelector); label != "" && nodetype == check.POLICIES {
+		controls.Text = fmt.Sprintf("%s (%s)", controls.Text, label)
+	}
+	if hostRoot != "" {
+		useHostRoot(controls)
+	}
+
+	if err := translateControls(controls, testYamlFile, language); err != nil {
+		exitWithError(fmt.Errorf("error translating %s controls: %v", nodetype, err))
+	}
+
+	// Mock results don't use any file of the host.
+	if mockMode == "" && !remote {
+		if raw, err := check.NewControls(nodetype, in); err == nil {
+			addMissingFileErrors(controls, raw, []fileVariables{
+				{ext: "config", fileType: "componentconfig", files: componentconfmap},
+				{ext: "conf", fileType: "config", files: confmap},
+				{ext: "svc", fileType: "service", files: svcmap},
+				{ext: "kubeconfig", fileType: "kubeconfig", files: kubeconfmap},
+				{ext: "cafile", fileType: "ca", files: cafilemap},
+			})
+		}
+		for _, e := range markUnsubstitutedChecks(controls) {
+			continueWithError(nil, fmt.Sprintf("Substitution failed, %s: these checks generate WARN", e.Message))
+		}
+	}
+
+	if useSudo {
+		for _, g := range controls.Groups {
+			for _, c := range g.Checks {
+				c.UseSudo = true
+			}
+		}
+	}
+
+	runner, err := newRunner()
+	if err != nil {
+		exitWithError(fmt.Errorf("error setting up runner: %v", err))
+	}
 
-	runner := check.NewRunner()
 	filter, err := NewRunFilter(filterOpts)
 	if err != nil {
 		exitWithError(fmt.Errorf("error setting up run filter: %v", err))
 	}
 
-	summary = controls.RunChecks(runner, filter)
+	// Mock results don't run any audit.
+	if mockMode == "" {
+		reportMissingDependencies(os.Stderr, controls, filter)
+		if err := checkOfflineControls(controls, filter); err != nil {
+			exitWithError(err)
+		}
+	}
 
-	if (summary.Fail > 0 || summary.Warn > 0 || summary.Pass > 0 || summary.Info > 0) && junitFmt {
+	return &target{
+		controls: controls,
+		runner:   runner,
+		filter:   filter,
+		files:    []map[string]string{confmap, componentconfmap, svcmap, kubeconfmap, cafilemap},
+	}
+}
+
+// run runs the checks of the target.
+func (t *target) run() {
+	t.critical = quarantineCriticalChecks(t.controls)
+	t.summary = t.controls.RunChecks(t.runner, t.filter)
+}
+
+// report adds the results of the target to the ones of the run, and
+// outputs them.
+func (t *target) report() {
+	setExpiry(t.controls)
+	addToHistory(t.controls)
+	addToSummaryFile(t.controls)
+	addToMetrics(t.controls)
+	addToPermissionSummary(t.controls)
+	addToRemediationPlan(t.controls)
+	addToPolicyResults(t.controls)
+	addToNodeAnnotation(t.controls)
+	addToQuarantine(t.critical)
+	addToServerScan(t.controls)
+	addToSeverityExitCode(t.controls)
+	addToWatch(t.controls, t.runner, t.files...)
+	addToTotalSummary(t.summary)
+	writeOutput(t.controls, t.summary)
+	if showTimings {
+		printTimings(os.Stderr, t.controls)
+	}
+}
+
+// writeOutput outputs the results of a set of controls in the requested format.
+func writeOutput(controls *check.Controls, summary check.Summary) {
+	if (summary.Fail > 0 || summary.Warn > 0 || summary.Pass > 0 || summary.Info > 0 || summary.Skip > 0) && junitFmt {
 		out, err := controls.JUnit()
 		if err != nil {
 			exitWithError(fmt.Errorf("failed to output in JUnit format: %v", err))
@@ -127,16 +305,34 @@ func runChecks(nodetype check.NodeType, testYamlFile string) {
 
 		PrintOutput(string(out), outputFile)
 		// if we successfully ran some tests and it's json format, ignore the warnings
-	} else if (summary.Fail > 0 || summary.Warn > 0 || summary.Pass > 0 || summary.Info > 0) && jsonFmt {
+	} else if (summary.Fail > 0 || summary.Warn > 0 || summary.Pass > 0 || summary.Info > 0 || summary.Skip > 0) && jsonFmt {
 		out, err := controls.JSON()
 		if err != nil {
 			exitWithError(fmt.Errorf("failed to output in JSON format: %v", err))
 		}
 
 		PrintOutput(string(out), outputFile)
+	} else if (summary.Fail > 0 || summary.Warn > 0 || summary.Pass > 0 || summary.Info > 0 || summary.Skip > 0) && gitlabFmt {
+		out, err := gitlabReportJSON(controls)
+		if err != nil {
+			exitWithError(fmt.Errorf("failed to output in GitLab security report format: %v", err))
+		}
+
+		PrintOutput(string(out), outputFile)
+	} else if (summary.Fail > 0 || summary.Warn > 0 || summary.Pass > 0 || summary.Info > 0 || summary.Skip > 0) && sonarQubeFmt {
+		out, err := sonarQubeIssuesJSON(controls)
+		if err != nil {
+			exitWithError(fmt.Errorf("failed to output in SonarQube generic issue format: %v", err))
+		}
+
+		PrintOutput(string(out), outputFile)
+	} else if (summary.Fail > 0 || summary.Warn > 0 || summary.Pass > 0 || summary.Info > 0 || summary.Skip > 0) && githubFmt {
+		if err := writeGitHubOutput(controls, summary); err != nil {
+			exitWithError(fmt.Errorf("failed to output in GitHub Actions format: %v", err))
+		}
 	} else {
 		// if we want to store in PostgreSQL, convert to JSON and save it
-		if (summary.Fail > 0 || summary.Warn > 0 || summary.Pass > 0 || summary.Info > 0) && pgSQL {
+		if (summary.Fail > 0 || summary.Warn > 0 || summary.Pass > 0 || summary.Info > 0 || summary.Skip > 0) && pgSQL {
 			out, err := controls.JSON()
 			if err != nil {
 				exitWithError(fmt.Errorf("failed to output in JSON format: %v", err))
@@ -163,7 +359,11 @@ func prettyPrint(r *check.Controls, summary check.Summary) {
 		for _, g := range r.Groups {
 			colorPrint(check.INFO, fmt.Sprintf("%s %s\n", g.ID, g.Text))
 			for _, c := range g.Checks {
-				colorPrint(c.State, fmt.Sprintf("%s %s\n", c.ID, c.Text))
+				if c.Flaky {
+					colorPrint(c.State, fmt.Sprintf("%s %s (flaky)\n", reportedID(c), c.Text))
+				} else {
+					colorPrint(c.State, fmt.Sprintf("%s %s\n", reportedID(c), c.Text))
+				}
 
 				if includeTestOutput && c.State == check.FAIL && len(c.ActualValue) > 0 {
 					printRawOutput(c.ActualValue)
@@ -175,22 +375,25 @@ func prettyPrint(r *check.Controls, summary check.Summary) {
 	}
 
 	// Print remediations.
-	if !noRemediations {
+	if !noRemediations && !remediationPlan {
 		if summary.Fail > 0 || summary.Warn > 0 {
 			colors[check.WARN].Printf("== Remediations ==\n")
 			for _, g := range r.Groups {
 				for _, c := range g.Checks {
 					if c.State == check.FAIL {
-						fmt.Printf("%s %s\n", c.ID, c.Remediation)
+						fmt.Printf("%s %s\n", reportedID(c), c.Remediation)
 					}
 					if c.State == check.WARN {
 						// Print the error if test failed due to problem with the audit command
-						if c.Reason != "" && c.Type != "manual"{
-							fmt.Printf("%s audit test did not run: %s\n", c.ID, c.Reason)
+						if c.Reason != "" && c.Type != check.MANUAL {
+							fmt.Printf("%s audit test did not run: %s\n", reportedID(c), c.Reason)
 						} else {
-							fmt.Printf("%s %s\n", c.ID, c.Remediation)
+							fmt.Printf("%s %s\n", reportedID(c), c.Remediation)
 						}
 					}
+					if (c.State == check.FAIL || c.State == check.WARN) && c.Reference != "" {
+						fmt.Printf("See %s\n", c.Reference)
+					}
 				}
 			}
 			fmt.Println()
@@ -209,9 +412,12 @@ func prettyPrint(r *check.Controls, summary check.Summary) {
 		}
 
 		colors[res].Printf("== Summary ==\n")
-		fmt.Printf("%d checks PASS\n%d checks FAIL\n%d checks WARN\n%d checks INFO\n",
-			summary.Pass, summary.Fail, summary.Warn, summary.Info,
+		fmt.Printf("%d checks PASS\n%d checks FAIL\n%d checks WARN\n%d checks INFO\n%d checks SKIP\n",
+			summary.Pass, summary.Fail, summary.Warn, summary.Info, summary.Skip,
 		)
+		for _, g := range summary.Groups {
+			fmt.Printf("%s %s: %d PASS, %d FAIL, %d WARN, %d INFO, %d SKIP\n", g.ID, g.Text, g.Pass, g.Fail, g.Warn, g.Info, g.Skip)
+		}
 	}
 }
 
@@ -235,6 +441,8 @@ func loadConfig(nodetype check.NodeType) string {
 		file = policiesFile
 	case check.MANAGEDSERVICES:
 		file = managedservicesFile
+	default:
+		file = string(nodetype) + ".yaml"
 	}
 
 	benchmarkVersion, err := getBenchmarkVersion(kubeVersion, benchmarkVersion, viper.GetViper())
@@ -243,6 +451,11 @@ func loadConfig(nodetype check.NodeType) string {
 	}
 
 	path, err := getConfigFilePath(benchmarkVersion, file)
+	if err != nil && controlsFile != "" && !builtinTarget(nodetype) {
+		// The controls of an extra target given with --controls need no
+		// controls file in the benchmark directory.
+		path, err = filepath.Join(cfgDir, benchmarkVersion), nil
+	}
 	if err != nil {
 		exitWithError(fmt.Errorf("can't find %s controls file in %s: %v", nodetype, cfgDir, err))
 	}
@@ -250,6 +463,11 @@ func loadConfig(nodetype check.NodeType) string {
 	// Merge version-specific config if any.
 	mergeConfig(path)
 
+	// The controls given with --controls replace the ones of the benchmark.
+	if controlsFile != "" {
+		return controlsFile
+	}
+
 	return filepath.Join(path, file)
 }
 
@@ -284,6 +502,11 @@ func mapToBenchmarkVersion(kubeToBenchmarkMap map[string]string, kv string) (str
 		glog.V(3).Info(fmt.Sprintf("mapToBenchmarkVersion kubeToBenchmarkSMap: %#v", kubeToBenchmarkMap))
 		return "", fmt.Errorf("unable to find a matching Benchmark Version match for kubernetes version: %s", kvOriginal)
 	}
+	// A cluster newer than the versions mapped gets the latest benchmark
+	// known, which may not cover it.
+	if kv != kvOriginal {
+		glog.Warningf("No benchmark mapped to Kubernetes %s, using %s of Kubernetes %s", kvOriginal, cisVersion, kv)
+	}
 
 	return cisVersion, nil
 }
@@ -304,6 +527,9 @@ func getBenchmarkVersion(kubeVersion, benchmarkVersion string, v *viper.Viper) (
 
 	if isEmpty(benchmarkVersion) {
 		if isEmpty(kubeVersion) {
+			if mockMode != "" {
+				return "", fmt.Errorf("--version or --benchmark must be specified with --mock")
+			}
 			kubeVersion, err = getKubeVersion()
 			if err != nil {
 				return "", fmt.Errorf("Version check failed: %s\nAlternatively, you can specify the version with --version", err)
@@ -339,6 +565,11 @@ func isEtcd() bool {
 }
 
 func isThisNodeRunning(nodeType check.NodeType) bool {
+	if mockMode != "" {
+		// Mock results are produced for every node type.
+		return true
+	}
+
 	glog.V(2).Infof("Checking if the current node is running %s components", nodeType)
 	etcdConf := viper.Sub(string(nodeType))
 	if etcdConf == nil {
@@ -398,8 +629,8 @@ var benchmarkVersionToTargetsMap = map[string][]string{
 // validTargets helps determine if the targets
 // are legitimate for the benchmarkVersion.
 func validTargets(benchmarkVersion string, targets []string) bool {
-	providedTargets, found := benchmarkVersionToTargetsMap[benchmarkVersion]
-	if !found {
+	providedTargets := availableTargets(benchmarkVersion)
+	if len(providedTargets) == 0 {
 		return false
 	}
 
@@ -419,3 +650,45 @@ func validTargets(benchmarkVersion string, targets []string) bool {
 
 	return true
 }
+
+// availableTargets returns the targets of the benchmarkVersion followed by
+// its extra targets.
+func availableTargets(benchmarkVersion string) []string {
+	targets := append([]string{}, benchmarkVersionToTargetsMap[benchmarkVersion]...)
+	return append(targets, extraTargets(benchmarkVersion)...)
+}
+
+// extraTargets returns the targets of the controls files in the directory of
+// the benchmarkVersion which are not targets of the benchmark itself, such as
+// plugin control sets, or all of them for a benchmark of your own. Any
+// controls file named <target>.yaml is run as the checks of that target.
+func extraTargets(benchmarkVersion string) []string {
+	files, err := getYamlFilesFromDir(filepath.Join(cfgDir, benchmarkVersion))
+	if err != nil {
+		return nil
+	}
+
+	known := map[string]bool{}
+	for _, t := range benchmarkVersionToTargetsMap[benchmarkVersion] {
+		known[t] = true
+	}
+
+	var extra []string
+	for _, file := range files {
+		target := strings.TrimSuffix(filepath.Base(file), ".yaml")
+		if !known[target] {
+			extra = append(extra, target)
+		}
+	}
+	return extra
+}
+
+// builtinTarget tells if the target is one of the benchmarks rather than an
+// extra target.
+func builtinTarget(nodetype check.NodeType) bool {
+	switch nodetype {
+	case check.MASTER, check.NODE, check.CONTROLPLANE, check.ETCD, check.POLICIES, check.MANAGEDSERVICES:
+		return true
+	}
+	return false
+}
@@ -98,6 +98,42 @@ func TestNewRunFilter(t *testing.T) {
 			Check:      &check.Check{ID: "C2"},
 			Expected:   false,
 		},
+
+		{
+			Name:       "Should return false when skip-group flag contains group's ID",
+			FilterOpts: FilterOpts{Scored: true, Unscored: true, SkipGroupList: "G1,G2"},
+			Group:      &check.Group{ID: "G2"},
+			Check:      &check.Check{ID: "C1"},
+			Expected:   false,
+		},
+		{
+			Name:       "Should return true when skip-group flag doesn't contain group's ID",
+			FilterOpts: FilterOpts{Scored: true, Unscored: true, SkipGroupList: "G1,G3"},
+			Group:      &check.Group{ID: "G2"},
+			Check:      &check.Check{ID: "C1"},
+			Expected:   true,
+		},
+		{
+			Name:       "Should return false when skip-check flag contains check's ID",
+			FilterOpts: FilterOpts{Scored: true, Unscored: true, SkipCheckList: "C1, C2"},
+			Group:      &check.Group{},
+			Check:      &check.Check{ID: "C2"},
+			Expected:   false,
+		},
+		{
+			Name:       "Should return false when a check of an included group is skipped",
+			FilterOpts: FilterOpts{Scored: true, Unscored: true, GroupList: "G2", SkipCheckList: "C2"},
+			Group:      &check.Group{ID: "G2"},
+			Check:      &check.Check{ID: "C2"},
+			Expected:   false,
+		},
+		{
+			Name:       "Should return true when skip-check flag doesn't contain check's ID",
+			FilterOpts: FilterOpts{Scored: true, Unscored: true, SkipCheckList: "C1,C3"},
+			Group:      &check.Group{},
+			Check:      &check.Check{ID: "C2"},
+			Expected:   true,
+		},
 	}
 
 	for _, testCase := range testCases {
@@ -107,13 +143,16 @@ func TestNewRunFilter(t *testing.T) {
 		})
 	}
 
-	t.Run("Should return error when both group and check flags are used", func(t *testing.T) {
+	t.Run("Should select the checks of the groups and the checks listed when both group and check flags are used", func(t *testing.T) {
 		// given
-		opts := FilterOpts{GroupList: "G1", CheckList: "C1"}
+		opts := FilterOpts{Scored: true, Unscored: true, GroupList: "G1", CheckList: "C3,C4"}
 		// when
-		_, err := NewRunFilter(opts)
+		filter, err := NewRunFilter(opts)
 		// then
-		assert.EqualError(t, err, "group option and check option can't be used together")
+		assert.NoError(t, err)
+		assert.True(t, filter(&check.Group{ID: "G1"}, &check.Check{ID: "C1"}))
+		assert.True(t, filter(&check.Group{ID: "G2"}, &check.Check{ID: "C3"}))
+		assert.False(t, filter(&check.Group{ID: "G2"}, &check.Check{ID: "C2"}))
 	})
 
 }
@@ -408,6 +447,40 @@ func TestValidTargets(t *testing.T) {
 	}
 }
 
+func TestExtraTargets(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-test")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	defer func(old string) { cfgDir = old }(cfgDir)
+	cfgDir = dir
+
+	for _, file := range []string{"cis-1.5/master.yaml", "cis-1.5/node.yaml", "cis-1.5/federated.yaml", "cis-1.5/config.yaml", "custom/node.yaml", "custom/plugin.yaml"} {
+		path := filepath.Join(dir, file)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := ioutil.WriteFile(path, []byte("controls:\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	assert.Equal(t, []string{"federated"}, extraTargets("cis-1.5"))
+	if !validTargets("cis-1.5", []string{"master", "federated"}) {
+		t.Errorf("expected the federated target to be valid")
+	}
+	if validTargets("cis-1.5", []string{"plugin"}) {
+		t.Errorf("expected the plugin target to be invalid for cis-1.5")
+	}
+	if !validTargets("custom", []string{"node", "plugin"}) {
+		t.Errorf("expected the targets of the controls files of custom to be valid")
+	}
+	if validTargets("missing", []string{"master"}) {
+		t.Errorf("expected no valid targets for a missing benchmark")
+	}
+}
+
 func TestIsEtcd(t *testing.T) {
 	testCases := []struct {
 		name            string
@@ -0,0 +1,85 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sync"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"gopkg.in/yaml.v2"
+)
+
+// stdinControlsFile is the name of the controls file given on stdin.
+const stdinControlsFile = "-"
+
+var (
+	stdinControlsOnce sync.Once
+	// stdinControls is the controls document read from stdin, kept for the
+	// scans of serve.
+	stdinControls    []byte
+	stdinControlsErr error
+)
+
+// readControls reads a controls file, or the controls document given on
+// stdin for "-".
+func readControls(file string) ([]byte, error) {
+	if file != stdinControlsFile {
+		return ioutil.ReadFile(file)
+	}
+
+	stdinControlsOnce.Do(func() {
+		stdinControls, stdinControlsErr = ioutil.ReadAll(os.Stdin)
+	})
+	return stdinControls, stdinControlsErr
+}
+
+// controlsType returns the type of the controls of a file, which tells the
+// target they are for.
+func controlsType(file string) (check.NodeType, error) {
+	in, err := readControls(file)
+	if err != nil {
+		return "", fmt.Errorf("error opening %s test file: %v", file, err)
+	}
+
+	var controls struct {
+		Type check.NodeType `yaml:"type"`
+	}
+	if err := yaml.Unmarshal(in, &controls); err != nil {
+		return "", fmt.Errorf("error reading %s test file: %v", file, err)
+	}
+
+	// Controls of another type than the benchmark targets are run as an
+	// extra target.
+	if controls.Type == "" {
+		return "", fmt.Errorf("no type of the controls of %s", file)
+	}
+	return controls.Type, nil
+}
+
+// runControls runs the checks of the controls given with --controls, as the
+// target of their type.
+func runControls() {
+	nodetype, err := controlsType(controlsFile)
+	if err != nil {
+		exitWithError(err)
+	}
+
+	runTargets(func() {
+		runTarget(nodetype, loadConfig(nodetype))
+	})
+}
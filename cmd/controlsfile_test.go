@@ -0,0 +1,91 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sync"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+func TestReadControlsFromStdin(t *testing.T) {
+	f, err := ioutil.TempFile("", "kube-bench-stdin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.Remove(f.Name())
+	if _, err := f.WriteString("controls:\ntype: policies\n"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.Seek(0, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(stdin *os.File) {
+		os.Stdin = stdin
+		stdinControlsOnce, stdinControls, stdinControlsErr = sync.Once{}, nil, nil
+	}(os.Stdin)
+	os.Stdin = f
+	stdinControlsOnce = sync.Once{}
+
+	// The document is read once, for the type and for each scan.
+	for i := 0; i < 2; i++ {
+		in, err := readControls("-")
+		if err != nil || string(in) != "controls:\ntype: policies\n" {
+			t.Errorf("unexpected controls %q, %v", in, err)
+		}
+	}
+	if nodetype, err := controlsType("-"); err != nil || nodetype != check.POLICIES {
+		t.Errorf("expected policies controls, got %q, %v", nodetype, err)
+	}
+}
+
+func TestControlsType(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-controls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	cases := []struct {
+		controls string
+		expected check.NodeType
+		fail     bool
+	}{
+		{"controls:\ntype: \"node\"\ngroups: []\n", check.NODE, false},
+		{"controls:\ntype: etcd\n", check.ETCD, false},
+		{"controls:\ntype: federated\n", check.NodeType("federated"), false},
+		{"controls:\ngroups: []\n", "", true},
+		{"type: [", "", true},
+	}
+	for i, c := range cases {
+		file := filepath.Join(dir, "controls.yaml")
+		if err := ioutil.WriteFile(file, []byte(c.controls), 0644); err != nil {
+			t.Fatal(err)
+		}
+		nodetype, err := controlsType(file)
+		if (err != nil) != c.fail || nodetype != c.expected {
+			t.Errorf("case %d: unexpected type %q, %v", i, nodetype, err)
+		}
+	}
+
+	if _, err := controlsType(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Errorf("expected an error for a missing file")
+	}
+}
@@ -0,0 +1,223 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+	"gopkg.in/yaml.v2"
+)
+
+// recommendationsFile lists the recommendations of a benchmark, in the
+// benchmark subdirectory of its config directory.
+var recommendationsFile = filepath.Join("benchmark", "recommendations.yaml")
+
+const (
+	// coverageAutomated recommendations are checked by audits.
+	coverageAutomated = "automated"
+	// coverageManual recommendations have manual or skipped checks, that
+	// must be reviewed by hand.
+	coverageManual = "manual"
+	// coverageMissing recommendations have no check.
+	coverageMissing = "missing"
+)
+
+// recommendation is a recommendation of a benchmark.
+type recommendation struct {
+	ID   string `yaml:"id" json:"id"`
+	Text string `yaml:"text" json:"text"`
+}
+
+// recommendationCoverage tells how a recommendation is checked.
+type recommendationCoverage struct {
+	recommendation
+	Coverage string `json:"coverage"`
+	// Targets are the targets whose controls check the recommendation.
+	Targets []string `json:"targets,omitempty"`
+}
+
+// benchmarkCoverage reports how the controls of a benchmark cover its
+// recommendations.
+type benchmarkCoverage struct {
+	Benchmark       string                   `json:"benchmark"`
+	Name            string                   `json:"name,omitempty"`
+	Automated       int                      `json:"automated"`
+	Manual          int                      `json:"manual"`
+	Missing         int                      `json:"missing"`
+	Recommendations []recommendationCoverage `json:"recommendations"`
+	// Extra are the checks that are not recommendations of the benchmark.
+	Extra []benchmarkCheck `json:"extra,omitempty"`
+}
+
+// coverageCmd represents the coverage command
+var coverageCmd = &cobra.Command{
+	Use:   "coverage",
+	Short: "Report which recommendations of the benchmark are checked automatically",
+	Long: `Compare the controls of the benchmark with the full list of its recommendations,
+and report which recommendations are automated, which are manual and which are missing
+from the controls, without running any check. The benchmark is chosen as for a scan, use
+--benchmark or --version to pick another one.`,
+	Args: cobra.NoArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		benchmarkVersion, err := getBenchmarkVersion(kubeVersion, benchmarkVersion, viper.GetViper())
+		if err != nil {
+			exitWithError(fmt.Errorf("unable to determine benchmark version: %v", err))
+		}
+
+		coverage, err := coverBenchmark(benchmarkVersion)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		if jsonFmt {
+			out, err := json.MarshalIndent(coverage, "", "  ")
+			if err != nil {
+				exitWithError(fmt.Errorf("failed to output in JSON format: %v", err))
+			}
+			PrintOutput(string(out), outputFile)
+			return
+		}
+		printCoverage(os.Stdout, coverage)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(coverageCmd)
+}
+
+// loadRecommendations reads the list of the recommendations of a benchmark.
+func loadRecommendations(benchmark string) (string, []recommendation, error) {
+	file := filepath.Join(cfgDir, benchmark, recommendationsFile)
+	in, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", nil, fmt.Errorf("no list of the recommendations of benchmark %s: %v", benchmark, err)
+	}
+
+	var list struct {
+		Benchmark       string           `yaml:"benchmark"`
+		Recommendations []recommendation `yaml:"recommendations"`
+	}
+	if err := yaml.Unmarshal(in, &list); err != nil {
+		return "", nil, fmt.Errorf("error reading %s: %v", file, err)
+	}
+	return list.Benchmark, list.Recommendations, nil
+}
+
+// coverBenchmark compares the controls of a benchmark with its
+// recommendations. A recommendation checked by several targets is automated
+// when any of them audits it.
+func coverBenchmark(benchmark string) (*benchmarkCoverage, error) {
+	name, recommendations, err := loadRecommendations(benchmark)
+	if err != nil {
+		return nil, err
+	}
+	checks, err := loadBenchmarkChecks(benchmark)
+	if err != nil {
+		return nil, err
+	}
+
+	byID := map[string][]benchmarkCheck{}
+	for _, c := range checks {
+		byID[c.ID] = append(byID[c.ID], c)
+	}
+
+	coverage := &benchmarkCoverage{Benchmark: benchmark, Name: name}
+	listed := map[string]bool{}
+	for _, r := range recommendations {
+		listed[r.ID] = true
+		rc := recommendationCoverage{recommendation: r, Coverage: coverageMissing}
+
+		found := byID[r.ID]
+		sortBenchmarkChecks(found)
+		for _, c := range found {
+			rc.Targets = append(rc.Targets, c.Target)
+			if c.check.Type != check.MANUAL && c.check.Type != "skip" {
+				rc.Coverage = coverageAutomated
+			} else if rc.Coverage == coverageMissing {
+				rc.Coverage = coverageManual
+			}
+		}
+
+		switch rc.Coverage {
+		case coverageAutomated:
+			coverage.Automated++
+		case coverageManual:
+			coverage.Manual++
+		default:
+			coverage.Missing++
+		}
+		coverage.Recommendations = append(coverage.Recommendations, rc)
+	}
+
+	for _, c := range checks {
+		if !listed[c.ID] {
+			coverage.Extra = append(coverage.Extra, c)
+		}
+	}
+	sortBenchmarkChecks(coverage.Extra)
+	return coverage, nil
+}
+
+func printCoverage(w io.Writer, c *benchmarkCoverage) {
+	name := c.Benchmark
+	if c.Name != "" {
+		name = fmt.Sprintf("%s (%s)", c.Name, c.Benchmark)
+	}
+	total := len(c.Recommendations)
+	fmt.Fprintf(w, "Coverage of %s: %d recommendations\n", name, total)
+	fmt.Fprintf(w, "%d automated (%s)\n", c.Automated, percentage(c.Automated, total))
+	fmt.Fprintf(w, "%d manual (%s)\n", c.Manual, percentage(c.Manual, total))
+	fmt.Fprintf(w, "%d missing (%s)\n", c.Missing, percentage(c.Missing, total))
+
+	for _, section := range []struct {
+		coverage, title string
+		count           int
+	}{
+		{coverageManual, "Manual recommendations", c.Manual},
+		{coverageMissing, "Recommendations missing from the controls", c.Missing},
+	} {
+		if section.count == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "\n%s (%d):\n", section.title, section.count)
+		for _, r := range c.Recommendations {
+			if r.Coverage == section.coverage {
+				fmt.Fprintf(w, "  %s %s\n", r.ID, r.Text)
+			}
+		}
+	}
+
+	if len(c.Extra) > 0 {
+		fmt.Fprintf(w, "\nChecks that are not recommendations of the benchmark (%d):\n", len(c.Extra))
+		for _, e := range c.Extra {
+			fmt.Fprintf(w, "  [%s] %s %s\n", e.Target, e.ID, e.Text)
+		}
+	}
+}
+
+func percentage(n, total int) string {
+	if total == 0 {
+		return "0%"
+	}
+	return fmt.Sprintf("%.0f%%", float64(n)*100/float64(total))
+}
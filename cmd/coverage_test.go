@@ -0,0 +1,122 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+const coverageControls = `---
+controls:
+id: 1
+text: "Master Node Security Configuration"
+type: "master"
+groups:
+  - id: 1.1
+    text: "Master Node Configuration Files"
+    checks:
+      - id: 1.1.1
+        text: "Ensure that the API server pod specification file permissions are set to 644 or more restrictive (Scored)"
+        audit: "stat -c %a /etc/kubernetes/manifests/kube-apiserver.yaml"
+        scored: true
+      - id: 1.1.9
+        text: "Ensure that the Container Network Interface file permissions are set to 644 or more restrictive (Not Scored)"
+        type: "manual"
+        scored: false
+      - id: 1.1.99
+        text: "Ensure that the custom file permissions are set to 600 (Scored)"
+        audit: "stat -c %a /etc/custom"
+        scored: true
+`
+
+const coverageRecommendations = `---
+benchmark: "CIS Kubernetes Benchmark v1.5.1"
+recommendations:
+  - id: 1.1.1
+    text: "Ensure that the API server pod specification file permissions are set to 644 or more restrictive (Scored)"
+  - id: 1.1.2
+    text: "Ensure that the API server pod specification file ownership is set to root:root (Scored)"
+  - id: 1.1.9
+    text: "Ensure that the Container Network Interface file permissions are set to 644 or more restrictive (Not Scored)"
+`
+
+func TestCoverBenchmark(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-coverage")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.MkdirAll(filepath.Join(dir, "cis-1.5", "benchmark"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "cis-1.5", "master.yaml"), []byte(coverageControls), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "cis-1.5", recommendationsFile), []byte(coverageRecommendations), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(d string) { cfgDir = d }(cfgDir)
+	cfgDir = dir
+
+	if _, err := coverBenchmark("cis-1.4"); err == nil {
+		t.Errorf("expected an error for a benchmark without recommendations")
+	}
+
+	coverage, err := coverBenchmark("cis-1.5")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var out bytes.Buffer
+	printCoverage(&out, coverage)
+	expected := `Coverage of CIS Kubernetes Benchmark v1.5.1 (cis-1.5): 3 recommendations
+1 automated (33%)
+1 manual (33%)
+1 missing (33%)
+
+Manual recommendations (1):
+  1.1.9 Ensure that the Container Network Interface file permissions are set to 644 or more restrictive (Not Scored)
+
+Recommendations missing from the controls (1):
+  1.1.2 Ensure that the API server pod specification file ownership is set to root:root (Scored)
+
+Checks that are not recommendations of the benchmark (1):
+  [master] 1.1.99 Ensure that the custom file permissions are set to 600 (Scored)
+`
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestShippedRecommendations(t *testing.T) {
+	defer func(d string) { cfgDir = d }(cfgDir)
+	cfgDir = "../cfg"
+
+	for _, benchmark := range []string{"cis-1.3", "cis-1.4", "cis-1.5", "gke-1.0"} {
+		coverage, err := coverBenchmark(benchmark)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", benchmark, err)
+		}
+		if coverage.Missing != 0 || len(coverage.Extra) != 0 {
+			t.Errorf("%s: the recommendations and controls differ", benchmark)
+		}
+	}
+}
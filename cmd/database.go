@@ -46,6 +46,7 @@ func savePgsql(jsonInfo string) {
 		ScanHost string    `gorm:"type:varchar(63) not null"` // https://www.ietf.org/rfc/rfc1035.txt
 		ScanTime time.Time `gorm:"not null"`
 		ScanInfo string    `gorm:"type:jsonb not null"`
+		RunID    string    `gorm:"type:text"`
 	}
 
 	db, err := gorm.Open("postgres", connInfo)
@@ -55,6 +56,28 @@ func savePgsql(jsonInfo string) {
 	defer db.Close()
 	
 	db.Debug().AutoMigrate(&ScanResult{})
-	db.Save(&ScanResult{ScanHost: hostname, ScanTime: timestamp, ScanInfo: jsonInfo})
+	db.Save(&ScanResult{ScanHost: hostname, ScanTime: timestamp, ScanInfo: jsonInfo, RunID: currentRunID()})
 	glog.V(2).Info(fmt.Sprintf("successfully stored result to: %s", envVars["PGSQL_HOST"]))
+
+	if err := prunePgsql(db, db.NewScope(&ScanResult{}).TableName(), hostname, historyRetention, timestamp); err != nil {
+		exitWithError(fmt.Errorf("received error removing expired results: %s", err))
+	}
+}
+
+// prunePgsql removes the results of the host that the retention policy
+// doesn't keep from the table.
+func prunePgsql(db *gorm.DB, table, host string, p retentionPolicy, now time.Time) error {
+	if cutoff := p.cutoff(now); !cutoff.IsZero() {
+		query := fmt.Sprintf("DELETE FROM %s WHERE scan_host = ? AND scan_time < ?", table)
+		if err := db.Exec(query, host, cutoff).Error; err != nil {
+			return err
+		}
+	}
+	if p.Runs > 0 {
+		query := fmt.Sprintf("DELETE FROM %[1]s WHERE scan_host = ? AND id NOT IN (SELECT id FROM %[1]s WHERE scan_host = ? ORDER BY scan_time DESC LIMIT ?)", table)
+		if err := db.Exec(query, host, host, p.Runs).Error; err != nil {
+			return err
+		}
+	}
+	return nil
 }
@@ -0,0 +1,45 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+// reportMissingDependencies lists, before the checks of the controls run,
+// the commands their audits run that are not available, with the checks
+// that need them. Those checks generate WARN.
+func reportMissingDependencies(w io.Writer, controls *check.Controls, filter check.Predicate) {
+	missing := controls.MissingDependencies(filter)
+	if len(missing) == 0 {
+		return
+	}
+
+	names := make([]string, 0, len(missing))
+	for name := range missing {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	fmt.Fprintf(w, "Missing dependencies of the checks of %s %s:\n", controls.ID, controls.Text)
+	for _, name := range names {
+		fmt.Fprintf(w, "%s: %s\n", name, strings.Join(missing[name], ", "))
+	}
+}
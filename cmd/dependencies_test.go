@@ -0,0 +1,47 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+func TestReportMissingDependencies(t *testing.T) {
+	all := func(*check.Group, *check.Check) bool { return true }
+	controls := &check.Controls{ID: "1", Text: "Master Node Security Configuration", Groups: []*check.Group{{ID: "1.1", Checks: []*check.Check{
+		{ID: "1.1.1", Audit: "/bin/sh -c 'kube-bench-missing-jq .a; kube-bench-missing-openssl x509'"},
+		{ID: "1.1.2", Audit: "kube-bench-missing-jq .b"},
+	}}}}
+
+	var out bytes.Buffer
+	reportMissingDependencies(&out, controls, all)
+	expected := `Missing dependencies of the checks of 1 Master Node Security Configuration:
+kube-bench-missing-jq: 1.1.1, 1.1.2
+kube-bench-missing-openssl: 1.1.1
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+
+	out.Reset()
+	controls.Groups[0].Checks = []*check.Check{{ID: "1.1.3", Audit: "echo ok"}}
+	reportMissingDependencies(&out, controls, all)
+	if out.Len() != 0 {
+		t.Errorf("expected nothing reported, got %q", out.String())
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// detectedComponent is what was found of a component on the node.
+type detectedComponent struct {
+	Name     string `json:"name"`
+	Optional bool   `json:"optional,omitempty"`
+	// Binary is the running binary, empty if none of the candidates runs.
+	Binary     string   `json:"binary,omitempty"`
+	Candidates []string `json:"candidates,omitempty"`
+	// Files maps file types to the file found, empty if none of the
+	// candidates exists.
+	Files map[string]string `json:"files,omitempty"`
+}
+
+// detectedTarget is what was found of the components of a target.
+type detectedTarget struct {
+	Name       string              `json:"name"`
+	Running    bool                `json:"running"`
+	Components []detectedComponent `json:"components,omitempty"`
+}
+
+// detectReport is what kube-bench discovered about the node.
+type detectReport struct {
+	KubeVersion    string              `json:"kube_version,omitempty"`
+	Benchmark      string              `json:"benchmark"`
+	ProcessBackend string              `json:"process_backend"`
+	Node           *check.NodeMetadata `json:"node,omitempty"`
+	Targets        []detectedTarget    `json:"targets"`
+}
+
+// detectCmd represents the detect command
+var detectCmd = &cobra.Command{
+	Use:   "detect",
+	Short: "Report what kube-bench discovers on the node",
+	Long: `Report what kube-bench discovers on the node without running any check: the detected
+Kubernetes version and selected benchmark, which components are running and which of their
+binaries and files were found. This helps finding out why checks don't run as expected.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		report, err := detect()
+		if err != nil {
+			exitWithError(err)
+		}
+
+		if jsonFmt {
+			out, err := json.MarshalIndent(report, "", "  ")
+			if err != nil {
+				exitWithError(fmt.Errorf("failed to output in JSON format: %v", err))
+			}
+			PrintOutput(string(out), outputFile)
+			return
+		}
+		printDetectReport(os.Stdout, report)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(detectCmd)
+}
+
+func detect() (*detectReport, error) {
+	report := &detectReport{
+		KubeVersion:    kubeVersion,
+		ProcessBackend: viper.GetString("process_backend"),
+		Node:           currentNodeMetadata(),
+	}
+	if report.ProcessBackend == "" {
+		report.ProcessBackend = check.PSBackend
+	}
+
+	if kubeVersion == "" && benchmarkVersion == "" {
+		v, err := getKubeVersion()
+		if err != nil {
+			return nil, fmt.Errorf("unable to detect the Kubernetes version: %v", err)
+		}
+		report.KubeVersion = v
+	}
+
+	bv, err := getBenchmarkVersion(report.KubeVersion, benchmarkVersion, viper.GetViper())
+	if err != nil {
+		return nil, fmt.Errorf("unable to determine benchmark version: %v", err)
+	}
+	report.Benchmark = bv
+
+	if err := mergeConfig(filepath.Join(cfgDir, bv)); err != nil {
+		return nil, err
+	}
+
+	for _, target := range benchmarkVersionToTargetsMap[bv] {
+		report.Targets = append(report.Targets, detectTarget(target))
+	}
+	return report, nil
+}
+
+// detectTarget finds the components of a target configured in config.yaml.
+// The target is running if all of its mandatory components are.
+func detectTarget(target string) detectedTarget {
+	t := detectedTarget{Name: target}
+	v := viper.Sub(target)
+	if v == nil {
+		return t
+	}
+
+	var missing bool
+	for _, name := range v.GetStringSlice("components") {
+		s := v.Sub(name)
+		if s == nil {
+			continue
+		}
+
+		c := detectedComponent{
+			Name:       name,
+			Optional:   s.GetBool("optional"),
+			Candidates: s.GetStringSlice("bins"),
+			Files:      map[string]string{},
+		}
+		if len(c.Candidates) > 0 {
+			c.Binary, _ = findExecutable(c.Candidates)
+			if c.Binary != "" {
+				t.Running = true
+			} else if !c.Optional {
+				missing = true
+			}
+		}
+		for fileType, opts := range TypeMap {
+			if !s.IsSet(opts[0]) {
+				continue
+			}
+			c.Files[fileType] = findConfigFile(s.GetStringSlice(opts[0]))
+		}
+		t.Components = append(t.Components, c)
+	}
+	t.Running = t.Running && !missing
+	return t
+}
+
+func printDetectReport(w io.Writer, r *detectReport) {
+	if r.KubeVersion != "" {
+		fmt.Fprintf(w, "Kubernetes version: %s\n", r.KubeVersion)
+	}
+	fmt.Fprintf(w, "Benchmark: %s\n", r.Benchmark)
+	if r.Node != nil {
+		fmt.Fprintf(w, "Node: %s (hostname %s)\n", r.Node.NodeName, r.Node.Hostname)
+		if r.Node.OS != "" {
+			fmt.Fprintf(w, "OS: %s, kernel %s\n", r.Node.OS, r.Node.KernelVersion)
+		}
+		if r.Node.CloudProvider != "" {
+			fmt.Fprintf(w, "Cloud provider: %s\n", r.Node.CloudProvider)
+		}
+	}
+	fmt.Fprintf(w, "Process backend: %s\n", r.ProcessBackend)
+
+	for _, t := range r.Targets {
+		running := "not running"
+		if t.Running {
+			running = "running"
+		}
+		if len(t.Components) == 0 {
+			running = "no components"
+		}
+		fmt.Fprintf(w, "\n%s: %s\n", t.Name, running)
+
+		for _, c := range t.Components {
+			optional := ""
+			if c.Optional {
+				optional = " (optional)"
+			}
+			switch {
+			case len(c.Candidates) == 0:
+				fmt.Fprintf(w, "  %s%s\n", c.Name, optional)
+			case c.Binary == "":
+				fmt.Fprintf(w, "  %s%s: not running, looked for %v\n", c.Name, optional, c.Candidates)
+			default:
+				fmt.Fprintf(w, "  %s%s: %s\n", c.Name, optional, c.Binary)
+			}
+
+			var types []string
+			for fileType := range c.Files {
+				types = append(types, fileType)
+			}
+			sort.Strings(types)
+			for _, fileType := range types {
+				file := c.Files[fileType]
+				if file == "" {
+					file = "not found"
+				}
+				fmt.Fprintf(w, "    %s: %s\n", fileType, file)
+			}
+		}
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/spf13/viper"
+)
+
+func TestDetectTarget(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-detect")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	kubeletConf := filepath.Join(dir, "kubelet.conf")
+	if err := ioutil.WriteFile(kubeletConf, []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func() { psFunc, statFunc = ps, os.Stat }()
+	psFunc = fakeps
+	statFunc = os.Stat
+	defer viper.Reset()
+
+	viper.Set("node", map[string]interface{}{
+		"components": []string{"kubelet", "proxy"},
+		"kubelet": map[string]interface{}{
+			"bins":       []string{"hyperkube kubelet", "kubelet"},
+			"kubeconfig": []string{filepath.Join(dir, "missing.conf"), kubeletConf},
+		},
+		"proxy": map[string]interface{}{
+			"bins":     []string{"kube-proxy"},
+			"optional": true,
+			"confs":    []string{filepath.Join(dir, "kube-proxy.yaml")},
+		},
+	})
+
+	g = "/usr/bin/kubelet --config=/var/lib/kubelet/config.yaml"
+	expected := detectedTarget{
+		Name:    "node",
+		Running: true,
+		Components: []detectedComponent{
+			{Name: "kubelet", Binary: "kubelet", Candidates: []string{"hyperkube kubelet", "kubelet"}, Files: map[string]string{"kubeconfig": kubeletConf}},
+			{Name: "proxy", Optional: true, Candidates: []string{"kube-proxy"}, Files: map[string]string{"config": ""}},
+		},
+	}
+	got := detectTarget("node")
+	if !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+
+	var out bytes.Buffer
+	printDetectReport(&out, &detectReport{Benchmark: "cis-1.5", ProcessBackend: "ps", Targets: []detectedTarget{got}})
+	for _, line := range []string{
+		"node: running",
+		"  kubelet: kubelet",
+		"    kubeconfig: " + kubeletConf,
+		"  proxy (optional): not running, looked for [kube-proxy]",
+		"    config: not found",
+	} {
+		if !strings.Contains(out.String(), line+"\n") {
+			t.Errorf("expected %q in report:\n%s", line, out.String())
+		}
+	}
+
+	// The kubelet is mandatory.
+	g = ""
+	if got := detectTarget("node"); got.Running {
+		t.Errorf("expected node not to be running without the kubelet")
+	}
+
+	if got := detectTarget("etcd"); got.Running || len(got.Components) != 0 {
+		t.Errorf("expected no etcd components, got %+v", got)
+	}
+}
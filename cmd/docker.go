@@ -0,0 +1,91 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/golang/glog"
+	"gopkg.in/yaml.v2"
+)
+
+// dockerControlsFile holds the checks of the Docker daemon, a subset of the
+// CIS Docker Benchmark, added to the node checks with --docker-checks.
+const dockerControlsFile = "docker.yaml"
+
+// dockerRunning reports whether the Docker daemon was detected among the
+// node components. A missing optional component is given its own name.
+func dockerRunning(binmap map[string]string) bool {
+	bin, ok := binmap["docker"]
+	return ok && bin != "docker"
+}
+
+// addDockerChecks adds the groups of the Docker controls to the node
+// controls, when the checks of the Docker daemon are enabled and Docker is
+// the container runtime.
+func addDockerChecks(in []byte, binmap map[string]string) ([]byte, error) {
+	if !dockerChecks {
+		return in, nil
+	}
+	if !dockerRunning(binmap) {
+		glog.V(1).Info("Docker is not running, skipping the Docker checks")
+		return in, nil
+	}
+
+	file := filepath.Join(cfgDir, dockerControlsFile)
+	docker, err := readControls(file)
+	if err != nil {
+		return nil, fmt.Errorf("error opening %s test file: %v", file, err)
+	}
+	return mergeGroups(in, docker)
+}
+
+// mergeGroups appends the groups of the extra controls to the groups of the
+// controls.
+func mergeGroups(in, extra []byte) ([]byte, error) {
+	var controls, extraControls yaml.MapSlice
+	if err := yaml.Unmarshal(in, &controls); err != nil {
+		return nil, fmt.Errorf("error reading controls: %v", err)
+	}
+	if err := yaml.Unmarshal(extra, &extraControls); err != nil {
+		return nil, fmt.Errorf("error reading extra controls: %v", err)
+	}
+
+	var extraGroups []interface{}
+	for _, item := range extraControls {
+		if item.Key == "groups" {
+			groups, ok := item.Value.([]interface{})
+			if !ok {
+				return nil, fmt.Errorf("invalid groups of extra controls")
+			}
+			extraGroups = groups
+		}
+	}
+
+	merged := false
+	for i, item := range controls {
+		if item.Key != "groups" {
+			continue
+		}
+		groups, _ := item.Value.([]interface{})
+		controls[i].Value = append(groups, extraGroups...)
+		merged = true
+	}
+	if !merged {
+		controls = append(controls, yaml.MapItem{Key: "groups", Value: extraGroups})
+	}
+	return yaml.Marshal(controls)
+}
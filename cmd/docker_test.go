@@ -0,0 +1,103 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+func TestMergeGroups(t *testing.T) {
+	in := []byte(`---
+controls:
+version: 1.5
+id: 4
+text: "Worker Node Security Configuration"
+type: "node"
+groups:
+  - id: 4.1
+    text: "Worker Node Configuration Files"
+    checks:
+      - id: 4.1.1
+        text: "kubelet"
+`)
+	extra := []byte(`---
+id: D
+type: "node"
+groups:
+  - id: D.2
+    text: "Docker Daemon Configuration"
+    checks:
+      - id: D.2.1
+        text: "icc"
+`)
+
+	out, err := mergeGroups(in, extra)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	controls, err := check.NewControls(check.NODE, out)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if controls.ID != "4" || len(controls.Groups) != 2 {
+		t.Fatalf("unexpected controls %s with %d groups", controls.ID, len(controls.Groups))
+	}
+	if g := controls.Groups[1]; g.ID != "D.2" || len(g.Checks) != 1 || g.Checks[0].ID != "D.2.1" {
+		t.Errorf("unexpected group %+v", g)
+	}
+
+	if _, err := mergeGroups(in, []byte("groups: none")); err == nil {
+		t.Errorf("expected an error for invalid groups")
+	}
+}
+
+func TestAddDockerChecks(t *testing.T) {
+	defer func(enabled bool, dir string) { dockerChecks, cfgDir = enabled, dir }(dockerChecks, cfgDir)
+	cfgDir = "../cfg"
+
+	in, err := ioutil.ReadFile("../cfg/cis-1.5/node.yaml")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	running := map[string]string{"kubelet": "kubelet", "docker": "dockerd"}
+
+	cases := []struct {
+		enabled bool
+		binmap  map[string]string
+		groups  int
+	}{
+		{enabled: false, binmap: running, groups: 3},
+		// Docker is not the runtime.
+		{enabled: true, binmap: map[string]string{"kubelet": "kubelet", "docker": "docker"}, groups: 3},
+		{enabled: true, binmap: running, groups: 5},
+	}
+	for _, c := range cases {
+		dockerChecks = c.enabled
+		out, err := addDockerChecks(in, c.binmap)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		controls, err := check.NewControls(check.NODE, out)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if len(controls.Groups) != c.groups {
+			t.Errorf("expected %d groups, got %d", c.groups, len(controls.Groups))
+		}
+	}
+}
@@ -0,0 +1,65 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+	"github.com/spf13/viper"
+)
+
+// etcdMember is the client URL of the remote etcd member the etcd checks are
+// run against, if etcd doesn't run on this node.
+var etcdMember string
+
+// remoteEtcdMembers returns the client URLs of the remote etcd members set
+// with endpoints in the etcd section of the config.
+func remoteEtcdMembers() []string {
+	return viper.GetStringSlice("etcd.endpoints")
+}
+
+// remoteEtcdClientOptions returns the client certificate to connect to the
+// remote etcd members with, as audit options of "etcd" checks.
+func remoteEtcdClientOptions() map[string]string {
+	return map[string]string{
+		"cafile":   viper.GetString("etcd.cafile"),
+		"certfile": viper.GetString("etcd.certfile"),
+		"keyfile":  viper.GetString("etcd.keyfile"),
+	}
+}
+
+// runRemoteEtcdChecks runs the etcd checks against each remote etcd member
+// in turn, each member having its own results.
+func runRemoteEtcdChecks(testYamlFile string, members []string) {
+	defer func() { etcdMember = "" }()
+	for _, member := range members {
+		glog.V(1).Infof("== Running etcd checks against %s ==\n", member)
+		etcdMember = member
+		runChecks(check.ETCD, testYamlFile)
+	}
+}
+
+// useRemoteEtcdMember prepares the etcd controls to be run against the
+// remote etcd member, and labels them with it.
+func useRemoteEtcdMember(controls *check.Controls) error {
+	if err := controls.UseRemoteEtcdMember(etcdMember, remoteEtcdClientOptions()); err != nil {
+		return err
+	}
+	controls.Instance = etcdMember
+	controls.Text = fmt.Sprintf("%s (%s)", controls.Text, etcdMember)
+	return nil
+}
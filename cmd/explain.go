@@ -0,0 +1,135 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// explanation describes a check of a benchmark.
+type explanation struct {
+	Benchmark   string `json:"benchmark"`
+	Target      string `json:"target"`
+	ID          string `json:"id"`
+	CISID       string `json:"cis_id,omitempty"`
+	Text        string `json:"text"`
+	Type        string `json:"type,omitempty"`
+	Scored      bool   `json:"scored"`
+	Rationale   string `json:"rationale,omitempty"`
+	Remediation string `json:"remediation,omitempty"`
+	Reference   string `json:"reference,omitempty"`
+}
+
+// explainCmd represents the explain command
+var explainCmd = &cobra.Command{
+	Use:   "explain <check>",
+	Short: "Describe why a check matters and how to remediate it",
+	Long: `Describe a check of the benchmark, e.g. kube-bench explain 1.2.16: its
+rationale, remediation and reference to the CIS documentation, without running it.
+The benchmark is chosen as for a scan, use --benchmark or --version to pick another one.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		benchmarkVersion, err := getBenchmarkVersion(kubeVersion, benchmarkVersion, viper.GetViper())
+		if err != nil {
+			exitWithError(fmt.Errorf("unable to determine benchmark version: %v", err))
+		}
+
+		explanations, err := explainCheck(benchmarkVersion, args[0])
+		if err != nil {
+			exitWithError(err)
+		}
+
+		if jsonFmt {
+			out, err := json.MarshalIndent(explanations, "", "  ")
+			if err != nil {
+				exitWithError(fmt.Errorf("failed to output in JSON format: %v", err))
+			}
+			PrintOutput(string(out), outputFile)
+			return
+		}
+		printExplanations(os.Stdout, explanations)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(explainCmd)
+}
+
+// explainCheck returns the explanations of the checks of a benchmark with
+// the given ID, one for each target defining it.
+func explainCheck(benchmark, id string) ([]explanation, error) {
+	checks, err := loadBenchmarkChecks(benchmark)
+	if err != nil {
+		return nil, err
+	}
+
+	var found []benchmarkCheck
+	for _, c := range checks {
+		if hasID(map[string]bool{id: true}, c.check) {
+			found = append(found, c)
+		}
+	}
+	if len(found) == 0 {
+		return nil, fmt.Errorf("no check %s in benchmark %s", id, benchmark)
+	}
+	sortBenchmarkChecks(found)
+
+	explanations := make([]explanation, 0, len(found))
+	for _, c := range found {
+		explanations = append(explanations, explanation{
+			Benchmark:   benchmark,
+			Target:      c.Target,
+			ID:          c.ID,
+			CISID:       c.CISID,
+			Text:        c.Text,
+			Type:        c.check.Type,
+			Scored:      c.check.Scored,
+			Rationale:   strings.TrimSpace(c.check.Rationale),
+			Remediation: strings.TrimSpace(c.check.Remediation),
+			Reference:   c.check.Reference,
+		})
+	}
+	return explanations, nil
+}
+
+func printExplanations(w io.Writer, explanations []explanation) {
+	for i, e := range explanations {
+		if i > 0 {
+			fmt.Fprintln(w)
+		}
+		fmt.Fprintf(w, "%s %s\n", e.ID, e.Text)
+		fmt.Fprintf(w, "Benchmark: %s, target: %s\n", e.Benchmark, e.Target)
+		if e.CISID != "" {
+			fmt.Fprintf(w, "CIS recommendation: %s\n", e.CISID)
+		}
+
+		if e.Rationale != "" {
+			fmt.Fprintf(w, "\nRationale:\n%s\n", e.Rationale)
+		}
+		if e.Remediation != "" {
+			fmt.Fprintf(w, "\nRemediation:\n%s\n", e.Remediation)
+		}
+		if e.Reference != "" {
+			fmt.Fprintf(w, "\nReference: %s\n", e.Reference)
+		}
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const explainControls = `---
+controls:
+id: 1
+text: "Master Node Security Configuration"
+type: "master"
+groups:
+  - id: 1.2
+    text: "API Server"
+    checks:
+      - id: 1.2.16
+        text: "Ensure that the admission control plugin PodSecurityPolicy is set (Scored)"
+        audit: "ps -ef | grep kube-apiserver"
+        rationale: |
+          A Pod Security Policy enforces the security settings of pods.
+        remediation: |
+          Follow the documentation and create Pod Security Policy objects.
+        reference: "https://kubernetes.io/docs/concepts/policy/pod-security-policy/"
+        scored: true
+      - id: 1.2.17
+        text: "Ensure that the admission control plugin NodeRestriction is set (Scored)"
+        audit: "ps -ef | grep kube-apiserver"
+        scored: true
+`
+
+func TestExplainCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-explain")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.Mkdir(filepath.Join(dir, "cis-1.5"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "cis-1.5", "master.yaml"), []byte(explainControls), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	defer func(d string) { cfgDir = d }(cfgDir)
+	cfgDir = dir
+
+	explanations, err := explainCheck("cis-1.5", "1.2.16")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	var out bytes.Buffer
+	printExplanations(&out, explanations)
+	expected := `1.2.16 Ensure that the admission control plugin PodSecurityPolicy is set (Scored)
+Benchmark: cis-1.5, target: master
+
+Rationale:
+A Pod Security Policy enforces the security settings of pods.
+
+Remediation:
+Follow the documentation and create Pod Security Policy objects.
+
+Reference: https://kubernetes.io/docs/concepts/policy/pod-security-policy/
+`
+	if out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+
+	explanations, err = explainCheck("cis-1.5", "1.2.17")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	out.Reset()
+	printExplanations(&out, explanations)
+	if expected := "1.2.17 Ensure that the admission control plugin NodeRestriction is set (Scored)\nBenchmark: cis-1.5, target: master\n"; out.String() != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out.String())
+	}
+
+	if _, err := explainCheck("cis-1.5", "9.9.9"); err == nil {
+		t.Errorf("expected an error for an unknown check")
+	}
+}
+
+func TestExplainShippedCheck(t *testing.T) {
+	defer func(d string) { cfgDir = d }(cfgDir)
+	cfgDir = "../cfg"
+
+	explanations, err := explainCheck("cis-1.5", "1.2.16")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(explanations) != 1 {
+		t.Fatalf("expected 1 explanation, got %d", len(explanations))
+	}
+	e := explanations[0]
+	if !strings.Contains(e.Rationale, "Pod Security Policy") {
+		t.Errorf("expected the rationale of 1.2.16, got %q", e.Rationale)
+	}
+	if expected := "https://kubernetes.io/docs/reference/access-authn-authz/admission-controllers/#podsecuritypolicy"; e.Reference != expected {
+		t.Errorf("expected reference %s, got %s", expected, e.Reference)
+	}
+
+	var out bytes.Buffer
+	printExplanations(&out, explanations)
+	for _, s := range []string{"\nRationale:\n", "\nRemediation:\n", "\nReference: "} {
+		if !strings.Contains(out.String(), s) {
+			t.Errorf("expected %q in\n%s", s, out.String())
+		}
+	}
+
+	// The checks of cis-1.5 are numbered as the recommendations of the CIS
+	// Kubernetes Benchmark v1.5.1.
+	explanations, err = explainCheck("cis-1.5", "cis:1.2.16")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(explanations) != 1 || explanations[0].ID != "1.2.16" || explanations[0].CISID != "1.2.16" {
+		t.Errorf("expected check 1.2.16 by its CIS ID, got %+v", explanations)
+	}
+
+	checks, err := loadBenchmarkChecks("cis-1.5")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, c := range checks {
+		if c.check.Rationale == "" || c.check.Reference == "" {
+			t.Errorf("%s %s: expected a rationale and a reference", c.Target, c.ID)
+		}
+		if c.CISID != c.ID {
+			t.Errorf("%s %s: expected CIS ID %s, got %q", c.Target, c.ID, c.ID, c.CISID)
+		}
+	}
+}
@@ -0,0 +1,45 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+// resultTTL is how long results are trusted, set with --result-ttl. Results
+// never go stale when it's 0.
+var resultTTL time.Duration
+
+// setExpiry records when the results of a target go stale, so that every
+// copy of them, in the output, the history or the summary file, tells it.
+func setExpiry(controls *check.Controls) {
+	if resultTTL <= 0 {
+		return
+	}
+	end := controls.EndTime
+	if end.IsZero() {
+		end = time.Now().UTC()
+	}
+	expires := end.Add(resultTTL)
+	controls.ExpiresAt = &expires
+}
+
+// resultsStale reports whether results produced at scanned are older than
+// --result-ttl at now.
+func resultsStale(scanned, now time.Time) bool {
+	return resultTTL > 0 && now.Sub(scanned) > resultTTL
+}
@@ -0,0 +1,46 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"testing"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+func TestSetExpiry(t *testing.T) {
+	defer func(ttl time.Duration) { resultTTL = ttl }(resultTTL)
+
+	end := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	controls := &check.Controls{Summary: check.Summary{EndTime: end}}
+	setExpiry(controls)
+	if controls.ExpiresAt != nil {
+		t.Errorf("expected no expiry without a TTL, got %v", controls.ExpiresAt)
+	}
+
+	resultTTL = 24 * time.Hour
+	setExpiry(controls)
+	if controls.ExpiresAt == nil || !controls.ExpiresAt.Equal(end.Add(24*time.Hour)) {
+		t.Errorf("expected the results to expire a day after the end of the run, got %v", controls.ExpiresAt)
+	}
+
+	if resultsStale(end, end.Add(24*time.Hour)) {
+		t.Errorf("expected results of the age of the TTL to be fresh")
+	}
+	if !resultsStale(end, end.Add(25*time.Hour)) {
+		t.Errorf("expected results older than the TTL to be stale")
+	}
+}
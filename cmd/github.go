@@ -0,0 +1,117 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+// githubAnnotations returns GitHub Actions workflow commands annotating the
+// checks that failed with errors, and the ones that warned with warnings.
+func githubAnnotations(controls *check.Controls) string {
+	var b strings.Builder
+	for _, g := range controls.Groups {
+		for _, c := range g.Checks {
+			var command string
+			switch c.State {
+			case check.FAIL:
+				command = "error"
+			case check.WARN:
+				command = "warning"
+			default:
+				continue
+			}
+
+			message := strings.TrimSpace(c.Text)
+			// Manual checks are described by their remediation.
+			if c.State == check.WARN && c.Reason != "" && c.Type != check.MANUAL {
+				message += "\n" + c.Reason
+			} else if c.Remediation != "" {
+				message += "\n" + strings.TrimSpace(c.Remediation)
+			}
+			if c.Reference != "" {
+				message += "\nSee " + c.Reference
+			}
+			title := fmt.Sprintf("%s %s [%s]", controls.Text, c.ID, c.State)
+			fmt.Fprintf(&b, "::%s title=%s::%s\n", command, escapeGitHubProperty(title), escapeGitHubData(message))
+		}
+	}
+	return b.String()
+}
+
+// githubStepSummary returns a Markdown summary of the results, for the
+// summary of a GitHub Actions job step.
+func githubStepSummary(controls *check.Controls, summary check.Summary) string {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "### %s %s\n\n", controls.ID, controls.Text)
+	fmt.Fprintf(&b, "| PASS | FAIL | WARN | INFO | SKIP |\n|---|---|---|---|---|\n| %d | %d | %d | %d | %d |\n",
+		summary.Pass, summary.Fail, summary.Warn, summary.Info, summary.Skip)
+
+	if len(summary.Groups) > 0 {
+		b.WriteString("\n| Group | PASS | FAIL | WARN | INFO | SKIP |\n|---|---|---|---|---|---|\n")
+		for _, g := range summary.Groups {
+			fmt.Fprintf(&b, "| %s %s | %d | %d | %d | %d | %d |\n", g.ID, escapeMarkdownCell(g.Text), g.Pass, g.Fail, g.Warn, g.Info, g.Skip)
+		}
+	}
+
+	var rows []string
+	for _, g := range controls.Groups {
+		for _, c := range g.Checks {
+			if c.State == check.FAIL || c.State == check.WARN {
+				rows = append(rows, fmt.Sprintf("| %s | %s | %s |", c.ID, c.State, escapeMarkdownCell(strings.TrimSpace(c.Text))))
+			}
+		}
+	}
+	if len(rows) > 0 {
+		fmt.Fprintf(&b, "\n| Check | Status | Description |\n|---|---|---|\n%s\n", strings.Join(rows, "\n"))
+	}
+	b.WriteString("\n")
+	return b.String()
+}
+
+// writeGitHubOutput outputs the workflow commands of the results, and
+// appends their summary to the step summary when running in GitHub Actions.
+func writeGitHubOutput(controls *check.Controls, summary check.Summary) error {
+	PrintOutput(strings.TrimSuffix(githubAnnotations(controls), "\n"), outputFile)
+
+	file := os.Getenv("GITHUB_STEP_SUMMARY")
+	if file == "" {
+		return nil
+	}
+	f, err := os.OpenFile(file, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.WriteString(githubStepSummary(controls, summary))
+	return err
+}
+
+func escapeGitHubData(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A").Replace(s)
+}
+
+func escapeGitHubProperty(s string) string {
+	return strings.NewReplacer("%", "%25", "\r", "%0D", "\n", "%0A", ":", "%3A", ",", "%2C").Replace(s)
+}
+
+func escapeMarkdownCell(s string) string {
+	return strings.NewReplacer("|", "\\|", "\n", " ").Replace(s)
+}
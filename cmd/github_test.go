@@ -0,0 +1,96 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+func githubControls() *check.Controls {
+	return &check.Controls{
+		ID:   "4",
+		Text: "Worker Node Security Configuration",
+		Groups: []*check.Group{{
+			ID: "4.2",
+			Checks: []*check.Check{
+				{ID: "4.2.1", Text: "Ensure that the --anonymous-auth argument is set to false (Scored)", State: check.FAIL, Remediation: "Set authentication: anonymous: enabled to false.\nRestart the kubelet."},
+				{ID: "4.2.2", Text: "Ensure that the --authorization-mode argument is not set to AlwaysAllow (Scored)", State: check.PASS},
+				{ID: "4.2.3", Text: "Ensure that the --client-ca-file argument is set as appropriate | 100% (Scored)", State: check.WARN, Reason: "insufficient privileges: cannot read /etc/kubernetes/kubelet.conf"},
+			},
+		}},
+	}
+}
+
+func TestGitHubAnnotations(t *testing.T) {
+	expected := "::error title=Worker Node Security Configuration 4.2.1 [FAIL]::Ensure that the --anonymous-auth argument is set to false (Scored)%0ASet authentication: anonymous: enabled to false.%0ARestart the kubelet.\n" +
+		"::warning title=Worker Node Security Configuration 4.2.3 [WARN]::Ensure that the --client-ca-file argument is set as appropriate | 100%25 (Scored)%0Ainsufficient privileges: cannot read /etc/kubernetes/kubelet.conf\n"
+	if got := githubAnnotations(githubControls()); got != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, got)
+	}
+
+	manual := &check.Controls{Text: "Policies", Groups: []*check.Group{{Checks: []*check.Check{
+		{ID: "5.2.1", Text: "Minimize the admission of privileged containers", Type: check.MANUAL, State: check.WARN, Reason: "Test marked as a manual test", Remediation: "Create a PSP."},
+	}}}}
+	if got := githubAnnotations(manual); got != "::warning title=Policies 5.2.1 [WARN]::Minimize the admission of privileged containers%0ACreate a PSP.\n" {
+		t.Errorf("expected the guidance of a manual check, got %q", got)
+	}
+
+	if got := escapeGitHubProperty("1.1: a, b"); got != "1.1%3A a%2C b" {
+		t.Errorf("unexpected escaped property %q", got)
+	}
+}
+
+func TestWriteGitHubOutputStepSummary(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-github")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "summary.md")
+	os.Setenv("GITHUB_STEP_SUMMARY", file)
+	defer os.Unsetenv("GITHUB_STEP_SUMMARY")
+	defer func(f string) { outputFile = f }(outputFile)
+	outputFile = filepath.Join(dir, "annotations.txt")
+
+	if err := writeGitHubOutput(githubControls(), check.Summary{Pass: 1, Fail: 1, Warn: 1, Groups: []check.GroupSummary{{ID: "4.2", Text: "Kubelet", Pass: 1, Fail: 1, Warn: 1}}}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, line := range []string{
+		"### 4 Worker Node Security Configuration",
+		"| 1 | 1 | 1 | 0 | 0 |",
+		"| 4.2 Kubelet | 1 | 1 | 1 | 0 | 0 |",
+		"| 4.2.1 | FAIL | Ensure that the --anonymous-auth argument is set to false (Scored) |",
+		`| 4.2.3 | WARN | Ensure that the --client-ca-file argument is set as appropriate \| 100% (Scored) |`,
+	} {
+		if !strings.Contains(string(data), line+"\n") {
+			t.Errorf("expected %q in step summary:\n%s", line, data)
+		}
+	}
+	if strings.Contains(string(data), "4.2.2") {
+		t.Errorf("expected passing checks not to be listed:\n%s", data)
+	}
+}
@@ -0,0 +1,170 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+const (
+	// gitlabReportVersion is the version of the GitLab security report schema.
+	gitlabReportVersion = "15.0.0"
+	// gitlabTimeFormat is the format of the times of GitLab security reports.
+	gitlabTimeFormat = "2006-01-02T15:04:05"
+)
+
+// gitlabReport is a GitLab SAST security report.
+type gitlabReport struct {
+	Version         string                `json:"version"`
+	Vulnerabilities []gitlabVulnerability `json:"vulnerabilities"`
+	Scan            gitlabScan            `json:"scan"`
+}
+
+type gitlabVulnerability struct {
+	ID          string             `json:"id"`
+	Name        string             `json:"name"`
+	Description string             `json:"description,omitempty"`
+	Severity    string             `json:"severity"`
+	Solution    string             `json:"solution,omitempty"`
+	Location    gitlabLocation     `json:"location"`
+	Identifiers []gitlabIdentifier `json:"identifiers"`
+	Links       []gitlabLink       `json:"links,omitempty"`
+}
+
+type gitlabLink struct {
+	URL string `json:"url"`
+}
+
+type gitlabLocation struct {
+	File string `json:"file"`
+}
+
+type gitlabIdentifier struct {
+	Type  string `json:"type"`
+	Name  string `json:"name"`
+	Value string `json:"value"`
+}
+
+type gitlabScan struct {
+	Analyzer  gitlabTool `json:"analyzer"`
+	Scanner   gitlabTool `json:"scanner"`
+	Type      string     `json:"type"`
+	StartTime string     `json:"start_time"`
+	EndTime   string     `json:"end_time"`
+	Status    string     `json:"status"`
+}
+
+type gitlabTool struct {
+	ID      string       `json:"id"`
+	Name    string       `json:"name"`
+	Version string       `json:"version"`
+	Vendor  gitlabVendor `json:"vendor"`
+}
+
+type gitlabVendor struct {
+	Name string `json:"name"`
+}
+
+// gitlabSeverity maps the state of a check to the severity of its finding.
+// Failed scored checks are the most severe.
+func gitlabSeverity(c *check.Check) string {
+	switch {
+	case c.State == check.FAIL && c.Scored:
+		return "High"
+	case c.State == check.FAIL:
+		return "Medium"
+	default:
+		return "Low"
+	}
+}
+
+// gitlabReportJSON encodes the checks that failed or warned as a GitLab SAST
+// security report, each check being located in its controls file.
+func gitlabReportJSON(controls *check.Controls) ([]byte, error) {
+	version := KubeBenchVersion
+	if version == "" {
+		version = "unknown"
+	}
+	tool := gitlabTool{ID: "kube-bench", Name: "kube-bench", Version: version, Vendor: gitlabVendor{Name: "Aqua Security"}}
+
+	start, end := controls.StartTime, controls.EndTime
+	if start.IsZero() {
+		start = time.Now().UTC()
+	}
+	if end.IsZero() {
+		end = start
+	}
+
+	report := gitlabReport{
+		Version:         gitlabReportVersion,
+		Vulnerabilities: []gitlabVulnerability{},
+		Scan: gitlabScan{
+			Analyzer:  tool,
+			Scanner:   tool,
+			Type:      "sast",
+			StartTime: start.Format(gitlabTimeFormat),
+			EndTime:   end.Format(gitlabTimeFormat),
+			Status:    "success",
+		},
+	}
+
+	file := string(controls.Type) + ".yaml"
+	for _, g := range controls.Groups {
+		for _, c := range g.Checks {
+			if c.State != check.FAIL && c.State != check.WARN {
+				continue
+			}
+
+			description := strings.TrimSpace(c.Text)
+			if c.Rationale != "" {
+				description += "\n\n" + strings.TrimSpace(c.Rationale)
+			}
+			if c.Reason != "" {
+				description += "\n\n" + c.Reason
+			}
+			var links []gitlabLink
+			if c.Reference != "" {
+				links = []gitlabLink{{URL: c.Reference}}
+			}
+			report.Vulnerabilities = append(report.Vulnerabilities, gitlabVulnerability{
+				ID:          gitlabID(file, c.ID),
+				Name:        fmt.Sprintf("%s %s", c.ID, strings.TrimSpace(c.Text)),
+				Description: description,
+				Severity:    gitlabSeverity(c),
+				Solution:    strings.TrimSpace(c.Remediation),
+				Location:    gitlabLocation{File: file},
+				Identifiers: []gitlabIdentifier{{Type: "kube_bench_check", Name: "kube-bench check " + c.ID, Value: c.ID}},
+				Links:       links,
+			})
+		}
+	}
+
+	return json.Marshal(report)
+}
+
+// gitlabID derives a stable UUID for the finding of a check, so that GitLab
+// tracks it across pipelines.
+func gitlabID(file, id string) string {
+	h := sha256.Sum256([]byte(file + "/" + id))
+	h[6] = (h[6] & 0x0f) | 0x50
+	h[8] = (h[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", h[0:4], h[4:6], h[6:8], h[8:10], h[10:16])
+}
@@ -0,0 +1,69 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+func TestGitLabReport(t *testing.T) {
+	controls := githubControls()
+	controls.Type = check.NODE
+	controls.Groups[0].Checks[0].Scored = true
+	controls.Groups[0].Checks[0].Reference = "https://kubernetes.io/docs/reference/access-authn-authz/kubelet-authn-authz/"
+	controls.StartTime = time.Date(2020, 3, 2, 10, 0, 0, 0, time.UTC)
+	controls.EndTime = controls.StartTime.Add(3 * time.Second)
+
+	out, err := gitlabReportJSON(controls)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var report gitlabReport
+	if err := json.Unmarshal(out, &report); err != nil {
+		t.Fatal(err)
+	}
+
+	if report.Version != gitlabReportVersion || report.Scan.Type != "sast" || report.Scan.Status != "success" {
+		t.Errorf("unexpected report header %+v", report)
+	}
+	if report.Scan.StartTime != "2020-03-02T10:00:00" || report.Scan.EndTime != "2020-03-02T10:00:03" {
+		t.Errorf("unexpected scan times %s %s", report.Scan.StartTime, report.Scan.EndTime)
+	}
+
+	if len(report.Vulnerabilities) != 2 {
+		t.Fatalf("expected the failed and warned checks, got %+v", report.Vulnerabilities)
+	}
+	fail, warn := report.Vulnerabilities[0], report.Vulnerabilities[1]
+	if fail.Severity != "High" || fail.Location.File != "node.yaml" || fail.Identifiers[0].Value != "4.2.1" {
+		t.Errorf("unexpected vulnerability %+v", fail)
+	}
+	if fail.Solution != "Set authentication: anonymous: enabled to false.\nRestart the kubelet." {
+		t.Errorf("unexpected solution %q", fail.Solution)
+	}
+	if len(fail.Links) != 1 || fail.Links[0].URL != "https://kubernetes.io/docs/reference/access-authn-authz/kubelet-authn-authz/" || warn.Links != nil {
+		t.Errorf("unexpected links %+v %+v", fail.Links, warn.Links)
+	}
+	if warn.Severity != "Low" || warn.Description != "Ensure that the --client-ca-file argument is set as appropriate | 100% (Scored)\n\ninsufficient privileges: cannot read /etc/kubernetes/kubelet.conf" {
+		t.Errorf("unexpected vulnerability %+v", warn)
+	}
+
+	if fail.ID != gitlabID("node.yaml", "4.2.1") || fail.ID == warn.ID || len(fail.ID) != 36 {
+		t.Errorf("unexpected IDs %s %s", fail.ID, warn.ID)
+	}
+}
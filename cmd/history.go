@@ -0,0 +1,165 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+)
+
+// historyTimeFormat names the files of the history store, so that they sort
+// in chronological order.
+const historyTimeFormat = "20060102T150405.000000000Z"
+
+// historyRun is a run saved in the history store.
+type historyRun struct {
+	Time     time.Time         `json:"time"`
+	Controls []*check.Controls `json:"controls"`
+}
+
+// currentRun collects the results of this run when --history-dir is given.
+var currentRun *historyRun
+
+// addToHistory adds the results of a target to the current run.
+func addToHistory(controls *check.Controls) {
+	if historyDir == "" {
+		return
+	}
+	if currentRun == nil {
+		currentRun = &historyRun{Time: time.Now().UTC()}
+	}
+	currentRun.Controls = append(currentRun.Controls, controls)
+}
+
+// saveHistory saves the current run in the history store, and returns the
+// checks that regressed since the previous run.
+func saveHistory() ([]string, error) {
+	if currentRun == nil {
+		return nil, nil
+	}
+
+	runs, err := loadHistory(historyDir)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := os.MkdirAll(historyDir, 0755); err != nil {
+		return nil, err
+	}
+	data, err := json.Marshal(currentRun)
+	if err != nil {
+		return nil, err
+	}
+	file := filepath.Join(historyDir, currentRun.Time.Format(historyTimeFormat)+".json")
+	if err := ioutil.WriteFile(file, data, 0644); err != nil {
+		return nil, fmt.Errorf("failed to save results in history: %v", err)
+	}
+	glog.V(1).Infof("Results saved in %s", file)
+
+	if err := pruneHistory(historyDir, historyRetention, currentRun.Time); err != nil {
+		return nil, err
+	}
+
+	if len(runs) == 0 {
+		return nil, nil
+	}
+	return regressions(&runs[len(runs)-1], currentRun), nil
+}
+
+// loadHistory returns the runs of the history store, oldest first.
+func loadHistory(dir string) ([]historyRun, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(files)
+
+	var runs []historyRun
+	for _, file := range files {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		var run historyRun
+		if err := json.Unmarshal(data, &run); err != nil {
+			return nil, fmt.Errorf("invalid history file %s: %v", file, err)
+		}
+		runs = append(runs, run)
+	}
+	return runs, nil
+}
+
+// summary adds up the results of all the targets of a run.
+func (r historyRun) summary() check.Summary {
+	var s check.Summary
+	for _, c := range r.Controls {
+		s.Pass += c.Pass
+		s.Fail += c.Fail
+		s.Warn += c.Warn
+		s.Info += c.Info
+		s.Skip += c.Skip
+	}
+	return s
+}
+
+// states returns the state of each check of a run, keyed by target and ID.
+func (r historyRun) states() map[string]check.State {
+	states := map[string]check.State{}
+	for _, controls := range r.Controls {
+		for _, g := range controls.Groups {
+			for _, c := range g.Checks {
+				states[string(controls.Type)+"/"+c.ID] = c.State
+			}
+		}
+	}
+	return states
+}
+
+// regressions lists the checks that passed in a run and no longer pass in a
+// later one.
+func regressions(previous, current *historyRun) []string {
+	before := previous.states()
+	var regressed []string
+	for key, state := range current.states() {
+		if before[key] == check.PASS && state != check.PASS && state != check.INFO && state != check.SKIP {
+			regressed = append(regressed, fmt.Sprintf("%s: %s -> %s", key, before[key], state))
+		}
+	}
+	sort.Strings(regressed)
+	return regressed
+}
+
+// reportRegressions prints regressions on stderr, not to mix them with the
+// results, and exits with an error when --fail-on-regression is given.
+func reportRegressions(regressed []string) {
+	if len(regressed) == 0 {
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "%d checks regressed since the previous run:\n%s\n", len(regressed), strings.Join(regressed, "\n"))
+	if failOnRegression {
+		glog.Flush()
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,115 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+func historyControls(target check.NodeType, states ...check.State) *check.Controls {
+	c := &check.Controls{Type: target, Groups: []*check.Group{{ID: "4.2"}}}
+	for i, state := range states {
+		id := strconv.Itoa(i + 1)
+		c.Groups[0].Checks = append(c.Groups[0].Checks, &check.Check{ID: "4.2." + id, Text: "Check " + id, State: state})
+		switch state {
+		case check.PASS:
+			c.Pass++
+		case check.FAIL:
+			c.Fail++
+		case check.WARN:
+			c.Warn++
+		case check.INFO:
+			c.Info++
+		}
+	}
+	return c
+}
+
+func TestHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(d string) { historyDir, currentRun = d, nil }(historyDir)
+	historyDir = dir
+
+	runs := [][]check.State{
+		{check.PASS, check.FAIL, check.WARN, check.INFO},
+		{check.PASS, check.PASS, check.WARN, check.INFO},
+		{check.FAIL, check.PASS, check.PASS, check.INFO},
+	}
+	expected := [][]string{
+		nil,
+		nil,
+		{"node/4.2.1: PASS -> FAIL"},
+	}
+	for i, states := range runs {
+		currentRun = nil
+		addToHistory(historyControls(check.NODE, states...))
+		regressed, err := saveHistory()
+		if err != nil {
+			t.Fatalf("run %d: unexpected error: %v", i, err)
+		}
+		if !reflect.DeepEqual(regressed, expected[i]) {
+			t.Errorf("run %d: expected regressions %v, got %v", i, expected[i], regressed)
+		}
+	}
+
+	saved, err := loadHistory(dir)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(saved) != 3 {
+		t.Fatalf("expected 3 runs, got %d", len(saved))
+	}
+	if s := saved[1].summary(); s.Pass != 2 || s.Warn != 1 || s.Info != 1 {
+		t.Errorf("unexpected summary %+v", s)
+	}
+
+	var out bytes.Buffer
+	printTrend(&out, saved)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+	if len(lines) != 4 {
+		t.Fatalf("expected a header and 3 runs, got:\n%s", out.String())
+	}
+	for i, suffix := range []string{"33.3%", "66.7% (+33.3)", "66.7%"} {
+		if !strings.HasSuffix(lines[i+1], suffix) {
+			t.Errorf("expected run %d to end with %q, got %q", i, suffix, lines[i+1])
+		}
+	}
+}
+
+func TestAddToHistoryDisabled(t *testing.T) {
+	defer func(d string) { historyDir, currentRun = d, nil }(historyDir)
+	historyDir = ""
+
+	addToHistory(historyControls(check.NODE, check.PASS))
+	if currentRun != nil {
+		t.Errorf("expected no history without --history-dir")
+	}
+	if regressed, err := saveHistory(); err != nil || regressed != nil {
+		t.Errorf("expected nothing to be saved, got %v, %v", regressed, err)
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+// hostPathPrefixes are the directories of the host holding the files that
+// audits inspect. They are remapped under --host-root, unlike the
+// directories of the executables the audits run.
+var hostPathPrefixes = []string{"/etc/", "/var/lib/", "/var/snap/", "/var/log/", "/lib/systemd/", "/usr/lib/systemd/", "/home/", "/opt/", "/srv/"}
+
+// hostPathRe matches the paths in audits that are under one of the
+// hostPathPrefixes.
+var hostPathRe = func() *regexp.Regexp {
+	prefixes := make([]string, 0, len(hostPathPrefixes))
+	for _, p := range hostPathPrefixes {
+		prefixes = append(prefixes, regexp.QuoteMeta(p))
+	}
+	return regexp.MustCompile(`(^|[\s'"=])((?:` + strings.Join(prefixes, "|") + `)\S*)`)
+}()
+
+// checkHostRoot verifies the directory given with --host-root.
+func checkHostRoot(root string) error {
+	if root == "" {
+		return nil
+	}
+	if !filepath.IsAbs(root) {
+		return fmt.Errorf("%s is not an absolute path", root)
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", root)
+	}
+	return nil
+}
+
+// hostPath returns where a file of the host is, under --host-root if set.
+func hostPath(path string) string {
+	if hostRoot == "" || !hostPathRe.MatchString(path) || !filepath.IsAbs(path) {
+		return path
+	}
+	return strings.TrimRight(hostRoot, "/") + path
+}
+
+// useHostRoot makes the audits of the controls inspect the files of the
+// host under --host-root. The files named by the flags of processes are
+// read under it too.
+func useHostRoot(controls *check.Controls) {
+	controls.RewriteAudits(remapHostPaths)
+	for _, g := range controls.Groups {
+		for _, c := range g.Checks {
+			if c.Type == check.FILE && c.AuditOptions["flag"] != "" {
+				c.AuditOptions["root"] = hostRoot
+			}
+		}
+	}
+}
+
+// remapHostPaths remaps the files of the host used by an audit under
+// --host-root.
+func remapHostPaths(audit string) string {
+	if hostRoot == "" {
+		return audit
+	}
+	return hostPathRe.ReplaceAllStringFunc(audit, func(m string) string {
+		i := strings.Index(m, "/")
+		return m[:i] + strings.TrimRight(hostRoot, "/") + m[i:]
+	})
+}
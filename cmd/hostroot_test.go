@@ -0,0 +1,103 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+func TestRemapHostPaths(t *testing.T) {
+	defer func(r string) { hostRoot = r }(hostRoot)
+	hostRoot = "/host/"
+
+	cases := []struct {
+		audit    string
+		expected string
+	}{
+		{
+			"/bin/sh -c 'if test -e /etc/kubernetes/admin.conf; then stat -c permissions=%a /etc/kubernetes/admin.conf; fi'",
+			"/bin/sh -c 'if test -e /host/etc/kubernetes/admin.conf; then stat -c permissions=%a /host/etc/kubernetes/admin.conf; fi'",
+		},
+		{`stat -c %n\ %a /etc/kubernetes/pki/*.crt`, `stat -c %n\ %a /host/etc/kubernetes/pki/*.crt`},
+		{"/bin/cat /var/lib/kubelet/config.yaml", "/bin/cat /host/var/lib/kubelet/config.yaml"},
+		// Executables, processes and files already remapped are left alone.
+		{"/bin/ps -fC kubelet", "/bin/ps -fC kubelet"},
+		{"/bin/cat /proc/4242/root/etc/kubernetes/kubelet.conf", "/bin/cat /proc/4242/root/etc/kubernetes/kubelet.conf"},
+		{"/bin/cat /tmp/kube-bench-proxy/config.conf", "/bin/cat /tmp/kube-bench-proxy/config.conf"},
+		{"https://127.0.0.1:10250/configz", "https://127.0.0.1:10250/configz"},
+	}
+	for _, c := range cases {
+		if got := remapHostPaths(c.audit); got != c.expected {
+			t.Errorf("expected %q, got %q", c.expected, got)
+		}
+	}
+
+	if got := hostPath("/etc/kubernetes/manifests/kube-apiserver.yaml"); got != "/host/etc/kubernetes/manifests/kube-apiserver.yaml" {
+		t.Errorf("unexpected host path %q", got)
+	}
+	if got := hostPath("apiserver"); got != "apiserver" {
+		t.Errorf("unexpected host path %q", got)
+	}
+
+	hostRoot = ""
+	if got := remapHostPaths("/bin/cat /var/lib/kubelet/config.yaml"); got != "/bin/cat /var/lib/kubelet/config.yaml" {
+		t.Errorf("expected no remapping without host root, got %q", got)
+	}
+}
+
+func TestUseHostRoot(t *testing.T) {
+	defer func(r string) { hostRoot = r }(hostRoot)
+	hostRoot = "/host"
+
+	controls := &check.Controls{Groups: []*check.Group{{Checks: []*check.Check{
+		{ID: "1.2.22", Type: check.FILE, Audit: "/bin/ps -ef | grep kube-apiserver", AuditOptions: map[string]string{"flag": "--audit-policy-file"}},
+		{ID: "4.2.1", Audit: "/bin/ps -fC kubelet", AuditConfig: "/bin/cat /var/lib/kubelet/config.yaml"},
+		{ID: "2.8", Type: check.ETCDCONN, Audit: "https://127.0.0.1:2379", AuditOptions: map[string]string{"cafile": "/etc/kubernetes/pki/etcd/ca.crt"}},
+	}}}}
+	useHostRoot(controls)
+
+	checks := controls.Groups[0].Checks
+	if root := checks[0].AuditOptions["root"]; root != "/host" {
+		t.Errorf("expected the files named by flags to be read under the host root, got %q", root)
+	}
+	if c := checks[1]; c.Audit != "/bin/ps -fC kubelet" || c.AuditConfig != "/bin/cat /host/var/lib/kubelet/config.yaml" || len(c.ConfigCommands) != 1 || c.ConfigCommands[0].Args[1] != "/host/var/lib/kubelet/config.yaml" {
+		t.Errorf("unexpected audits %q %q", c.Audit, c.AuditConfig)
+	}
+	if c := checks[2]; c.AuditOptions["cafile"] != "/host/etc/kubernetes/pki/etcd/ca.crt" {
+		t.Errorf("unexpected audit options %v", c.AuditOptions)
+	}
+}
+
+func TestCheckHostRoot(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-host")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := checkHostRoot(dir); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	for _, root := range []string{"host", filepath.Join(dir, "missing")} {
+		if err := checkHostRoot(root); err == nil {
+			t.Errorf("%s: expected an error", root)
+		}
+	}
+}
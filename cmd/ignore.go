@@ -0,0 +1,144 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"regexp"
+	"strings"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+)
+
+// The annotations of a Node skipping checks on it, e.g. on CI runners: a
+// comma-separated list of check IDs, and why they are skipped.
+const (
+	skipAnnotation       = "kube-bench.aquasec.com/skip"
+	skipReasonAnnotation = "kube-bench.aquasec.com/skip-reason"
+)
+
+// defaultIgnoreFile is the ignore file used when --ignore-file isn't given,
+// if it exists.
+const defaultIgnoreFile = ".kubebenchignore"
+
+// ignoredChecks are the reasons the checks listed in the ignore file are
+// ignored, by check ID.
+var ignoredChecks map[string]string
+
+var ignoreEntryRe = regexp.MustCompile(`^(cis:)?[\w.\-]+$`)
+
+// loadIgnoreFile reads the checks ignored on this cluster, e.g. from a file
+// kept in the GitOps repository of the cluster so that ignoring a check is
+// reviewed like any other change.
+func loadIgnoreFile(file string) error {
+	ignoredChecks = nil
+	if file == "" {
+		if _, err := os.Stat(defaultIgnoreFile); err != nil {
+			return nil
+		}
+		file = defaultIgnoreFile
+	}
+
+	f, err := os.Open(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	ignoredChecks, err = parseIgnoreFile(f)
+	if err != nil {
+		return fmt.Errorf("%s: %v", file, err)
+	}
+	glog.V(1).Info(fmt.Sprintf("Ignoring %d checks listed in %s", len(ignoredChecks), file))
+	return nil
+}
+
+// parseIgnoreFile parses an ignore file: a check ID per line, with why it is
+// ignored in a comment at the end of the line or in the comment lines right
+// above it, e.g.
+//
+//	# The API server is managed by the cloud provider.
+//	1.2.16
+//	4.2.6 # The kubelet flags are set by the node image.
+func parseIgnoreFile(r io.Reader) (map[string]string, error) {
+	ignored := map[string]string{}
+	var comments []string
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" {
+			comments = nil
+			continue
+		}
+		if strings.HasPrefix(line, "#") {
+			comments = append(comments, strings.TrimSpace(strings.TrimPrefix(line, "#")))
+			continue
+		}
+
+		id, comment := line, ""
+		if i := strings.Index(line, "#"); i >= 0 {
+			id, comment = strings.TrimSpace(line[:i]), strings.TrimSpace(line[i+1:])
+		}
+		if !ignoreEntryRe.MatchString(id) {
+			return nil, fmt.Errorf("invalid check ID %q on line %d", id, n)
+		}
+
+		reason := comment
+		if reason == "" {
+			reason = strings.TrimSpace(strings.Join(comments, " "))
+		}
+		ignored[id] = reason
+		comments = nil
+	}
+	return ignored, scanner.Err()
+}
+
+// ignoreChecks marks the checks listed in the ignore file, or in the skip
+// annotation of the Node being scanned, as ignored.
+func ignoreChecks(controls *check.Controls, metadata *check.NodeMetadata) {
+	var skipped map[string]bool
+	var skipReason string
+	if metadata != nil && metadata.Annotations[skipAnnotation] != "" {
+		skipped = cleanIDs(metadata.Annotations[skipAnnotation])
+		skipReason = strings.TrimSpace(metadata.Annotations[skipReasonAnnotation])
+	}
+	if len(ignoredChecks) == 0 && len(skipped) == 0 {
+		return
+	}
+
+	for _, g := range controls.Groups {
+		for _, c := range g.Checks {
+			reason, ok := ignoredChecks[c.ID]
+			if !ok && c.CISID != "" {
+				reason, ok = ignoredChecks[cisIDPrefix+c.CISID]
+			}
+			if ok {
+				c.Ignored = "Ignored on this cluster"
+			} else if hasID(skipped, c) {
+				c.Ignored = fmt.Sprintf("Skipped on node %s by its %s annotation", metadata.NodeName, skipAnnotation)
+				reason = skipReason
+			} else {
+				continue
+			}
+			if reason != "" {
+				c.Ignored += ": " + reason
+			}
+		}
+	}
+}
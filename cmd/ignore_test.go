@@ -0,0 +1,115 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+func TestParseIgnoreFile(t *testing.T) {
+	in := `# Suppressions reviewed with the platform team.
+
+# The API server is managed by the cloud provider,
+# see the provider documentation.
+1.2.16
+4.2.6 # The kubelet flags are set by the node image.
+5.1.5
+`
+	ignored, err := parseIgnoreFile(strings.NewReader(in))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	expected := map[string]string{
+		"1.2.16": "The API server is managed by the cloud provider, see the provider documentation.",
+		"4.2.6":  "The kubelet flags are set by the node image.",
+		"5.1.5":  "",
+	}
+	if !reflect.DeepEqual(ignored, expected) {
+		t.Errorf("expected %v, got %v", expected, ignored)
+	}
+
+	if _, err := parseIgnoreFile(strings.NewReader("1.2.16 1.2.17\n")); err == nil {
+		t.Errorf("expected an error for an invalid entry")
+	}
+}
+
+func TestIgnoreChecks(t *testing.T) {
+	defer func(ignored map[string]string) { ignoredChecks = ignored }(ignoredChecks)
+
+	dir, err := ioutil.TempDir("", "kube-bench-ignore")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, defaultIgnoreFile)
+	if err := ioutil.WriteFile(file, []byte("1.1.1 # Accepted risk\n1.1.2\ncis:1.1.4 # Renumbered\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := loadIgnoreFile(file); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if err := loadIgnoreFile(filepath.Join(dir, "missing")); err == nil {
+		t.Errorf("expected an error for a missing ignore file")
+	}
+	if err := loadIgnoreFile(file); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	controls := &check.Controls{Groups: []*check.Group{{ID: "1.1", Checks: []*check.Check{
+		{ID: "1.1.1"}, {ID: "1.1.2"}, {ID: "1.1.3"}, {ID: "1.1.4"}, {ID: "1.1.5", CISID: "1.1.4"},
+	}}}}
+	ignoreChecks(controls, &check.NodeMetadata{NodeName: "node-1"})
+
+	checks := controls.Groups[0].Checks
+	for i, expected := range []string{"Ignored on this cluster: Accepted risk", "Ignored on this cluster", "", "", "Ignored on this cluster: Renumbered"} {
+		if checks[i].Ignored != expected {
+			t.Errorf("%s: expected %q, got %q", checks[i].ID, expected, checks[i].Ignored)
+		}
+	}
+}
+
+func TestIgnoreChecksSkipAnnotation(t *testing.T) {
+	defer func(ignored map[string]string) { ignoredChecks = ignored }(ignoredChecks)
+	ignoredChecks = map[string]string{"4.2.1": ""}
+
+	metadata := &check.NodeMetadata{NodeName: "ci-runner-1", Annotations: map[string]string{
+		skipAnnotation:       "4.2.1, 4.2.6",
+		skipReasonAnnotation: "CI runners run privileged builds",
+	}}
+	controls := &check.Controls{Groups: []*check.Group{{ID: "4.2", Checks: []*check.Check{
+		{ID: "4.2.1"}, {ID: "4.2.6"}, {ID: "4.2.10"},
+	}}}}
+	ignoreChecks(controls, metadata)
+
+	checks := controls.Groups[0].Checks
+	for i, expected := range []string{
+		// The ignore file takes precedence.
+		"Ignored on this cluster",
+		"Skipped on node ci-runner-1 by its kube-bench.aquasec.com/skip annotation: CI runners run privileged builds",
+		"",
+	} {
+		if checks[i].Ignored != expected {
+			t.Errorf("%s: expected %q, got %q", checks[i].ID, expected, checks[i].Ignored)
+		}
+	}
+}
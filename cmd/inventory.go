@@ -0,0 +1,61 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+// showInventory is set with --inventory.
+var showInventory bool
+
+// writeInventory prints the inventory of the host at the end of the text
+// output, with --inventory. The other formats have it in their metadata.
+func writeInventory() {
+	if !showInventory || nodeMetadata == nil || jsonFmt || junitFmt || gitlabFmt || sonarQubeFmt || githubFmt || pgSQL {
+		return
+	}
+	printInventory(os.Stdout, nodeMetadata)
+}
+
+// printInventory outputs what auditors ask about the host the checks were
+// run on, in human-readable format.
+func printInventory(w io.Writer, m *check.NodeMetadata) {
+	colors[check.INFO].Fprintf(w, "== Host inventory ==\n")
+	for _, item := range []struct {
+		name  string
+		value string
+	}{
+		{"Node", m.NodeName},
+		{"Hostname", m.Hostname},
+		{"OS", m.OS},
+		{"Kernel", m.KernelVersion},
+		{"Container runtime", strings.TrimSpace(m.ContainerRuntime + " " + m.ContainerRuntimeVersion)},
+		{"Kubelet", m.KubeletVersion},
+		{"Cgroup driver", m.CgroupDriver},
+		{"Cloud provider", m.CloudProvider},
+		{"Cluster", m.ClusterName},
+	} {
+		if item.value == "" {
+			item.value = "unknown"
+		}
+		fmt.Fprintf(w, "%s: %s\n", item.name, item.value)
+	}
+}
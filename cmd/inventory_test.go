@@ -0,0 +1,78 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/spf13/viper"
+)
+
+func TestPrintInventory(t *testing.T) {
+	var out bytes.Buffer
+	printInventory(&out, &check.NodeMetadata{
+		NodeName: "ip-10-0-1-12", Hostname: "ip-10-0-1-12", OS: "Ubuntu 20.04.1 LTS", KernelVersion: "5.4.0-1029-aws",
+		ContainerRuntime: "containerd", ContainerRuntimeVersion: "1.4.3", KubeletVersion: "v1.19.4", CgroupDriver: "systemd",
+	})
+	expected := `== Host inventory ==
+Node: ip-10-0-1-12
+Hostname: ip-10-0-1-12
+OS: Ubuntu 20.04.1 LTS
+Kernel: 5.4.0-1029-aws
+Container runtime: containerd 1.4.3
+Kubelet: v1.19.4
+Cgroup driver: systemd
+Cloud provider: unknown
+Cluster: unknown
+`
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestKubeletCgroupDriver(t *testing.T) {
+	defer func(ps func(string) string) { psFunc = ps }(psFunc)
+	defer viper.Set("node.kubelet.confs", nil)
+
+	dir, err := ioutil.TempDir("", "kube-bench-inventory")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	psFunc = func(string) string { return "/usr/bin/kubelet --config=/var/lib/kubelet/config.yaml\n" }
+	if got := kubeletCgroupDriver(); got != "" {
+		t.Errorf("expected no cgroup driver, got %q", got)
+	}
+
+	config := filepath.Join(dir, "kubelet-config.json")
+	if err := ioutil.WriteFile(config, []byte(`{"kind": "KubeletConfiguration", "cgroupDriver": "cgroupfs"}`), 0644); err != nil {
+		t.Fatal(err)
+	}
+	viper.Set("node.kubelet.confs", []string{filepath.Join(dir, "config.yaml"), config})
+	if got := kubeletCgroupDriver(); got != "cgroupfs" {
+		t.Errorf("expected the cgroup driver of the config file, got %q", got)
+	}
+
+	psFunc = func(string) string { return "/usr/bin/kubelet --cgroup-driver=systemd\n" }
+	if got := kubeletCgroupDriver(); got != "systemd" {
+		t.Errorf("expected the cgroup driver of the flags, got %q", got)
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+	"github.com/spf13/viper"
+)
+
+// kubeletInstance is the kubelet the node checks are run against, when
+// several kubelets run on the host.
+var kubeletInstance *check.ProcessInstance
+
+// kubeletPsAuditRe matches the audits listing the kubelet processes.
+var kubeletPsAuditRe = regexp.MustCompile(`(/bin/)?ps -fC \$kubeletbin\b`)
+
+// findKubeletInstances returns the running kubelets.
+func findKubeletInstances() ([]check.ProcessInstance, error) {
+	typeConf := viper.Sub(string(check.NODE))
+	if typeConf == nil {
+		return nil, fmt.Errorf("no config settings for %s", check.NODE)
+	}
+	binmap, err := getBinariesFunc(typeConf, check.NODE)
+	if err != nil {
+		return nil, err
+	}
+	bin := binmap["kubelet"]
+	if bin == "" {
+		return nil, nil
+	}
+
+	procs, err := check.FindProcessInstances(strings.Fields(bin)[0])
+	if err != nil {
+		return nil, err
+	}
+	var instances []check.ProcessInstance
+	for _, p := range procs {
+		if strings.Contains(p.Cmdline, bin) {
+			instances = append(instances, p)
+		}
+	}
+	return instances, nil
+}
+
+// runNodeChecks runs the node checks. With --kubelet-instances, they are
+// run against each kubelet in turn when several of them run on the host,
+// e.g. with kind, each kubelet having its own results.
+func runNodeChecks(testYamlFile string) {
+	if !kubeletInstances || mockMode != "" {
+		runChecks(check.NODE, testYamlFile)
+		return
+	}
+
+	instances, err := findKubeletInstances()
+	if err != nil {
+		exitWithError(fmt.Errorf("failed to find the kubelets running: %v", err))
+	}
+	if len(instances) < 2 {
+		runChecks(check.NODE, testYamlFile)
+		return
+	}
+
+	defer func() { kubeletInstance = nil }()
+	for i := range instances {
+		glog.V(1).Infof("== Running node checks against kubelet %d ==\n", instances[i].PID)
+		kubeletInstance = &instances[i]
+		runChecks(check.NODE, testYamlFile)
+	}
+}
+
+// useKubeletInstanceFiles uses the files of the kubelet instance, seen
+// through its root directory since kubelets of nested clusters run in
+// containers. Its config and kubeconfig files are the ones given by its
+// flags, the other files are the ones found for the host if the instance
+// has them.
+func useKubeletInstanceFiles(confmap, svcmap, kubeconfmap, cafilemap map[string]string) {
+	root := filepath.Join("/proc", strconv.Itoa(kubeletInstance.PID), "root")
+	for _, m := range []map[string]string{confmap, svcmap, kubeconfmap, cafilemap} {
+		file, ok := m["kubelet"]
+		if !ok {
+			continue
+		}
+		if _, err := statFunc(filepath.Join(root, file)); err == nil {
+			m["kubelet"] = filepath.Join(root, file)
+		}
+	}
+
+	for flag, m := range map[string]map[string]string{"--config": confmap, "--kubeconfig": kubeconfmap} {
+		if file := commandFlag(kubeletInstance.Cmdline, flag); file != "" {
+			m["kubelet"] = filepath.Join(root, file)
+		}
+	}
+}
+
+// useKubeletInstanceAudits makes the audits listing the kubelet processes
+// in the controls only list the kubelet instance.
+func useKubeletInstanceAudits(s string) string {
+	return kubeletPsAuditRe.ReplaceAllString(s, fmt.Sprintf("/bin/ps -fp %d", kubeletInstance.PID))
+}
+
+// kubeletInstanceLabel labels the results of the kubelet instance with its
+// hostname if overridden, as with kind, and its PID.
+func kubeletInstanceLabel() string {
+	if hostname := commandFlag(kubeletInstance.Cmdline, "--hostname-override"); hostname != "" {
+		return fmt.Sprintf("kubelet %s, PID %d", hostname, kubeletInstance.PID)
+	}
+	return fmt.Sprintf("kubelet PID %d", kubeletInstance.PID)
+}
+
+// kubeletInstanceName names the kubelet instance in the results, by its
+// hostname if overridden and else by its PID.
+func kubeletInstanceName() string {
+	if hostname := commandFlag(kubeletInstance.Cmdline, "--hostname-override"); hostname != "" {
+		return hostname
+	}
+	return strconv.Itoa(kubeletInstance.PID)
+}
+
+// commandFlag returns the value of a flag in a command line, given as
+// --flag=value or --flag value.
+func commandFlag(cmdline, flag string) string {
+	re := regexp.MustCompile(regexp.QuoteMeta(flag) + `(?:=|\s+)(\S+)`)
+	vals := re.FindStringSubmatch(cmdline)
+	if len(vals) < 2 {
+		return ""
+	}
+	return strings.Trim(vals[1], `'"`)
+}
@@ -0,0 +1,90 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+func withKubeletInstance(pid int, cmdline string) func() {
+	kubeletInstance = &check.ProcessInstance{PID: pid, Cmdline: cmdline}
+	return func() { kubeletInstance = nil }
+}
+
+func TestUseKubeletInstanceFiles(t *testing.T) {
+	defer withKubeletInstance(4242, "/usr/bin/kubelet --kubeconfig=/etc/kubernetes/kubelet.conf --config /var/lib/kubelet/config.yaml --hostname-override=kind-worker")()
+	defer func() { statFunc = os.Stat }()
+	statFunc = func(file string) (os.FileInfo, error) {
+		if file == "/proc/4242/root/etc/systemd/system/kubelet.service.d/10-kubeadm.conf" {
+			return nil, nil
+		}
+		return nil, os.ErrNotExist
+	}
+
+	confmap := map[string]string{"kubelet": "/etc/kubernetes/kubelet/kubelet-config.json", "proxy": "/var/lib/kube-proxy/config.conf"}
+	svcmap := map[string]string{"kubelet": "/etc/systemd/system/kubelet.service.d/10-kubeadm.conf"}
+	kubeconfmap := map[string]string{"kubelet": "kubelet"}
+	cafilemap := map[string]string{"kubelet": "/etc/kubernetes/pki/ca.crt"}
+	useKubeletInstanceFiles(confmap, svcmap, kubeconfmap, cafilemap)
+
+	for _, c := range []struct {
+		m        map[string]string
+		expected map[string]string
+	}{
+		{confmap, map[string]string{"kubelet": "/proc/4242/root/var/lib/kubelet/config.yaml", "proxy": "/var/lib/kube-proxy/config.conf"}},
+		{svcmap, map[string]string{"kubelet": "/proc/4242/root/etc/systemd/system/kubelet.service.d/10-kubeadm.conf"}},
+		{kubeconfmap, map[string]string{"kubelet": "/proc/4242/root/etc/kubernetes/kubelet.conf"}},
+		// Files the instance doesn't have are the ones of the host.
+		{cafilemap, map[string]string{"kubelet": "/etc/kubernetes/pki/ca.crt"}},
+	} {
+		if !reflect.DeepEqual(c.m, c.expected) {
+			t.Errorf("expected %v, got %v", c.expected, c.m)
+		}
+	}
+
+	if label := kubeletInstanceLabel(); label != "kubelet kind-worker, PID 4242" {
+		t.Errorf("unexpected label %q", label)
+	}
+	if name := kubeletInstanceName(); name != "kind-worker" {
+		t.Errorf("unexpected name %q", name)
+	}
+}
+
+func TestUseKubeletInstanceAudits(t *testing.T) {
+	defer withKubeletInstance(4242, "kubelet --anonymous-auth=false")()
+
+	in := `audit: "/bin/ps -fC $kubeletbin"
+audit: "/bin/ps -fC $kubeletbin "
+audit_config: "/bin/cat $kubeletconf"
+audit: "/bin/ps -fC $kubeletbinary"`
+	expected := `audit: "/bin/ps -fp 4242"
+audit: "/bin/ps -fp 4242 "
+audit_config: "/bin/cat $kubeletconf"
+audit: "/bin/ps -fC $kubeletbinary"`
+	if got := useKubeletInstanceAudits(in); got != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, got)
+	}
+
+	if label := kubeletInstanceLabel(); label != "kubelet PID 4242" {
+		t.Errorf("unexpected label %q", label)
+	}
+	if name := kubeletInstanceName(); name != "4242" {
+		t.Errorf("unexpected name %q", name)
+	}
+}
@@ -0,0 +1,74 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+)
+
+// catalogsDir is the directory of the message catalogs of a benchmark,
+// next to its controls files.
+const catalogsDir = "i18n"
+
+// languageRe matches language tags such as fr or pt-BR.
+var languageRe = regexp.MustCompile(`^[a-z]{2,3}([-_][A-Za-z0-9]{2,8})*$`)
+
+// checkLanguage verifies the language given with --lang.
+func checkLanguage(lang string) error {
+	if lang != "" && !languageRe.MatchString(lang) {
+		return fmt.Errorf("%q is not a language tag, e.g. fr or pt-BR", lang)
+	}
+	return nil
+}
+
+// translateControls translates the messages of the controls read from a
+// controls file with its catalog for the language, i18n/<lang>/<file> in the
+// directory of the controls file. IDs are never translated, and the messages
+// missing from the catalog are left in English.
+func translateControls(controls *check.Controls, testYamlFile, lang string) error {
+	if lang == "" {
+		return nil
+	}
+
+	dir := filepath.Join(filepath.Dir(testYamlFile), catalogsDir, lang)
+	if _, err := os.Stat(dir); err != nil {
+		continueWithError(err, fmt.Sprintf("No %s messages for the benchmark in %s, the results are in English", lang, filepath.Dir(testYamlFile)))
+		return nil
+	}
+
+	file := filepath.Join(dir, filepath.Base(testYamlFile))
+	in, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		glog.V(1).Infof("No %s messages for %s", lang, testYamlFile)
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	catalog, err := check.NewCatalog(in)
+	if err != nil {
+		return fmt.Errorf("error reading %s: %v", file, err)
+	}
+	controls.Translate(catalog)
+	return nil
+}
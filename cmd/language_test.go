@@ -0,0 +1,79 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+func TestCheckLanguage(t *testing.T) {
+	for _, lang := range []string{"", "fr", "pt-BR", "zh_Hans"} {
+		if err := checkLanguage(lang); err != nil {
+			t.Errorf("%q: unexpected error %v", lang, err)
+		}
+	}
+	for _, lang := range []string{"French", "../fr", "fr/../../etc"} {
+		if err := checkLanguage(lang); err == nil {
+			t.Errorf("%q: expected an error", lang)
+		}
+	}
+}
+
+func TestTranslateControls(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-lang")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := os.MkdirAll(filepath.Join(dir, catalogsDir, "fr"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	catalog := "checks:\n  4.2.1:\n    text: \"Vérifier que l'argument --anonymous-auth est à false (Scored)\"\n"
+	if err := ioutil.WriteFile(filepath.Join(dir, catalogsDir, "fr", "node.yaml"), []byte(catalog), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	controls := githubControls()
+	if err := translateControls(controls, filepath.Join(dir, "node.yaml"), "fr"); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if text := controls.Groups[0].Checks[0].Text; text != "Vérifier que l'argument --anonymous-auth est à false (Scored)" {
+		t.Errorf("unexpected text %q", text)
+	}
+
+	// Controls files and languages without catalogs are left in English.
+	for _, c := range []struct{ file, lang string }{{"master.yaml", "fr"}, {"node.yaml", "de"}, {"node.yaml", ""}} {
+		controls := githubControls()
+		if err := translateControls(controls, filepath.Join(dir, c.file), c.lang); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		if controls.Groups[0].Checks[0].Text != githubControls().Groups[0].Checks[0].Text {
+			t.Errorf("%s %q: expected the controls in English", c.file, c.lang)
+		}
+	}
+
+	if err := ioutil.WriteFile(filepath.Join(dir, catalogsDir, "fr", "master.yaml"), []byte("checks: ["), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := translateControls(&check.Controls{}, filepath.Join(dir, "master.yaml"), "fr"); err == nil {
+		t.Errorf("expected an error for an invalid catalog")
+	}
+}
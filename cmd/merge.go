@@ -0,0 +1,256 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/spf13/cobra"
+)
+
+// nodeResults are the results of the targets run on a node.
+type nodeResults struct {
+	Node    string
+	Results []*check.Controls
+}
+
+// resultsCohort is a group of nodes with identical results.
+type resultsCohort struct {
+	// Nodes are the names of the nodes of the cohort, the first one being
+	// the one whose results are reported.
+	Nodes   []string          `json:"nodes"`
+	Results []*check.Controls `json:"results"`
+	key     string
+	// scans are when the results of each node were produced.
+	scans []resultsScan
+}
+
+// resultsScan is when the results of a node were produced, and when they
+// expire if they were given a TTL.
+type resultsScan struct {
+	End       time.Time
+	ExpiresAt *time.Time
+}
+
+// scan returns when the last target of the node finished running, and when
+// the first one to expire does.
+func (n *nodeResults) scan() resultsScan {
+	var s resultsScan
+	for _, controls := range n.Results {
+		if controls.EndTime.After(s.End) {
+			s.End = controls.EndTime
+		}
+		if controls.ExpiresAt != nil && (s.ExpiresAt == nil || controls.ExpiresAt.Before(*s.ExpiresAt)) {
+			s.ExpiresAt = controls.ExpiresAt
+		}
+	}
+	return s
+}
+
+// stale reports whether the results are past their expiry, or older than
+// --result-ttl, at now.
+func (s resultsScan) stale(now time.Time) bool {
+	if s.ExpiresAt != nil && now.After(*s.ExpiresAt) {
+		return true
+	}
+	return !s.End.IsZero() && resultsStale(s.End, now)
+}
+
+// mergedResults are the results of a fleet of nodes, grouped into cohorts.
+type mergedResults struct {
+	Nodes   int              `json:"nodes"`
+	Cohorts []*resultsCohort `json:"cohorts"`
+}
+
+// mergeCmd represents the merge command
+var mergeCmd = &cobra.Command{
+	Use:   "merge <results>...",
+	Short: "Merge the JSON results of many nodes, grouping the nodes with identical results",
+	Long: `Merge the results of many nodes, saved with --json, e.g. kube-bench merge results/*.json.
+Nodes whose checks have the same results are grouped into cohorts, reported once as
+"42 nodes identical to node-1", keeping the report of a fleet readable. Directories are
+read for the .json files they hold.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		nodes, err := loadNodeResults(args)
+		if err != nil {
+			exitWithError(err)
+		}
+		merged := mergeNodeResults(nodes)
+		if clusterSummary {
+			if err := writeClusterSummary(summarizeFleet(merged, time.Now())); err != nil {
+				exitWithError(err)
+			}
+		}
+
+		if jsonFmt {
+			out, err := json.MarshalIndent(merged, "", "  ")
+			if err != nil {
+				exitWithError(fmt.Errorf("failed to output in JSON format: %v", err))
+			}
+			PrintOutput(string(out), outputFile)
+			return
+		}
+		printMergedResults(os.Stdout, merged)
+	},
+}
+
+// clusterSummary is set with --cluster-summary.
+var clusterSummary bool
+
+func init() {
+	mergeCmd.Flags().BoolVar(&clusterSummary, "cluster-summary", false, "Also maintains the ClusterComplianceSummary resource of the cluster with the score of the fleet and its worst offenders")
+	RootCmd.AddCommand(mergeCmd)
+}
+
+// loadNodeResults reads the results of the nodes from files holding the JSON
+// results of their targets. The results of a node may be spread over
+// several files.
+func loadNodeResults(paths []string) ([]*nodeResults, error) {
+	var files []string
+	for _, path := range paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, err
+		}
+		if !info.IsDir() {
+			files = append(files, path)
+			continue
+		}
+		matches, err := filepath.Glob(filepath.Join(path, "*.json"))
+		if err != nil {
+			return nil, err
+		}
+		files = append(files, matches...)
+	}
+
+	var nodes []*nodeResults
+	byName := map[string]*nodeResults{}
+	for _, file := range files {
+		f, err := os.Open(file)
+		if err != nil {
+			return nil, err
+		}
+		decoder := json.NewDecoder(f)
+		for decoder.More() {
+			controls := new(check.Controls)
+			if err := decoder.Decode(controls); err != nil {
+				f.Close()
+				return nil, fmt.Errorf("error reading results of %s: %v", file, err)
+			}
+
+			name := resultsNodeName(controls, file)
+			n, ok := byName[name]
+			if !ok {
+				n = &nodeResults{Node: name}
+				byName[name] = n
+				nodes = append(nodes, n)
+			}
+			n.Results = append(n.Results, controls)
+		}
+		f.Close()
+	}
+	if len(nodes) == 0 {
+		return nil, fmt.Errorf("no results found in %s", strings.Join(paths, ", "))
+	}
+	return nodes, nil
+}
+
+// resultsNodeName returns the name of the node results are from, or the name
+// of their file when they have no metadata.
+func resultsNodeName(controls *check.Controls, file string) string {
+	if m := controls.Metadata; m != nil {
+		if m.NodeName != "" {
+			return m.NodeName
+		}
+		if m.Hostname != "" {
+			return m.Hostname
+		}
+	}
+	return strings.TrimSuffix(filepath.Base(file), filepath.Ext(file))
+}
+
+// resultsKey identifies the results of a node: the targets run and the state
+// of each of their checks.
+func resultsKey(n *nodeResults) string {
+	var lines []string
+	for _, controls := range n.Results {
+		for _, g := range controls.Groups {
+			for _, c := range g.Checks {
+				lines = append(lines, fmt.Sprintf("%s %s %s", controls.Type, c.ID, c.State))
+			}
+		}
+	}
+	sort.Strings(lines)
+	sum := sha256.Sum256([]byte(strings.Join(lines, "\n")))
+	return hex.EncodeToString(sum[:])
+}
+
+// mergeNodeResults groups the nodes with identical results into cohorts,
+// the largest first.
+func mergeNodeResults(nodes []*nodeResults) *mergedResults {
+	merged := &mergedResults{Nodes: len(nodes)}
+	byKey := map[string]*resultsCohort{}
+	for _, n := range nodes {
+		key := resultsKey(n)
+		cohort, ok := byKey[key]
+		if !ok {
+			cohort = &resultsCohort{Results: n.Results, key: key}
+			byKey[key] = cohort
+			merged.Cohorts = append(merged.Cohorts, cohort)
+		}
+		cohort.Nodes = append(cohort.Nodes, n.Node)
+		cohort.scans = append(cohort.scans, n.scan())
+	}
+
+	sort.SliceStable(merged.Cohorts, func(i, j int) bool {
+		return len(merged.Cohorts[i].Nodes) > len(merged.Cohorts[j].Nodes)
+	})
+	return merged
+}
+
+func printMergedResults(w io.Writer, m *mergedResults) {
+	fmt.Fprintf(w, "Results of %d nodes in %d cohorts\n", m.Nodes, len(m.Cohorts))
+	for _, cohort := range m.Cohorts {
+		fmt.Fprintln(w)
+		if others := len(cohort.Nodes) - 1; others > 0 {
+			fmt.Fprintf(w, "== %s and %d nodes identical to it ==\n", cohort.Nodes[0], others)
+		} else {
+			fmt.Fprintf(w, "== %s ==\n", cohort.Nodes[0])
+		}
+
+		for _, controls := range cohort.Results {
+			for _, g := range controls.Groups {
+				for _, c := range g.Checks {
+					if c.State == check.FAIL || c.State == check.WARN {
+						fmt.Fprintf(w, "[%s] %s %s\n", c.State, c.ID, c.Text)
+					}
+				}
+			}
+			fmt.Fprintf(w, "%s: %d checks PASS, %d checks FAIL, %d checks WARN, %d checks INFO, %d checks SKIP\n",
+				controls.Type, controls.Pass, controls.Fail, controls.Warn, controls.Info, controls.Skip)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func nodeResultsJSON(node, state string) string {
+	return fmt.Sprintf(`{"id":"4","text":"Worker Node Security Configuration","node_type":"node","tests":[{"section":"4.2","desc":"Kubelet","results":[`+
+		`{"test_number":"4.2.1","test_desc":"Ensure that the --anonymous-auth argument is set to false (Scored)","status":"PASS"},`+
+		`{"test_number":"4.2.6","test_desc":"Ensure that the --protect-kernel-defaults argument is set to true (Scored)","status":"%s"}]}],`+
+		`"metadata":{"node_name":"%s"},"total_pass":1,"total_fail":%d}`, state, node, map[string]int{"FAIL": 1}[state])
+}
+
+func TestMergeNodeResults(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-merge")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	results := map[string]string{
+		"node-1.json": nodeResultsJSON("node-1", "FAIL"),
+		"node-2.json": nodeResultsJSON("node-2", "PASS"),
+		// The results of a run of several targets, or of several nodes.
+		"nodes.json": nodeResultsJSON("node-3", "FAIL") + "\n" + nodeResultsJSON("node-4", "FAIL") + "\n",
+	}
+	for name, data := range results {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(data), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	nodes, err := loadNodeResults([]string{dir})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	merged := mergeNodeResults(nodes)
+
+	var out bytes.Buffer
+	printMergedResults(&out, merged)
+	expected := `Results of 4 nodes in 2 cohorts
+
+== node-1 and 2 nodes identical to it ==
+[FAIL] 4.2.6 Ensure that the --protect-kernel-defaults argument is set to true (Scored)
+node: 1 checks PASS, 1 checks FAIL, 0 checks WARN, 0 checks INFO, 0 checks SKIP
+
+== node-2 ==
+node: 1 checks PASS, 0 checks FAIL, 0 checks WARN, 0 checks INFO, 0 checks SKIP
+`
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+	if nodes := merged.Cohorts[0].Nodes; len(nodes) != 3 || nodes[1] != "node-3" || nodes[2] != "node-4" {
+		t.Errorf("unexpected nodes %v", nodes)
+	}
+
+	if _, err := loadNodeResults([]string{filepath.Join(dir, "missing.json")}); err == nil {
+		t.Errorf("expected an error for missing results")
+	}
+}
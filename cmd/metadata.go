@@ -0,0 +1,188 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bufio"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"regexp"
+	"strings"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+	"github.com/spf13/viper"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// nodeMetadata is the metadata of the node being scanned, it's gathered once.
+var nodeMetadata *check.NodeMetadata
+
+// kubeClient, osRelease, kernelRelease, kubeletVersion and runtimeVersion
+// are replaced in tests.
+var (
+	kubeClient     = check.KubeClient
+	osRelease      = "/etc/os-release"
+	kernelRelease  = "/proc/sys/kernel/osrelease"
+	kubeletVersion = func() (string, error) {
+		out, err := exec.Command("kubelet", "--version").Output()
+		return string(out), err
+	}
+	runtimeVersion = func() (string, error) {
+		out, err := exec.Command("crictl", "version").Output()
+		return string(out), err
+	}
+)
+
+var (
+	kubeletVersionRe = regexp.MustCompile(`Kubernetes (v\S+)`)
+	runtimeNameRe    = regexp.MustCompile(`(?m)^RuntimeName:\s*(\S+)`)
+	runtimeVersionRe = regexp.MustCompile(`(?m)^RuntimeVersion:\s*v?(\S+)`)
+	// The cgroup driver in the flags of the kubelet, and in its config file
+	// in YAML or JSON.
+	cgroupDriverFlagRe   = regexp.MustCompile(`--cgroup-driver[= ]"?(\w+)`)
+	cgroupDriverConfigRe = regexp.MustCompile(`"?cgroupDriver"?\s*:\s*"?(\w+)`)
+)
+
+func currentNodeMetadata() *check.NodeMetadata {
+	if nodeMetadata == nil {
+		nodeMetadata = getNodeMetadata()
+	}
+	return nodeMetadata
+}
+
+// nodeVariables returns the values of the node variables of the controls,
+// e.g. $nodename, from the metadata of the node. Unknown values are left out,
+// the variables are then left as is.
+func nodeVariables(m *check.NodeMetadata) map[string]string {
+	return map[string]string{
+		"nodename":       m.NodeName,
+		"hostname":       m.Hostname,
+		"kubeletversion": m.KubeletVersion,
+		"kernelversion":  m.KernelVersion,
+		"cloudprovider":  m.CloudProvider,
+		"clustername":    m.ClusterName,
+	}
+}
+
+// getNodeMetadata describes the node being scanned from what can be found
+// locally, completed with its Node object when running in a pod. The node
+// name defaults to the hostname, and can be set with node_name in the config,
+// or the KUBE_BENCH_NODE_NAME environment variable.
+func getNodeMetadata() *check.NodeMetadata {
+	m := &check.NodeMetadata{
+		NodeName:    viper.GetString("node_name"),
+		ClusterName: viper.GetString("cluster_name"),
+	}
+	m.Hostname, _ = os.Hostname()
+	if m.NodeName == "" {
+		m.NodeName = m.Hostname
+	}
+
+	// Mock results are not gathered from the node.
+	if mockMode != "" {
+		return m
+	}
+
+	m.OS = prettyOSName(osRelease)
+	if data, err := ioutil.ReadFile(kernelRelease); err == nil {
+		m.KernelVersion = strings.TrimSpace(string(data))
+	}
+	if out, err := kubeletVersion(); err == nil {
+		if subs := kubeletVersionRe.FindStringSubmatch(out); subs != nil {
+			m.KubeletVersion = subs[1]
+		}
+	}
+
+	if out, err := runtimeVersion(); err == nil {
+		if subs := runtimeNameRe.FindStringSubmatch(out); subs != nil {
+			m.ContainerRuntime = subs[1]
+		}
+		if subs := runtimeVersionRe.FindStringSubmatch(out); subs != nil {
+			m.ContainerRuntimeVersion = subs[1]
+		}
+	}
+	m.CgroupDriver = kubeletCgroupDriver()
+
+	// Outside of a cluster, the kubeconfig could point to an unreachable API
+	// server, making every scan wait for it.
+	if os.Getenv("KUBERNETES_SERVICE_HOST") == "" {
+		return m
+	}
+	client, err := kubeClient()
+	if err != nil {
+		glog.V(2).Infof("Node metadata not completed from the Kubernetes API: %v", err)
+		return m
+	}
+	node, err := client.CoreV1().Nodes().Get(m.NodeName, metav1.GetOptions{})
+	if err != nil {
+		glog.V(2).Infof("Node metadata not completed from the Kubernetes API: %v", err)
+		return m
+	}
+
+	m.Labels = node.Labels
+	m.Annotations = node.Annotations
+	m.OS = node.Status.NodeInfo.OSImage
+	m.KernelVersion = node.Status.NodeInfo.KernelVersion
+	m.KubeletVersion = node.Status.NodeInfo.KubeletVersion
+	// The container runtime version is <runtime>://<version>.
+	if i := strings.Index(node.Status.NodeInfo.ContainerRuntimeVersion, "://"); i > 0 {
+		m.ContainerRuntime = node.Status.NodeInfo.ContainerRuntimeVersion[:i]
+		m.ContainerRuntimeVersion = node.Status.NodeInfo.ContainerRuntimeVersion[i+3:]
+	}
+	// The provider ID is <provider>://<provider specific ID>.
+	if i := strings.Index(node.Spec.ProviderID, "://"); i > 0 {
+		m.CloudProvider = node.Spec.ProviderID[:i]
+	}
+	return m
+}
+
+// kubeletCgroupDriver returns the cgroup driver of the kubelet, from its
+// flags or else from its config file, or "" when it's not set in either.
+func kubeletCgroupDriver() string {
+	if subs := cgroupDriverFlagRe.FindStringSubmatch(psFunc("kubelet")); subs != nil {
+		return subs[1]
+	}
+	file := findConfigFile(viper.GetStringSlice("node.kubelet.confs"))
+	if file == "" {
+		return ""
+	}
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+	if subs := cgroupDriverConfigRe.FindSubmatch(data); subs != nil {
+		return string(subs[1])
+	}
+	return ""
+}
+
+// prettyOSName returns the name of the operating system from its os-release file.
+func prettyOSName(file string) string {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return ""
+	}
+
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if v := strings.TrimPrefix(scanner.Text(), "PRETTY_NAME="); v != scanner.Text() {
+			return strings.Trim(v, `"'`)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,134 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/spf13/viper"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestGetNodeMetadata(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-metadata")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	savedOS, savedKernel, savedKubelet, savedRuntime, savedClient, savedPs := osRelease, kernelRelease, kubeletVersion, runtimeVersion, kubeClient, psFunc
+	defer func() {
+		osRelease, kernelRelease, kubeletVersion, runtimeVersion, kubeClient, psFunc = savedOS, savedKernel, savedKubelet, savedRuntime, savedClient, savedPs
+		viper.Set("node_name", "")
+		viper.Set("cluster_name", "")
+		os.Unsetenv("KUBERNETES_SERVICE_HOST")
+	}()
+
+	osRelease = filepath.Join(dir, "os-release")
+	kernelRelease = filepath.Join(dir, "osrelease")
+	if err := ioutil.WriteFile(osRelease, []byte("NAME=\"Ubuntu\"\nPRETTY_NAME=\"Ubuntu 18.04.4 LTS\"\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(kernelRelease, []byte("4.15.0-1057-aws\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+	kubeletVersion = func() (string, error) { return "Kubernetes v1.15.10\n", nil }
+	runtimeVersion = func() (string, error) {
+		return "Version:  0.1.0\nRuntimeName:  containerd\nRuntimeVersion:  v1.4.3\nRuntimeApiVersion:  v1alpha2\n", nil
+	}
+	psFunc = func(string) string {
+		return "/usr/bin/kubelet --config=/var/lib/kubelet/config.yaml --cgroup-driver=systemd\n"
+	}
+
+	viper.Set("node_name", "ip-10-0-1-12")
+	viper.Set("cluster_name", "prod")
+	hostname, _ := os.Hostname()
+
+	// Outside of a cluster only local information is available.
+	kubeClient = func() (kubernetes.Interface, error) { return nil, errors.New("unexpected API call") }
+	expected := &check.NodeMetadata{
+		Hostname:                hostname,
+		NodeName:                "ip-10-0-1-12",
+		OS:                      "Ubuntu 18.04.4 LTS",
+		KernelVersion:           "4.15.0-1057-aws",
+		KubeletVersion:          "v1.15.10",
+		ClusterName:             "prod",
+		ContainerRuntime:        "containerd",
+		ContainerRuntimeVersion: "1.4.3",
+		CgroupDriver:            "systemd",
+	}
+	if got := getNodeMetadata(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+
+	os.Setenv("KUBERNETES_SERVICE_HOST", "10.96.0.1")
+	kubeClient = func() (kubernetes.Interface, error) {
+		return fake.NewSimpleClientset(&corev1.Node{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:        "ip-10-0-1-12",
+				Labels:      map[string]string{"node-role.kubernetes.io/worker": ""},
+				Annotations: map[string]string{skipAnnotation: "4.2.6"},
+			},
+			Spec: corev1.NodeSpec{ProviderID: "aws:///us-east-1a/i-0123456789abcdef0"},
+			Status: corev1.NodeStatus{NodeInfo: corev1.NodeSystemInfo{
+				OSImage:                 "Amazon Linux 2",
+				KernelVersion:           "4.14.173-137.229.amzn2.x86_64",
+				KubeletVersion:          "v1.15.11-eks-af3caf",
+				ContainerRuntimeVersion: "docker://19.3.6",
+			}},
+		}), nil
+	}
+	expected = &check.NodeMetadata{
+		Hostname:                hostname,
+		NodeName:                "ip-10-0-1-12",
+		Labels:                  map[string]string{"node-role.kubernetes.io/worker": ""},
+		OS:                      "Amazon Linux 2",
+		KernelVersion:           "4.14.173-137.229.amzn2.x86_64",
+		KubeletVersion:          "v1.15.11-eks-af3caf",
+		CloudProvider:           "aws",
+		ClusterName:             "prod",
+		Annotations:             map[string]string{skipAnnotation: "4.2.6"},
+		ContainerRuntime:        "docker",
+		ContainerRuntimeVersion: "19.3.6",
+		CgroupDriver:            "systemd",
+	}
+	if got := getNodeMetadata(); !reflect.DeepEqual(got, expected) {
+		t.Errorf("expected %+v, got %+v", expected, got)
+	}
+
+	// The node name defaults to the hostname, which is not a known node.
+	viper.Set("node_name", "")
+	got := getNodeMetadata()
+	if got.NodeName != hostname || got.CloudProvider != "" || got.OS != "Ubuntu 18.04.4 LTS" {
+		t.Errorf("expected local metadata of %s, got %+v", hostname, got)
+	}
+}
+
+func TestNodeVariables(t *testing.T) {
+	m := &check.NodeMetadata{NodeName: "gpu-1", Hostname: "ip-10-0-0-1", KubeletVersion: "v1.18.2", CloudProvider: "aws"}
+	s := makeSubstitutions("kubelet $kubeletversion on $nodename ($hostname) in $cloudprovider $clustername", "", nodeVariables(m))
+	if expected := "kubelet v1.18.2 on gpu-1 (ip-10-0-0-1) in aws $clustername"; s != expected {
+		t.Errorf("expected %q, got %q", expected, s)
+	}
+}
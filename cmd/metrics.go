@@ -0,0 +1,153 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+)
+
+// metricsResults collects the results of this run when --metrics-textfile
+// is given.
+var metricsResults []*check.Controls
+
+func addToMetrics(controls *check.Controls) {
+	if metricsTextfile != "" {
+		metricsResults = append(metricsResults, controls)
+	}
+}
+
+// writeMetricsTextfile writes the results of the run in the textfile format
+// of the node_exporter textfile collector. The file is replaced atomically,
+// so that the collector never reads it half written.
+func writeMetricsTextfile() error {
+	if metricsTextfile == "" || len(metricsResults) == 0 {
+		return nil
+	}
+
+	var buf bytes.Buffer
+	writeMetrics(&buf, metricsResults, time.Now())
+
+	tmp, err := ioutil.TempFile(filepath.Dir(metricsTextfile), "."+filepath.Base(metricsTextfile))
+	if err != nil {
+		return fmt.Errorf("failed to write metrics to %s: %v", metricsTextfile, err)
+	}
+	defer os.Remove(tmp.Name())
+	if _, err := tmp.Write(buf.Bytes()); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to write metrics to %s: %v", metricsTextfile, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("failed to write metrics to %s: %v", metricsTextfile, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0644); err != nil {
+		return fmt.Errorf("failed to write metrics to %s: %v", metricsTextfile, err)
+	}
+	if err := os.Rename(tmp.Name(), metricsTextfile); err != nil {
+		return fmt.Errorf("failed to write metrics to %s: %v", metricsTextfile, err)
+	}
+	glog.V(1).Infof("Metrics written to %s", metricsTextfile)
+	return nil
+}
+
+// writeMetrics outputs the results in the Prometheus text format.
+func writeMetrics(w io.Writer, results []*check.Controls, now time.Time) {
+	fmt.Fprintln(w, "# HELP kube_bench_checks Number of checks of the target in each state.")
+	fmt.Fprintln(w, "# TYPE kube_bench_checks gauge")
+	for _, controls := range results {
+		for _, s := range []struct {
+			state check.State
+			count int
+		}{
+			{check.PASS, controls.Pass},
+			{check.FAIL, controls.Fail},
+			{check.WARN, controls.Warn},
+			{check.INFO, controls.Info},
+			{check.SKIP, controls.Skip},
+		} {
+			fmt.Fprintf(w, "kube_bench_checks{%s,state=%s} %d\n", targetLabels(controls), metricLabel(string(s.state)), s.count)
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP kube_bench_score Percentage of the checks of the target that passed, out of the ones that passed, failed or warned.")
+	fmt.Fprintln(w, "# TYPE kube_bench_score gauge")
+	for _, controls := range results {
+		fmt.Fprintf(w, "kube_bench_score{%s} %g\n", targetLabels(controls), controls.Summary.Score())
+	}
+
+	fmt.Fprintln(w, "# HELP kube_bench_check_state State of each check, 1 for its current state.")
+	fmt.Fprintln(w, "# TYPE kube_bench_check_state gauge")
+	for _, controls := range results {
+		for _, g := range controls.Groups {
+			for _, c := range g.Checks {
+				fmt.Fprintf(w, "kube_bench_check_state{%s,id=%s,state=%s} 1\n", targetLabels(controls), metricLabel(c.ID), metricLabel(string(c.State)))
+			}
+		}
+	}
+
+	fmt.Fprintln(w, "# HELP kube_bench_run_duration_seconds Time taken to run the checks of the target.")
+	fmt.Fprintln(w, "# TYPE kube_bench_run_duration_seconds gauge")
+	for _, controls := range results {
+		var d float64
+		if !controls.StartTime.IsZero() && controls.EndTime.After(controls.StartTime) {
+			d = controls.EndTime.Sub(controls.StartTime).Seconds()
+		}
+		fmt.Fprintf(w, "kube_bench_run_duration_seconds{%s} %g\n", targetLabels(controls), d)
+	}
+
+	fmt.Fprintln(w, "# HELP kube_bench_last_run_timestamp_seconds Time the results were written, in seconds since the epoch.")
+	fmt.Fprintln(w, "# TYPE kube_bench_last_run_timestamp_seconds gauge")
+	fmt.Fprintf(w, "kube_bench_last_run_timestamp_seconds %d\n", now.Unix())
+
+	// Results are stale past their expiry, e.g. when kube-bench stopped
+	// running: time() > kube_bench_results_expiry_timestamp_seconds.
+	if resultTTL > 0 {
+		fmt.Fprintln(w, "# HELP kube_bench_results_expiry_timestamp_seconds Time the results go stale, with a result TTL, in seconds since the epoch.")
+		fmt.Fprintln(w, "# TYPE kube_bench_results_expiry_timestamp_seconds gauge")
+		fmt.Fprintf(w, "kube_bench_results_expiry_timestamp_seconds %d\n", now.Add(resultTTL).Unix())
+	}
+
+	if len(results) > 0 && results[0].RunID != "" {
+		fmt.Fprintln(w, "# HELP kube_bench_run_info Identity of the run the results are from, always 1.")
+		fmt.Fprintln(w, "# TYPE kube_bench_run_info gauge")
+		fmt.Fprintf(w, "kube_bench_run_info{run_id=%s} 1\n", metricLabel(results[0].RunID))
+	}
+}
+
+// targetLabels labels the series of a target, and of the instance of the
+// target with --kubelet-instances or remote etcd members, which would else
+// give several series with the same labels.
+func targetLabels(controls *check.Controls) string {
+	labels := "target=" + metricLabel(string(controls.Type))
+	if controls.Instance != "" {
+		labels += ",instance=" + metricLabel(controls.Instance)
+	}
+	return labels
+}
+
+// metricLabel quotes the value of a label, escaping it as the text format
+// requires.
+func metricLabel(v string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(v) + `"`
+}
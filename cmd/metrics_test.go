@@ -0,0 +1,156 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+func TestWriteMetrics(t *testing.T) {
+	start := time.Date(2020, 6, 1, 12, 0, 0, 0, time.UTC)
+	controls := &check.Controls{
+		Type:  check.NODE,
+		RunID: "run-1",
+		Groups: []*check.Group{{ID: "4.2", Checks: []*check.Check{
+			{ID: "4.2.1", State: check.PASS},
+			{ID: "4.2.6", State: check.FAIL},
+		}}},
+		Summary: check.Summary{Pass: 1, Fail: 1, StartTime: start, EndTime: start.Add(1500 * time.Millisecond)},
+	}
+
+	var out bytes.Buffer
+	writeMetrics(&out, []*check.Controls{controls}, start.Add(2*time.Second))
+	expected := `# HELP kube_bench_checks Number of checks of the target in each state.
+# TYPE kube_bench_checks gauge
+kube_bench_checks{target="node",state="PASS"} 1
+kube_bench_checks{target="node",state="FAIL"} 1
+kube_bench_checks{target="node",state="WARN"} 0
+kube_bench_checks{target="node",state="INFO"} 0
+kube_bench_checks{target="node",state="SKIP"} 0
+# HELP kube_bench_score Percentage of the checks of the target that passed, out of the ones that passed, failed or warned.
+# TYPE kube_bench_score gauge
+kube_bench_score{target="node"} 50
+# HELP kube_bench_check_state State of each check, 1 for its current state.
+# TYPE kube_bench_check_state gauge
+kube_bench_check_state{target="node",id="4.2.1",state="PASS"} 1
+kube_bench_check_state{target="node",id="4.2.6",state="FAIL"} 1
+# HELP kube_bench_run_duration_seconds Time taken to run the checks of the target.
+# TYPE kube_bench_run_duration_seconds gauge
+kube_bench_run_duration_seconds{target="node"} 1.5
+# HELP kube_bench_last_run_timestamp_seconds Time the results were written, in seconds since the epoch.
+# TYPE kube_bench_last_run_timestamp_seconds gauge
+kube_bench_last_run_timestamp_seconds 1591012802
+# HELP kube_bench_run_info Identity of the run the results are from, always 1.
+# TYPE kube_bench_run_info gauge
+kube_bench_run_info{run_id="run-1"} 1
+`
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+
+	defer func(ttl time.Duration) { resultTTL = ttl }(resultTTL)
+	resultTTL = time.Hour
+	out.Reset()
+	writeMetrics(&out, []*check.Controls{controls}, start.Add(2*time.Second))
+	if expiry := "\nkube_bench_results_expiry_timestamp_seconds 1591016402\n"; !strings.Contains(out.String(), expiry) {
+		t.Errorf("expected %q in:\n%s", expiry, out.String())
+	}
+}
+
+func TestWriteMetricsInstances(t *testing.T) {
+	// Two kubelets run on the host with --kubelet-instances, e.g. with kind.
+	var results []*check.Controls
+	for _, instance := range []string{"kind-control-plane", "kind-worker"} {
+		results = append(results, &check.Controls{
+			Type:     check.NODE,
+			Instance: instance,
+			Groups: []*check.Group{{ID: "4.2", Checks: []*check.Check{
+				{ID: "4.2.1", State: check.PASS},
+			}}},
+			Summary: check.Summary{Pass: 1},
+		})
+	}
+
+	var out bytes.Buffer
+	writeMetrics(&out, results, time.Now())
+	series := map[string]bool{}
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		if strings.HasPrefix(line, "#") {
+			continue
+		}
+		name := line[:strings.LastIndex(line, " ")]
+		if series[name] {
+			t.Errorf("duplicate series %s", name)
+		}
+		series[name] = true
+	}
+	for _, expected := range []string{
+		`kube_bench_score{target="node",instance="kind-control-plane"}`,
+		`kube_bench_check_state{target="node",instance="kind-worker",id="4.2.1",state="PASS"}`,
+	} {
+		if !series[expected] {
+			t.Errorf("expected series %s in:\n%s", expected, out.String())
+		}
+	}
+}
+
+func TestMetricLabel(t *testing.T) {
+	if label := metricLabel("a \"b\"\\c\nd"); label != `"a \"b\"\\c\nd"` {
+		t.Errorf("unexpected label %s", label)
+	}
+}
+
+func TestWriteMetricsTextfile(t *testing.T) {
+	defer func(file string, results []*check.Controls) {
+		metricsTextfile, metricsResults = file, results
+	}(metricsTextfile, metricsResults)
+
+	dir, err := ioutil.TempDir("", "kube-bench-metrics")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	metricsTextfile, metricsResults = filepath.Join(dir, "kube_bench.prom"), nil
+	addToMetrics(&check.Controls{Type: check.MASTER, Summary: check.Summary{Pass: 3, Fail: 1}})
+	if err := writeMetricsTextfile(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := ioutil.ReadFile(metricsTextfile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(data), `kube_bench_score{target="master"} 75`) {
+		t.Errorf("unexpected metrics:\n%s", data)
+	}
+	// No temporary file is left behind for the collector to read.
+	if files, _ := filepath.Glob(filepath.Join(dir, "*")); len(files) != 1 {
+		t.Errorf("unexpected files %v", files)
+	}
+
+	metricsTextfile = filepath.Join(dir, "missing", "kube_bench.prom")
+	if err := writeMetricsTextfile(); err == nil {
+		t.Errorf("expected an error writing to a missing directory")
+	}
+}
@@ -0,0 +1,77 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+func TestMockRunner(t *testing.T) {
+	if _, err := newMockRunner("bogus"); err == nil {
+		t.Errorf("expected an error for an invalid mode")
+	}
+
+	for mode, expected := range map[string]check.State{mockPass: check.PASS, mockFail: check.FAIL} {
+		r, err := newMockRunner(mode)
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		// The audit would fail if it was run.
+		c := &check.Check{ID: "1.1.1", Audit: "/nonexistent", Scored: true}
+		if state := r.Run(c); state != expected || c.State != expected {
+			t.Errorf("%s: expected %s, got %s", mode, expected, state)
+		}
+	}
+
+	r, err := newMockRunner(mockMixed)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	seen := map[check.State]bool{}
+	for i := 1; i <= 20; i++ {
+		id := fmt.Sprintf("1.1.%d", i)
+		state := r.Run(&check.Check{ID: id})
+		if again := r.Run(&check.Check{ID: id}); again != state {
+			t.Errorf("%s: expected the same state on every run, got %s and %s", id, state, again)
+		}
+		seen[state] = true
+	}
+	for _, state := range mixedStates {
+		if !seen[state] {
+			t.Errorf("expected mixed results to include %s", state)
+		}
+	}
+}
+
+func TestNewRunner(t *testing.T) {
+	defer func(m string) { mockMode = m }(mockMode)
+
+	mockMode = ""
+	if r, err := newRunner(); err != nil || r == nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if _, ok := r.(*mockRunner); ok {
+		t.Errorf("expected the default runner without --mock")
+	}
+
+	mockMode = mockFail
+	if r, err := newRunner(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	} else if _, ok := r.(*mockRunner); !ok {
+		t.Errorf("expected a mock runner with --mock")
+	}
+}
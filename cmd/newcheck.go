@@ -0,0 +1,190 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// newCheckOpts describes the check generated by new-check.
+type newCheckOpts struct {
+	ID    string
+	Group string
+	Text  string
+	// Type is the node type of the controls file, if it is created.
+	Type string
+	// Manual adds a manual check, with no audit.
+	Manual bool
+}
+
+const controlsTemplate = `---
+controls:
+id: %s
+text: "TODO: describe these controls"
+type: %q
+groups:
+`
+
+const groupTemplate = `
+  - id: %s
+    text: "TODO: describe this group"
+    checks:
+`
+
+const checkTemplate = `
+      - id: %s
+        text: %q
+        audit: "TODO: command whose output is tested"
+        tests:
+          test_items:
+            - flag: "TODO"
+              set: true
+        remediation: |
+          TODO: describe how to fix a failure of this check.
+        scored: true
+`
+
+const manualCheckTemplate = `
+      - id: %s
+        text: %q
+        type: "manual"
+        remediation: |
+          TODO: describe how to verify and apply this recommendation.
+        scored: false
+`
+
+var newCheckFlags newCheckOpts
+
+// newCheckCmd represents the new-check command
+var newCheckCmd = &cobra.Command{
+	Use:   "new-check <controls.yaml>",
+	Short: "Add a skeleton check to a controls file",
+	Long: `Append a skeleton check, with placeholders for its audit, tests and remediation, to a controls file.
+The check is added to its group, which is created if needed. The controls file is created if it does not exist.`,
+	Args: cobra.ExactArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := scaffoldCheck(args[0], newCheckFlags); err != nil {
+			exitWithError(fmt.Errorf("failed to add check %s to %s: %v", newCheckFlags.ID, args[0], err))
+		}
+		fmt.Printf("Added check %s to %s\n", newCheckFlags.ID, args[0])
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(newCheckCmd)
+	newCheckCmd.Flags().StringVar(&newCheckFlags.ID, "id", "", "ID of the check, e.g. 9.1.1")
+	newCheckCmd.Flags().StringVar(&newCheckFlags.Group, "group-id", "", "ID of the group of the check, defaults to the ID of the check without its last part")
+	newCheckCmd.Flags().StringVar(&newCheckFlags.Text, "text", "TODO: describe the recommendation", "Description of the check")
+	newCheckCmd.Flags().StringVar(&newCheckFlags.Type, "type", string(check.NODE), "Node type of the controls file, if it is created")
+	newCheckCmd.Flags().BoolVar(&newCheckFlags.Manual, "manual", false, "Add a manual check, reported for human verification with its remediation as guidance")
+	newCheckCmd.MarkFlagRequired("id")
+}
+
+// scaffoldCheck appends a skeleton check to a controls file. Checks can only
+// be added to the last group of the file, as the rest of the file is left
+// untouched.
+func scaffoldCheck(file string, opts newCheckOpts) error {
+	parts := strings.Split(opts.ID, ".")
+	if opts.Group == "" {
+		if len(parts) < 2 {
+			return fmt.Errorf("can't derive the group of check %q, use --group-id", opts.ID)
+		}
+		opts.Group = strings.Join(parts[:len(parts)-1], ".")
+	}
+
+	template := checkTemplate
+	if opts.Manual {
+		template = manualCheckTemplate
+	}
+
+	in, err := ioutil.ReadFile(file)
+	if os.IsNotExist(err) {
+		s := fmt.Sprintf(controlsTemplate, parts[0], opts.Type) +
+			fmt.Sprintf(groupTemplate, opts.Group) +
+			fmt.Sprintf(template, opts.ID, opts.Text)
+		return ioutil.WriteFile(file, []byte(s), 0644)
+	}
+	if err != nil {
+		return err
+	}
+
+	resolved, err := check.ResolveIncludes(in, filepath.Dir(file))
+	if err != nil {
+		return err
+	}
+	var controls check.Controls
+	if err := yaml.Unmarshal(resolved, &controls); err != nil {
+		return fmt.Errorf("failed to unmarshal YAML: %v", err)
+	}
+
+	var s string
+	for i, g := range controls.Groups {
+		for _, c := range g.Checks {
+			if c.ID == opts.ID {
+				return fmt.Errorf("check %s already exists", opts.ID)
+			}
+		}
+
+		if g.ID == opts.Group {
+			if i != len(controls.Groups)-1 {
+				return fmt.Errorf("group %s is not the last group of the file", opts.Group)
+			}
+			s = fmt.Sprintf(template, opts.ID, opts.Text)
+		}
+	}
+	if s == "" {
+		s = fmt.Sprintf(groupTemplate, opts.Group) + fmt.Sprintf(template, opts.ID, opts.Text)
+	}
+
+	if len(in) > 0 && !strings.HasSuffix(string(in), "\n") {
+		s = "\n" + s
+	}
+	out := append(in, s...)
+
+	// The skeleton uses the indentation of the shipped controls files, make
+	// sure it fits in this one.
+	resolved, err = check.ResolveIncludes(out, filepath.Dir(file))
+	if err != nil {
+		return err
+	}
+	controls = check.Controls{}
+	if err := yaml.Unmarshal(resolved, &controls); err != nil || !hasCheck(&controls, opts.Group, opts.ID) {
+		return fmt.Errorf("the indentation of the file does not match the skeleton check")
+	}
+
+	return ioutil.WriteFile(file, out, 0644)
+}
+
+func hasCheck(controls *check.Controls, groupID, checkID string) bool {
+	for _, g := range controls.Groups {
+		if g.ID != groupID {
+			continue
+		}
+		for _, c := range g.Checks {
+			if c.ID == checkID {
+				return true
+			}
+		}
+	}
+	return false
+}
@@ -0,0 +1,143 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+func TestScaffoldCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-new-check")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "custom.yaml")
+	steps := []struct {
+		opts      newCheckOpts
+		expectErr bool
+	}{
+		// Creates the file.
+		{opts: newCheckOpts{ID: "9.1.1", Text: "Ensure that foo is set", Type: "node"}},
+		// Appends to the last group.
+		{opts: newCheckOpts{ID: "9.1.2", Text: "Ensure that bar is set"}},
+		// Adds a group.
+		{opts: newCheckOpts{ID: "9.2.1", Text: "Ensure that baz is set"}},
+		{opts: newCheckOpts{ID: "9.2.2", Group: "9.3", Text: "Ensure that qux is set"}},
+		{opts: newCheckOpts{ID: "9.2.1"}, expectErr: true},
+		{opts: newCheckOpts{ID: "9.1.3"}, expectErr: true},
+		{opts: newCheckOpts{ID: "9"}, expectErr: true},
+	}
+	for _, s := range steps {
+		err := scaffoldCheck(file, s.opts)
+		if s.expectErr && err == nil {
+			t.Errorf("%s: expected an error", s.opts.ID)
+		}
+		if !s.expectErr && err != nil {
+			t.Errorf("%s: unexpected error: %v", s.opts.ID, err)
+		}
+	}
+
+	in, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	controls, err := check.NewControls(check.NODE, in)
+	if err != nil {
+		t.Fatalf("generated controls are invalid: %v\n%s", err, in)
+	}
+
+	expected := map[string][]string{"9.1": {"9.1.1", "9.1.2"}, "9.2": {"9.2.1"}, "9.3": {"9.2.2"}}
+	if len(controls.Groups) != len(expected) {
+		t.Fatalf("expected %d groups, got %d\n%s", len(expected), len(controls.Groups), in)
+	}
+	for _, g := range controls.Groups {
+		if len(g.Checks) != len(expected[g.ID]) {
+			t.Errorf("group %s: expected checks %v, got %d", g.ID, expected[g.ID], len(g.Checks))
+			continue
+		}
+		for i, c := range g.Checks {
+			if c.ID != expected[g.ID][i] {
+				t.Errorf("group %s: expected check %s, got %s", g.ID, expected[g.ID][i], c.ID)
+			}
+			if c.Tests == nil || len(c.Tests.TestItems) != 1 || c.Remediation == "" || !c.Scored {
+				t.Errorf("check %s: expected a skeleton with tests and remediation, got %+v", c.ID, c)
+			}
+		}
+	}
+	if controls.Groups[0].Checks[0].Text != "Ensure that foo is set" {
+		t.Errorf("unexpected text %q", controls.Groups[0].Checks[0].Text)
+	}
+}
+
+func TestScaffoldCheckIndentation(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-new-check")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "custom.yaml")
+	in := "controls:\ntype: node\ngroups:\n- id: 9.1\n  checks:\n  - id: 9.1.1\n"
+	if err := ioutil.WriteFile(file, []byte(in), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if err := scaffoldCheck(file, newCheckOpts{ID: "9.1.2"}); err == nil {
+		t.Errorf("expected an error for a file with different indentation")
+	}
+	out, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != in {
+		t.Errorf("expected the file to be left untouched, got %q", out)
+	}
+}
+
+func TestScaffoldManualCheck(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-new-check")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	file := filepath.Join(dir, "custom.yaml")
+	if err := scaffoldCheck(file, newCheckOpts{ID: "9.1.1", Text: "Minimize the admission of privileged containers", Type: "policies", Manual: true}); err != nil {
+		t.Fatal(err)
+	}
+	in, err := ioutil.ReadFile(file)
+	if err != nil {
+		t.Fatal(err)
+	}
+	controls, err := check.NewControls(check.POLICIES, in)
+	if err != nil {
+		t.Fatalf("generated controls are invalid: %v\n%s", err, in)
+	}
+
+	c := controls.Groups[0].Checks[0]
+	if c.Type != check.MANUAL || c.Audit != "" || c.Tests != nil || c.Remediation == "" || c.Scored {
+		t.Errorf("expected a manual skeleton with remediation, got %+v", c)
+	}
+	if state := check.NewRunner().Run(c); state != check.WARN {
+		t.Errorf("expected manual check to need attention, got %s", state)
+	}
+}
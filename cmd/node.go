@@ -26,7 +26,7 @@ var nodeCmd = &cobra.Command{
 	Long:  `Run Kubernetes benchmark checks from the node.yaml file in cfg/<version>.`,
 	Run: func(cmd *cobra.Command, args []string) {
 		filename := loadConfig(check.NODE)
-		runChecks(check.NODE, filename)
+		runNodeChecks(filename)
 	},
 }
 
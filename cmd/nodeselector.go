@@ -0,0 +1,94 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+	"k8s.io/apimachinery/pkg/labels"
+)
+
+// nodeNotSelectedReported is set once it's been reported that the node
+// doesn't match --node-selector.
+var nodeNotSelectedReported bool
+
+// checkNodeSelector verifies the label selector given with --node-selector.
+func checkNodeSelector(selector string) error {
+	_, err := labels.Parse(selector)
+	return err
+}
+
+// nodeSelected reports whether the checks are run on this node, that is
+// whether its labels match --node-selector. The labels are those of the
+// Node object, so kube-bench must run in a pod of the cluster.
+func nodeSelected(m *check.NodeMetadata) (bool, error) {
+	if nodeSelector == "" {
+		return true, nil
+	}
+	// Mock results are not gathered from the node.
+	if mockMode != "" {
+		return true, nil
+	}
+
+	selector, err := labels.Parse(nodeSelector)
+	if err != nil {
+		return false, err
+	}
+	if m.Labels == nil {
+		return false, fmt.Errorf("the labels of node %s are unknown, --node-selector needs kube-bench to run in a pod of the cluster", m.NodeName)
+	}
+	return selector.Matches(labels.Set(m.Labels)), nil
+}
+
+// skipUnselectedNode reports whether the checks are not run on this node
+// because it doesn't match --node-selector, telling it once.
+func skipUnselectedNode(m *check.NodeMetadata) bool {
+	selected, err := nodeSelected(m)
+	if err != nil {
+		exitWithError(fmt.Errorf("failed to match the node selector: %v", err))
+	}
+	if selected {
+		return false
+	}
+
+	if !nodeNotSelectedReported {
+		nodeNotSelectedReported = true
+		glog.V(1).Infof("Node labels: %v", m.Labels)
+		continueWithError(nil, fmt.Sprintf("Node %s doesn't match the node selector %q, no checks are run", m.NodeName, nodeSelector))
+	}
+	return true
+}
+
+// resultMetadata returns the metadata of the node to attach to the results,
+// with only the labels given with --node-labels when set.
+func resultMetadata(m *check.NodeMetadata) *check.NodeMetadata {
+	if len(nodeLabels) == 0 || m == nil {
+		return m
+	}
+
+	r := *m
+	r.Labels = nil
+	for _, k := range nodeLabels {
+		if v, ok := m.Labels[k]; ok {
+			if r.Labels == nil {
+				r.Labels = map[string]string{}
+			}
+			r.Labels[k] = v
+		}
+	}
+	return &r
+}
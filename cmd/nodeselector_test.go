@@ -0,0 +1,80 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+func TestNodeSelected(t *testing.T) {
+	defer func(s string) { nodeSelector = s }(nodeSelector)
+
+	gpuNode := &check.NodeMetadata{NodeName: "gpu-1", Labels: map[string]string{"accelerator": "nvidia", "zone": "edge"}}
+	cases := []struct {
+		selector string
+		metadata *check.NodeMetadata
+		selected bool
+		fail     bool
+	}{
+		{"", &check.NodeMetadata{}, true, false},
+		{"accelerator=nvidia", gpuNode, true, false},
+		{"accelerator in (nvidia, amd),zone!=core", gpuNode, true, false},
+		{"!accelerator", gpuNode, false, false},
+		{"zone=core", gpuNode, false, false},
+		// The labels are unknown when not running in a pod.
+		{"zone=core", &check.NodeMetadata{NodeName: "worker"}, false, true},
+	}
+	for _, c := range cases {
+		nodeSelector = c.selector
+		selected, err := nodeSelected(c.metadata)
+		if c.fail {
+			if err == nil {
+				t.Errorf("%q: expected an error", c.selector)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", c.selector, err)
+		}
+		if selected != c.selected {
+			t.Errorf("%q: expected selected %t, got %t", c.selector, c.selected, selected)
+		}
+	}
+
+	if err := checkNodeSelector("zone in (edge"); err == nil {
+		t.Errorf("expected an invalid selector to be rejected")
+	}
+}
+
+func TestResultMetadata(t *testing.T) {
+	defer func(l []string) { nodeLabels = l }(nodeLabels)
+
+	m := &check.NodeMetadata{NodeName: "gpu-1", Labels: map[string]string{"accelerator": "nvidia", "zone": "edge", "kubernetes.io/os": "linux"}}
+	if got := resultMetadata(m); got != m {
+		t.Errorf("expected all the labels to be attached by default")
+	}
+
+	nodeLabels = []string{"accelerator", "zone", "missing"}
+	got := resultMetadata(m)
+	if expected := map[string]string{"accelerator": "nvidia", "zone": "edge"}; !reflect.DeepEqual(got.Labels, expected) {
+		t.Errorf("expected labels %v, got %v", expected, got.Labels)
+	}
+	if got.NodeName != "gpu-1" || len(m.Labels) != 3 {
+		t.Errorf("expected the other metadata to be kept and the node labels unchanged")
+	}
+}
@@ -0,0 +1,287 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/spf13/viper"
+)
+
+// Controls bundles are pushed to OCI registries as the layer of an artifact,
+// e.g. with oras, signed with the same ed25519 key as the bundles downloaded
+// by update-controls. The signature is an annotation of the layer.
+const (
+	ociScheme                   = "oci://"
+	controlsBundleMediaType     = "application/vnd.aquasec.kube-bench.controls.v1.tar+gzip"
+	controlsSignatureAnnotation = "com.aquasec.kube-bench.signature"
+	ociManifestMediaType        = "application/vnd.oci.image.manifest.v1+json"
+)
+
+// registryClient is replaced in tests.
+var registryClient = &http.Client{Timeout: 30 * time.Second}
+
+var challengeParamRe = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+// ociReference is a reference to an artifact in an OCI registry.
+type ociReference struct {
+	registry   string
+	repository string
+	// reference is a tag or a digest.
+	reference string
+}
+
+type ociDescriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type ociManifest struct {
+	Layers []ociDescriptor `json:"layers"`
+}
+
+// parseOCIReference parses oci://registry/repository:tag, or
+// oci://registry/repository@digest. The tag defaults to latest.
+func parseOCIReference(ref string) (ociReference, error) {
+	s := strings.TrimPrefix(ref, ociScheme)
+	slash := strings.Index(s, "/")
+	if slash <= 0 {
+		return ociReference{}, fmt.Errorf("invalid OCI reference %s, expected oci://registry/repository:tag", ref)
+	}
+
+	r := ociReference{registry: s[:slash], repository: s[slash+1:], reference: "latest"}
+	if i := strings.Index(r.repository, "@"); i >= 0 {
+		r.repository, r.reference = r.repository[:i], r.repository[i+1:]
+	} else if i := strings.LastIndex(r.repository, ":"); i >= 0 {
+		r.repository, r.reference = r.repository[:i], r.repository[i+1:]
+	}
+	if r.repository == "" || r.reference == "" {
+		return ociReference{}, fmt.Errorf("invalid OCI reference %s, expected oci://registry/repository:tag", ref)
+	}
+	return r, nil
+}
+
+// pullControls pulls a controls bundle from an OCI registry, and verifies
+// its digest and signature.
+func pullControls(ref string, key ed25519.PublicKey) ([]byte, error) {
+	r, err := parseOCIReference(ref)
+	if err != nil {
+		return nil, err
+	}
+
+	data, err := r.get("manifests/"+r.reference, ociManifestMediaType)
+	if err != nil {
+		return nil, err
+	}
+	var m ociManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("invalid manifest of %s: %v", ref, err)
+	}
+	layer := controlsLayer(m)
+	if layer == nil {
+		return nil, fmt.Errorf("no controls bundle in %s, expected a layer of type %s", ref, controlsBundleMediaType)
+	}
+	if layer.Size > maxControlsBundleSize {
+		return nil, fmt.Errorf("the controls bundle of %s is larger than %d bytes", ref, maxControlsBundleSize)
+	}
+
+	bundle, err := r.get("blobs/"+layer.Digest, "")
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyDigest(bundle, layer.Digest); err != nil {
+		return nil, fmt.Errorf("%v for %s", err, ref)
+	}
+	sig := layer.Annotations[controlsSignatureAnnotation]
+	if sig == "" {
+		return nil, fmt.Errorf("no signature for %s, expected the %s annotation of its layer", ref, controlsSignatureAnnotation)
+	}
+	if err := verifyControls(bundle, []byte(sig), key); err != nil {
+		return nil, fmt.Errorf("%v for %s", err, ref)
+	}
+	return bundle, nil
+}
+
+// controlsLayer returns the layer of the manifest holding the controls
+// bundle, or the only gzipped tar layer of artifacts pushed without the
+// media type of controls bundles.
+func controlsLayer(m ociManifest) *ociDescriptor {
+	var tars []*ociDescriptor
+	for i, l := range m.Layers {
+		if l.MediaType == controlsBundleMediaType {
+			return &m.Layers[i]
+		}
+		if strings.HasSuffix(l.MediaType, "tar+gzip") {
+			tars = append(tars, &m.Layers[i])
+		}
+	}
+	if len(tars) == 1 {
+		return tars[0]
+	}
+	return nil
+}
+
+// verifyDigest verifies that data has the given sha256 digest.
+func verifyDigest(data []byte, digest string) error {
+	if !strings.HasPrefix(digest, "sha256:") {
+		return fmt.Errorf("unsupported digest %s", digest)
+	}
+	sum := sha256.Sum256(data)
+	if hex.EncodeToString(sum[:]) != strings.TrimPrefix(digest, "sha256:") {
+		return fmt.Errorf("digest mismatch, expected %s", digest)
+	}
+	return nil
+}
+
+// get gets a manifest or a blob of the repository, authenticating with an
+// anonymous token when the registry asks for one.
+func (r ociReference) get(path, accept string) ([]byte, error) {
+	u := fmt.Sprintf("https://%s/v2/%s/%s", r.registry, r.repository, path)
+	resp, err := registryRequest(u, accept, "")
+	if err != nil {
+		return nil, err
+	}
+	if resp.StatusCode == http.StatusUnauthorized {
+		challenge := resp.Header.Get("WWW-Authenticate")
+		resp.Body.Close()
+		token, err := registryToken(challenge)
+		if err != nil {
+			return nil, fmt.Errorf("failed to authenticate to %s: %v", r.registry, err)
+		}
+		if resp, err = registryRequest(u, accept, token); err != nil {
+			return nil, err
+		}
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", u, resp.Status)
+	}
+	return readDownload(u, resp.Body)
+}
+
+func registryRequest(u, accept, token string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, u, nil)
+	if err != nil {
+		return nil, err
+	}
+	if accept != "" {
+		req.Header.Set("Accept", accept)
+	}
+	if token != "" {
+		req.Header.Set("Authorization", "Bearer "+token)
+	}
+	return registryClient.Do(req)
+}
+
+// registryToken gets an anonymous token from the realm of a Bearer
+// challenge of a registry.
+func registryToken(challenge string) (string, error) {
+	if !strings.HasPrefix(challenge, "Bearer ") {
+		return "", fmt.Errorf("unsupported authentication %q", challenge)
+	}
+	params := map[string]string{}
+	for _, m := range challengeParamRe.FindAllStringSubmatch(challenge, -1) {
+		params[m[1]] = m[2]
+	}
+	if params["realm"] == "" {
+		return "", fmt.Errorf("no realm in %q", challenge)
+	}
+
+	query := url.Values{}
+	for _, name := range []string{"service", "scope"} {
+		if params[name] != "" {
+			query.Set(name, params[name])
+		}
+	}
+	resp, err := registryClient.Get(params["realm"] + "?" + query.Encode())
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("failed to get a token from %s: %s", params["realm"], resp.Status)
+	}
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return "", fmt.Errorf("invalid token from %s: %v", params["realm"], err)
+	}
+	if token.Token != "" {
+		return token.Token, nil
+	}
+	return token.AccessToken, nil
+}
+
+// useOCIControls pulls the controls bundle given with --controls
+// oci://registry/repository:tag, installs it in the oci directory of
+// --controls-dir and runs its benchmark as if it was the config directory.
+func useOCIControls() error {
+	if !strings.HasPrefix(controlsFile, ociScheme) {
+		return nil
+	}
+	if offline {
+		return fmt.Errorf("%s is pulled from a registry, it can't be used with --offline", controlsFile)
+	}
+	if controlsDir == "" {
+		return fmt.Errorf("no --controls-dir to install %s in", controlsFile)
+	}
+	publicKey := viper.GetString("controls_public_key")
+	if publicKey == "" {
+		return fmt.Errorf("no public key to verify %s, set it with controls_public_key in the config or the KUBE_BENCH_CONTROLS_PUBLIC_KEY environment variable", controlsFile)
+	}
+	key, err := controlsPublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	bundle, err := pullControls(controlsFile, key)
+	if err != nil {
+		return err
+	}
+	dir := filepath.Join(controlsDir, "oci")
+	if err := installControls(bundle, dir); err != nil {
+		return err
+	}
+	glog.V(1).Infof("Using controls pulled from %s", controlsFile)
+
+	cfgDir = filepath.Join(dir, "cfg")
+	controlsFile = ""
+	if cfgFile != "" {
+		return nil
+	}
+	viper.SetConfigFile(filepath.Join(cfgDir, "config.yaml"))
+	configFileError = nil
+	if err := viper.ReadInConfig(); err != nil {
+		return fmt.Errorf("failed to read the config of the controls: %v", err)
+	}
+	return nil
+}
@@ -0,0 +1,163 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestParseOCIReference(t *testing.T) {
+	for _, c := range []struct {
+		ref      string
+		expected ociReference
+		err      bool
+	}{
+		{ref: "oci://ghcr.io/org/cis-bundles:1.6", expected: ociReference{registry: "ghcr.io", repository: "org/cis-bundles", reference: "1.6"}},
+		{ref: "oci://localhost:5000/cis-bundles", expected: ociReference{registry: "localhost:5000", repository: "cis-bundles", reference: "latest"}},
+		{ref: "oci://ghcr.io/org/cis-bundles@sha256:abc", expected: ociReference{registry: "ghcr.io", repository: "org/cis-bundles", reference: "sha256:abc"}},
+		{ref: "oci://ghcr.io", err: true},
+		{ref: "oci://ghcr.io/org/cis-bundles:", err: true},
+	} {
+		r, err := parseOCIReference(c.ref)
+		if c.err {
+			if err == nil {
+				t.Errorf("%s: expected an error", c.ref)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", c.ref, err)
+		} else if r != c.expected {
+			t.Errorf("%s: expected %+v, got %+v", c.ref, c.expected, r)
+		}
+	}
+}
+
+// registryServer serves controls bundles like an OCI registry giving
+// anonymous tokens, the tag of each manifest being its key.
+func registryServer(layers map[string]ociDescriptor, blobs map[string][]byte) *httptest.Server {
+	var server *httptest.Server
+	server = httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/token" {
+			if r.URL.Query().Get("scope") != "repository:org/cis-bundles:pull" {
+				w.WriteHeader(http.StatusForbidden)
+				return
+			}
+			fmt.Fprint(w, `{"token":"anonymous"}`)
+			return
+		}
+		if r.Header.Get("Authorization") != "Bearer anonymous" {
+			w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="%s/token",service="registry",scope="repository:org/cis-bundles:pull"`, server.URL))
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+
+		const prefix = "/v2/org/cis-bundles/"
+		switch {
+		case strings.HasPrefix(r.URL.Path, prefix+"manifests/"):
+			layer, ok := layers[strings.TrimPrefix(r.URL.Path, prefix+"manifests/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			json.NewEncoder(w).Encode(map[string]interface{}{"mediaType": ociManifestMediaType, "layers": []ociDescriptor{layer}})
+		case strings.HasPrefix(r.URL.Path, prefix+"blobs/"):
+			blob, ok := blobs[strings.TrimPrefix(r.URL.Path, prefix+"blobs/")]
+			if !ok {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			w.Write(blob)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	return server
+}
+
+func TestPullControls(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	bundle := controlsBundle(t, map[string]string{"cfg/config.yaml": "master: {}\n", "cfg/cis-1.6/master.yaml": "controls:\n"})
+	sum := sha256.Sum256(bundle)
+	digest := "sha256:" + hex.EncodeToString(sum[:])
+	signature := base64.StdEncoding.EncodeToString(ed25519.Sign(priv, bundle))
+	layer := func(mediaType, digest, signature string) ociDescriptor {
+		return ociDescriptor{MediaType: mediaType, Digest: digest, Size: int64(len(bundle)), Annotations: map[string]string{controlsSignatureAnnotation: signature}}
+	}
+
+	server := registryServer(map[string]ociDescriptor{
+		"1.6":          layer(controlsBundleMediaType, digest, signature),
+		"oras":         layer("application/vnd.oci.image.layer.v1.tar+gzip", digest, signature),
+		"unsigned":     layer(controlsBundleMediaType, digest, ""),
+		"other-key":    layer(controlsBundleMediaType, digest, base64.StdEncoding.EncodeToString(ed25519.Sign(ed25519.NewKeyFromSeed(make([]byte, 32)), bundle))),
+		"wrong-digest": layer(controlsBundleMediaType, "sha256:"+strings.Repeat("0", 64), signature),
+		"config":       layer("application/vnd.oci.image.config.v1+json", digest, signature),
+	}, map[string][]byte{
+		digest:                              bundle,
+		"sha256:" + strings.Repeat("0", 64): bundle,
+	})
+	defer server.Close()
+	defer func(client *http.Client) { registryClient = client }(registryClient)
+	registryClient = server.Client()
+	registry := "oci://" + strings.TrimPrefix(server.URL, "https://") + "/org/cis-bundles"
+
+	for _, tag := range []string{"1.6", "oras"} {
+		pulled, err := pullControls(registry+":"+tag, pub)
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tag, err)
+		} else if string(pulled) != string(bundle) {
+			t.Errorf("%s: unexpected bundle", tag)
+		}
+	}
+	for tag, expected := range map[string]string{
+		"unsigned":     "no signature",
+		"other-key":    "invalid signature",
+		"wrong-digest": "digest mismatch",
+		"config":       "no controls bundle",
+		"missing":      "404",
+	} {
+		if _, err := pullControls(registry+":"+tag, pub); err == nil || !strings.Contains(err.Error(), expected) {
+			t.Errorf("%s: expected an error with %q, got %v", tag, expected, err)
+		}
+	}
+
+	// update-controls installs the bundles pulled from a registry.
+	dir, err := ioutil.TempDir("", "kube-bench-controls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	if err := updateControls(registry+":1.6", base64.StdEncoding.EncodeToString(pub), dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "cfg", "cis-1.6", "master.yaml")); err != nil {
+		t.Errorf("expected the controls to be installed: %v", err)
+	}
+}
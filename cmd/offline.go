@@ -0,0 +1,63 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+// checkOffline rejects the options sending results over the network or
+// connecting to remote members when running with --offline, and disables
+// the network operations of checks.
+func checkOffline(disabled bool) error {
+	check.SetOffline(disabled)
+	if !disabled {
+		return nil
+	}
+
+	var options []string
+	if pgSQL {
+		options = append(options, "--pgsql")
+	}
+	if annotateNode {
+		options = append(options, "--annotate-node")
+	}
+	if quarantineAction != "" {
+		options = append(options, "--quarantine-node")
+	}
+	if len(remoteEtcdMembers()) > 0 {
+		options = append(options, "etcd.endpoints")
+	}
+	if len(options) > 0 {
+		return fmt.Errorf("%s need network access", strings.Join(options, ", "))
+	}
+	return nil
+}
+
+// checkOfflineControls fails when checks of the controls selected by the
+// filter would need network access, rather than letting them fail one by
+// one.
+func checkOfflineControls(controls *check.Controls, filter check.Predicate) error {
+	if !offline {
+		return nil
+	}
+	if ids := controls.NetworkChecks(filter); len(ids) > 0 {
+		return fmt.Errorf("the checks %s of %s %s need network access, leave them out with --skip-check", strings.Join(ids, ", "), controls.ID, controls.Text)
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/spf13/viper"
+)
+
+func TestCheckOffline(t *testing.T) {
+	defer func(pg, annotate, disabled bool) {
+		pgSQL, annotateNode, offline = pg, annotate, disabled
+		check.SetOffline(disabled)
+	}(pgSQL, annotateNode, offline)
+	defer viper.Set("etcd.endpoints", nil)
+
+	if err := checkOffline(true); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	pgSQL, annotateNode = true, true
+	viper.Set("etcd.endpoints", []string{"https://10.0.0.2:2379"})
+	err := checkOffline(true)
+	if err == nil || err.Error() != "--pgsql, --annotate-node, etcd.endpoints need network access" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := checkOffline(false); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
+
+func TestCheckOfflineControls(t *testing.T) {
+	defer func(disabled bool) { offline = disabled }(offline)
+
+	controls := &check.Controls{ID: "5", Text: "Kubernetes Policies", Groups: []*check.Group{{ID: "5.1", Checks: []*check.Check{
+		{ID: "5.1.1", Audit: "kubectl get clusterrolebindings -o json"},
+		{ID: "5.1.2", Audit: "stat -c %a /etc/kubernetes/admin.conf"},
+	}}}}
+	all := func(g *check.Group, c *check.Check) bool { return true }
+
+	offline = false
+	if err := checkOfflineControls(controls, all); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+
+	offline = true
+	err := checkOfflineControls(controls, all)
+	if err == nil || err.Error() != "the checks 5.1.1 of 5 Kubernetes Policies need network access, leave them out with --skip-check" {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := checkOfflineControls(controls, func(g *check.Group, c *check.Check) bool { return c.ID != "5.1.1" }); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+}
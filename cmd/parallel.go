@@ -0,0 +1,112 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+)
+
+var (
+	// batching is set while the targets to run in parallel are set up, they
+	// are then added to the batch rather than run.
+	batching bool
+	batch    []*target
+
+	// reportedTargets and totalSummary sum up the results of the targets
+	// reported by runTargets.
+	reportedTargets int
+	totalSummary    check.Summary
+)
+
+// runTargets runs the targets that the given function runs, followed by the
+// total summary of their results when there are several of them. With
+// --parallel, the targets are set up in turn and their checks are then run
+// concurrently, e.g. the master and node checks of a control plane node;
+// their results are output in order.
+func runTargets(runAll func()) {
+	reportedTargets, totalSummary = 0, check.Summary{}
+	defer func() {
+		if reportedTargets > 1 {
+			printTotalSummary(os.Stdout, totalSummary)
+		}
+		reportedTargets, totalSummary = 0, check.Summary{}
+	}()
+
+	if !parallel {
+		runAll()
+		return
+	}
+
+	batching = true
+	runAll()
+	batching = false
+	targets := batch
+	batch = nil
+
+	// The node metadata and the Kubernetes client are set up once before
+	// the checks run.
+	currentNodeMetadata()
+
+	glog.V(1).Infof("== Running the checks of %d targets in parallel ==\n", len(targets))
+	var wg sync.WaitGroup
+	for _, t := range targets {
+		wg.Add(1)
+		go func(t *target) {
+			defer wg.Done()
+			t.run()
+		}(t)
+	}
+	wg.Wait()
+
+	for _, t := range targets {
+		t.report()
+	}
+}
+
+// addToTotalSummary adds the results of a target to the total summary of
+// the targets run.
+func addToTotalSummary(summary check.Summary) {
+	reportedTargets++
+	totalSummary.Pass += summary.Pass
+	totalSummary.Fail += summary.Fail
+	totalSummary.Warn += summary.Warn
+	totalSummary.Info += summary.Info
+	totalSummary.Skip += summary.Skip
+}
+
+// printTotalSummary prints the total summary of the targets in
+// human-readable format.
+func printTotalSummary(w io.Writer, total check.Summary) {
+	if noSummary || jsonFmt || junitFmt || gitlabFmt || sonarQubeFmt || githubFmt || pgSQL {
+		return
+	}
+
+	res := check.PASS
+	if total.Fail > 0 {
+		res = check.FAIL
+	} else if total.Warn > 0 {
+		res = check.WARN
+	}
+	colors[res].Fprintf(w, "== Summary total ==\n")
+	fmt.Fprintf(w, "%d checks PASS\n%d checks FAIL\n%d checks WARN\n%d checks INFO\n%d checks SKIP\n",
+		total.Pass, total.Fail, total.Warn, total.Info, total.Skip,
+	)
+}
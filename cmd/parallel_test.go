@@ -0,0 +1,111 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+// barrierRunner only lets the checks complete once all of them are running.
+type barrierRunner struct {
+	started sync.WaitGroup
+	state   check.State
+}
+
+func (r *barrierRunner) Run(c *check.Check) check.State {
+	r.started.Done()
+	r.started.Wait()
+	c.State = r.state
+	return c.State
+}
+
+func TestRunTargets(t *testing.T) {
+	defer func(p, r, m, s bool, history []*check.Controls) {
+		parallel, noResults, noRemediations, noSummary, policyResults = p, r, m, s, history
+	}(parallel, noResults, noRemediations, noSummary, policyResults)
+	defer func(file string) { policyFile = file }(policyFile)
+	parallel, noResults, noRemediations, noSummary = true, true, true, true
+	// The results of the targets are collected for the policy.
+	policyFile, policyResults = "policy.rego", nil
+
+	runner := &barrierRunner{state: check.PASS}
+	runner.started.Add(2)
+	newTarget := func(nodetype check.NodeType) *target {
+		return &target{
+			controls: &check.Controls{Type: nodetype, Groups: []*check.Group{{ID: "1", Checks: []*check.Check{{ID: "1.1"}}}}},
+			runner:   runner,
+			filter:   func(*check.Group, *check.Check) bool { return true },
+		}
+	}
+
+	done := make(chan bool)
+	go func() {
+		runTargets(func() {
+			if !batching {
+				t.Errorf("expected the targets to be batched")
+			}
+			batch = append(batch, newTarget(check.MASTER), newTarget(check.NODE))
+		})
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(5 * time.Second):
+		t.Fatalf("expected the checks of the targets to run concurrently")
+	}
+
+	if len(policyResults) != 2 || policyResults[0].Type != check.MASTER || policyResults[1].Type != check.NODE {
+		t.Fatalf("expected the results of the targets in order, got %v", policyResults)
+	}
+	for _, controls := range policyResults {
+		if controls.Pass != 1 {
+			t.Errorf("%s: expected the checks to have run, got %+v", controls.Type, controls.Summary)
+		}
+	}
+	if batching || batch != nil {
+		t.Errorf("expected the batch to be cleared")
+	}
+}
+
+func TestTotalSummary(t *testing.T) {
+	defer func(s bool) { noSummary = s }(noSummary)
+	noSummary = false
+
+	reportedTargets, totalSummary = 0, check.Summary{}
+	addToTotalSummary(check.Summary{Pass: 10, Fail: 2, Warn: 3})
+	addToTotalSummary(check.Summary{Pass: 5, Fail: 1, Info: 1, Skip: 2})
+	if reportedTargets != 2 {
+		t.Errorf("expected 2 targets, got %d", reportedTargets)
+	}
+
+	var out bytes.Buffer
+	printTotalSummary(&out, totalSummary)
+	expected := `== Summary total ==
+15 checks PASS
+3 checks FAIL
+3 checks WARN
+1 checks INFO
+2 checks SKIP
+`
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+	reportedTargets, totalSummary = 0, check.Summary{}
+}
@@ -0,0 +1,64 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+// permissionResults collects the results of this run, to summarize the
+// checks that permission problems prevented from being carried out.
+var permissionResults []*check.Controls
+
+func addToPermissionSummary(controls *check.Controls) {
+	permissionResults = append(permissionResults, controls)
+}
+
+// reportPermissionProblems lists, at the end of the run, the permission
+// problems that made checks generate WARN, with the checks affected by each.
+func reportPermissionProblems(w io.Writer, results []*check.Controls) {
+	problems := map[string][]string{}
+	count := 0
+	for _, controls := range results {
+		for _, g := range controls.Groups {
+			for _, c := range g.Checks {
+				if err := c.Err(); err != nil && errors.Is(err, check.ErrPermissionDenied) {
+					problems[err.Error()] = append(problems[err.Error()], c.ID)
+					count++
+				}
+			}
+		}
+	}
+	if count == 0 {
+		return
+	}
+
+	messages := make([]string, 0, len(problems))
+	for message := range problems {
+		messages = append(messages, message)
+	}
+	sort.Strings(messages)
+
+	fmt.Fprintf(w, "Permission problems prevented %d checks from being carried out, they generate WARN:\n", count)
+	for _, message := range messages {
+		fmt.Fprintf(w, "%s: %s\n", message, strings.Join(problems[message], ", "))
+	}
+}
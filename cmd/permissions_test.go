@@ -0,0 +1,59 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+func TestReportPermissionProblems(t *testing.T) {
+	denied := func(id, message string) *check.Check {
+		c := &check.Check{ID: id, State: check.WARN}
+		c.AddError(check.PermissionError, message)
+		return c
+	}
+	missing := &check.Check{ID: "4.1.3", State: check.WARN}
+	missing.AddError(check.MissingFileError, "no config file")
+	results := []*check.Controls{
+		{Groups: []*check.Group{{Checks: []*check.Check{
+			denied("4.2.1", "insufficient privileges: permission denied reading /var/lib/kubelet/config.yaml"),
+			denied("4.1.1", "insufficient privileges: permission denied reading /etc/kubernetes/kubelet.conf"),
+			missing,
+			{ID: "4.1.2", State: check.FAIL},
+		}}}},
+		{Groups: []*check.Group{{Checks: []*check.Check{
+			denied("4.2.2", "insufficient privileges: permission denied reading /var/lib/kubelet/config.yaml"),
+		}}}},
+	}
+
+	var out bytes.Buffer
+	reportPermissionProblems(&out, results)
+	expected := `Permission problems prevented 3 checks from being carried out, they generate WARN:
+insufficient privileges: permission denied reading /etc/kubernetes/kubelet.conf: 4.1.1
+insufficient privileges: permission denied reading /var/lib/kubelet/config.yaml: 4.2.1, 4.2.2
+`
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+
+	out.Reset()
+	reportPermissionProblems(&out, results[:0])
+	if out.Len() != 0 {
+		t.Errorf("expected no report, got %q", out.String())
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+// planResults collects the results of this run when --remediation-plan is
+// given.
+var planResults []*check.Controls
+
+func addToRemediationPlan(controls *check.Controls) {
+	if remediationPlan {
+		planResults = append(planResults, controls)
+	}
+}
+
+// writeRemediationPlan outputs the remediation plan of the failed checks of
+// the run, after its results: as JSON with --json, as text otherwise.
+func writeRemediationPlan() error {
+	if !remediationPlan || len(planResults) == 0 {
+		return nil
+	}
+
+	plan := check.NewRemediationPlan(planResults)
+	if jsonFmt {
+		out, err := json.Marshal(plan)
+		if err != nil {
+			return fmt.Errorf("failed to output the remediation plan in JSON format: %v", err)
+		}
+		fmt.Println(string(out))
+		return nil
+	}
+	printRemediationPlan(os.Stdout, plan)
+	return nil
+}
+
+// printRemediationPlan outputs the remediation plan in human-readable
+// format, a section for each file or component.
+func printRemediationPlan(w io.Writer, plan *check.RemediationPlan) {
+	colors[check.WARN].Fprintf(w, "== Remediation plan ==\n")
+	if len(plan.Components) == 0 {
+		fmt.Fprintln(w, "No failed checks to remediate")
+		return
+	}
+
+	for _, cr := range plan.Components {
+		fmt.Fprintf(w, "%s (%s)\n", cr.Component, strings.Join(cr.Checks, ", "))
+		if len(cr.Flags) > 0 {
+			fmt.Fprintln(w, "  Set the parameters:")
+			printIndented(w, "    ", cr.Flags)
+		}
+		if len(cr.Keys) > 0 {
+			fmt.Fprintln(w, "  Set the keys:")
+			printIndented(w, "    ", cr.Keys)
+		}
+		if len(cr.Commands) > 0 {
+			fmt.Fprintln(w, "  Run the commands:")
+			printIndented(w, "    ", cr.Commands)
+		}
+		for _, r := range cr.Remediations {
+			fmt.Fprintf(w, "  %s:\n", r.ID)
+			printIndented(w, "    ", strings.Split(r.Remediation, "\n"))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+func printIndented(w io.Writer, indent string, lines []string) {
+	for _, l := range lines {
+		fmt.Fprintf(w, "%s%s\n", indent, strings.TrimSpace(l))
+	}
+}
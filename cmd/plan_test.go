@@ -0,0 +1,61 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+func TestPrintRemediationPlan(t *testing.T) {
+	plan := &check.RemediationPlan{Components: []*check.ComponentRemediation{
+		{Component: "/etc/kubernetes/manifests/kube-apiserver.yaml", Checks: []string{"1.1.1", "1.2.1", "1.2.15"},
+			Flags:    []string{"--anonymous-auth=false"},
+			Commands: []string{"chmod 644 /etc/kubernetes/manifests/kube-apiserver.yaml"},
+			Remediations: []check.CheckRemediation{
+				{ID: "1.2.15", Remediation: "Edit the API server pod specification file\nand set the --disable-admission-plugins parameter."},
+			}},
+		{Component: "/var/lib/kubelet/config.yaml", Checks: []string{"4.2.6"}, Keys: []string{"protectKernelDefaults: true"}},
+	}}
+
+	var out bytes.Buffer
+	printRemediationPlan(&out, plan)
+	expected := `== Remediation plan ==
+/etc/kubernetes/manifests/kube-apiserver.yaml (1.1.1, 1.2.1, 1.2.15)
+  Set the parameters:
+    --anonymous-auth=false
+  Run the commands:
+    chmod 644 /etc/kubernetes/manifests/kube-apiserver.yaml
+  1.2.15:
+    Edit the API server pod specification file
+    and set the --disable-admission-plugins parameter.
+
+/var/lib/kubelet/config.yaml (4.2.6)
+  Set the keys:
+    protectKernelDefaults: true
+
+`
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+
+	out.Reset()
+	printRemediationPlan(&out, &check.RemediationPlan{})
+	if expected := "== Remediation plan ==\nNo failed checks to remediate\n"; out.String() != expected {
+		t.Errorf("expected %q, got %q", expected, out.String())
+	}
+}
@@ -0,0 +1,153 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+	"github.com/open-policy-agent/opa/ast"
+)
+
+const (
+	// policyPackage is the package of a policy.
+	policyPackage = "data.kubebench"
+	// policyRule is the rule of a policy listing why the results are denied.
+	policyRule = "deny"
+	// policyQuery queries the deny rule of a policy.
+	policyQuery = policyPackage + "." + policyRule
+)
+
+// policyInput is the document a policy is evaluated against.
+type policyInput struct {
+	Controls []*check.Controls `json:"controls"`
+	Totals   check.Summary     `json:"totals"`
+}
+
+// policyResults collects the results of this run when --policy is given.
+var policyResults []*check.Controls
+
+// checkPolicy checks that the policy can be evaluated, before running the
+// checks.
+func checkPolicy(file string) error {
+	if file == "" {
+		return nil
+	}
+	_, err := readPolicy(file)
+	return err
+}
+
+// readPolicy reads the policy in file, and checks that it is a module of
+// package kubebench with a deny rule.
+func readPolicy(file string) (string, error) {
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		return "", err
+	}
+	module, err := ast.ParseModule(file, string(data))
+	if err != nil {
+		return "", err
+	}
+	if module == nil {
+		return "", fmt.Errorf("policy %s is empty", file)
+	}
+	if pkg := module.Package.Path.String(); pkg != policyPackage {
+		return "", fmt.Errorf("policy %s is in package %s, not in package %s", file, strings.TrimPrefix(pkg, "data."), strings.TrimPrefix(policyPackage, "data."))
+	}
+	for _, rule := range module.Rules {
+		if string(rule.Head.Name) == policyRule {
+			return string(data), nil
+		}
+	}
+	return "", fmt.Errorf("policy %s has no %s rule", file, policyRule)
+}
+
+// addToPolicyResults adds the results of a target to the ones the policy is
+// evaluated against.
+func addToPolicyResults(controls *check.Controls) {
+	if policyFile != "" {
+		policyResults = append(policyResults, controls)
+	}
+}
+
+// evaluatePolicy evaluates the deny rule of the policy in package kubebench
+// against the results, and returns the reasons the results are denied for.
+func evaluatePolicy(file string, controls []*check.Controls) ([]string, error) {
+	input := policyInput{Controls: controls}
+	for _, c := range controls {
+		input.Totals.Pass += c.Pass
+		input.Totals.Fail += c.Fail
+		input.Totals.Warn += c.Warn
+		input.Totals.Info += c.Info
+		input.Totals.Skip += c.Skip
+	}
+	module, err := readPolicy(file)
+	if err != nil {
+		return nil, err
+	}
+	results, err := check.EvalRego(policyQuery, input, map[string]string{file: module})
+	if err != nil {
+		return nil, fmt.Errorf("failed to evaluate policy %s: %v", file, err)
+	}
+	// An undefined deny rule, e.g. one whose body fails on the input, can't
+	// be told apart from a policy allowing everything.
+	if len(results) == 0 {
+		return nil, fmt.Errorf("failed to evaluate policy %s: %s is undefined", file, policyQuery)
+	}
+
+	var denied []string
+	for _, values := range results {
+		for _, value := range values {
+			reasons, _ := value.([]interface{})
+			for _, v := range reasons {
+				if s, ok := v.(string); ok {
+					denied = append(denied, s)
+					continue
+				}
+				b, _ := json.Marshal(v)
+				denied = append(denied, string(b))
+			}
+		}
+	}
+	sort.Strings(denied)
+	return denied, nil
+}
+
+// enforcePolicy evaluates the policy given with --policy, and exits with an
+// error when it denies the results.
+func enforcePolicy() {
+	if policyFile == "" {
+		return
+	}
+
+	denied, err := evaluatePolicy(policyFile, policyResults)
+	if err != nil {
+		exitWithError(err)
+	}
+	if len(denied) == 0 {
+		glog.V(1).Infof("Results allowed by policy %s", policyFile)
+		return
+	}
+
+	fmt.Fprintf(os.Stderr, "Results denied by policy %s:\n%s\n", policyFile, strings.Join(denied, "\n"))
+	glog.Flush()
+	os.Exit(1)
+}
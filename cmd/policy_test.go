@@ -0,0 +1,136 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+// writePolicy writes a policy in a temporary file.
+func writePolicy(t *testing.T, policy string) (string, func()) {
+	f, err := ioutil.TempFile("", "kube-bench-policy-*.rego")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := f.WriteString(policy); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	return f.Name(), func() { os.Remove(f.Name()) }
+}
+
+func TestEvaluatePolicy(t *testing.T) {
+	controls := []*check.Controls{
+		{ID: "1", Summary: check.Summary{Pass: 3, Fail: 1}},
+		{ID: "4", Summary: check.Summary{Pass: 2, Warn: 2}},
+	}
+
+	cases := []struct {
+		name     string
+		policy   string
+		expected []string
+	}{
+		{"allowed", "package kubebench\n\ndeny[msg] {\n  input.totals.total_fail > 1\n  msg := \"too many failures\"\n}\n", nil},
+		{
+			"denied",
+			`package kubebench
+
+deny[msg] {
+  input.totals.total_warn >= 2
+  input.totals.total_pass == 5
+  msg := "too many warnings"
+}
+
+deny[{"target": c.id}] {
+  c := input.controls[_]
+  c.total_fail > 0
+}
+`,
+			[]string{`too many warnings`, `{"target":"1"}`},
+		},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			file, remove := writePolicy(t, c.policy)
+			defer remove()
+			if err := checkPolicy(file); err != nil {
+				t.Fatal(err)
+			}
+			denied, err := evaluatePolicy(file, controls)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(denied, c.expected) {
+				t.Errorf("expected %v, got %v", c.expected, denied)
+			}
+		})
+	}
+}
+
+func TestEvaluatePolicyError(t *testing.T) {
+	file, remove := writePolicy(t, "package kubebench\n\ndeny[msg] {\n")
+	defer remove()
+	if err := checkPolicy(file); err == nil || !strings.Contains(err.Error(), "rego_parse_error") {
+		t.Errorf("expected a parse error, got %v", err)
+	}
+	if _, err := evaluatePolicy(file, nil); err == nil || !strings.Contains(err.Error(), "rego_parse_error") {
+		t.Errorf("expected a parse error, got %v", err)
+	}
+	if err := checkPolicy("/does/not/exist.rego"); err == nil {
+		t.Errorf("expected an error for a missing policy")
+	}
+}
+
+func TestEvaluatePolicyInvalid(t *testing.T) {
+	cases := []struct {
+		name     string
+		policy   string
+		expected string
+	}{
+		{"other package", "package other\n\ndeny[msg] {\n  msg := \"denied\"\n}\n", "not in package kubebench"},
+		{"no deny rule", "package kubebench\n\nallow = true\n", "has no deny rule"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			file, remove := writePolicy(t, c.policy)
+			defer remove()
+			if err := checkPolicy(file); err == nil || !strings.Contains(err.Error(), c.expected) {
+				t.Errorf("expected an error containing %q, got %v", c.expected, err)
+			}
+			if _, err := evaluatePolicy(file, nil); err == nil || !strings.Contains(err.Error(), c.expected) {
+				t.Errorf("expected an error containing %q, got %v", c.expected, err)
+			}
+		})
+	}
+}
+
+func TestEvaluatePolicyUndefined(t *testing.T) {
+	// A complete deny rule whose body doesn't hold is undefined.
+	file, remove := writePolicy(t, "package kubebench\n\ndeny = reasons {\n  input.totals.total_fail > 10\n  reasons := [\"too many failures\"]\n}\n")
+	defer remove()
+	if err := checkPolicy(file); err != nil {
+		t.Fatal(err)
+	}
+	controls := []*check.Controls{{ID: "1", Summary: check.Summary{Pass: 3, Fail: 1}}}
+	if _, err := evaluatePolicy(file, controls); err == nil || !strings.Contains(err.Error(), "data.kubebench.deny is undefined") {
+		t.Errorf("expected an undefined result to be an error, got %v", err)
+	}
+}
@@ -0,0 +1,219 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/golang/glog"
+	yaml "gopkg.in/yaml.v2"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+)
+
+// kubeProxyFilesDir holds the files of kube-proxy taken from its ConfigMap,
+// it's removed when kube-bench exits.
+var kubeProxyFilesDir string
+
+// useKubeProxyPodFiles replaces the config and kubeconfig files of kube-proxy
+// that can't be found on the host with the ones of its pod, when kube-proxy
+// runs as a DaemonSet: they are then taken from the pod's ConfigMap, so that
+// the checks evaluate the configuration kube-proxy actually uses.
+func useKubeProxyPodFiles(confmap, kubeconfmap map[string]string) {
+	if !missingFile(confmap["proxy"]) && !missingFile(kubeconfmap["proxy"]) {
+		return
+	}
+	if mockMode != "" || os.Getenv("KUBERNETES_SERVICE_HOST") == "" {
+		return
+	}
+
+	client, err := kubeClient()
+	if err != nil {
+		glog.V(2).Infof("kube-proxy pod not looked up: %v", err)
+		return
+	}
+	conf, kubeconfig, err := kubeProxyPodFiles(client, currentNodeMetadata().NodeName)
+	if err != nil {
+		glog.V(1).Infof("kube-proxy files not taken from its pod: %v", err)
+		return
+	}
+	if conf != "" && missingFile(confmap["proxy"]) {
+		glog.V(1).Infof("Using kube-proxy config %s from its ConfigMap", conf)
+		confmap["proxy"] = conf
+	}
+	if kubeconfig != "" && missingFile(kubeconfmap["proxy"]) {
+		glog.V(1).Infof("Using kube-proxy kubeconfig %s from its ConfigMap", kubeconfig)
+		kubeconfmap["proxy"] = kubeconfig
+	}
+}
+
+func missingFile(file string) bool {
+	_, err := os.Stat(hostPath(file))
+	return err != nil
+}
+
+// kubeProxyPodFiles finds the kube-proxy pod running on the node, and writes
+// the files given with its --config and --kubeconfig flags, or with the
+// kubeconfig of its config, that come from a ConfigMap.
+func kubeProxyPodFiles(client kubernetes.Interface, nodeName string) (conf, kubeconfig string, err error) {
+	pods, err := client.CoreV1().Pods(metav1.NamespaceSystem).List(metav1.ListOptions{FieldSelector: "spec.nodeName=" + nodeName})
+	if err != nil {
+		return "", "", err
+	}
+
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName != nodeName {
+			continue
+		}
+		for _, c := range pod.Spec.Containers {
+			flags := containerFlags(c)
+			if flags == nil {
+				continue
+			}
+
+			if path := flags["--config"]; path != "" {
+				data, mode, err := configMapFile(client, &pod, c, path)
+				if err != nil {
+					return "", "", err
+				}
+				if conf, err = writeKubeProxyFile(path, data, mode); err != nil {
+					return "", "", err
+				}
+				if flags["--kubeconfig"] == "" {
+					flags["--kubeconfig"] = kubeconfigOfProxyConfig(data)
+				}
+			}
+			if path := flags["--kubeconfig"]; path != "" {
+				data, mode, err := configMapFile(client, &pod, c, path)
+				if err != nil {
+					return "", "", err
+				}
+				if kubeconfig, err = writeKubeProxyFile(path, data, mode); err != nil {
+					return "", "", err
+				}
+			}
+			return conf, kubeconfig, nil
+		}
+	}
+	return "", "", fmt.Errorf("no kube-proxy pod found on node %s", nodeName)
+}
+
+// containerFlags returns the flags of a kube-proxy container, or nil if the
+// container doesn't run kube-proxy.
+func containerFlags(c corev1.Container) map[string]string {
+	args := append(append([]string{}, c.Command...), c.Args...)
+	if len(args) == 0 || filepath.Base(args[0]) != "kube-proxy" {
+		return nil
+	}
+
+	flags := map[string]string{}
+	for i := 1; i < len(args); i++ {
+		if kv := strings.SplitN(args[i], "=", 2); len(kv) == 2 {
+			flags[kv[0]] = kv[1]
+		} else if i+1 < len(args) && !strings.HasPrefix(args[i+1], "-") {
+			flags[args[i]] = args[i+1]
+			i++
+		}
+	}
+	return flags
+}
+
+// kubeconfigOfProxyConfig returns the kubeconfig set in a kube-proxy config.
+func kubeconfigOfProxyConfig(data string) string {
+	var config struct {
+		ClientConnection struct {
+			Kubeconfig string `yaml:"kubeconfig"`
+		} `yaml:"clientConnection"`
+	}
+	if err := yaml.Unmarshal([]byte(data), &config); err != nil {
+		return ""
+	}
+	return config.ClientConnection.Kubeconfig
+}
+
+// configMapFile returns the content and mode of a file of a container, when
+// it comes from a ConfigMap volume.
+func configMapFile(client kubernetes.Interface, pod *corev1.Pod, c corev1.Container, path string) (string, os.FileMode, error) {
+	for _, m := range c.VolumeMounts {
+		rel, err := filepath.Rel(m.MountPath, path)
+		if err != nil || strings.HasPrefix(rel, "..") {
+			continue
+		}
+		if m.SubPath != "" {
+			rel = filepath.Join(m.SubPath, rel)
+		}
+
+		for _, v := range pod.Spec.Volumes {
+			if v.Name != m.Name || v.ConfigMap == nil {
+				continue
+			}
+
+			key, mode := rel, int32(corev1.ConfigMapVolumeSourceDefaultMode)
+			if v.ConfigMap.DefaultMode != nil {
+				mode = *v.ConfigMap.DefaultMode
+			}
+			for _, item := range v.ConfigMap.Items {
+				if item.Path == rel {
+					key = item.Key
+					if item.Mode != nil {
+						mode = *item.Mode
+					}
+				}
+			}
+
+			cm, err := client.CoreV1().ConfigMaps(pod.Namespace).Get(v.ConfigMap.Name, metav1.GetOptions{})
+			if err != nil {
+				return "", 0, err
+			}
+			data, ok := cm.Data[key]
+			if !ok {
+				return "", 0, fmt.Errorf("no key %s in ConfigMap %s/%s", key, pod.Namespace, cm.Name)
+			}
+			return data, os.FileMode(mode), nil
+		}
+	}
+	return "", 0, fmt.Errorf("%s of pod %s/%s doesn't come from a ConfigMap", path, pod.Namespace, pod.Name)
+}
+
+// writeKubeProxyFile writes a file of kube-proxy with the mode it has in its
+// pod, so that permission checks are evaluated against it.
+func writeKubeProxyFile(path, data string, mode os.FileMode) (string, error) {
+	if kubeProxyFilesDir == "" {
+		dir, err := ioutil.TempDir("", "kube-bench-proxy")
+		if err != nil {
+			return "", err
+		}
+		kubeProxyFilesDir = dir
+	}
+
+	file := filepath.Join(kubeProxyFilesDir, filepath.Base(path))
+	if err := ioutil.WriteFile(file, []byte(data), mode); err != nil {
+		return "", err
+	}
+	// The mode given to WriteFile is subject to the umask.
+	return file, os.Chmod(file, mode)
+}
+
+// removeKubeProxyFiles removes the files of kube-proxy taken from its pod.
+func removeKubeProxyFiles() {
+	if kubeProxyFilesDir != "" {
+		os.RemoveAll(kubeProxyFilesDir)
+	}
+}
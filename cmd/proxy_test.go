@@ -0,0 +1,105 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+// kubeProxyPod is a kube-proxy pod as deployed by kubeadm.
+func kubeProxyPod(nodeName string) *corev1.Pod {
+	return &corev1.Pod{
+		ObjectMeta: metav1.ObjectMeta{Name: "kube-proxy-" + nodeName, Namespace: metav1.NamespaceSystem},
+		Spec: corev1.PodSpec{
+			NodeName: nodeName,
+			Containers: []corev1.Container{{
+				Name:         "kube-proxy",
+				Command:      []string{"/usr/local/bin/kube-proxy", "--config=/var/lib/kube-proxy/config.conf", "--hostname-override=$(NODE_NAME)"},
+				VolumeMounts: []corev1.VolumeMount{{Name: "kube-proxy", MountPath: "/var/lib/kube-proxy"}},
+			}},
+			Volumes: []corev1.Volume{{
+				Name: "kube-proxy",
+				VolumeSource: corev1.VolumeSource{ConfigMap: &corev1.ConfigMapVolumeSource{
+					LocalObjectReference: corev1.LocalObjectReference{Name: "kube-proxy"},
+				}},
+			}},
+		},
+	}
+}
+
+func TestKubeProxyPodFiles(t *testing.T) {
+	defer func() {
+		removeKubeProxyFiles()
+		kubeProxyFilesDir = ""
+	}()
+
+	client := fake.NewSimpleClientset(
+		kubeProxyPod("node-1"),
+		&corev1.ConfigMap{
+			ObjectMeta: metav1.ObjectMeta{Name: "kube-proxy", Namespace: metav1.NamespaceSystem},
+			Data: map[string]string{
+				"config.conf":     "clientConnection:\n  kubeconfig: /var/lib/kube-proxy/kubeconfig.conf\nmode: iptables\n",
+				"kubeconfig.conf": "apiVersion: v1\nkind: Config\n",
+			},
+		},
+	)
+
+	conf, kubeconfig, err := kubeProxyPodFiles(client, "node-1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for file, expected := range map[string]string{
+		conf:       "clientConnection:\n  kubeconfig: /var/lib/kube-proxy/kubeconfig.conf\nmode: iptables\n",
+		kubeconfig: "apiVersion: v1\nkind: Config\n",
+	} {
+		data, err := ioutil.ReadFile(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if string(data) != expected {
+			t.Errorf("unexpected content of %s: %q", file, data)
+		}
+		info, err := os.Stat(file)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if info.Mode().Perm() != 0644 {
+			t.Errorf("expected the mode of the ConfigMap volume, got %v", info.Mode())
+		}
+	}
+	if filepath.Base(conf) != "config.conf" || filepath.Base(kubeconfig) != "kubeconfig.conf" {
+		t.Errorf("unexpected files %s %s", conf, kubeconfig)
+	}
+
+	if _, _, err := kubeProxyPodFiles(client, "node-2"); err == nil {
+		t.Errorf("expected an error for a node without kube-proxy pod")
+	}
+}
+
+func TestKubeProxyPodFilesNotFromConfigMap(t *testing.T) {
+	pod := kubeProxyPod("node-1")
+	pod.Spec.Volumes[0].VolumeSource = corev1.VolumeSource{HostPath: &corev1.HostPathVolumeSource{Path: "/var/lib/kube-proxy"}}
+
+	if _, _, err := kubeProxyPodFiles(fake.NewSimpleClientset(pod), "node-1"); err == nil {
+		t.Errorf("expected an error for a config that doesn't come from a ConfigMap")
+	}
+}
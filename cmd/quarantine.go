@@ -0,0 +1,207 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// The actions of --quarantine-node.
+const (
+	cordonNode = "cordon"
+	taintNode  = "taint"
+)
+
+// The taint and the annotations of a Node quarantined by kube-bench. The
+// quarantined annotation records what kube-bench did, so that only that is
+// undone once the critical checks pass again.
+const (
+	criticalFailureTaint       = "kube-bench.aquasec.com/critical-failure"
+	quarantinedAnnotation      = "kube-bench.aquasec.com/quarantined"
+	criticalFailuresAnnotation = "kube-bench.aquasec.com/critical-failures"
+)
+
+var (
+	quarantineAction string
+	criticalChecks   string
+	// quarantineChecks collects the critical checks of the targets of this
+	// run, whether they're run or not, when --quarantine-node is given.
+	quarantineChecks []*check.Check
+)
+
+// checkQuarantine verifies the action given with --quarantine-node.
+func checkQuarantine(action string) error {
+	switch action {
+	case "", cordonNode, taintNode:
+		return nil
+	}
+	return fmt.Errorf("%q is not one of %s or %s", action, cordonNode, taintNode)
+}
+
+// quarantineCriticalChecks returns the critical checks of a target with
+// --quarantine-node. It's called before the target runs, as only the checks
+// that ran are left in its groups afterwards.
+func quarantineCriticalChecks(controls *check.Controls) []*check.Check {
+	if quarantineAction == "" {
+		return nil
+	}
+	var ids map[string]bool
+	if criticalChecks != "" {
+		ids = cleanIDs(criticalChecks)
+	}
+	return findCriticalChecks(controls, ids)
+}
+
+// addToQuarantine adds the critical checks of a target to the ones the Node
+// is quarantined on.
+func addToQuarantine(critical []*check.Check) {
+	quarantineChecks = append(quarantineChecks, critical...)
+}
+
+// findCriticalChecks returns the critical checks of controls: the ones given
+// with --critical-checks, or else the checks of critical severity.
+func findCriticalChecks(controls *check.Controls, ids map[string]bool) []*check.Check {
+	var critical []*check.Check
+	for _, g := range controls.Groups {
+		for _, ch := range g.Checks {
+			if len(ids) > 0 && hasID(ids, ch) || len(ids) == 0 && ch.CheckSeverity() == check.SeverityCritical {
+				critical = append(critical, ch)
+			}
+		}
+	}
+	return critical
+}
+
+// criticalFailures returns the IDs of the critical checks that failed, and
+// of the ones that were not evaluated in this run, e.g. not selected with
+// --check or skipped, or that didn't pass nor fail.
+func criticalFailures(critical []*check.Check) (failed, unevaluated []string) {
+	for _, ch := range critical {
+		switch ch.State {
+		case check.FAIL:
+			failed = append(failed, ch.ID)
+		case check.PASS:
+		default:
+			unevaluated = append(unevaluated, ch.ID)
+		}
+	}
+	return failed, unevaluated
+}
+
+// quarantine cordons or taints the Node when critical checks failed, and
+// undoes it when none failed. The caller only passes no failures once all
+// the critical checks passed. It reports whether the Node was changed.
+func quarantine(node *corev1.Node, action string, failed []string) bool {
+	if len(failed) == 0 {
+		done, quarantined := node.Annotations[quarantinedAnnotation]
+		if _, ok := node.Annotations[criticalFailuresAnnotation]; !ok && !quarantined {
+			return false
+		}
+		switch done {
+		case cordonNode:
+			node.Spec.Unschedulable = false
+		case taintNode:
+			taints := node.Spec.Taints[:0]
+			for _, t := range node.Spec.Taints {
+				if t.Key != criticalFailureTaint {
+					taints = append(taints, t)
+				}
+			}
+			node.Spec.Taints = taints
+		}
+		delete(node.Annotations, quarantinedAnnotation)
+		delete(node.Annotations, criticalFailuresAnnotation)
+		return true
+	}
+
+	if node.Annotations == nil {
+		node.Annotations = map[string]string{}
+	}
+	ids := strings.Join(failed, ",")
+	changed := node.Annotations[criticalFailuresAnnotation] != ids
+	node.Annotations[criticalFailuresAnnotation] = ids
+
+	switch action {
+	case cordonNode:
+		// A Node cordoned by someone else is left to them to uncordon.
+		if !node.Spec.Unschedulable {
+			node.Spec.Unschedulable = true
+			node.Annotations[quarantinedAnnotation] = cordonNode
+			changed = true
+		}
+	case taintNode:
+		for _, t := range node.Spec.Taints {
+			if t.Key == criticalFailureTaint {
+				return changed
+			}
+		}
+		node.Spec.Taints = append(node.Spec.Taints, corev1.Taint{Key: criticalFailureTaint, Effect: corev1.TaintEffectNoSchedule})
+		node.Annotations[quarantinedAnnotation] = taintNode
+		changed = true
+	}
+	return changed
+}
+
+// quarantineNode cordons or taints the Node kube-bench runs on when critical
+// checks failed, with --quarantine-node, and undoes it once they pass.
+func quarantineNode() error {
+	if quarantineAction == "" || len(quarantineChecks) == 0 {
+		return nil
+	}
+	// Mock results are not the ones of the node.
+	if mockMode != "" {
+		glog.V(1).Info("Node not quarantined on mock results")
+		return nil
+	}
+	if os.Getenv("KUBERNETES_SERVICE_HOST") == "" {
+		return fmt.Errorf("--quarantine-node needs kube-bench to run in a pod of the cluster")
+	}
+
+	failed, unevaluated := criticalFailures(quarantineChecks)
+	// The quarantine is only lifted once all the critical checks passed.
+	if len(failed) == 0 && len(unevaluated) > 0 {
+		glog.V(1).Infof("Node left as it is, critical checks not evaluated: %s", strings.Join(unevaluated, ", "))
+		return nil
+	}
+	client, err := kubeClient()
+	if err != nil {
+		return fmt.Errorf("failed to quarantine node: %v", err)
+	}
+	name := currentNodeMetadata().NodeName
+	node, err := client.CoreV1().Nodes().Get(name, metav1.GetOptions{})
+	if err != nil {
+		return fmt.Errorf("failed to quarantine node %s: %v", name, err)
+	}
+	if !quarantine(node, quarantineAction, failed) {
+		return nil
+	}
+	if _, err := client.CoreV1().Nodes().Update(node); err != nil {
+		return fmt.Errorf("failed to quarantine node %s: %v", name, err)
+	}
+
+	if len(failed) > 0 {
+		colors[check.FAIL].Fprintf(os.Stderr, "Node %s quarantined (%s), critical checks failed: %s\n", name, quarantineAction, strings.Join(failed, ", "))
+	} else {
+		glog.V(1).Infof("Node %s no longer quarantined, the critical checks pass", name)
+	}
+	return nil
+}
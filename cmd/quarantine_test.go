@@ -0,0 +1,197 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+	"reflect"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/client-go/kubernetes"
+	"k8s.io/client-go/kubernetes/fake"
+)
+
+func TestCriticalFailures(t *testing.T) {
+	controls := &check.Controls{Groups: []*check.Group{{Checks: []*check.Check{
+		{ID: "1.2.1", State: check.FAIL, Severity: check.SeverityCritical},
+		{ID: "1.2.2", CISID: "1.2.3", State: check.FAIL},
+		{ID: "1.2.4", State: check.PASS, Severity: check.SeverityCritical},
+		{ID: "1.2.5", State: check.WARN, Severity: check.SeverityCritical},
+		{ID: "1.2.6", Severity: check.SeverityCritical},
+	}}}}
+
+	failed, unevaluated := criticalFailures(findCriticalChecks(controls, nil))
+	if !reflect.DeepEqual(failed, []string{"1.2.1"}) || !reflect.DeepEqual(unevaluated, []string{"1.2.5", "1.2.6"}) {
+		t.Errorf("expected the failed and unevaluated checks of critical severity, got %v and %v", failed, unevaluated)
+	}
+	failed, unevaluated = criticalFailures(findCriticalChecks(controls, map[string]bool{"cis:1.2.3": true, "1.2.4": true}))
+	if !reflect.DeepEqual(failed, []string{"1.2.2"}) || len(unevaluated) != 0 {
+		t.Errorf("expected the failed checks given, by either ID, got %v and %v", failed, unevaluated)
+	}
+}
+
+func TestQuarantine(t *testing.T) {
+	node := &corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}, Spec: corev1.NodeSpec{
+		Taints: []corev1.Taint{{Key: "dedicated", Value: "gpu", Effect: corev1.TaintEffectNoSchedule}},
+	}}
+
+	if quarantine(node, taintNode, nil) {
+		t.Errorf("expected no change to a Node without critical failures")
+	}
+	if !quarantine(node, taintNode, []string{"1.2.1", "4.2.1"}) {
+		t.Fatalf("expected the Node to be tainted")
+	}
+	if len(node.Spec.Taints) != 2 || node.Spec.Taints[1].Key != criticalFailureTaint || node.Annotations[criticalFailuresAnnotation] != "1.2.1,4.2.1" {
+		t.Errorf("unexpected quarantined Node %+v", node)
+	}
+	if quarantine(node, taintNode, []string{"1.2.1", "4.2.1"}) {
+		t.Errorf("expected no change to a Node already tainted for the same checks")
+	}
+	if !quarantine(node, taintNode, nil) {
+		t.Fatalf("expected the taint to be removed")
+	}
+	if len(node.Spec.Taints) != 1 || node.Spec.Taints[0].Key != "dedicated" || len(node.Annotations) != 0 {
+		t.Errorf("unexpected Node after the quarantine %+v", node)
+	}
+
+	quarantine(node, cordonNode, []string{"1.2.1"})
+	if !node.Spec.Unschedulable || node.Annotations[quarantinedAnnotation] != cordonNode {
+		t.Errorf("expected the Node to be cordoned, got %+v", node)
+	}
+	quarantine(node, cordonNode, nil)
+	if node.Spec.Unschedulable {
+		t.Errorf("expected the Node to be uncordoned")
+	}
+
+	// A Node cordoned by someone else stays cordoned.
+	node.Spec.Unschedulable = true
+	quarantine(node, cordonNode, []string{"1.2.1"})
+	quarantine(node, cordonNode, nil)
+	if !node.Spec.Unschedulable || len(node.Annotations) != 0 {
+		t.Errorf("expected the Node to stay cordoned, got %+v", node)
+	}
+}
+
+func TestQuarantineNode(t *testing.T) {
+	savedClient, savedMetadata, savedChecks := kubeClient, nodeMetadata, quarantineChecks
+	defer func() {
+		kubeClient, nodeMetadata, quarantineChecks, quarantineAction = savedClient, savedMetadata, savedChecks, ""
+	}()
+	defer os.Unsetenv("KUBERNETES_SERVICE_HOST")
+
+	client := fake.NewSimpleClientset(&corev1.Node{ObjectMeta: metav1.ObjectMeta{Name: "node-1"}})
+	kubeClient = func() (kubernetes.Interface, error) { return client, nil }
+	nodeMetadata = &check.NodeMetadata{NodeName: "node-1"}
+
+	quarantineAction = cordonNode
+	addToQuarantine([]*check.Check{{ID: "4.2.1", State: check.FAIL, Severity: check.SeverityCritical}})
+	if err := quarantineNode(); err == nil {
+		t.Errorf("expected an error outside of a pod")
+	}
+
+	os.Setenv("KUBERNETES_SERVICE_HOST", "10.96.0.1")
+	if err := quarantineNode(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	node, err := client.CoreV1().Nodes().Get("node-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !node.Spec.Unschedulable || node.Annotations[criticalFailuresAnnotation] != "4.2.1" {
+		t.Errorf("expected the Node to be cordoned, got %+v", node)
+	}
+}
+
+func TestQuarantineNodeUnevaluated(t *testing.T) {
+	savedClient, savedMetadata, savedChecks := kubeClient, nodeMetadata, quarantineChecks
+	defer func() {
+		kubeClient, nodeMetadata, quarantineChecks, quarantineAction = savedClient, savedMetadata, savedChecks, ""
+	}()
+	os.Setenv("KUBERNETES_SERVICE_HOST", "10.96.0.1")
+	defer os.Unsetenv("KUBERNETES_SERVICE_HOST")
+
+	client := fake.NewSimpleClientset(&corev1.Node{
+		ObjectMeta: metav1.ObjectMeta{Name: "node-1", Annotations: map[string]string{
+			quarantinedAnnotation:      cordonNode,
+			criticalFailuresAnnotation: "4.2.1",
+		}},
+		Spec: corev1.NodeSpec{Unschedulable: true},
+	})
+	kubeClient = func() (kubernetes.Interface, error) { return client, nil }
+	nodeMetadata = &check.NodeMetadata{NodeName: "node-1"}
+	quarantineAction = cordonNode
+
+	controls, err := check.NewControls(check.NODE, []byte(`---
+controls:
+type: "node"
+groups:
+  - id: 4.2
+    checks:
+      - id: 4.2.1
+        text: "Ensure that the --anonymous-auth argument is set to false"
+        audit: "echo --anonymous-auth=true"
+        severity: critical
+        tests:
+          test_items:
+            - flag: "--anonymous-auth"
+              compare:
+                op: eq
+                value: false
+              set: true
+        scored: true
+      - id: 4.2.2
+        text: "Ensure that the --authorization-mode argument is not set to AlwaysAllow"
+        audit: "echo --authorization-mode=Webhook"
+        tests:
+          test_items:
+            - flag: "--authorization-mode"
+              set: true
+        scored: true
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Only 4.2.2 runs, e.g. with --check 4.2.2: the failing critical check
+	// isn't evaluated, so the Node stays cordoned.
+	critical := quarantineCriticalChecks(controls)
+	controls.RunChecks(check.NewRunner(), func(g *check.Group, c *check.Check) bool { return c.ID == "4.2.2" })
+	addToQuarantine(critical)
+	if err := quarantineNode(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	node, err := client.CoreV1().Nodes().Get("node-1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !node.Spec.Unschedulable || node.Annotations[criticalFailuresAnnotation] != "4.2.1" {
+		t.Errorf("expected the Node to stay cordoned, got %+v", node)
+	}
+
+	// Once the critical check passes, the Node is uncordoned.
+	quarantineChecks = []*check.Check{{ID: "4.2.1", State: check.PASS, Severity: check.SeverityCritical}}
+	if err := quarantineNode(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if node, err = client.CoreV1().Nodes().Get("node-1", metav1.GetOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if node.Spec.Unschedulable {
+		t.Errorf("expected the Node to be uncordoned, got %+v", node)
+	}
+}
@@ -0,0 +1,268 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+)
+
+const (
+	bundleMetadataFile  = "metadata.json"
+	bundleEvidenceFile  = "evidence.json"
+	bundleProcessesFile = "processes.txt"
+	bundleControlsDir   = "controls"
+	bundleFilesDir      = "files"
+)
+
+// bundleMetadata describes the scan an evidence bundle was recorded from.
+type bundleMetadata struct {
+	KubeBenchVersion string    `json:"kube_bench_version"`
+	Benchmark        string    `json:"benchmark"`
+	KubeVersion      string    `json:"kube_version,omitempty"`
+	Hostname         string    `json:"hostname"`
+	Time             time.Time `json:"time"`
+	// Targets lists the recorded controls in the order they were run.
+	Targets []string            `json:"targets"`
+	Node    *check.NodeMetadata `json:"node,omitempty"`
+}
+
+// evidenceBundle collects everything a scan looked at, so that the evidence
+// can be preserved or analyzed later without access to the node.
+type evidenceBundle struct {
+	Metadata bundleMetadata
+	// Controls holds the controls of each target after substitution.
+	Controls  map[string]string
+	Evidence  check.Evidence
+	Files     map[string][]byte
+	Processes string
+}
+
+// recording is the bundle being recorded with --record, if any.
+var recording *evidenceBundle
+
+func newEvidenceBundle() *evidenceBundle {
+	hostname, _ := os.Hostname()
+	return &evidenceBundle{
+		Metadata: bundleMetadata{
+			KubeBenchVersion: KubeBenchVersion,
+			KubeVersion:      kubeVersion,
+			Hostname:         hostname,
+			Time:             time.Now().UTC(),
+		},
+		Controls: map[string]string{},
+		Evidence: check.Evidence{},
+		Files:    map[string][]byte{},
+	}
+}
+
+// startRecording starts recording an evidence bundle if --record was given.
+func startRecording() {
+	if recordFile == "" {
+		return
+	}
+
+	recording = newEvidenceBundle()
+	check.RecordEvidence(recording.Evidence)
+}
+
+// finishRecording captures the process table and writes the recorded bundle.
+func finishRecording() error {
+	if recording == nil {
+		return nil
+	}
+	check.RecordEvidence(nil)
+
+	out, err := exec.Command("ps", "-eo", "pid,ppid,user,args").Output()
+	if err != nil {
+		glog.V(1).Infof("failed to capture the process table: %v", err)
+	}
+	recording.Processes = string(out)
+
+	if err := recording.write(recordFile); err != nil {
+		return fmt.Errorf("failed to write evidence bundle %s: %v", recordFile, err)
+	}
+	glog.V(1).Infof("Evidence bundle written to %s", recordFile)
+	return nil
+}
+
+// addControls records the substituted controls of a target, along with the
+// files they were configured with.
+func (b *evidenceBundle) addControls(nodetype check.NodeType, testYamlFile, controls string, filemaps ...map[string]string) {
+	if _, ok := b.Controls[string(nodetype)]; !ok {
+		b.Metadata.Targets = append(b.Metadata.Targets, string(nodetype))
+	}
+	b.Controls[string(nodetype)] = controls
+	b.Metadata.Benchmark = filepath.Base(filepath.Dir(testYamlFile))
+
+	for _, m := range filemaps {
+		for _, f := range m {
+			b.addFile(f)
+		}
+	}
+}
+
+func (b *evidenceBundle) addFile(file string) {
+	if _, ok := b.Files[file]; ok {
+		return
+	}
+
+	fi, err := os.Stat(file)
+	if err != nil || !fi.Mode().IsRegular() {
+		return
+	}
+
+	data, err := ioutil.ReadFile(file)
+	if err != nil {
+		glog.V(2).Infof("failed to record %s: %v", file, err)
+		return
+	}
+	b.Files[file] = data
+}
+
+// write saves the bundle as a gzipped tarball.
+func (b *evidenceBundle) write(file string) error {
+	f, err := os.Create(file)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	gz := gzip.NewWriter(f)
+	tw := tar.NewWriter(gz)
+
+	metadata, err := json.MarshalIndent(b.Metadata, "", "  ")
+	if err != nil {
+		return err
+	}
+	evidence, err := json.MarshalIndent(b.Evidence, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	entries := map[string][]byte{
+		bundleMetadataFile:  metadata,
+		bundleEvidenceFile:  evidence,
+		bundleProcessesFile: []byte(b.Processes),
+	}
+	for target, controls := range b.Controls {
+		entries[path.Join(bundleControlsDir, target+".yaml")] = []byte(controls)
+	}
+	for name, data := range b.Files {
+		entries[path.Join(bundleFilesDir, filepath.ToSlash(name))] = data
+	}
+
+	names := make([]string, 0, len(entries))
+	for name := range entries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		hdr := &tar.Header{
+			Name:    name,
+			Mode:    0600,
+			Size:    int64(len(entries[name])),
+			ModTime: b.Metadata.Time,
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if _, err := tw.Write(entries[name]); err != nil {
+			return err
+		}
+	}
+
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return f.Close()
+}
+
+// readBundle loads an evidence bundle written by --record.
+func readBundle(file string) (*evidenceBundle, error) {
+	f, err := os.Open(file)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	tr := tar.NewReader(gz)
+
+	b := &evidenceBundle{
+		Controls: map[string]string{},
+		Evidence: check.Evidence{},
+		Files:    map[string][]byte{},
+	}
+	var hasMetadata bool
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+
+		switch name := path.Clean(hdr.Name); {
+		case name == bundleMetadataFile:
+			if err := json.Unmarshal(data, &b.Metadata); err != nil {
+				return nil, fmt.Errorf("invalid %s: %v", name, err)
+			}
+			hasMetadata = true
+		case name == bundleEvidenceFile:
+			if err := json.Unmarshal(data, &b.Evidence); err != nil {
+				return nil, fmt.Errorf("invalid %s: %v", name, err)
+			}
+		case name == bundleProcessesFile:
+			b.Processes = string(data)
+		case path.Dir(name) == bundleControlsDir && path.Ext(name) == ".yaml":
+			b.Controls[strings.TrimSuffix(path.Base(name), ".yaml")] = string(data)
+		case strings.HasPrefix(name, bundleFilesDir+"/"):
+			b.Files[filepath.FromSlash(strings.TrimPrefix(name, bundleFilesDir))] = data
+		}
+	}
+
+	if !hasMetadata {
+		return nil, fmt.Errorf("%s not found, is this a kube-bench evidence bundle?", bundleMetadataFile)
+	}
+	return b, nil
+}
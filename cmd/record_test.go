@@ -0,0 +1,109 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+func TestEvidenceBundleWrite(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-record")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	conf := filepath.Join(dir, "kubelet.conf")
+	if err := ioutil.WriteFile(conf, []byte("apiVersion: v1"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	b := newEvidenceBundle()
+	b.addControls(check.NODE, "cfg/cis-1.5/node.yaml", "controls: node", map[string]string{
+		"kubelet": conf,
+		"proxy":   filepath.Join(dir, "missing.conf"),
+	})
+	b.addControls(check.ETCD, "cfg/cis-1.5/etcd.yaml", "controls: etcd")
+	b.Evidence["ps -ef"] = "kubelet"
+	b.Processes = "1 0 root /sbin/init"
+
+	if b.Metadata.Benchmark != "cis-1.5" {
+		t.Errorf("expected benchmark cis-1.5, got %q", b.Metadata.Benchmark)
+	}
+	if !reflect.DeepEqual(b.Metadata.Targets, []string{"node", "etcd"}) {
+		t.Errorf("unexpected targets %v", b.Metadata.Targets)
+	}
+
+	bundle := filepath.Join(dir, "bundle.tar.gz")
+	if err := b.write(bundle); err != nil {
+		t.Fatalf("unexpected error writing bundle: %v", err)
+	}
+
+	f, err := os.Open(bundle)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	entries := map[string]string{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := ioutil.ReadAll(tr)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries[hdr.Name] = string(data)
+	}
+
+	expected := map[string]string{
+		"controls/node.yaml":         "controls: node",
+		"controls/etcd.yaml":         "controls: etcd",
+		"processes.txt":              "1 0 root /sbin/init",
+		filepath.Join("files", conf): "apiVersion: v1",
+	}
+	for name, content := range expected {
+		if entries[name] != content {
+			t.Errorf("expected %s to contain %q, got %q", name, content, entries[name])
+		}
+	}
+	for _, name := range []string{"metadata.json", "evidence.json"} {
+		if _, ok := entries[name]; !ok {
+			t.Errorf("expected %s in bundle", name)
+		}
+	}
+	if len(entries) != 6 {
+		t.Errorf("expected 6 entries, got %d", len(entries))
+	}
+}
@@ -0,0 +1,290 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+type remediateOpts struct {
+	Targets       []string
+	DryRun        bool
+	Restart       bool
+	HealthTimeout time.Duration
+}
+
+var remediateFlags remediateOpts
+
+// fixResult is the outcome of the remediation of a failed check.
+type fixResult struct {
+	ID   string
+	Text string
+	// Steps are the remediation steps of the check, as text.
+	Steps []string
+	// Files are the files the steps edit.
+	Files []string
+	// Err is why the remediation could not be applied.
+	Err error
+	// State is the state of the check when run again after the remediation.
+	State check.State
+	// Restarted are the components restarted after the remediation.
+	Restarted []string
+}
+
+// remediateCmd represents the remediate command
+var remediateCmd = &cobra.Command{
+	Use:   "remediate",
+	Short: "Apply the remediation steps of the failed checks and verify that they took effect",
+	Long: `Run the checks of the targets like kube-bench run, apply the remediation_steps of the
+checks that fail, then run these checks again and report which fixes took effect and which
+checks still fail, e.g. because a component needs restarting, with how to restart it. The
+files are edited in place, with a copy of each file kept with a .kube-bench.bak suffix.
+Failed checks without remediation_steps are listed, to be remediated manually.
+--dry-run lists the steps without applying them.
+
+With --restart, the components whose files were edited are restarted with the restart
+commands of their section of the config, e.g. node.kubelet.restart, before the checks are
+run again. Components with a healthcheck command are only edited when healthy, and must be
+healthy again within --health-timeout after restarting them, otherwise their files are
+restored and they're restarted again.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if mockMode != "" {
+			exitWithError(fmt.Errorf("remediate edits the files of the node, it can't run with --mock"))
+		}
+
+		files, err := remediationFiles(remediateFlags.Targets)
+		if err != nil {
+			exitWithError(err)
+		}
+
+		var results []fixResult
+		var manual []string
+		for _, f := range files {
+			r, m := remediateTarget(f.nodetype, f.file, remediateFlags.DryRun)
+			results = append(results, r...)
+			manual = append(manual, m...)
+		}
+		printFixResults(os.Stdout, results, manual, remediateFlags.DryRun)
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(remediateCmd)
+	remediateCmd.Flags().StringSliceVarP(&remediateFlags.Targets, "targets", "s", []string{}, "Targets of the benchmark to remediate, all of them when not given")
+	remediateCmd.Flags().BoolVar(&remediateFlags.DryRun, "dry-run", false, "Lists the remediation steps of the failed checks without applying them")
+	remediateCmd.Flags().BoolVar(&remediateFlags.Restart, "restart", false, "Restarts the components whose files were edited with the restart commands of the config, checking their health before and after")
+	remediateCmd.Flags().DurationVar(&remediateFlags.HealthTimeout, "health-timeout", 2*time.Minute, "How long to wait for a restarted component to be healthy before restoring its files")
+}
+
+type controlsFileTarget struct {
+	nodetype check.NodeType
+	file     string
+}
+
+// remediationFiles returns the controls files of the targets to remediate:
+// the one given with --controls, or the ones of the benchmark.
+func remediationFiles(targets []string) ([]controlsFileTarget, error) {
+	if controlsFile != "" {
+		nodetype, err := controlsType(controlsFile)
+		if err != nil {
+			return nil, err
+		}
+		return []controlsFileTarget{{nodetype: nodetype, file: controlsFile}}, nil
+	}
+
+	benchmarkVersion, err := getBenchmarkVersion(kubeVersion, benchmarkVersion, viper.GetViper())
+	if err != nil {
+		return nil, fmt.Errorf("unable to determine benchmark version: %v", err)
+	}
+	mergeConfig(filepath.Join(cfgDir, benchmarkVersion))
+	yamlFiles, err := getTestYamlFiles(targets, benchmarkVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	files := make([]controlsFileTarget, 0, len(yamlFiles))
+	for _, f := range yamlFiles {
+		name := filepath.Base(f)
+		files = append(files, controlsFileTarget{nodetype: check.NodeType(strings.Split(name, ".")[0]), file: f})
+	}
+	return files, nil
+}
+
+// remediateTarget applies the remediation steps of the failed checks of a
+// target, and runs them again to verify the fixes. It also returns the
+// failed checks without remediation steps.
+func remediateTarget(nodetype check.NodeType, file string, dryRun bool) ([]fixResult, []string) {
+	t := prepareChecks(nodetype, file)
+	if t == nil {
+		return nil, nil
+	}
+	t.run()
+
+	var results []fixResult
+	var manual []string
+	fixed := map[string]bool{}
+	var hooks []*restartHook
+	if remediateFlags.Restart {
+		hooks = restartHooks(viper.Sub(string(nodetype)))
+	}
+	healthy := map[string]error{}
+	originals := map[string][]byte{}
+	for _, g := range t.controls.Groups {
+		for _, c := range g.Checks {
+			if c.State != check.FAIL {
+				continue
+			}
+			if len(c.RemediationSteps) == 0 {
+				manual = append(manual, c.ID)
+				continue
+			}
+
+			r := fixResult{ID: c.ID, Text: c.Text}
+			for _, s := range c.RemediationSteps {
+				r.Steps = append(r.Steps, s.Text())
+				if s.File != "" {
+					r.Files = appendNewFile(r.Files, s.File)
+				}
+			}
+			if !dryRun {
+				r.Err = checkHealthyBefore(c.RemediationSteps, hooks, healthy)
+				if r.Err == nil {
+					keepOriginals(c.RemediationSteps, originals)
+				}
+				for _, s := range c.RemediationSteps {
+					if r.Err != nil {
+						break
+					}
+					r.Err = s.Apply(hostRoot)
+				}
+				fixed[c.ID] = r.Err == nil
+			}
+			results = append(results, r)
+		}
+	}
+	if len(fixed) == 0 {
+		return results, manual
+	}
+	restartComponents(results, fixed, hooks, originals, remediateFlags.HealthTimeout)
+
+	// The fixed checks are run again, never from the cache of results.
+	v := prepareChecks(nodetype, file)
+	v.runner = check.NewRunner()
+	v.filter = func(g *check.Group, c *check.Check) bool { return fixed[c.ID] }
+	v.run()
+	states := map[string]check.State{}
+	for _, g := range v.controls.Groups {
+		for _, c := range g.Checks {
+			if fixed[c.ID] {
+				states[c.ID] = c.State
+			}
+		}
+	}
+	for i := range results {
+		results[i].State = states[results[i].ID]
+	}
+	return results, manual
+}
+
+func appendNewFile(files []string, file string) []string {
+	for _, f := range files {
+		if f == file {
+			return files
+		}
+	}
+	return append(files, file)
+}
+
+// printFixResults reports which fixes took effect and which checks still
+// fail, with how to restart the components whose files were edited for them.
+func printFixResults(w io.Writer, results []fixResult, manual []string, dryRun bool) {
+	if dryRun {
+		colors[check.WARN].Fprintf(w, "== Remediation steps ==\n")
+		for _, r := range results {
+			fmt.Fprintf(w, "%s %s\n", r.ID, r.Text)
+			for _, s := range r.Steps {
+				printIndented(w, "  ", strings.Split(s, "\n"))
+			}
+		}
+	} else {
+		colors[check.WARN].Fprintf(w, "== Remediation ==\n")
+		var restart []string
+		hints := map[string][]string{}
+		for _, r := range results {
+			switch {
+			case r.Err != nil:
+				colors[check.WARN].Fprintf(w, "[%s] ", check.WARN)
+				fmt.Fprintf(w, "%s %s: not applied: %v\n", r.ID, r.Text, r.Err)
+			case r.State == check.PASS:
+				colors[check.PASS].Fprintf(w, "[%s] ", check.PASS)
+				fmt.Fprintf(w, "%s %s: fixed%s\n", r.ID, r.Text, restartedText(" after restarting ", r))
+			default:
+				colors[check.FAIL].Fprintf(w, "[%s] ", check.FAIL)
+				fmt.Fprintf(w, "%s %s: still fails after the remediation%s\n", r.ID, r.Text, restartedText(" and restarting ", r))
+				if len(r.Restarted) > 0 {
+					continue
+				}
+				for _, f := range r.Files {
+					if _, ok := hints[f]; !ok {
+						restart = append(restart, f)
+					}
+					hints[f] = append(hints[f], r.ID)
+				}
+			}
+		}
+		if len(restart) > 0 {
+			fmt.Fprintln(w, "\nThe components reading the edited files may need restarting:")
+			for _, f := range restart {
+				fmt.Fprintf(w, "%s (%s): %s\n", f, strings.Join(hints[f], ", "), restartHint(f))
+			}
+		}
+	}
+
+	if len(manual) > 0 {
+		fmt.Fprintf(w, "\nFailed checks without remediation steps, to remediate manually: %s\n", strings.Join(manual, ", "))
+	}
+}
+
+func restartedText(prefix string, r fixResult) string {
+	if len(r.Restarted) == 0 {
+		return ""
+	}
+	return prefix + strings.Join(r.Restarted, ", ")
+}
+
+// restartHint tells how the component reading a file picks up its changes.
+func restartHint(file string) string {
+	switch {
+	case strings.Contains(file, "/manifests/"):
+		return "the kubelet restarts the static pod when its manifest changes, wait for it to be running again"
+	case strings.HasSuffix(filepath.Dir(file), ".service.d"):
+		unit := strings.TrimSuffix(filepath.Base(filepath.Dir(file)), ".d")
+		return fmt.Sprintf("systemctl daemon-reload && systemctl restart %s", unit)
+	case strings.HasSuffix(file, ".service"):
+		return fmt.Sprintf("systemctl daemon-reload && systemctl restart %s", filepath.Base(file))
+	case strings.Contains(file, "kubelet"):
+		return "systemctl restart kubelet"
+	}
+	return fmt.Sprintf("restart the component reading %s", file)
+}
@@ -0,0 +1,141 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+func TestPrintFixResults(t *testing.T) {
+	results := []fixResult{
+		{ID: "4.2.1", Text: "Ensure that the anonymous-auth argument is set to false", Files: []string{"/var/lib/kubelet/config.yaml"}, State: check.PASS, Restarted: []string{"kubelet"}},
+		{ID: "4.2.6", Text: "Ensure that the --protect-kernel-defaults argument is set to true", Files: []string{"/var/lib/kubelet/config.yaml"}, State: check.FAIL},
+		{ID: "1.2.1", Text: "Ensure that the --anonymous-auth argument is set to false", Files: []string{"/etc/kubernetes/manifests/kube-apiserver.yaml"}, State: check.FAIL},
+		{ID: "1.2.2", Text: "Ensure that the --basic-auth-file argument is not set", Err: fmt.Errorf("permission denied")},
+	}
+
+	var out bytes.Buffer
+	printFixResults(&out, results, []string{"1.1.9", "1.1.10"}, false)
+	expected := `== Remediation ==
+[PASS] 4.2.1 Ensure that the anonymous-auth argument is set to false: fixed after restarting kubelet
+[FAIL] 4.2.6 Ensure that the --protect-kernel-defaults argument is set to true: still fails after the remediation
+[FAIL] 1.2.1 Ensure that the --anonymous-auth argument is set to false: still fails after the remediation
+[WARN] 1.2.2 Ensure that the --basic-auth-file argument is not set: not applied: permission denied
+
+The components reading the edited files may need restarting:
+/var/lib/kubelet/config.yaml (4.2.6): systemctl restart kubelet
+/etc/kubernetes/manifests/kube-apiserver.yaml (1.2.1): the kubelet restarts the static pod when its manifest changes, wait for it to be running again
+
+Failed checks without remediation steps, to remediate manually: 1.1.9, 1.1.10
+`
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+
+	out.Reset()
+	printFixResults(&out, []fixResult{{ID: "4.2.1", Text: "Ensure that the anonymous-auth argument is set to false",
+		Steps: []string{"Edit the file /var/lib/kubelet/config.yaml and set authentication.anonymous.enabled to false."}}}, nil, true)
+	expected = `== Remediation steps ==
+4.2.1 Ensure that the anonymous-auth argument is set to false
+  Edit the file /var/lib/kubelet/config.yaml and set authentication.anonymous.enabled to false.
+`
+	if out.String() != expected {
+		t.Errorf("expected:\n%s\ngot:\n%s", expected, out.String())
+	}
+}
+
+func TestRestartHint(t *testing.T) {
+	cases := map[string]string{
+		"/etc/systemd/system/kubelet.service.d/10-kubeadm.conf": "systemctl daemon-reload && systemctl restart kubelet.service",
+		"/lib/systemd/system/etcd.service":                      "systemctl daemon-reload && systemctl restart etcd.service",
+		"/etc/kubernetes/kubelet.conf":                          "systemctl restart kubelet",
+		"/etc/kubernetes/proxy.conf":                            "restart the component reading /etc/kubernetes/proxy.conf",
+	}
+	for file, expected := range cases {
+		if got := restartHint(file); got != expected {
+			t.Errorf("%s: expected %q, got %q", file, expected, got)
+		}
+	}
+}
+
+// shippedKubeletCheck loads a check of the node controls of cis-1.5 with the
+// given kubelet config file. The kubelet isn't running, only its config file
+// is audited.
+func shippedKubeletCheck(t *testing.T, kubeletConf, id string) (*check.Controls, *check.Check) {
+	in, err := ioutil.ReadFile("../cfg/cis-1.5/node.yaml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	controls, err := check.NewControls(check.NODE, []byte(makeSubstitutions(string(in), "conf", map[string]string{"kubelet": kubeletConf})))
+	if err != nil {
+		t.Fatal(err)
+	}
+	controls.RewriteAudits(func(audit string) string {
+		if strings.HasPrefix(audit, "/bin/ps ") {
+			return "true"
+		}
+		return audit
+	})
+	for _, g := range controls.Groups {
+		for _, c := range g.Checks {
+			if c.ID == id {
+				return controls, c
+			}
+		}
+	}
+	t.Fatalf("check %s not found", id)
+	return nil, nil
+}
+
+func TestShippedRemediationSteps(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-remediate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	kubeletConf := filepath.Join(dir, "config.yaml")
+	if err := ioutil.WriteFile(kubeletConf, []byte("authentication:\n  anonymous:\n    enabled: true\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	only := func(id string) check.Predicate {
+		return func(g *check.Group, c *check.Check) bool { return c.ID == id }
+	}
+	controls, c := shippedKubeletCheck(t, kubeletConf, "4.2.1")
+	if controls.RunChecks(check.NewRunner(), only("4.2.1")); c.State != check.FAIL {
+		t.Fatalf("expected %s before the remediation, got %s", check.FAIL, c.State)
+	}
+	if len(c.RemediationSteps) == 0 {
+		t.Fatal("expected remediation steps")
+	}
+	for _, s := range c.RemediationSteps {
+		if err := s.Apply(""); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	controls, c = shippedKubeletCheck(t, kubeletConf, "4.2.1")
+	if controls.RunChecks(check.NewRunner(), only("4.2.1")); c.State != check.PASS {
+		t.Errorf("expected %s after the remediation, got %s", check.PASS, c.State)
+	}
+}
@@ -0,0 +1,217 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+	"github.com/spf13/viper"
+)
+
+// healthCheckInterval is how often the health check of a restarted
+// component is run until it succeeds.
+var healthCheckInterval = 2 * time.Second
+
+// restartHook is how kube-bench remediate restarts a component after editing
+// its files, and tells whether it's healthy, from the restart and
+// healthcheck commands of the component in the config.
+type restartHook struct {
+	Component   string
+	Restart     string
+	HealthCheck string
+	// files are the files of the component in the config.
+	files map[string]bool
+}
+
+// restartHooks returns the restart hooks of the components of a section of
+// the config.
+func restartHooks(typeConf *viper.Viper) []*restartHook {
+	if typeConf == nil {
+		return nil
+	}
+
+	var hooks []*restartHook
+	for _, component := range typeConf.GetStringSlice("components") {
+		s := typeConf.Sub(component)
+		if s == nil {
+			continue
+		}
+		h := &restartHook{
+			Component:   component,
+			Restart:     s.GetString("restart"),
+			HealthCheck: s.GetString("healthcheck"),
+			files:       map[string]bool{},
+		}
+		for _, opts := range TypeMap {
+			for _, opt := range opts {
+				for _, f := range s.GetStringSlice(opt) {
+					h.files[f] = true
+				}
+			}
+		}
+		hooks = append(hooks, h)
+	}
+	return hooks
+}
+
+// componentHook returns the restart hook of the component a file belongs to,
+// or nil.
+func componentHook(hooks []*restartHook, file string) *restartHook {
+	for _, h := range hooks {
+		if h.files[file] {
+			return h
+		}
+	}
+	return nil
+}
+
+// runHook runs a restart or health check command with the audit shell.
+func runHook(command string) error {
+	return check.RemediationStep{Type: check.CommandStep, Command: command}.Apply("")
+}
+
+// waitHealthy runs the health check of a component until it succeeds, or
+// the timeout elapses.
+func waitHealthy(h *restartHook, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		err := runHook(h.HealthCheck)
+		if err == nil || !time.Now().Before(deadline) {
+			return err
+		}
+		time.Sleep(healthCheckInterval)
+	}
+}
+
+// checkHealthyBefore checks that the components whose files the steps edit
+// are healthy before they're edited, so that a remediation is never blamed
+// for a component that was already broken. The health of each component is
+// only checked once.
+func checkHealthyBefore(steps []check.RemediationStep, hooks []*restartHook, healthy map[string]error) error {
+	for _, s := range steps {
+		h := componentHook(hooks, s.File)
+		if s.File == "" || h == nil || h.Restart == "" || h.HealthCheck == "" {
+			continue
+		}
+		if _, ok := healthy[h.Component]; !ok {
+			healthy[h.Component] = runHook(h.HealthCheck)
+		}
+		if err := healthy[h.Component]; err != nil {
+			return fmt.Errorf("%s is not healthy before the remediation: %v", h.Component, err)
+		}
+	}
+	return nil
+}
+
+// keepOriginals reads the files the steps edit before their first edit, to
+// restore them if their component isn't healthy after restarting.
+func keepOriginals(steps []check.RemediationStep, originals map[string][]byte) {
+	for _, s := range steps {
+		if s.File == "" {
+			continue
+		}
+		if _, ok := originals[s.File]; ok {
+			continue
+		}
+		if data, err := ioutil.ReadFile(filepath.Join(hostRoot, s.File)); err == nil {
+			originals[s.File] = data
+		}
+	}
+}
+
+// restartComponents restarts the components whose files were edited for the
+// fixed checks, then waits for them to be healthy. The files of a component
+// that isn't healthy are restored, it's restarted again, and its checks are
+// no longer fixed.
+func restartComponents(results []fixResult, fixed map[string]bool, hooks []*restartHook, originals map[string][]byte, timeout time.Duration) {
+	var edited []*restartHook
+	for _, r := range results {
+		if !fixed[r.ID] {
+			continue
+		}
+		for _, f := range r.Files {
+			if h := componentHook(hooks, f); h != nil && h.Restart != "" && !hasHook(edited, h) {
+				edited = append(edited, h)
+			}
+		}
+	}
+
+	for _, h := range edited {
+		glog.V(1).Info(fmt.Sprintf("Restarting %s", h.Component))
+		err := runHook(h.Restart)
+		if err == nil && h.HealthCheck != "" {
+			err = waitHealthy(h, timeout)
+		}
+
+		for i, r := range results {
+			if !fixed[r.ID] || !touchesComponent(r, hooks, h) {
+				continue
+			}
+			if err == nil {
+				results[i].Restarted = append(results[i].Restarted, h.Component)
+				continue
+			}
+			results[i].Err = fmt.Errorf("%s is not healthy after restarting it, its files were restored: %v", h.Component, err)
+			fixed[r.ID] = false
+		}
+		if err == nil {
+			continue
+		}
+
+		for file, data := range originals {
+			if !h.files[file] {
+				continue
+			}
+			if err := restoreFile(filepath.Join(hostRoot, file), data); err != nil {
+				glog.Warningf("failed to restore %s: %v", file, err)
+			}
+		}
+		if err := runHook(h.Restart); err != nil {
+			glog.Warningf("failed to restart %s after restoring its files: %v", h.Component, err)
+		}
+	}
+}
+
+func hasHook(hooks []*restartHook, h *restartHook) bool {
+	for _, hook := range hooks {
+		if hook == h {
+			return true
+		}
+	}
+	return false
+}
+
+func touchesComponent(r fixResult, hooks []*restartHook, h *restartHook) bool {
+	for _, f := range r.Files {
+		if componentHook(hooks, f) == h {
+			return true
+		}
+	}
+	return false
+}
+
+func restoreFile(path string, data []byte) error {
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+	return ioutil.WriteFile(path, data, info.Mode().Perm())
+}
@@ -0,0 +1,134 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/spf13/viper"
+)
+
+func TestRestartHooks(t *testing.T) {
+	v := viper.New()
+	v.SetConfigType("yaml")
+	err := v.ReadConfig(bytes.NewBufferString(`
+components:
+  - kubelet
+  - proxy
+kubelet:
+  confs:
+    - /var/lib/kubelet/config.yaml
+  defaultsvc: /etc/systemd/system/kubelet.service.d/10-kubeadm.conf
+  restart: systemctl restart kubelet
+  healthcheck: curl -sf http://localhost:10248/healthz
+proxy:
+  defaultkubeconfig: /etc/kubernetes/proxy.conf
+`))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	hooks := restartHooks(v)
+	if len(hooks) != 2 {
+		t.Fatalf("expected 2 hooks, got %d", len(hooks))
+	}
+	h := componentHook(hooks, "/etc/systemd/system/kubelet.service.d/10-kubeadm.conf")
+	if h == nil || h.Component != "kubelet" || h.Restart != "systemctl restart kubelet" || h.HealthCheck != "curl -sf http://localhost:10248/healthz" {
+		t.Errorf("unexpected hook of the kubelet: %+v", h)
+	}
+	if h := componentHook(hooks, "/etc/kubernetes/proxy.conf"); h == nil || h.Component != "proxy" || h.Restart != "" {
+		t.Errorf("unexpected hook of the proxy: %+v", h)
+	}
+	if h := componentHook(hooks, "/etc/kubernetes/admin.conf"); h != nil {
+		t.Errorf("expected no hook, got %+v", h)
+	}
+}
+
+func TestRestartComponents(t *testing.T) {
+	defer func(old time.Duration) { healthCheckInterval = old }(healthCheckInterval)
+	healthCheckInterval = time.Millisecond
+	defer func(old string) { hostRoot = old }(hostRoot)
+
+	dir, err := ioutil.TempDir("", "kube-bench-restart")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	hostRoot = dir
+
+	restarted := filepath.Join(dir, "restarted")
+	writeConfig := func(data string) {
+		if err := ioutil.WriteFile(filepath.Join(dir, "config.yaml"), []byte(data), 0600); err != nil {
+			t.Fatal(err)
+		}
+	}
+	steps := []check.RemediationStep{{Type: check.FileStep, File: "/config.yaml", Key: "readOnlyPort", Value: "0"}}
+
+	cases := []struct {
+		name        string
+		healthCheck string
+		fixed       bool
+		config      string
+	}{
+		{name: "healthy", healthCheck: "true", fixed: true, config: "readOnlyPort: 0\n"},
+		{name: "unhealthy", healthCheck: "test ! -e " + restarted, fixed: false, config: "readOnlyPort: 10255\n"},
+	}
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			writeConfig("readOnlyPort: 10255\n")
+			os.Remove(restarted)
+			hooks := []*restartHook{{Component: "kubelet", Restart: "touch " + restarted, HealthCheck: c.healthCheck,
+				files: map[string]bool{"/config.yaml": true}}}
+
+			healthy := map[string]error{}
+			if err := checkHealthyBefore(steps, hooks, healthy); err != nil {
+				t.Fatal(err)
+			}
+			originals := map[string][]byte{}
+			keepOriginals(steps, originals)
+			if err := steps[0].Apply(hostRoot); err != nil {
+				t.Fatal(err)
+			}
+
+			results := []fixResult{{ID: "4.2.4", Files: []string{"/config.yaml"}}}
+			fixed := map[string]bool{"4.2.4": true}
+			restartComponents(results, fixed, hooks, originals, 0)
+
+			if fixed["4.2.4"] != c.fixed {
+				t.Errorf("expected fixed to be %v, got %v (%v)", c.fixed, fixed["4.2.4"], results[0].Err)
+			}
+			if c.fixed && (len(results[0].Restarted) != 1 || results[0].Err != nil) {
+				t.Errorf("expected the kubelet to be restarted, got %+v", results[0])
+			}
+			if !c.fixed && results[0].Err == nil {
+				t.Errorf("expected an error for the unhealthy kubelet")
+			}
+			if got, _ := ioutil.ReadFile(filepath.Join(dir, "config.yaml")); string(got) != c.config {
+				t.Errorf("expected the config file %q, got %q", c.config, got)
+			}
+		})
+	}
+
+	hooks := []*restartHook{{Component: "kubelet", Restart: "true", HealthCheck: "false", files: map[string]bool{"/config.yaml": true}}}
+	if err := checkHealthyBefore(steps, hooks, map[string]error{}); err == nil {
+		t.Errorf("expected an error for a component unhealthy before the remediation")
+	}
+}
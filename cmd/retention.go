@@ -0,0 +1,108 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+)
+
+// retentionPolicy limits the runs kept in the history store, and in
+// PostgreSQL, so that the results of long running scans don't grow
+// unbounded. Zero values keep everything.
+type retentionPolicy struct {
+	// Runs is the number of most recent runs kept.
+	Runs int
+	// Days is how many days runs are kept for.
+	Days int
+}
+
+// historyRetention is set with --history-keep-runs and --history-keep-days.
+var historyRetention retentionPolicy
+
+func checkRetention(p retentionPolicy) error {
+	if p.Runs < 0 {
+		return fmt.Errorf("invalid number of runs to keep %d, must be positive", p.Runs)
+	}
+	if p.Days < 0 {
+		return fmt.Errorf("invalid number of days to keep runs for %d, must be positive", p.Days)
+	}
+	return nil
+}
+
+// cutoff returns the time before which runs are removed, or the zero time
+// when runs are kept whatever their age.
+func (p retentionPolicy) cutoff(now time.Time) time.Time {
+	if p.Days == 0 {
+		return time.Time{}
+	}
+	return now.AddDate(0, 0, -p.Days)
+}
+
+// expired returns which of runs, given by their times oldest first, are
+// removed by the policy.
+func (p retentionPolicy) expired(times []time.Time, now time.Time) []bool {
+	expired := make([]bool, len(times))
+	cutoff := p.cutoff(now)
+	for i, t := range times {
+		tooMany := p.Runs > 0 && i < len(times)-p.Runs
+		tooOld := !cutoff.IsZero() && t.Before(cutoff)
+		expired[i] = tooMany || tooOld
+	}
+	return expired
+}
+
+// pruneHistory removes the runs of the history store in dir that the
+// policy doesn't keep.
+func pruneHistory(dir string, p retentionPolicy, now time.Time) error {
+	if p.Runs == 0 && p.Days == 0 {
+		return nil
+	}
+
+	files, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		return err
+	}
+	sort.Strings(files)
+
+	var runs []string
+	var times []time.Time
+	for _, file := range files {
+		t, err := time.Parse(historyTimeFormat, strings.TrimSuffix(filepath.Base(file), ".json"))
+		if err != nil {
+			// Not a run saved by kube-bench.
+			continue
+		}
+		runs = append(runs, file)
+		times = append(times, t)
+	}
+
+	for i, expired := range p.expired(times, now) {
+		if !expired {
+			continue
+		}
+		if err := os.Remove(runs[i]); err != nil {
+			return fmt.Errorf("failed to remove expired run from history: %v", err)
+		}
+		glog.V(1).Infof("Removed expired run %s from history", runs[i])
+	}
+	return nil
+}
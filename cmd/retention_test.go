@@ -0,0 +1,105 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+)
+
+func TestCheckRetention(t *testing.T) {
+	for _, c := range []struct {
+		policy    retentionPolicy
+		expectErr bool
+	}{
+		{policy: retentionPolicy{}},
+		{policy: retentionPolicy{Runs: 10, Days: 30}},
+		{policy: retentionPolicy{Runs: -1}, expectErr: true},
+		{policy: retentionPolicy{Days: -1}, expectErr: true},
+	} {
+		if err := checkRetention(c.policy); (err != nil) != c.expectErr {
+			t.Errorf("%+v: expected error %t, got %v", c.policy, c.expectErr, err)
+		}
+	}
+}
+
+func TestRetentionPolicyExpired(t *testing.T) {
+	now := time.Date(2020, 6, 30, 12, 0, 0, 0, time.UTC)
+	times := []time.Time{
+		now.AddDate(0, 0, -20),
+		now.AddDate(0, 0, -8),
+		now.AddDate(0, 0, -2),
+		now.AddDate(0, 0, -1),
+	}
+
+	cases := []struct {
+		policy   retentionPolicy
+		expected []bool
+	}{
+		{policy: retentionPolicy{}, expected: []bool{false, false, false, false}},
+		{policy: retentionPolicy{Runs: 3}, expected: []bool{true, false, false, false}},
+		{policy: retentionPolicy{Runs: 10}, expected: []bool{false, false, false, false}},
+		{policy: retentionPolicy{Days: 7}, expected: []bool{true, true, false, false}},
+		{policy: retentionPolicy{Runs: 1, Days: 7}, expected: []bool{true, true, true, false}},
+	}
+	for _, c := range cases {
+		if expired := c.policy.expired(times, now); !reflect.DeepEqual(expired, c.expected) {
+			t.Errorf("%+v: expected %v, got %v", c.policy, c.expected, expired)
+		}
+	}
+}
+
+func TestPruneHistory(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-history")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	now := time.Date(2020, 6, 30, 12, 0, 0, 0, time.UTC)
+	var files []string
+	for _, days := range []int{30, 10, 3, 2, 0} {
+		files = append(files, now.AddDate(0, 0, -days).Format(historyTimeFormat)+".json")
+	}
+	files = append(files, "baseline.json")
+	for _, f := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, f), []byte("{}"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := pruneHistory(dir, retentionPolicy{Runs: 3, Days: 7}, now); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	left, err := filepath.Glob(filepath.Join(dir, "*.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range left {
+		left[i] = filepath.Base(left[i])
+	}
+	// Files that aren't runs saved by kube-bench are left alone.
+	expected := []string{files[2], files[3], files[4], "baseline.json"}
+	sort.Strings(expected)
+	if !reflect.DeepEqual(left, expected) {
+		t.Errorf("expected %v left, got %v", expected, left)
+	}
+}
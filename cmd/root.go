@@ -26,10 +26,16 @@ import (
 )
 
 type FilterOpts struct {
-	CheckList string
-	GroupList string
-	Scored    bool
-	Unscored  bool
+	CheckList     string
+	GroupList     string
+	SkipCheckList string
+	SkipGroupList string
+	Scored        bool
+	Unscored      bool
+	// Samples is the number of samples the checks are split into with
+	// --sample, only the checks of the sample Sample are run.
+	Samples int
+	Sample  int
 }
 
 var (
@@ -41,6 +47,22 @@ var (
 	cfgDir              = "./cfg/"
 	jsonFmt             bool
 	junitFmt            bool
+	githubFmt           bool
+	gitlabFmt           bool
+	sonarQubeFmt        bool
+	policyFile          string
+	language            string
+	reportIDs           string
+	kubeletInstances    bool
+	hostRoot            string
+	nodeSelector        string
+	namespaces          string
+	namespaceSelector   string
+	nodeLabels          []string
+	annotateNode        bool
+	watch               bool
+	parallel            bool
+	showTimings         bool
 	pgSQL               bool
 	masterFile          = "master.yaml"
 	nodeFile            = "node.yaml"
@@ -51,9 +73,28 @@ var (
 	noResults           bool
 	noSummary           bool
 	noRemediations      bool
+	remediationPlan     bool
 	filterOpts          FilterOpts
 	includeTestOutput   bool
 	outputFile          string
+	summaryFile         string
+	metricsTextfile     string
+	runIDFlag           string
+	recordFile          string
+	mockMode            string
+	useSudo             bool
+	auditLimits         check.AuditLimits
+	historyDir          string
+	failOnRegression    bool
+	cacheFile           string
+	sample              string
+	controlsFile        string
+	severityExitCode    bool
+	dockerChecks        bool
+	ignoreFile          string
+	offline             bool
+	timezone            string
+	timeFormat          string
 	configFileError     error
 )
 
@@ -68,42 +109,52 @@ var RootCmd = &cobra.Command{
 			exitWithError(fmt.Errorf("unable to determine benchmark version: %v", err))
 		}
 
-		if isMaster() {
-			glog.V(1).Info("== Running master checks ==\n")
-			runChecks(check.MASTER, loadConfig(check.MASTER))
-
-			// Control Plane is only valid for CIS 1.5 and later,
-			// this a gatekeeper for previous versions
-			if validTargets(benchmarkVersion, []string{string(check.CONTROLPLANE)}) {
-				glog.V(1).Info("== Running control plane checks ==\n")
-				runChecks(check.CONTROLPLANE, loadConfig(check.CONTROLPLANE))
-			}
+		if controlsFile != "" {
+			runControls()
+			return
 		}
 
-		// Etcd is only valid for CIS 1.5 and later,
-		// this a gatekeeper for previous versions.
-		if validTargets(benchmarkVersion, []string{string(check.ETCD)}) && isEtcd() {
-			glog.V(1).Info("== Running etcd checks ==\n")
-			runChecks(check.ETCD, loadConfig(check.ETCD))
-		}
+		runTargets(func() {
+			if isMaster() {
+				glog.V(1).Info("== Running master checks ==\n")
+				runChecks(check.MASTER, loadConfig(check.MASTER))
 
-		glog.V(1).Info("== Running node checks ==\n")
-		runChecks(check.NODE, loadConfig(check.NODE))
+				// Control Plane is only valid for CIS 1.5 and later,
+				// this a gatekeeper for previous versions
+				if validTargets(benchmarkVersion, []string{string(check.CONTROLPLANE)}) {
+					glog.V(1).Info("== Running control plane checks ==\n")
+					runChecks(check.CONTROLPLANE, loadConfig(check.CONTROLPLANE))
+				}
+			}
 
-		// Policies is only valid for CIS 1.5 and later,
-		// this a gatekeeper for previous versions.
-		if validTargets(benchmarkVersion, []string{string(check.POLICIES)}) {
-			glog.V(1).Info("== Running policies checks ==\n")
-			runChecks(check.POLICIES, loadConfig(check.POLICIES))
-		}
+			// Etcd is only valid for CIS 1.5 and later,
+			// this a gatekeeper for previous versions.
+			if validTargets(benchmarkVersion, []string{string(check.ETCD)}) {
+				if isEtcd() {
+					glog.V(1).Info("== Running etcd checks ==\n")
+					runChecks(check.ETCD, loadConfig(check.ETCD))
+				} else if members := remoteEtcdMembers(); len(members) > 0 {
+					runRemoteEtcdChecks(loadConfig(check.ETCD), members)
+				}
+			}
 
-		// Managedservices is only valid for GKE 1.0 and later,
-		// this a gatekeeper for previous versions.
-		if validTargets(benchmarkVersion, []string{string(check.MANAGEDSERVICES)}) {
-			glog.V(1).Info("== Running managed services checks ==\n")
-			runChecks(check.MANAGEDSERVICES, loadConfig(check.MANAGEDSERVICES))
-		}
+			glog.V(1).Info("== Running node checks ==\n")
+			runNodeChecks(loadConfig(check.NODE))
+
+			// Policies is only valid for CIS 1.5 and later,
+			// this a gatekeeper for previous versions.
+			if validTargets(benchmarkVersion, []string{string(check.POLICIES)}) {
+				glog.V(1).Info("== Running policies checks ==\n")
+				runChecks(check.POLICIES, loadConfig(check.POLICIES))
+			}
 
+			// Managedservices is only valid for GKE 1.0 and later,
+			// this a gatekeeper for previous versions.
+			if validTargets(benchmarkVersion, []string{string(check.MANAGEDSERVICES)}) {
+				glog.V(1).Info("== Running managed services checks ==\n")
+				runChecks(check.MANAGEDSERVICES, loadConfig(check.MANAGEDSERVICES))
+			}
+		})
 	},
 }
 
@@ -112,30 +163,115 @@ var RootCmd = &cobra.Command{
 func Execute() {
 	goflag.CommandLine.Parse([]string{})
 
-	if err := RootCmd.Execute(); err != nil {
+	err := RootCmd.Execute()
+	removeKubeProxyFiles()
+	if err != nil {
 		fmt.Println(err)
 		// flush before exit non-zero
 		glog.Flush()
 		os.Exit(-1)
 	}
+
+	if err := finishRecording(); err != nil {
+		exitWithError(err)
+	}
+	if err := writeRemediationPlan(); err != nil {
+		exitWithError(err)
+	}
+	writeInventory()
+	reportPermissionProblems(os.Stderr, permissionResults)
+
+	if err := writeSummaryFile(); err != nil {
+		exitWithError(err)
+	}
+	if err := writeMetricsTextfile(); err != nil {
+		exitWithError(err)
+	}
+
+	regressed, err := saveHistory()
+	if err != nil {
+		exitWithError(err)
+	}
+	reportRegressions(regressed)
+
+	if err := saveResultCache(); err != nil {
+		exitWithError(err)
+	}
+
+	if err := annotateNodeWithResults(); err != nil {
+		exitWithError(err)
+	}
+	if err := quarantineNode(); err != nil {
+		exitWithError(err)
+	}
+	enforcePolicy()
+
+	if err := watchForDrift(); err != nil {
+		exitWithError(err)
+	}
+	exitWithSeverity()
+
 	// flush before exit
 	glog.Flush()
 }
 
 func init() {
-	cobra.OnInitialize(initConfig)
+	cobra.OnInitialize(initConfig, startRecording)
 
 	// Output control
 	RootCmd.PersistentFlags().BoolVar(&noResults, "noresults", false, "Disable printing of results section")
 	RootCmd.PersistentFlags().BoolVar(&noSummary, "nosummary", false, "Disable printing of summary section")
 	RootCmd.PersistentFlags().BoolVar(&noRemediations, "noremediations", false, "Disable printing of remediations section")
+	RootCmd.PersistentFlags().BoolVar(&remediationPlan, "remediation-plan", false, "Prints a remediation plan grouping the failed checks by the file or component they touch, instead of the remediations of each target")
 	RootCmd.PersistentFlags().BoolVar(&jsonFmt, "json", false, "Prints the results as JSON")
 	RootCmd.PersistentFlags().BoolVar(&junitFmt, "junit", false, "Prints the results as JUnit")
+	RootCmd.PersistentFlags().BoolVar(&githubFmt, "github", false, "Prints the results as GitHub Actions annotations, and adds them to the step summary")
+	RootCmd.PersistentFlags().BoolVar(&gitlabFmt, "gitlab", false, "Prints the results as a GitLab SAST security report")
+	RootCmd.PersistentFlags().BoolVar(&sonarQubeFmt, "sonarqube", false, "Prints the results as SonarQube generic issues")
 	RootCmd.PersistentFlags().BoolVar(&pgSQL, "pgsql", false, "Save the results to PostgreSQL")
 	RootCmd.PersistentFlags().BoolVar(&filterOpts.Scored, "scored", true, "Run the scored CIS checks")
 	RootCmd.PersistentFlags().BoolVar(&filterOpts.Unscored, "unscored", true, "Run the unscored CIS checks")
+	RootCmd.PersistentFlags().StringVar(&reportIDs, "report-ids", internalIDs, "IDs the checks are reported by: internal, cis for the numbers of the CIS recommendations, or both")
+	RootCmd.PersistentFlags().StringVar(&language, "lang", "", "Language of the texts and remediations of the checks, from the message catalogs of the benchmark, e.g. fr")
 	RootCmd.PersistentFlags().BoolVar(&includeTestOutput, "include-test-output", false, "Prints the actual result when test fails")
 	RootCmd.PersistentFlags().StringVar(&outputFile, "outputfile", "", "Writes the JSON results to output file")
+	RootCmd.PersistentFlags().StringVar(&summaryFile, "summary-file", "", "Writes the totals, score, duration and node metadata of the run as JSON to this file")
+	RootCmd.PersistentFlags().StringVar(&runIDFlag, "run-id", "", "Identifies the run in all its results and logs, instead of a generated UUID, e.g. to correlate the runs of a pipeline")
+	RootCmd.PersistentFlags().StringVar(&metricsTextfile, "metrics-textfile", "", "Writes the results in the format of the node_exporter textfile collector to this file, e.g. /var/lib/node_exporter/textfile/kube_bench.prom")
+	RootCmd.PersistentFlags().StringVar(&hostRoot, "host-root", "", "Directory where the filesystem of the host is mounted, e.g. /host, the files the audits inspect are read under it")
+	RootCmd.PersistentFlags().BoolVar(&showInventory, "inventory", false, "Prints the inventory of the host at the end of the text output: OS, kernel, container runtime, kubelet version and cgroup driver")
+	RootCmd.PersistentFlags().BoolVar(&showTimings, "show-timings", false, "Prints on stderr the time taken by each group of checks and the slowest checks")
+	RootCmd.PersistentFlags().BoolVar(&useSudo, "use-sudo", false, "Runs the audit commands of all checks through sudo when not running as root")
+	RootCmd.PersistentFlags().IntVar(&auditLimits.Nice, "nice", 0, "Runs the audit commands with this niceness, e.g. 10 to yield the CPU to other workloads")
+	RootCmd.PersistentFlags().StringVar(&auditLimits.IOClass, "ionice", "", "Runs the audit commands with this I/O scheduling class (idle or best-effort)")
+	RootCmd.PersistentFlags().IntVar(&auditLimits.MaxCPU, "max-cpu", 0, "Limits the audit commands to this percentage of a CPU on average, by waiting between audits")
+	RootCmd.PersistentFlags().IntVar(&auditLimits.MaxOutput, "max-audit-output", 1<<20, "Keeps at most this number of bytes of the output of each audit, 0 keeps all of it")
+	RootCmd.PersistentFlags().StringVar(&nodeSelector, "node-selector", "", "Only runs the checks on nodes whose labels match this selector, e.g. node-role.kubernetes.io/gpu=true, when running in a pod")
+	RootCmd.PersistentFlags().StringVar(&namespaces, "namespaces", "", "Limits the checks of the Kubernetes API to the objects of these comma-separated namespaces, e.g. for the report of a tenant")
+	RootCmd.PersistentFlags().StringVar(&namespaceSelector, "namespace-selector", "", "Limits the checks of the Kubernetes API to the objects of the namespaces whose labels match this selector, e.g. tenant=team-a")
+	RootCmd.PersistentFlags().StringSliceVar(&nodeLabels, "node-labels", nil, "Labels of the node attached to the results, all of them when not set")
+	RootCmd.PersistentFlags().BoolVar(&annotateNode, "annotate-node", false, "Annotates the Node with the score, the number of failed checks and the time of the scan, when running in a pod")
+	RootCmd.PersistentFlags().StringVar(&quarantineAction, "quarantine-node", "", "Cordons or taints the Node (cordon or taint) when critical checks fail, when running in a pod")
+	RootCmd.PersistentFlags().StringVar(&criticalChecks, "critical-checks", "", "Comma-separated IDs of the checks quarantining the Node when they fail, the checks of critical severity when not given")
+	RootCmd.PersistentFlags().BoolVar(&kubeletInstances, "kubelet-instances", false, "Runs the node checks against each kubelet separately when several run on the host, e.g. with kind")
+	RootCmd.PersistentFlags().StringVar(&mockMode, "mock", "", "Produces synthetic results (pass, fail or mixed) without running any audit on the host")
+	RootCmd.PersistentFlags().StringVar(&historyDir, "history-dir", "", "Saves the results of each run in this directory, for trend to report them over time")
+	RootCmd.PersistentFlags().DurationVar(&resultTTL, "result-ttl", 0, "How long the results are trusted, e.g. 24h: they record when they expire, and serve and the metrics report them as stale past it")
+	RootCmd.PersistentFlags().IntVar(&historyRetention.Runs, "history-keep-runs", 0, "Keeps this number of most recent runs in the history directory and PostgreSQL, 0 keeps them all")
+	RootCmd.PersistentFlags().IntVar(&historyRetention.Days, "history-keep-days", 0, "Keeps the runs of this number of days in the history directory and PostgreSQL, 0 keeps them all")
+	RootCmd.PersistentFlags().BoolVar(&failOnRegression, "fail-on-regression", false, "Exits with an error when checks that passed in the previous run saved in --history-dir no longer pass")
+	RootCmd.PersistentFlags().StringVar(&cacheFile, "cache-file", "", "Caches the results of the checks in this file, and takes them from it in later runs when the files and processes their audits look at haven't changed")
+	RootCmd.PersistentFlags().StringVar(&sample, "sample", "", "Runs a random sample of this percentage of the checks, e.g. 20%, the scans of serve running the other samples in turn")
+	RootCmd.PersistentFlags().BoolVar(&severityExitCode, "severity-exit-code", false, "Exits with 3 when critical checks fail, 2 when high ones do and 1 when medium or low ones do")
+	RootCmd.PersistentFlags().BoolVar(&dockerChecks, "docker-checks", false, "Adds the checks of the Docker daemon to the node checks when Docker is the container runtime")
+	RootCmd.PersistentFlags().StringVar(&ignoreFile, "ignore-file", "", "File listing the IDs of the checks ignored on this cluster, with why, which generate SKIP (default is ./.kubebenchignore when it exists)")
+	RootCmd.PersistentFlags().BoolVar(&offline, "offline", false, "Disables every network operation, failing when checks of the run need network access, for air-gapped environments")
+	RootCmd.PersistentFlags().StringVar(&timezone, "timezone", "UTC", "Timezone of the timestamps of the JSON output, e.g. Europe/Paris or Local")
+	RootCmd.PersistentFlags().StringVar(&timeFormat, "time-format", check.TimeRFC3339, "Format of the timestamps of the JSON output: rfc3339, epoch (seconds) or epoch-millis")
+	RootCmd.PersistentFlags().StringVar(&policyFile, "policy", "", "Rego policy deciding whether the results are acceptable")
+	RootCmd.PersistentFlags().BoolVar(&parallel, "parallel", false, "Runs the checks of the targets concurrently, e.g. the master and node checks of a control plane node, and prints their total summary")
+	RootCmd.PersistentFlags().BoolVar(&watch, "watch", false, "Keeps watching the config files found for the components after the run, running the checks auditing a file again when it changes and reporting the ones whose state changed")
+	RootCmd.PersistentFlags().StringVar(&recordFile, "record", "", "Records the audit outputs, config files and process table of the scan into a tar.gz evidence bundle")
 
 	RootCmd.PersistentFlags().StringVarP(
 		&filterOpts.CheckList,
@@ -151,8 +287,22 @@ func init() {
 		"",
 		`Run all the checks under this comma-delimited list of groups. Example --group="1.1"`,
 	)
+	RootCmd.PersistentFlags().StringVar(
+		&filterOpts.SkipCheckList,
+		"skip-check",
+		"",
+		`A comma-delimited list of checks not to run. Example --skip-check="1.2.12,4.2.6"`,
+	)
+	RootCmd.PersistentFlags().StringVar(
+		&filterOpts.SkipGroupList,
+		"skip-group",
+		"",
+		`Skip all the checks under this comma-delimited list of groups. Example --skip-group="1.4,1.5"`,
+	)
 	RootCmd.PersistentFlags().StringVar(&cfgFile, "config", "", "config file (default is ./cfg/config.yaml)")
 	RootCmd.PersistentFlags().StringVarP(&cfgDir, "config-dir", "D", cfgDir, "config directory")
+	RootCmd.PersistentFlags().StringVar(&controlsFile, "controls", "", "Runs the checks of this controls file instead of the ones of the benchmark, as the target given by its type; - reads it from stdin, oci://registry/repository:tag pulls a signed controls bundle and runs its benchmark")
+	RootCmd.PersistentFlags().StringVar(&controlsDir, "controls-dir", controlsDir, "Directory of the controls installed by update-controls, used instead of the config directory when not given")
 	RootCmd.PersistentFlags().StringVar(&kubeVersion, "version", "", "Manually specify Kubernetes version, automatically detected if unset")
 	RootCmd.PersistentFlags().StringVar(&benchmarkVersion, "benchmark", "", "Manually specify CIS benchmark version. It would be an error to specify both --version and --benchmark flags")
 
@@ -164,6 +314,8 @@ func init() {
 
 // initConfig reads in config file and ENV variables if set.
 func initConfig() {
+	useUpdatedControls()
+
 	if cfgFile != "" { // enable ability to specify config file via flag
 		viper.SetConfigFile(cfgFile)
 	} else {
@@ -194,4 +346,88 @@ func initConfig() {
 			os.Exit(1)
 		}
 	}
+
+	if err := useOCIControls(); err != nil {
+		colorPrint(check.FAIL, fmt.Sprintf("Invalid controls: %v\n", err))
+		os.Exit(1)
+	}
+
+	if backend := viper.GetString("process_backend"); backend != "" {
+		if err := check.SetProcessBackend(backend); err != nil {
+			colorPrint(check.FAIL, fmt.Sprintf("Invalid config: %v\n", err))
+			os.Exit(1)
+		}
+	}
+
+	if shell := viper.GetString("shell"); shell != "" {
+		if err := check.SetAuditShell(shell); err != nil {
+			colorPrint(check.FAIL, fmt.Sprintf("Invalid config: %v\n", err))
+			os.Exit(1)
+		}
+	}
+
+	if err := check.SetAuditLimits(auditLimits); err != nil {
+		colorPrint(check.FAIL, fmt.Sprintf("Invalid audit limits: %v\n", err))
+		os.Exit(1)
+	}
+
+	if err := checkHostRoot(hostRoot); err != nil {
+		colorPrint(check.FAIL, fmt.Sprintf("Invalid host root: %v\n", err))
+		os.Exit(1)
+	}
+
+	if err := checkNodeSelector(nodeSelector); err != nil {
+		colorPrint(check.FAIL, fmt.Sprintf("Invalid node selector: %v\n", err))
+		os.Exit(1)
+	}
+
+	if err := checkNamespaceScope(namespaces, namespaceSelector); err != nil {
+		colorPrint(check.FAIL, fmt.Sprintf("Invalid namespaces: %v\n", err))
+		os.Exit(1)
+	}
+
+	if err := checkLanguage(language); err != nil {
+		colorPrint(check.FAIL, fmt.Sprintf("Invalid language: %v\n", err))
+		os.Exit(1)
+	}
+
+	if err := checkReportIDs(reportIDs); err != nil {
+		colorPrint(check.FAIL, fmt.Sprintf("Invalid report IDs: %v\n", err))
+		os.Exit(1)
+	}
+
+	if err := checkPolicy(policyFile); err != nil {
+		colorPrint(check.FAIL, fmt.Sprintf("Invalid policy: %v\n", err))
+		os.Exit(1)
+	}
+
+	if err := checkQuarantine(quarantineAction); err != nil {
+		colorPrint(check.FAIL, fmt.Sprintf("Invalid quarantine action: %v\n", err))
+		os.Exit(1)
+	}
+
+	if err := checkRetention(historyRetention); err != nil {
+		colorPrint(check.FAIL, fmt.Sprintf("Invalid history retention: %v\n", err))
+		os.Exit(1)
+	}
+
+	if err := setSample(sample); err != nil {
+		colorPrint(check.FAIL, fmt.Sprintf("Invalid sample: %v\n", err))
+		os.Exit(1)
+	}
+
+	if err := loadIgnoreFile(ignoreFile); err != nil {
+		colorPrint(check.FAIL, fmt.Sprintf("Invalid ignore file: %v\n", err))
+		os.Exit(1)
+	}
+
+	if err := checkOffline(offline); err != nil {
+		colorPrint(check.FAIL, fmt.Sprintf("Invalid offline mode: %v\n", err))
+		os.Exit(1)
+	}
+
+	if err := check.SetTimeFormat(timezone, timeFormat); err != nil {
+		colorPrint(check.FAIL, fmt.Sprintf("Invalid timestamps: %v\n", err))
+		os.Exit(1)
+	}
 }
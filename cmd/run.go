@@ -27,6 +27,11 @@ var runCmd = &cobra.Command{
 	Short: "Run tests",
 	Long:  `Run tests. If no arguments are specified, runs tests from all files`,
 	Run: func(cmd *cobra.Command, args []string) {
+		if controlsFile != "" {
+			runControls()
+			return
+		}
+
 		targets, err := cmd.Flags().GetStringSlice("targets")
 		if err != nil {
 			exitWithError(fmt.Errorf("unable to get `targets` from command line :%v", err))
@@ -39,7 +44,7 @@ var runCmd = &cobra.Command{
 
 		glog.V(2).Infof("Checking targets %v for %v", targets, benchmarkVersion)
 		if len(targets) > 0 && !validTargets(benchmarkVersion, targets) {
-			exitWithError(fmt.Errorf(fmt.Sprintf(`The specified --targets "%s" does not apply to the CIS Benchmark %s \n Valid targets %v`, strings.Join(targets, ","), benchmarkVersion, benchmarkVersionToTargetsMap[benchmarkVersion])))
+			exitWithError(fmt.Errorf(fmt.Sprintf(`The specified --targets "%s" does not apply to the CIS Benchmark %s \n Valid targets %v`, strings.Join(targets, ","), benchmarkVersion, availableTargets(benchmarkVersion))))
 		}
 
 		// Merge version-specific config if any.
@@ -61,15 +66,30 @@ func run(targets []string, benchmarkVersion string) (err error) {
 
 	glog.V(3).Infof("Running tests from files %v\n", yamlFiles)
 
-	for _, yamlFile := range yamlFiles {
-		_, name := filepath.Split(yamlFile)
-		testType := check.NodeType(strings.Split(name, ".")[0])
-		runChecks(testType, yamlFile)
-	}
+	runTargets(func() {
+		for _, yamlFile := range yamlFiles {
+			_, name := filepath.Split(yamlFile)
+			runTarget(check.NodeType(strings.Split(name, ".")[0]), yamlFile)
+		}
+	})
 
 	return nil
 }
 
+// runTarget runs the checks of a controls file for the target of the given
+// type, against the remote etcd members or the kubelet instances found.
+func runTarget(testType check.NodeType, yamlFile string) {
+	if members := remoteEtcdMembers(); testType == check.ETCD && len(members) > 0 && !isEtcd() {
+		runRemoteEtcdChecks(yamlFile, members)
+		return
+	}
+	if testType == check.NODE {
+		runNodeChecks(yamlFile)
+		return
+	}
+	runChecks(testType, yamlFile)
+}
+
 func getTestYamlFiles(targets []string, benchmarkVersion string) (yamlFiles []string, err error) {
 	// Check that the specified targets have corresponding YAML files in the config directory
 	configFileDirectory := filepath.Join(cfgDir, benchmarkVersion)
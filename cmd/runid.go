@@ -0,0 +1,54 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"crypto/rand"
+	"fmt"
+
+	"github.com/golang/glog"
+)
+
+// runID identifies the current run in its results, it's generated once per
+// run, or once per scan of serve.
+var runID string
+
+// currentRunID returns the ID of the current run: the one given with
+// --run-id, or a random UUID.
+func currentRunID() string {
+	if runID == "" {
+		runID = runIDFlag
+		if runID == "" {
+			id, err := newRunID()
+			if err != nil {
+				exitWithError(fmt.Errorf("failed to generate the run ID: %v", err))
+			}
+			runID = id
+		}
+		glog.V(1).Infof("Run ID: %s", runID)
+	}
+	return runID
+}
+
+// newRunID generates a random (version 4) UUID.
+func newRunID() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	b[6] = b[6]&0x0f | 0x40
+	b[8] = b[8]&0x3f | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:]), nil
+}
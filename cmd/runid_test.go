@@ -0,0 +1,51 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"regexp"
+	"testing"
+)
+
+func TestNewRunID(t *testing.T) {
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	first, err := newRunID()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !uuid.MatchString(first) {
+		t.Errorf("expected a UUID, got %s", first)
+	}
+	if second, _ := newRunID(); second == first {
+		t.Errorf("expected different IDs, got %s twice", first)
+	}
+}
+
+func TestCurrentRunID(t *testing.T) {
+	defer func(id, flag string) {
+		runID, runIDFlag = id, flag
+	}(runID, runIDFlag)
+
+	runID, runIDFlag = "", ""
+	id := currentRunID()
+	if id == "" || currentRunID() != id {
+		t.Errorf("expected the same ID for the whole run, got %s then %s", id, currentRunID())
+	}
+
+	runID, runIDFlag = "", "pipeline-42"
+	if id := currentRunID(); id != "pipeline-42" {
+		t.Errorf("expected the ID given with --run-id, got %s", id)
+	}
+}
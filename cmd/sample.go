@@ -0,0 +1,60 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"hash/fnv"
+	"math"
+	"math/rand"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+// setSample splits the checks into samples of the given percentage of them,
+// e.g. "20%", and picks one at random to run.
+func setSample(percentage string) error {
+	if percentage == "" {
+		return nil
+	}
+
+	p, err := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(percentage), "%"), 64)
+	if err != nil || p <= 0 || p > 100 {
+		return fmt.Errorf("%q is not a percentage between 0 and 100", percentage)
+	}
+
+	filterOpts.Samples = int(math.Ceil(100 / p))
+	filterOpts.Sample = rand.New(rand.NewSource(time.Now().UnixNano())).Intn(filterOpts.Samples)
+	return nil
+}
+
+// nextSample picks the next sample to run, so that consecutive scans run
+// all the checks.
+func nextSample() {
+	if filterOpts.Samples > 1 {
+		filterOpts.Sample = (filterOpts.Sample + 1) % filterOpts.Samples
+	}
+}
+
+// checkSample returns the sample of a check. It only depends on its ID, so
+// that the checks of a sample are the same in every scan.
+func checkSample(c *check.Check, samples int) int {
+	h := fnv.New32a()
+	h.Write([]byte(c.ID))
+	return int(h.Sum32() % uint32(samples))
+}
@@ -0,0 +1,83 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+func TestSetSample(t *testing.T) {
+	defer func(opts FilterOpts) { filterOpts = opts }(filterOpts)
+
+	cases := []struct {
+		sample  string
+		samples int
+		fail    bool
+	}{
+		{"20%", 5, false},
+		{"30", 4, false},
+		{"100%", 1, false},
+		{"0%", 0, true},
+		{"150%", 0, true},
+		{"a few", 0, true},
+	}
+	for _, c := range cases {
+		filterOpts = FilterOpts{}
+		err := setSample(c.sample)
+		if (err != nil) != c.fail {
+			t.Errorf("%s: unexpected error %v", c.sample, err)
+		}
+		if filterOpts.Samples != c.samples || filterOpts.Sample < 0 || (c.samples > 0 && filterOpts.Sample >= c.samples) {
+			t.Errorf("%s: unexpected sample %d of %d", c.sample, filterOpts.Sample, filterOpts.Samples)
+		}
+	}
+}
+
+func TestSampleCoverage(t *testing.T) {
+	defer func(opts FilterOpts) { filterOpts = opts }(filterOpts)
+
+	filterOpts = FilterOpts{Scored: true, Unscored: true}
+	if err := setSample("25%"); err != nil {
+		t.Fatal(err)
+	}
+
+	group := &check.Group{ID: "1.1"}
+	for i := 1; i <= 40; i++ {
+		group.Checks = append(group.Checks, &check.Check{ID: fmt.Sprintf("1.1.%d", i), Scored: true})
+	}
+
+	// Consecutive scans run every check once.
+	runs := map[string]int{}
+	for i := 0; i < filterOpts.Samples; i++ {
+		filter, err := NewRunFilter(filterOpts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		for _, c := range group.Checks {
+			if filter(group, c) {
+				runs[c.ID]++
+			}
+		}
+		nextSample()
+	}
+	for _, c := range group.Checks {
+		if runs[c.ID] != 1 {
+			t.Errorf("%s: expected to run once, ran %d times", c.ID, runs[c.ID])
+		}
+	}
+}
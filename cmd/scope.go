@@ -0,0 +1,52 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+// checkNamespaceScope verifies --namespaces and --namespace-selector, and
+// limits the "api" checks to their namespaces.
+func checkNamespaceScope(list, selector string) error {
+	return check.SetNamespaceScope(namespaceList(list), selector)
+}
+
+// namespaceList returns the namespaces of a comma-separated list.
+func namespaceList(list string) []string {
+	var names []string
+	for _, ns := range strings.Split(list, ",") {
+		if ns = strings.TrimSpace(ns); ns != "" {
+			names = append(names, ns)
+		}
+	}
+	return names
+}
+
+// namespaceScopeLabel describes the namespaces the "api" checks are limited
+// to, for the text of the results, or returns "" when they are not.
+func namespaceScopeLabel(list, selector string) string {
+	var scope []string
+	if names := namespaceList(list); len(names) > 0 {
+		scope = append(scope, "namespaces "+strings.Join(names, ", "))
+	}
+	if selector != "" {
+		scope = append(scope, fmt.Sprintf("namespaces matching %s", selector))
+	}
+	return strings.Join(scope, ", ")
+}
@@ -0,0 +1,46 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNamespaceList(t *testing.T) {
+	if names := namespaceList(" team-a, ,team-b,"); !reflect.DeepEqual(names, []string{"team-a", "team-b"}) {
+		t.Errorf("unexpected namespaces %v", names)
+	}
+	if names := namespaceList(""); names != nil {
+		t.Errorf("expected no namespaces, got %v", names)
+	}
+}
+
+func TestNamespaceScopeLabel(t *testing.T) {
+	cases := []struct {
+		list, selector string
+		expected       string
+	}{
+		{expected: ""},
+		{list: "team-a,team-b", expected: "namespaces team-a, team-b"},
+		{selector: "tenant=a", expected: "namespaces matching tenant=a"},
+		{list: "team-a", selector: "tenant=a", expected: "namespaces team-a, namespaces matching tenant=a"},
+	}
+	for _, c := range cases {
+		if label := namespaceScopeLabel(c.list, c.selector); label != c.expected {
+			t.Errorf("%q %q: expected %q, got %q", c.list, c.selector, c.expected, label)
+		}
+	}
+}
@@ -0,0 +1,244 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+	"github.com/spf13/cobra"
+)
+
+type serveOpts struct {
+	Address  string
+	Interval time.Duration
+	MinScore float64
+}
+
+// complianceState is the compliance of the node according to the latest
+// scan, served at /healthz/compliance.
+type complianceState struct {
+	Time     time.Time `json:"time"`
+	Score    float64   `json:"score"`
+	MinScore float64   `json:"min_score"`
+	Pass     int       `json:"total_pass"`
+	Fail     int       `json:"total_fail"`
+	Warn     int       `json:"total_warn"`
+	// AgeSeconds is how old the latest scan is, and Stale whether it's older
+	// than --result-ttl, in which case the node is not compliant.
+	AgeSeconds float64 `json:"age_seconds"`
+	Stale      bool    `json:"stale"`
+	Compliant  bool    `json:"compliant"`
+}
+
+var serveFlags serveOpts
+
+// serverScan collects the results of the scan being run by serve.
+var serverScan *historyRun
+
+// latestScan is the last scan completed by serve.
+var latestScan struct {
+	sync.Mutex
+	run *historyRun
+}
+
+// serveCmd represents the serve command
+var serveCmd = &cobra.Command{
+	Use:   "serve",
+	Short: "Run the checks periodically and serve the compliance of the node",
+	Long: `Run the checks like kube-bench without a command every --interval, and serve the compliance
+of the node according to the latest scan at /healthz/compliance: 200 when its score is at
+least --min-score, 503 otherwise, before the first scan completes, or when the latest scan
+is older than --result-ttl. The score is the
+percentage of checks that passed, out of the ones that passed, failed or warned.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if serveFlags.Interval <= 0 {
+			exitWithError(fmt.Errorf("--interval must be positive"))
+		}
+
+		http.HandleFunc("/healthz/compliance", complianceHandler)
+		go func() {
+			glog.V(1).Infof("Serving the compliance of the node on %s", serveFlags.Address)
+			fatalError(http.ListenAndServe(serveFlags.Address, nil))
+		}()
+
+		flakiness = check.NewFlakinessTracker()
+		for {
+			if err := runScan(); err != nil {
+				// The compliance stays the one of the latest completed scan,
+				// until it is older than --result-ttl.
+				continueWithError(err, fmt.Sprintf("Scan failed: %v", err))
+				clearScan()
+			} else {
+				setLatestScan(serverScan)
+				finishScan()
+			}
+			time.Sleep(serveFlags.Interval)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(serveCmd)
+	serveCmd.Flags().StringVar(&serveFlags.Address, "address", ":8080", "Address to serve the compliance of the node on")
+	serveCmd.Flags().DurationVar(&serveFlags.Interval, "interval", time.Hour, "Time to wait between scans")
+	serveCmd.Flags().Float64Var(&serveFlags.MinScore, "min-score", 100, "Score the latest scan must reach for the node to be compliant")
+}
+
+// flakiness tracks the checks whose state flaps between the scans of serve.
+var flakiness *check.FlakinessTracker
+
+// addToServerScan adds the results of a target to the scan being run by
+// serve.
+func addToServerScan(controls *check.Controls) {
+	if serverScan != nil {
+		serverScan.Controls = append(serverScan.Controls, controls)
+	}
+}
+
+// runScan runs the checks like kube-bench without a command, recording
+// them with --record, and returns the error that aborted the scan, if any.
+func runScan() (err error) {
+	startRecording()
+	serverScan = &historyRun{Time: time.Now().UTC()}
+	scanning = true
+	defer func() {
+		scanning = false
+		if r := recover(); r != nil {
+			aborted, ok := r.(scanAborted)
+			if !ok {
+				panic(r)
+			}
+			err = aborted.err
+		}
+	}()
+
+	RootCmd.Run(RootCmd, nil)
+	return nil
+}
+
+func setLatestScan(run *historyRun) {
+	latestScan.Lock()
+	defer latestScan.Unlock()
+	latestScan.run = run
+}
+
+// finishScan writes the evidence bundle with --record, saves the scan in the
+// history and annotates the Node with it when asked to, as kube-bench does
+// at the end of a run, and clears the results collected for the next scan.
+func finishScan() {
+	if err := finishRecording(); err != nil {
+		continueWithError(err, err.Error())
+	}
+	if err := writeRemediationPlan(); err != nil {
+		continueWithError(err, err.Error())
+	}
+	writeInventory()
+	reportPermissionProblems(os.Stderr, permissionResults)
+	if err := writeSummaryFile(); err != nil {
+		continueWithError(err, err.Error())
+	}
+	if err := writeMetricsTextfile(); err != nil {
+		continueWithError(err, err.Error())
+	}
+
+	regressed, err := saveHistory()
+	if err != nil {
+		continueWithError(err, err.Error())
+	}
+	reportRegressions(regressed)
+
+	if err := saveResultCache(); err != nil {
+		continueWithError(err, err.Error())
+	}
+
+	if err := annotateNodeWithResults(); err != nil {
+		continueWithError(err, err.Error())
+	}
+	if err := quarantineNode(); err != nil {
+		continueWithError(err, err.Error())
+	}
+	nextSample()
+	clearScan()
+}
+
+// clearScan clears the results collected by a scan, completed or aborted,
+// for the next scan.
+func clearScan() {
+	check.RecordEvidence(nil)
+	recording = nil
+	batching, batch = false, nil
+	planResults = nil
+	permissionResults = nil
+	summaryResults = nil
+	metricsResults = nil
+	currentRun = nil
+	nodeAnnotationResults = nil
+	quarantineChecks = nil
+	policyResults = nil
+	watchedTargets = nil
+	failedSeverityExitCode = 0
+	// The Node is read again by the next scan, for the changes of its labels
+	// and annotations to be taken into account.
+	nodeMetadata = nil
+	runID = ""
+}
+
+// currentCompliance returns the compliance of the node according to the
+// latest scan, or nil before the first scan completes.
+func currentCompliance() *complianceState {
+	latestScan.Lock()
+	defer latestScan.Unlock()
+	if latestScan.run == nil {
+		return nil
+	}
+
+	now := time.Now()
+	s := latestScan.run.summary()
+	state := &complianceState{
+		Time:       latestScan.run.Time,
+		Score:      s.Score(),
+		MinScore:   serveFlags.MinScore,
+		Pass:       s.Pass,
+		Fail:       s.Fail,
+		Warn:       s.Warn,
+		AgeSeconds: now.Sub(latestScan.run.Time).Truncate(time.Second).Seconds(),
+		Stale:      resultsStale(latestScan.run.Time, now),
+	}
+	state.Compliant = state.Score >= state.MinScore && !state.Stale
+	return state
+}
+
+// complianceHandler serves the compliance of the node: 200 if the latest
+// scan meets --min-score and isn't stale, 503 otherwise.
+func complianceHandler(w http.ResponseWriter, r *http.Request) {
+	state := currentCompliance()
+	if state == nil {
+		http.Error(w, "no scan completed yet", http.StatusServiceUnavailable)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	if !state.Compliant {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}
+	json.NewEncoder(w).Encode(state)
+}
@@ -0,0 +1,126 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/spf13/cobra"
+)
+
+func TestComplianceHandler(t *testing.T) {
+	defer func(minScore float64) {
+		serveFlags.MinScore = minScore
+		setLatestScan(nil)
+	}(serveFlags.MinScore)
+
+	get := func() (int, complianceState) {
+		rec := httptest.NewRecorder()
+		complianceHandler(rec, httptest.NewRequest("GET", "/healthz/compliance", nil))
+		var state complianceState
+		if rec.Header().Get("Content-Type") == "application/json" {
+			if err := json.Unmarshal(rec.Body.Bytes(), &state); err != nil {
+				t.Errorf("unexpected response %q: %v", rec.Body.String(), err)
+			}
+		}
+		return rec.Code, state
+	}
+
+	if code, _ := get(); code != http.StatusServiceUnavailable {
+		t.Errorf("expected 503 before the first scan, got %d", code)
+	}
+
+	scanned := time.Date(2020, 3, 9, 10, 0, 0, 0, time.UTC)
+	setLatestScan(&historyRun{Time: scanned, Controls: []*check.Controls{
+		{Summary: check.Summary{Pass: 6, Fail: 1, Warn: 1, Info: 2}},
+		{Summary: check.Summary{Pass: 2}},
+	}})
+
+	serveFlags.MinScore = 80
+	code, state := get()
+	if code != http.StatusOK || !state.Compliant || state.Score != 80 || state.Fail != 1 || !state.Time.Equal(scanned) {
+		t.Errorf("expected a compliant node, got %d %+v", code, state)
+	}
+
+	serveFlags.MinScore = 90
+	code, state = get()
+	if code != http.StatusServiceUnavailable || state.Compliant || state.MinScore != 90 {
+		t.Errorf("expected a non compliant node, got %d %+v", code, state)
+	}
+
+	defer func(ttl time.Duration) { resultTTL = ttl }(resultTTL)
+	resultTTL = 24 * time.Hour
+	serveFlags.MinScore = 80
+	code, state = get()
+	if code != http.StatusServiceUnavailable || state.Compliant || !state.Stale || state.AgeSeconds < 24*3600 {
+		t.Errorf("expected stale results, got %d %+v", code, state)
+	}
+}
+
+func TestRunScan(t *testing.T) {
+	defer func(run func(*cobra.Command, []string), file string) {
+		RootCmd.Run, recordFile = run, file
+		clearScan()
+		serverScan = nil
+	}(RootCmd.Run, recordFile)
+
+	dir, err := ioutil.TempDir("", "kube-bench-serve")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	recordFile = filepath.Join(dir, "evidence.tar.gz")
+
+	// Each scan writes its evidence bundle with --record.
+	for _, output := range []string{"--anonymous-auth=true", "--anonymous-auth=false"} {
+		RootCmd.Run = func(*cobra.Command, []string) {
+			recording.Evidence["/bin/ps -fC kubelet"] = output
+		}
+		if err := runScan(); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+		finishScan()
+		if recording != nil {
+			t.Errorf("expected the recording to be finished")
+		}
+		bundle, err := readBundle(recordFile)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := bundle.Evidence["/bin/ps -fC kubelet"]; got != output {
+			t.Errorf("expected the evidence of the scan, got %q", got)
+		}
+	}
+
+	// An error aborts the scan rather than the server.
+	RootCmd.Run = func(*cobra.Command, []string) {
+		exitWithError(errors.New("error opening node test file"))
+	}
+	if err := runScan(); err == nil || err.Error() != "error opening node test file" {
+		t.Errorf("expected the error of the scan, got %v", err)
+	}
+	if scanning {
+		t.Errorf("expected the scan to be over")
+	}
+}
@@ -0,0 +1,58 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"os"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+)
+
+// severityExitCodes are the exit codes of --severity-exit-code, by severity
+// of the failed checks.
+var severityExitCodes = map[string]int{
+	check.SeverityCritical: 3,
+	check.SeverityHigh:     2,
+	check.SeverityMedium:   1,
+	check.SeverityLow:      1,
+}
+
+// failedSeverityExitCode is the exit code of the most severe check that
+// failed in the run.
+var failedSeverityExitCode int
+
+// addToSeverityExitCode raises the exit code to the one of the most severe
+// failed check of the controls.
+func addToSeverityExitCode(controls *check.Controls) {
+	for _, g := range controls.Groups {
+		for _, c := range g.Checks {
+			if code := severityExitCodes[c.CheckSeverity()]; c.State == check.FAIL && code > failedSeverityExitCode {
+				failedSeverityExitCode = code
+			}
+		}
+	}
+}
+
+// exitWithSeverity exits with the exit code of the most severe failed check
+// when --severity-exit-code is given: 3 for critical, 2 for high and 1 for
+// medium or low.
+func exitWithSeverity() {
+	if !severityExitCode || failedSeverityExitCode == 0 {
+		return
+	}
+	glog.Flush()
+	os.Exit(failedSeverityExitCode)
+}
@@ -0,0 +1,55 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+func TestAddToSeverityExitCode(t *testing.T) {
+	defer func(code int) { failedSeverityExitCode = code }(failedSeverityExitCode)
+
+	controls := func(checks ...*check.Check) *check.Controls {
+		return &check.Controls{Groups: []*check.Group{{ID: "1.1", Checks: checks}}}
+	}
+	cases := []struct {
+		controls []*check.Controls
+		expected int
+	}{
+		{[]*check.Controls{controls(&check.Check{State: check.PASS, Severity: check.SeverityCritical})}, 0},
+		{[]*check.Controls{controls(&check.Check{State: check.WARN, Scored: true})}, 0},
+		{[]*check.Controls{controls(&check.Check{State: check.FAIL, Severity: check.SeverityLow, Scored: true})}, 1},
+		{[]*check.Controls{controls(&check.Check{State: check.FAIL})}, 1},
+		{[]*check.Controls{controls(&check.Check{State: check.FAIL, Scored: true})}, 2},
+		{
+			[]*check.Controls{
+				controls(&check.Check{State: check.FAIL, Severity: check.SeverityCritical}),
+				controls(&check.Check{State: check.FAIL, Severity: check.SeverityMedium}),
+			},
+			3,
+		},
+	}
+	for i, c := range cases {
+		failedSeverityExitCode = 0
+		for _, controls := range c.controls {
+			addToSeverityExitCode(controls)
+		}
+		if failedSeverityExitCode != c.expected {
+			t.Errorf("case %d: expected exit code %d, got %d", i, c.expected, failedSeverityExitCode)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"strings"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+// sonarQubeIssues is a SonarQube generic issue import report.
+type sonarQubeIssues struct {
+	Issues []sonarQubeIssue `json:"issues"`
+}
+
+type sonarQubeIssue struct {
+	EngineID        string            `json:"engineId"`
+	RuleID          string            `json:"ruleId"`
+	Severity        string            `json:"severity"`
+	Type            string            `json:"type"`
+	PrimaryLocation sonarQubeLocation `json:"primaryLocation"`
+}
+
+type sonarQubeLocation struct {
+	Message  string `json:"message"`
+	FilePath string `json:"filePath"`
+}
+
+// sonarQubeSeverity maps the state of a check to the severity of its issue.
+func sonarQubeSeverity(c *check.Check) string {
+	switch {
+	case c.State == check.FAIL && c.Scored:
+		return "CRITICAL"
+	case c.State == check.FAIL:
+		return "MAJOR"
+	default:
+		return "MINOR"
+	}
+}
+
+// sonarQubeIssuesJSON encodes the checks that failed or warned as SonarQube
+// generic issues, raised on the controls file of the checks.
+func sonarQubeIssuesJSON(controls *check.Controls) ([]byte, error) {
+	report := sonarQubeIssues{Issues: []sonarQubeIssue{}}
+	file := string(controls.Type) + ".yaml"
+	for _, g := range controls.Groups {
+		for _, c := range g.Checks {
+			if c.State != check.FAIL && c.State != check.WARN {
+				continue
+			}
+
+			message := c.ID + " " + strings.TrimSpace(c.Text)
+			if c.Type == check.MANUAL {
+				message += ": " + strings.TrimSpace(c.Remediation)
+			} else if c.Reason != "" {
+				message += ": " + c.Reason
+			}
+			if c.Reference != "" {
+				message += " (see " + c.Reference + ")"
+			}
+			report.Issues = append(report.Issues, sonarQubeIssue{
+				EngineID:        "kube-bench",
+				RuleID:          c.ID,
+				Severity:        sonarQubeSeverity(c),
+				Type:            "VULNERABILITY",
+				PrimaryLocation: sonarQubeLocation{Message: message, FilePath: file},
+			})
+		}
+	}
+	return json.Marshal(report)
+}
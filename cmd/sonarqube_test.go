@@ -0,0 +1,59 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+func TestSonarQubeIssues(t *testing.T) {
+	controls := githubControls()
+	controls.Type = check.NODE
+	controls.Groups[0].Checks = append(controls.Groups[0].Checks, &check.Check{ID: "4.2.4", Text: "Ensure that the --read-only-port argument is set to 0 (Scored)", State: check.FAIL, Scored: true})
+
+	out, err := sonarQubeIssuesJSON(controls)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	expected := `{"issues":[` +
+		`{"engineId":"kube-bench","ruleId":"4.2.1","severity":"MAJOR","type":"VULNERABILITY","primaryLocation":{"message":"4.2.1 Ensure that the --anonymous-auth argument is set to false (Scored)","filePath":"node.yaml"}},` +
+		`{"engineId":"kube-bench","ruleId":"4.2.3","severity":"MINOR","type":"VULNERABILITY","primaryLocation":{"message":"4.2.3 Ensure that the --client-ca-file argument is set as appropriate | 100% (Scored): insufficient privileges: cannot read /etc/kubernetes/kubelet.conf","filePath":"node.yaml"}},` +
+		`{"engineId":"kube-bench","ruleId":"4.2.4","severity":"CRITICAL","type":"VULNERABILITY","primaryLocation":{"message":"4.2.4 Ensure that the --read-only-port argument is set to 0 (Scored)","filePath":"node.yaml"}}]}`
+	if string(out) != expected {
+		t.Errorf("expected\n%s\ngot\n%s", expected, out)
+	}
+
+	out, err = sonarQubeIssuesJSON(&check.Controls{Type: check.POLICIES, Groups: []*check.Group{{Checks: []*check.Check{
+		{ID: "5.2.1", Text: "Minimize the admission of privileged containers", Type: check.MANUAL, State: check.WARN, Reason: "Test marked as a manual test", Remediation: "Create a PSP.\n"},
+	}}}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(out), `"message":"5.2.1 Minimize the admission of privileged containers: Create a PSP."`) {
+		t.Errorf("expected the guidance of a manual check, got %s", out)
+	}
+
+	out, err = sonarQubeIssuesJSON(&check.Controls{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(out) != `{"issues":[]}` {
+		t.Errorf("expected no issues, got %s", out)
+	}
+}
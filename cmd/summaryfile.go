@@ -0,0 +1,51 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/golang/glog"
+)
+
+// summaryResults collects the results of this run when --summary-file is
+// given.
+var summaryResults []*check.Controls
+
+func addToSummaryFile(controls *check.Controls) {
+	if summaryFile != "" {
+		summaryResults = append(summaryResults, controls)
+	}
+}
+
+// writeSummaryFile writes the totals, score, duration and node metadata of
+// the run to the summary file.
+func writeSummaryFile() error {
+	if summaryFile == "" || len(summaryResults) == 0 {
+		return nil
+	}
+
+	out, err := check.NewRunSummary(summaryResults).JSON()
+	if err != nil {
+		return fmt.Errorf("failed to encode the summary of the run: %v", err)
+	}
+	if err := ioutil.WriteFile(summaryFile, append(out, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to write summary file %s: %v", summaryFile, err)
+	}
+	glog.V(1).Infof("Summary written to %s", summaryFile)
+	return nil
+}
@@ -0,0 +1,75 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+func TestWriteSummaryFile(t *testing.T) {
+	defer func(file string, results []*check.Controls) {
+		summaryFile, summaryResults = file, results
+	}(summaryFile, summaryResults)
+
+	dir, err := ioutil.TempDir("", "kube-bench-summary")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	// Nothing is written without --summary-file.
+	summaryFile, summaryResults = "", nil
+	addToSummaryFile(&check.Controls{ID: "4"})
+	if len(summaryResults) != 0 {
+		t.Errorf("expected no results collected, got %d", len(summaryResults))
+	}
+
+	summaryFile = filepath.Join(dir, "summary.json")
+	if err := writeSummaryFile(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if _, err := os.Stat(summaryFile); !os.IsNotExist(err) {
+		t.Errorf("expected no summary file without results")
+	}
+
+	addToSummaryFile(&check.Controls{ID: "1", Summary: check.Summary{Pass: 3, Fail: 1}})
+	addToSummaryFile(&check.Controls{ID: "4", Summary: check.Summary{Pass: 1, Fail: 3}})
+	if err := writeSummaryFile(); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	data, err := ioutil.ReadFile(summaryFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var summary check.RunSummary
+	if err := json.Unmarshal(data, &summary); err != nil {
+		t.Fatalf("invalid summary file: %v", err)
+	}
+	if summary.Pass != 4 || summary.Fail != 4 || summary.Score != 50 || len(summary.Targets) != 2 {
+		t.Errorf("unexpected summary %+v", summary)
+	}
+
+	summaryFile = filepath.Join(dir, "missing", "summary.json")
+	if err := writeSummaryFile(); err == nil {
+		t.Errorf("expected an error writing to a missing directory")
+	}
+}
@@ -0,0 +1,193 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// controlsFixture declares the inputs a controls file is tested against and
+// the states its checks are expected to end up in.
+type controlsFixture struct {
+	// Controls is the controls file under test, relative to the fixture.
+	Controls string `yaml:"controls"`
+	// Substitutions replace $<name> variables in the controls file.
+	Substitutions map[string]string `yaml:"substitutions"`
+	Cases         []fixtureCase     `yaml:"cases"`
+}
+
+type fixtureCase struct {
+	Name string `yaml:"name"`
+	// Audits holds the output of the audit commands, keyed by the audit
+	// after substitution, e.g. a process table for "/bin/ps -fC kubelet".
+	Audits map[string]string `yaml:"audits"`
+	// Files holds the contents of the files read by "file" checks.
+	Files    map[string]string      `yaml:"files"`
+	Expected map[string]check.State `yaml:"expected"`
+}
+
+// testControlsCmd represents the test-controls command
+var testControlsCmd = &cobra.Command{
+	Use:   "test-controls <fixture.yaml>...",
+	Short: "Test controls files against fixtures",
+	Long: `Run the checks of a controls file against the audit outputs and files declared in a fixture,
+and verify that they end up in the expected states. No audit is run on the host.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		failed := false
+		for _, file := range args {
+			results, err := runFixture(file)
+			if err != nil {
+				exitWithError(fmt.Errorf("failed to run fixture %s: %v", file, err))
+			}
+
+			for _, r := range results {
+				if len(r.mismatches) == 0 {
+					colorPrint(check.PASS, fmt.Sprintf("%s: %s\n", file, r.name))
+					continue
+				}
+
+				failed = true
+				colorPrint(check.FAIL, fmt.Sprintf("%s: %s\n", file, r.name))
+				for _, m := range r.mismatches {
+					fmt.Printf("\t %s\n", m)
+				}
+			}
+		}
+
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(testControlsCmd)
+}
+
+type fixtureResult struct {
+	name       string
+	mismatches []string
+}
+
+// runFixture runs every case of a fixture file and reports the checks that
+// did not end up in the expected state.
+func runFixture(file string) ([]fixtureResult, error) {
+	in, err := ioutil.ReadFile(file)
+	if err != nil {
+		return nil, err
+	}
+
+	var f controlsFixture
+	if err := yaml.Unmarshal(in, &f); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal YAML: %v", err)
+	}
+	if f.Controls == "" {
+		return nil, fmt.Errorf("no controls file specified")
+	}
+
+	controlsFile := f.Controls
+	if !filepath.IsAbs(controlsFile) {
+		controlsFile = filepath.Join(filepath.Dir(file), controlsFile)
+	}
+	in, err = ioutil.ReadFile(controlsFile)
+	if err != nil {
+		return nil, err
+	}
+	in, err = check.ResolveIncludes(in, filepath.Dir(controlsFile))
+	if err != nil {
+		return nil, err
+	}
+	in, err = check.ResolveExtends(in, filepath.Dir(controlsFile))
+	if err != nil {
+		return nil, err
+	}
+	controlsText := makeSubstitutions(string(in), "", f.Substitutions)
+
+	var header struct {
+		Type check.NodeType `yaml:"type"`
+	}
+	if err := yaml.Unmarshal([]byte(controlsText), &header); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s: %v", controlsFile, err)
+	}
+
+	defer check.ReplayEvidence(nil)
+
+	var results []fixtureResult
+	for i, c := range f.Cases {
+		name := c.Name
+		if name == "" {
+			name = fmt.Sprintf("case %d", i+1)
+		}
+
+		evidence := check.Evidence{}
+		for audit, out := range c.Audits {
+			evidence[audit] = out
+		}
+		for path, content := range c.Files {
+			evidence[check.FILE+":"+path] = content
+		}
+		check.ReplayEvidence(evidence)
+
+		// The controls are loaded for each case as running them records
+		// their state.
+		controls, err := check.NewControls(header.Type, []byte(controlsText))
+		if err != nil {
+			return nil, fmt.Errorf("error setting up %s controls: %v", header.Type, err)
+		}
+		controls.RunChecks(check.NewRunner(), func(*check.Group, *check.Check) bool { return true })
+
+		checks := map[string]*check.Check{}
+		for _, g := range controls.Groups {
+			for _, chk := range g.Checks {
+				checks[chk.ID] = chk
+			}
+		}
+
+		ids := make([]string, 0, len(c.Expected))
+		for id := range c.Expected {
+			ids = append(ids, id)
+		}
+		sort.Strings(ids)
+
+		r := fixtureResult{name: name}
+		for _, id := range ids {
+			chk, ok := checks[id]
+			if !ok {
+				r.mismatches = append(r.mismatches, fmt.Sprintf("%s: check not found", id))
+				continue
+			}
+			if chk.State != c.Expected[id] {
+				m := fmt.Sprintf("%s: expected %s, got %s", id, c.Expected[id], chk.State)
+				if reason := strings.TrimSpace(chk.Reason); reason != "" {
+					m += fmt.Sprintf(" (%s)", reason)
+				}
+				r.mismatches = append(r.mismatches, m)
+			}
+		}
+		results = append(results, r)
+	}
+
+	return results, nil
+}
@@ -0,0 +1,133 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+const fixtureControls = `---
+controls:
+id: 4
+text: "Worker Node Security Configuration"
+type: "node"
+groups:
+- id: 4.2
+  text: "Kubelet"
+  checks:
+  - id: 4.2.1
+    text: "Ensure that the --anonymous-auth argument is set to false"
+    audit: "/bin/ps -fC $kubeletbin"
+    tests:
+      test_items:
+      - flag: "--anonymous-auth"
+        compare:
+          op: eq
+          value: false
+        set: true
+    scored: true
+  - id: 4.2.2
+    text: "Ensure that the audit policy covers key security concerns"
+    type: "file"
+    audit: "/etc/kubernetes/audit-policy.yaml"
+    tests:
+      test_items:
+      - path: "{.kind}"
+        compare:
+          op: eq
+          value: Policy
+        set: true
+    scored: true
+`
+
+const fixture = `
+controls: node.yaml
+substitutions:
+  kubeletbin: kubelet
+cases:
+- name: hardened node
+  audits:
+    "/bin/ps -fC kubelet": "kubelet --anonymous-auth=false"
+  files:
+    /etc/kubernetes/audit-policy.yaml: "kind: Policy"
+  expected:
+    4.2.1: PASS
+    4.2.2: PASS
+- audits:
+    "/bin/ps -fC kubelet": "kubelet --anonymous-auth=true"
+  expected:
+    4.2.1: PASS
+    4.2.2: WARN
+    4.2.3: FAIL
+`
+
+func TestRunFixture(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-fixture")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "node.yaml"), []byte(fixtureControls), 0600); err != nil {
+		t.Fatal(err)
+	}
+	file := filepath.Join(dir, "node_fixture.yaml")
+	if err := ioutil.WriteFile(file, []byte(fixture), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	results, err := runFixture(file)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	expected := []fixtureResult{
+		{name: "hardened node"},
+		{name: "case 2", mismatches: []string{
+			"4.2.1: expected PASS, got FAIL",
+			"4.2.3: check not found",
+		}},
+	}
+	if !reflect.DeepEqual(results, expected) {
+		t.Errorf("expected %+v, got %+v", expected, results)
+	}
+}
+
+func TestRunFixtureErrors(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-fixture")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	fixtures := map[string]string{
+		"no-controls.yaml":      "cases: []",
+		"missing-controls.yaml": "controls: missing.yaml",
+		"invalid.yaml":          "controls: [",
+	}
+	for name, content := range fixtures {
+		file := filepath.Join(dir, name)
+		if err := ioutil.WriteFile(file, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := runFixture(file); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
@@ -0,0 +1,62 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+// slowestChecks is the number of checks listed by the timing report.
+const slowestChecks = 10
+
+// printTimings prints how long the checks of the controls took to run: the
+// total time of each group, from the slowest, and the slowest checks.
+func printTimings(w io.Writer, controls *check.Controls) {
+	type timing struct {
+		id, text string
+		seconds  float64
+	}
+
+	var groups, checks []timing
+	var total float64
+	for _, g := range controls.Groups {
+		group := timing{id: g.ID, text: g.Text}
+		for _, c := range g.Checks {
+			group.seconds += c.Duration
+			checks = append(checks, timing{id: c.ID, text: c.Text, seconds: c.Duration})
+		}
+		total += group.seconds
+		groups = append(groups, group)
+	}
+	sort.SliceStable(groups, func(i, j int) bool { return groups[i].seconds > groups[j].seconds })
+	sort.SliceStable(checks, func(i, j int) bool { return checks[i].seconds > checks[j].seconds })
+	if len(checks) > slowestChecks {
+		checks = checks[:slowestChecks]
+	}
+
+	fmt.Fprintf(w, "== Timings of %s %s ==\n", controls.ID, controls.Text)
+	for _, g := range groups {
+		fmt.Fprintf(w, "%8.3fs %s %s\n", g.seconds, g.id, g.text)
+	}
+	fmt.Fprintf(w, "%8.3fs total\n", total)
+	fmt.Fprintf(w, "Slowest checks:\n")
+	for _, c := range checks {
+		fmt.Fprintf(w, "%8.3fs %s %s\n", c.seconds, c.id, c.text)
+	}
+}
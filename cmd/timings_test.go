@@ -0,0 +1,59 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+func TestPrintTimings(t *testing.T) {
+	files := &check.Group{ID: "1.1", Text: "Master Node Configuration Files"}
+	for i := 1; i <= 12; i++ {
+		files.Checks = append(files.Checks, &check.Check{ID: fmt.Sprintf("1.1.%d", i), Text: "File check", Duration: 0.01 * float64(i)})
+	}
+	apiserver := &check.Group{ID: "1.2", Text: "API Server", Checks: []*check.Check{
+		{ID: "1.2.1", Text: "Ensure that the --anonymous-auth argument is set to false", Duration: 0.5},
+		{ID: "1.2.2", Text: "Ensure that the --basic-auth-file argument is not set", Duration: 0.25},
+	}}
+	controls := &check.Controls{ID: "1", Text: "Master Node Security Configuration", Groups: []*check.Group{files, apiserver}}
+
+	var out bytes.Buffer
+	printTimings(&out, controls)
+	lines := strings.Split(strings.TrimSpace(out.String()), "\n")
+
+	expected := []string{
+		"== Timings of 1 Master Node Security Configuration ==",
+		"   0.780s 1.1 Master Node Configuration Files",
+		"   0.750s 1.2 API Server",
+		"   1.530s total",
+		"Slowest checks:",
+		"   0.500s 1.2.1 Ensure that the --anonymous-auth argument is set to false",
+		"   0.250s 1.2.2 Ensure that the --basic-auth-file argument is not set",
+		"   0.120s 1.1.12 File check",
+	}
+	for i, l := range expected {
+		if i >= len(lines) || lines[i] != l {
+			t.Fatalf("expected line %d to be %q, got\n%s", i, l, out.String())
+		}
+	}
+	if n := len(lines) - 5; n != slowestChecks {
+		t.Errorf("expected the %d slowest checks, got %d", slowestChecks, n)
+	}
+}
@@ -0,0 +1,69 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+)
+
+// trendCmd represents the trend command
+var trendCmd = &cobra.Command{
+	Use:   "trend",
+	Short: "Show the compliance score of the runs saved in the history",
+	Long: `Show the compliance score of the runs saved in --history-dir over time, and the checks
+that regressed in the last run: the ones that passed in the run before and no longer pass.
+The score is the percentage of checks that passed, out of the ones that passed, failed or warned.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if historyDir == "" {
+			exitWithError(fmt.Errorf("--history-dir must be specified"))
+		}
+
+		runs, err := loadHistory(historyDir)
+		if err != nil {
+			exitWithError(fmt.Errorf("failed to read history: %v", err))
+		}
+		if len(runs) == 0 {
+			exitWithError(fmt.Errorf("no runs saved in %s", historyDir))
+		}
+
+		printTrend(os.Stdout, runs)
+		if len(runs) > 1 {
+			reportRegressions(regressions(&runs[len(runs)-2], &runs[len(runs)-1]))
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(trendCmd)
+}
+
+func printTrend(w io.Writer, runs []historyRun) {
+	fmt.Fprintf(w, "%-25s %6s %6s %6s %6s %7s\n", "TIME", "PASS", "FAIL", "WARN", "INFO", "SCORE")
+	for i, r := range runs {
+		s := r.summary()
+		change := ""
+		if i > 0 {
+			if d := s.Score() - runs[i-1].summary().Score(); d >= 0.05 || d <= -0.05 {
+				change = fmt.Sprintf(" (%+.1f)", d)
+			}
+		}
+		fmt.Fprintf(w, "%-25s %6d %6d %6d %6d %6.1f%%%s\n", r.Time.Format(time.RFC3339), s.Pass, s.Fail, s.Warn, s.Info, s.Score(), change)
+	}
+}
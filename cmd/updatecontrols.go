@@ -0,0 +1,269 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"encoding/base64"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/golang/glog"
+	"github.com/spf13/cobra"
+	"github.com/spf13/viper"
+)
+
+// maxControlsBundleSize bounds the size of a downloaded controls bundle.
+const maxControlsBundleSize = 50 << 20
+
+// controlsDir is where update-controls installs downloaded controls.
+var controlsDir = defaultControlsDir()
+
+func defaultControlsDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, ".kube-bench", "controls")
+}
+
+// updateControlsCmd represents the update-controls command
+var updateControlsCmd = &cobra.Command{
+	Use:   "update-controls",
+	Short: "Download the latest published controls",
+	Long: `Download a signed controls bundle, a tar.gz of a cfg directory, and install it in --controls-dir.
+The bundle is only installed if its signature, found at the bundle URL with a .sig suffix, is
+valid for the configured ed25519 public key. With an oci://registry/repository:tag URL, the
+bundle is pulled from an OCI registry, with its signature in the
+com.aquasec.kube-bench.signature annotation of its layer. Subsequent runs use the installed controls
+instead of ./cfg, unless --config-dir is given.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		if offline {
+			exitWithError(fmt.Errorf("update-controls downloads the controls, it can't run with --offline"))
+		}
+
+		url := viper.GetString("controls_url")
+		if url == "" {
+			exitWithError(fmt.Errorf("no controls bundle URL, set it with --url or controls_url in the config"))
+		}
+		key := viper.GetString("controls_public_key")
+		if key == "" {
+			exitWithError(fmt.Errorf("no public key to verify the controls bundle, set it with --public-key or controls_public_key in the config"))
+		}
+
+		if err := updateControls(url, key, controlsDir); err != nil {
+			exitWithError(fmt.Errorf("failed to update controls: %v", err))
+		}
+		fmt.Printf("Controls from %s installed in %s\n", url, filepath.Join(controlsDir, "cfg"))
+	},
+}
+
+func init() {
+	updateControlsCmd.Flags().String("url", "", "URL of the controls bundle")
+	updateControlsCmd.Flags().String("public-key", "", "Base64 encoded ed25519 public key the controls bundle is signed with")
+	viper.BindPFlag("controls_url", updateControlsCmd.Flags().Lookup("url"))
+	viper.BindPFlag("controls_public_key", updateControlsCmd.Flags().Lookup("public-key"))
+	RootCmd.AddCommand(updateControlsCmd)
+}
+
+// useUpdatedControls makes kube-bench use the controls installed by
+// update-controls, unless another config directory was given.
+func useUpdatedControls() {
+	if controlsDir == "" || RootCmd.PersistentFlags().Changed("config-dir") {
+		return
+	}
+
+	dir := filepath.Join(controlsDir, "cfg")
+	if _, err := os.Stat(filepath.Join(dir, "config.yaml")); err != nil {
+		return
+	}
+	glog.V(1).Infof("Using controls installed in %s", dir)
+	cfgDir = dir
+}
+
+// updateControls downloads a controls bundle, from a URL or an OCI registry,
+// verifies its signature and installs it as the cfg directory of dir.
+func updateControls(url, publicKey, dir string) error {
+	key, err := controlsPublicKey(publicKey)
+	if err != nil {
+		return err
+	}
+
+	var bundle []byte
+	if strings.HasPrefix(url, ociScheme) {
+		bundle, err = pullControls(url, key)
+	} else {
+		bundle, err = downloadControls(url, key)
+	}
+	if err != nil {
+		return err
+	}
+	return installControls(bundle, dir)
+}
+
+// controlsPublicKey decodes the public key controls bundles are signed with.
+func controlsPublicKey(publicKey string) (ed25519.PublicKey, error) {
+	key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(publicKey))
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return nil, fmt.Errorf("invalid public key, expected a base64 encoded ed25519 public key")
+	}
+	return ed25519.PublicKey(key), nil
+}
+
+// downloadControls downloads a controls bundle and verifies it with its
+// signature, found at the bundle URL with a .sig suffix.
+func downloadControls(url string, key ed25519.PublicKey) ([]byte, error) {
+	bundle, err := download(url)
+	if err != nil {
+		return nil, err
+	}
+	sig, err := download(url + ".sig")
+	if err != nil {
+		return nil, err
+	}
+	if err := verifyControls(bundle, sig, key); err != nil {
+		return nil, fmt.Errorf("%v for %s", err, url)
+	}
+	return bundle, nil
+}
+
+// verifyControls verifies the signature of a controls bundle, raw or base64
+// encoded.
+func verifyControls(bundle, sig []byte, key ed25519.PublicKey) error {
+	if decoded, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig))); err == nil {
+		sig = decoded
+	}
+	if !ed25519.Verify(key, bundle, sig) {
+		return fmt.Errorf("invalid signature")
+	}
+	return nil
+}
+
+// installControls installs a controls bundle as the cfg directory of dir.
+// The previous controls are only replaced once the new ones are fully
+// extracted.
+func installControls(bundle []byte, dir string) error {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return err
+	}
+	tmp, err := ioutil.TempDir(dir, ".cfg-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(tmp)
+
+	if err := extractControls(bundle, tmp); err != nil {
+		return fmt.Errorf("invalid controls bundle: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(tmp, "config.yaml")); err != nil {
+		return fmt.Errorf("invalid controls bundle: no config.yaml")
+	}
+
+	cfg := filepath.Join(dir, "cfg")
+	old := cfg + ".old"
+	os.RemoveAll(old)
+	if err := os.Rename(cfg, old); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	if err := os.Rename(tmp, cfg); err != nil {
+		os.Rename(old, cfg)
+		return err
+	}
+	return os.RemoveAll(old)
+}
+
+func download(url string) ([]byte, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: %s", url, resp.Status)
+	}
+	return readDownload(url, resp.Body)
+}
+
+// readDownload reads a downloaded file, up to maxControlsBundleSize.
+func readDownload(url string, body io.Reader) ([]byte, error) {
+	data, err := ioutil.ReadAll(io.LimitReader(body, maxControlsBundleSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %v", url, err)
+	}
+	if len(data) > maxControlsBundleSize {
+		return nil, fmt.Errorf("%s is larger than %d bytes", url, maxControlsBundleSize)
+	}
+	return data, nil
+}
+
+// extractControls extracts the files of a controls bundle into dir. The
+// files can be at the root of the bundle, or in a cfg directory.
+func extractControls(bundle []byte, dir string) error {
+	gz, err := gzip.NewReader(bytes.NewReader(bundle))
+	if err != nil {
+		return err
+	}
+	tr := tar.NewReader(gz)
+
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		name := strings.TrimPrefix(filepath.Clean(strings.TrimPrefix(hdr.Name, "./")), "cfg/")
+		if name == "cfg" || name == "." {
+			continue
+		}
+		if filepath.IsAbs(name) || name == ".." || strings.HasPrefix(name, "../") {
+			return fmt.Errorf("%s is outside of the bundle", hdr.Name)
+		}
+		path := filepath.Join(dir, name)
+
+		switch hdr.Typeflag {
+		case tar.TypeDir:
+			if err := os.MkdirAll(path, 0755); err != nil {
+				return err
+			}
+		case tar.TypeReg:
+			if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+				return err
+			}
+			data, err := ioutil.ReadAll(tr)
+			if err != nil {
+				return err
+			}
+			if err := ioutil.WriteFile(path, data, 0644); err != nil {
+				return err
+			}
+		default:
+			return fmt.Errorf("unsupported file type for %s", hdr.Name)
+		}
+	}
+}
@@ -0,0 +1,147 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/ed25519"
+	"encoding/base64"
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func controlsBundle(t *testing.T, files map[string]string) []byte {
+	var b bytes.Buffer
+	gz := gzip.NewWriter(&b)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tw.Write([]byte(content)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tw.Close()
+	gz.Close()
+	return b.Bytes()
+}
+
+func TestUpdateControls(t *testing.T) {
+	pub, priv, err := ed25519.GenerateKey(nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	publicKey := base64.StdEncoding.EncodeToString(pub)
+
+	bundles := map[string][]byte{
+		"/good.tar.gz":      controlsBundle(t, map[string]string{"cfg/config.yaml": "master: {}\n", "cfg/cis-1.5/master.yaml": "controls:\n"}),
+		"/no-config.tar.gz": controlsBundle(t, map[string]string{"cis-1.5/master.yaml": "controls:\n"}),
+		"/escape.tar.gz":    controlsBundle(t, map[string]string{"config.yaml": "", "../evil.yaml": ""}),
+	}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if bundle, ok := bundles[r.URL.Path]; ok {
+			w.Write(bundle)
+			return
+		}
+		if bundle, ok := bundles[r.URL.Path[:len(r.URL.Path)-len(".sig")]]; ok {
+			w.Write([]byte(base64.StdEncoding.EncodeToString(ed25519.Sign(priv, bundle))))
+			return
+		}
+		if r.URL.Path == "/tampered.tar.gz" {
+			w.Write(bundles["/escape.tar.gz"])
+			return
+		}
+		if r.URL.Path == "/tampered.tar.gz.sig" {
+			w.Write(ed25519.Sign(priv, bundles["/good.tar.gz"]))
+			return
+		}
+		http.NotFound(w, r)
+	}))
+	defer server.Close()
+
+	dir, err := ioutil.TempDir("", "kube-bench-controls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := updateControls(server.URL+"/good.tar.gz", publicKey, dir); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if data, err := ioutil.ReadFile(filepath.Join(dir, "cfg", "cis-1.5", "master.yaml")); err != nil || string(data) != "controls:\n" {
+		t.Errorf("expected the controls to be installed, got %q, %v", data, err)
+	}
+
+	otherKey, _, _ := ed25519.GenerateKey(nil)
+	cases := []struct {
+		name string
+		url  string
+		key  string
+	}{
+		{"tampered", server.URL + "/tampered.tar.gz", publicKey},
+		{"wrong key", server.URL + "/good.tar.gz", base64.StdEncoding.EncodeToString(otherKey)},
+		{"invalid key", server.URL + "/good.tar.gz", "bm90IGEga2V5"},
+		{"no config", server.URL + "/no-config.tar.gz", publicKey},
+		{"escape", server.URL + "/escape.tar.gz", publicKey},
+		{"not found", server.URL + "/missing.tar.gz", publicKey},
+	}
+	for _, c := range cases {
+		if err := updateControls(c.url, c.key, dir); err == nil {
+			t.Errorf("%s: expected an error", c.name)
+		}
+		// The installed controls are kept.
+		if _, err := os.Stat(filepath.Join(dir, "cfg", "config.yaml")); err != nil {
+			t.Errorf("%s: expected the previous controls to be kept: %v", c.name, err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(filepath.Dir(dir), "evil.yaml")); err == nil {
+		t.Errorf("expected files outside of the bundle not to be written")
+	}
+}
+
+func TestUseUpdatedControls(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-controls")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	defer func(d, c string) { cfgDir, controlsDir = d, c }(cfgDir, controlsDir)
+	cfgDir = "./cfg/"
+	controlsDir = dir
+
+	useUpdatedControls()
+	if cfgDir != "./cfg/" {
+		t.Errorf("expected ./cfg/ without installed controls, got %s", cfgDir)
+	}
+
+	if err := os.MkdirAll(filepath.Join(dir, "cfg"), 0755); err != nil {
+		t.Fatal(err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "cfg", "config.yaml"), []byte{}, 0644); err != nil {
+		t.Fatal(err)
+	}
+	useUpdatedControls()
+	if cfgDir != filepath.Join(dir, "cfg") {
+		t.Errorf("expected the installed controls to be used, got %s", cfgDir)
+	}
+}
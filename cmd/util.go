@@ -6,6 +6,7 @@ import (
 	"os/exec"
 	"path/filepath"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -22,6 +23,7 @@ var (
 		check.FAIL: color.New(color.FgRed),
 		check.WARN: color.New(color.FgYellow),
 		check.INFO: color.New(color.FgBlue),
+		check.SKIP: color.New(color.FgCyan),
 	}
 )
 
@@ -33,6 +35,9 @@ var TypeMap = map[string][]string{
 	"kubeconfig": []string{"kubeconfig", "defaultkubeconfig"},
 	"service":    []string{"svc", "defaultsvc"},
 	"config":     []string{"confs", "defaultconf"},
+	// The file given with --config to components that also have a
+	// config file, e.g. the scheduler's, whose config file is its manifest.
+	"componentconfig": []string{"componentconfigs", "defaultcomponentconfig"},
 }
 
 func init() {
@@ -41,7 +46,24 @@ func init() {
 	getBinariesFunc = getBinaries
 }
 
+// scanAborted is raised by exitWithError while serve runs a scan, for the
+// scan to be abandoned rather than the server to exit.
+type scanAborted struct {
+	err error
+}
+
+// scanning is set while serve runs a scan.
+var scanning bool
+
 func exitWithError(err error) {
+	if scanning {
+		panic(scanAborted{err})
+	}
+	fatalError(err)
+}
+
+// fatalError prints the error and exits, even while serve runs a scan.
+func fatalError(err error) {
 	fmt.Fprintf(os.Stderr, "\n%v\n", err)
 	// flush before exit non-zero
 	glog.Flush()
@@ -74,19 +96,18 @@ func cleanIDs(list string) map[string]bool {
 	return set
 }
 
-// ps execs out to the ps command; it's separated into a function so we can write tests
+// ps lists the running processes of a binary with the configured process
+// backend; it's separated into a function so we can write tests
 func ps(proc string) string {
-	// TODO: truncate proc to 15 chars
-	// See https://github.com/aquasecurity/kube-bench/issues/328#issuecomment-506813344
 	glog.V(2).Info(fmt.Sprintf("ps - proc: %q", proc))
-	cmd := exec.Command("/bin/ps", "-C", proc, "-o", "cmd", "--no-headers")
-	out, err := cmd.Output()
+	cmdlines, err := check.FindProcesses(proc)
 	if err != nil {
-		continueWithError(fmt.Errorf("%s: %s", cmd.Args, err), "")
+		continueWithError(err, "")
 	}
 
-	glog.V(2).Info(fmt.Sprintf("ps - returning: %q", string(out)))
-	return string(out)
+	out := strings.Join(cmdlines, "\n")
+	glog.V(2).Info(fmt.Sprintf("ps - returning: %q", out))
+	return out
 }
 
 // getBinaries finds which of the set of candidate executables are running.
@@ -140,12 +161,17 @@ func getConfigFilePath(benchmarkVersion string, filename string) (path string, e
 }
 
 // getYamlFilesFromDir returns a list of yaml files in the specified directory, ignoring config.yaml
-func getYamlFilesFromDir(path string) (names []string, err error) {
-	err = filepath.Walk(path, func(path string, info os.FileInfo, err error) error {
+func getYamlFilesFromDir(dir string) (names []string, err error) {
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
 
+		// Subdirectories hold fragments included by controls files.
+		if info.IsDir() && path != dir {
+			return filepath.SkipDir
+		}
+
 		_, name := filepath.Split(path)
 		if name != "" && name != "config.yaml" && filepath.Ext(name) == ".yaml" {
 			names = append(names, path)
@@ -227,20 +253,26 @@ func verifyBin(bin string) bool {
 	reFirstWord := regexp.MustCompile(`^(\S*\/)*` + bin)
 	lines := strings.Split(out, "\n")
 	glog.V(2).Info(fmt.Sprintf("verifyBin - lines(%d)", len(lines)))
+	matches := 0
 	for _, l := range lines {
 		glog.V(2).Info(fmt.Sprintf("reFirstWord.Match(%s)\n\n\n\n", l))
 		if reFirstWord.Match([]byte(l)) {
-			return true
+			matches++
 		}
 	}
 
-	return false
+	// Audits see all the processes, "process" checks can select one of them.
+	if matches > 1 {
+		glog.V(1).Info(fmt.Sprintf("%d processes match %s, audits may test any of them", matches, bin))
+	}
+
+	return matches > 0
 }
 
 // fundConfigFile looks through a list of possible config files and finds the first one that exists
 func findConfigFile(candidates []string) string {
 	for _, c := range candidates {
-		_, err := statFunc(c)
+		_, err := statFunc(hostPath(c))
 		if err == nil {
 			return c
 		}
@@ -294,13 +326,19 @@ Alternatively, you can specify the version with --version
 
 func getKubeVersion() (string, error) {
 
-	if k8sVer, err := getKubeVersionFromRESTAPI(); err == nil {
-		glog.V(2).Info(fmt.Sprintf("Kubernetes REST API Reported version: %s", k8sVer))
-		return k8sVer, nil
+	if !offline {
+		if k8sVer, err := getKubeVersionFromRESTAPI(); err == nil {
+			glog.V(2).Info(fmt.Sprintf("Kubernetes REST API Reported version: %s", k8sVer))
+			return k8sVer, nil
+		}
 	}
 
 	// These executables might not be on the user's path.
 	_, err := exec.LookPath("kubectl")
+	if offline {
+		// kubectl gets the version of the API server.
+		err = check.ErrOffline
+	}
 
 	if err != nil {
 		_, err = exec.LookPath("kubelet")
@@ -322,7 +360,8 @@ func getKubeVersion() (string, error) {
 }
 
 func getKubeVersionFromKubectl() string {
-	cmd := exec.Command("kubectl", "version", "--short")
+	// --short was removed from kubectl 1.28, where it's the default output.
+	cmd := exec.Command("kubectl", "version")
 	out, err := cmd.CombinedOutput()
 	if err != nil {
 		continueWithError(fmt.Errorf("%s", out), "")
@@ -342,11 +381,14 @@ func getKubeVersionFromKubelet() string {
 	return getVersionFromKubeletOutput(string(out))
 }
 
+// getVersionFromKubectlOutput returns the version of the server from the
+// output of kubectl version, short as in "Server Version: v1.13.4", or the
+// version.Info structure printed by kubectl before 1.28.
 func getVersionFromKubectlOutput(s string) string {
-	serverVersionRe := regexp.MustCompile(`Server Version: v(\d+.\d+)`)
+	serverVersionRe := regexp.MustCompile(`Server Version: (?:version\.Info\{.*GitVersion:")?v(\d+\.\d+)`)
 	subs := serverVersionRe.FindStringSubmatch(s)
 	if len(subs) < 2 {
-		glog.V(1).Info(fmt.Sprintf("Unable to get Kubernetes version from kubectl, using default version: %s", defaultKubeVersion))
+		glog.Warningf("Unable to get Kubernetes version from kubectl, using default version %s, set the version with --version", defaultKubeVersion)
 		return defaultKubeVersion
 	}
 	return subs[1]
@@ -356,7 +398,7 @@ func getVersionFromKubeletOutput(s string) string {
 	serverVersionRe := regexp.MustCompile(`Kubernetes v(\d+.\d+)`)
 	subs := serverVersionRe.FindStringSubmatch(s)
 	if len(subs) < 2 {
-		glog.V(1).Info(fmt.Sprintf("Unable to get Kubernetes version from kubelet, using default version: %s", defaultKubeVersion))
+		glog.Warningf("Unable to get Kubernetes version from kubelet, using default version %s, set the version with --version", defaultKubeVersion)
 		return defaultKubeVersion
 	}
 	return subs[1]
@@ -376,6 +418,125 @@ func makeSubstitutions(s string, ext string, m map[string]string) string {
 	return s
 }
 
+// fileVariables are the files of components substituted for the variables
+// with the given extension, e.g. $kubeletconf.
+type fileVariables struct {
+	ext      string
+	fileType string
+	files    map[string]string
+}
+
+// addMissingFileErrors adds an error for each substituted file that does not
+// exist to the checks whose audits use it, and to the errors of the run when
+// any check does. The checks using the files are found in raw, the controls
+// before substitutions.
+func addMissingFileErrors(controls, raw *check.Controls, vars []fileVariables) {
+	type missingFile struct {
+		variable *regexp.Regexp
+		err      check.CheckError
+	}
+
+	var missing []missingFile
+	for _, v := range vars {
+		components := make([]string, 0, len(v.files))
+		for component := range v.files {
+			components = append(components, component)
+		}
+		sort.Strings(components)
+
+		for _, component := range components {
+			file := v.files[component]
+			if _, err := statFunc(hostPath(file)); err == nil {
+				continue
+			}
+
+			variable := "$" + component + v.ext
+			message := fmt.Sprintf("%s file %s of %s not found, used for %s", v.fileType, file, component, variable)
+			if file == component {
+				message = fmt.Sprintf("no %s file of %s found, used for %s", v.fileType, component, variable)
+			}
+			missing = append(missing, missingFile{
+				variable: regexp.MustCompile(regexp.QuoteMeta(variable) + `\b`),
+				err:      check.CheckError{Kind: check.MissingFileError, Message: message},
+			})
+		}
+	}
+
+	used := make([]bool, len(missing))
+	for i, g := range raw.Groups {
+		for j, c := range g.Checks {
+			if i >= len(controls.Groups) || j >= len(controls.Groups[i].Checks) {
+				continue
+			}
+			audits := c.Audit + "\n" + c.AuditConfig
+			for k, m := range missing {
+				if m.variable.MatchString(audits) {
+					controls.Groups[i].Checks[j].AddError(m.err.Kind, m.err.Message)
+					used[k] = true
+				}
+			}
+		}
+	}
+
+	for k, m := range missing {
+		if used[k] {
+			controls.Errors = append(controls.Errors, m.err)
+		}
+	}
+}
+
+// unsubstitutedVariableRe matches the substitution variables left in audits
+// when the component has no value for them, e.g. $apiserverbin when no API
+// server is running.
+var unsubstitutedVariableRe = regexp.MustCompile(`\$\w+(bin|config|conf|svc|kubeconfig|cafile)\b`)
+
+// markUnsubstitutedChecks makes the checks whose audits still use
+// substitution variables generate WARN, rather than running them with the
+// variables left as is, and adds an error for each variable to the errors of
+// the run, which it returns.
+func markUnsubstitutedChecks(controls *check.Controls) []check.CheckError {
+	unsubstituted := map[string][]string{}
+	for _, g := range controls.Groups {
+		for _, c := range g.Checks {
+			if c.Type == check.MANUAL || c.Type == "skip" || c.Unavailable != "" || c.Ignored != "" {
+				continue
+			}
+
+			var variables []string
+			seen := map[string]bool{}
+			for _, v := range unsubstitutedVariableRe.FindAllString(c.Audit+"\n"+c.AuditConfig, -1) {
+				if !seen[v] {
+					seen[v] = true
+					variables = append(variables, v)
+				}
+			}
+			if len(variables) == 0 {
+				continue
+			}
+
+			c.Unavailable = fmt.Sprintf("no value for %s: check the components of %s in the config", strings.Join(variables, ", "), controls.Type)
+			for _, v := range variables {
+				unsubstituted[v] = append(unsubstituted[v], c.ID)
+			}
+		}
+	}
+
+	variables := make([]string, 0, len(unsubstituted))
+	for v := range unsubstituted {
+		variables = append(variables, v)
+	}
+	sort.Strings(variables)
+	var errs []check.CheckError
+	for _, v := range variables {
+		errs = append(errs, check.CheckError{
+			Kind:    check.AuditError,
+			Message: fmt.Sprintf("no value for %s, used by %s", v, strings.Join(unsubstituted[v], ", ")),
+		})
+	}
+	controls.Errors = append(controls.Errors, errs...)
+	return errs
+}
+
 func isEmpty(str string) bool {
 	return len(strings.TrimSpace(str)) == 0
 
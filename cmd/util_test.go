@@ -209,6 +209,13 @@ func TestKubeVersionRegex(t *testing.T) {
 		t.Fatalf("Expected 1.8 got %s", ver)
 	}
 
+	ver = getVersionFromKubectlOutput(`Client Version: version.Info{Major:"1", Minor:"13", GitVersion:"v1.13.4", GitCommit:"c27b913fddd1a6c480c229191a087698aa92f0b1"}
+Server Version: version.Info{Major:"1", Minor:"13", GitVersion:"v1.13.2", GitCommit:"cff46ab41ff0bb44d8584413b598ad8360ec1def"}
+`)
+	if ver != "1.13" {
+		t.Fatalf("Expected 1.13 got %s", ver)
+	}
+
 	ver = getVersionFromKubectlOutput("Something completely different")
 	if ver != defaultKubeVersion {
 		t.Fatalf("Expected %s got %s", defaultKubeVersion, ver)
@@ -307,6 +314,33 @@ func TestGetConfigFiles(t *testing.T) {
 	}
 }
 
+func TestGetComponentConfigFiles(t *testing.T) {
+	v := viper.New()
+	v.Set("components", []string{"scheduler", "apiserver"})
+	v.Set("scheduler", map[string]interface{}{
+		"confs":            []string{"/etc/kubernetes/manifests/kube-scheduler.yaml"},
+		"componentconfigs": []string{"/etc/kubernetes/scheduler.yaml", "/etc/kubernetes/scheduler-config.yaml"},
+	})
+	v.Set("apiserver", map[string]interface{}{"confs": []string{"/etc/kubernetes/manifests/kube-apiserver.yaml"}})
+
+	statFunc = fakestat
+	e = []error{os.ErrNotExist, nil, os.ErrNotExist}
+	eIndex = 0
+
+	m := getFiles(v, "componentconfig")
+	exp := map[string]string{"scheduler": "/etc/kubernetes/scheduler-config.yaml", "apiserver": "apiserver"}
+	if !reflect.DeepEqual(m, exp) {
+		t.Fatalf("Got %v\nExpected %v", m, exp)
+	}
+
+	// The component config variable is substituted before the conf one it starts with.
+	s := makeSubstitutions("cat $schedulerconfig; stat $schedulerconf", "config", m)
+	s = makeSubstitutions(s, "conf", map[string]string{"scheduler": "/etc/kubernetes/manifests/kube-scheduler.yaml"})
+	if s != "cat /etc/kubernetes/scheduler-config.yaml; stat /etc/kubernetes/manifests/kube-scheduler.yaml" {
+		t.Errorf("unexpected substitutions %q", s)
+	}
+}
+
 func TestGetServiceFiles(t *testing.T) {
 	cases := []struct {
 		config      map[string]interface{}
@@ -402,6 +436,86 @@ func TestMakeSubsitutions(t *testing.T) {
 	}
 }
 
+func TestAddMissingFileErrors(t *testing.T) {
+	raw := &check.Controls{Groups: []*check.Group{{Checks: []*check.Check{
+		{ID: "4.1.1", Audit: "stat -c %a $kubeletsvc"},
+		{ID: "4.1.9", Audit: "stat -c %a $kubeletconf"},
+		{ID: "4.2.1", Audit: "cat $kubeletconf", AuditConfig: "cat $kubeletsvc"},
+		{ID: "4.2.2", Audit: "cat $proxyconf"},
+	}}}}
+	controls := &check.Controls{Groups: []*check.Group{{Checks: []*check.Check{
+		{ID: "4.1.1"}, {ID: "4.1.9"}, {ID: "4.2.1"}, {ID: "4.2.2"},
+	}}}}
+
+	defer func() { statFunc = os.Stat }()
+	statFunc = func(file string) (os.FileInfo, error) {
+		if file == "/var/lib/kubelet/config.yaml" {
+			return nil, nil
+		}
+		return nil, os.ErrNotExist
+	}
+
+	addMissingFileErrors(controls, raw, []fileVariables{
+		{ext: "conf", fileType: "config", files: map[string]string{"kubelet": "/var/lib/kubelet/config.yaml", "proxy": "proxy", "apiserver": "apiserver"}},
+		{ext: "svc", fileType: "service", files: map[string]string{"kubelet": "/etc/systemd/system/kubelet.service"}},
+	})
+
+	svcErr := check.CheckError{Kind: check.MissingFileError, Message: "service file /etc/systemd/system/kubelet.service of kubelet not found, used for $kubeletsvc"}
+	proxyErr := check.CheckError{Kind: check.MissingFileError, Message: "no config file of proxy found, used for $proxyconf"}
+	expected := map[string][]check.CheckError{
+		"4.1.1": {svcErr},
+		"4.1.9": nil,
+		"4.2.1": {svcErr},
+		"4.2.2": {proxyErr},
+	}
+	for _, c := range controls.Groups[0].Checks {
+		if !reflect.DeepEqual(c.Errors, expected[c.ID]) {
+			t.Errorf("%s: expected errors %v, got %v", c.ID, expected[c.ID], c.Errors)
+		}
+	}
+
+	// The missing files of components no check uses are not errors.
+	if !reflect.DeepEqual(controls.Errors, []check.CheckError{proxyErr, svcErr}) {
+		t.Errorf("unexpected errors of the run %v", controls.Errors)
+	}
+}
+
+func TestMarkUnsubstitutedChecks(t *testing.T) {
+	controls := &check.Controls{Type: check.MASTER, Groups: []*check.Group{{Checks: []*check.Check{
+		{ID: "1.2.1", Audit: "/bin/ps -ef | grep kube-apiserver | grep -v grep"},
+		{ID: "1.2.2", Audit: "/bin/ps -ef | grep $apiserverbin | grep -v grep", AuditConfig: "cat $apiserverconf $apiserverbin"},
+		{ID: "1.2.3", Audit: "/bin/ps -ef | grep $apiserverbin", Type: check.MANUAL},
+		{ID: "1.2.4", Audit: "/bin/ps -ef | grep $apiserverbin", Ignored: "Ignored on this cluster"},
+		{ID: "1.3.1", Audit: "/bin/ps -ef | grep $controllermanagerbin"},
+	}}}}
+
+	errs := markUnsubstitutedChecks(controls)
+
+	expected := []check.CheckError{
+		{Kind: check.AuditError, Message: "no value for $apiserverbin, used by 1.2.2"},
+		{Kind: check.AuditError, Message: "no value for $apiserverconf, used by 1.2.2"},
+		{Kind: check.AuditError, Message: "no value for $controllermanagerbin, used by 1.3.1"},
+	}
+	if !reflect.DeepEqual(errs, expected) {
+		t.Errorf("expected errors %v, got %v", expected, errs)
+	}
+	if !reflect.DeepEqual(controls.Errors, expected) {
+		t.Errorf("expected errors of the run %v, got %v", expected, controls.Errors)
+	}
+
+	for i, unavailable := range []string{
+		"",
+		"no value for $apiserverbin, $apiserverconf: check the components of master in the config",
+		"",
+		"",
+		"no value for $controllermanagerbin: check the components of master in the config",
+	} {
+		if c := controls.Groups[0].Checks[i]; c.Unavailable != unavailable {
+			t.Errorf("%s: expected %q, got %q", c.ID, unavailable, c.Unavailable)
+		}
+	}
+}
+
 func TestGetConfigFilePath(t *testing.T) {
 	var err error
 	cfgDir, err = ioutil.TempDir("", "kube-bench-test")
@@ -496,6 +610,14 @@ func TestGetYamlFilesFromDir(t *testing.T) {
 	if err != nil {
 		t.Fatalf("error writing file %v", err)
 	}
+	err = os.Mkdir(filepath.Join(d, "fragments"), 0766)
+	if err != nil {
+		t.Fatalf("Failed to create temp dir")
+	}
+	err = ioutil.WriteFile(filepath.Join(d, "fragments", "included.yaml"), []byte("hello world"), 0666)
+	if err != nil {
+		t.Fatalf("error writing file %v", err)
+	}
 
 	files, err := getYamlFilesFromDir(d)
 	if err != nil {
@@ -0,0 +1,90 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v2"
+)
+
+// validateCmd represents the validate command
+var validateCmd = &cobra.Command{
+	Use:   "validate <controls.yaml>...",
+	Short: "Validate controls files, and lint their audits",
+	Long: `Validate controls files as kube-bench loads them, and analyze their audits for problems
+that would only show at runtime: substitution variables left unquoted in shell scripts,
+flags that only GNU versions of commands support, and pipes that mask exit codes.
+Problems of the audits are warnings, invalid controls files make the command fail.`,
+	Args: cobra.MinimumNArgs(1),
+	Run: func(cmd *cobra.Command, args []string) {
+		failed := false
+		for _, file := range args {
+			warnings, err := validateControls(file)
+			if err != nil {
+				failed = true
+				colorPrint(check.FAIL, fmt.Sprintf("%s: %v\n", file, err))
+				continue
+			}
+			if len(warnings) == 0 {
+				colorPrint(check.PASS, fmt.Sprintf("%s\n", file))
+				continue
+			}
+			for _, w := range warnings {
+				colorPrint(check.WARN, fmt.Sprintf("%s: %s\n", file, w))
+			}
+		}
+
+		if failed {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	RootCmd.AddCommand(validateCmd)
+}
+
+// validateControls loads a controls file, and lints its audits.
+func validateControls(file string) ([]check.LintWarning, error) {
+	in, err := readControls(file)
+	if err != nil {
+		return nil, err
+	}
+	in, err = check.ResolveIncludes(in, filepath.Dir(file))
+	if err != nil {
+		return nil, err
+	}
+	in, err = check.ResolveExtends(in, filepath.Dir(file))
+	if err != nil {
+		return nil, err
+	}
+
+	var header struct {
+		Type check.NodeType `yaml:"type"`
+	}
+	if err := yaml.Unmarshal(in, &header); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal YAML: %v", err)
+	}
+	controls, err := check.NewControls(header.Type, in)
+	if err != nil {
+		return nil, err
+	}
+	return controls.Lint(), nil
+}
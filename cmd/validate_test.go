@@ -0,0 +1,65 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestValidateControls(t *testing.T) {
+	dir, err := ioutil.TempDir("", "kube-bench-validate")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	files := map[string]string{
+		"valid.yaml": `---
+id: 4
+type: node
+groups:
+- id: 4.1
+  checks:
+  - id: 4.1.1
+    audit: "/bin/sh -c 'if test -e $kubeletsvc; then stat -c %a $kubeletsvc; fi' "
+  - id: 4.1.2
+    audit: "stat -c %U:%G /etc/kubernetes/kubelet.conf"
+`,
+		"invalid.yaml": "id: [4\n",
+	}
+	for name, content := range files {
+		if err := ioutil.WriteFile(filepath.Join(dir, name), []byte(content), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	warnings, err := validateControls(filepath.Join(dir, "valid.yaml"))
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if len(warnings) != 2 || warnings[0].ID != "4.1.1" || warnings[0].Field != "audit" {
+		t.Errorf("expected two warnings of the audit of 4.1.1, got %v", warnings)
+	}
+
+	if _, err := validateControls(filepath.Join(dir, "invalid.yaml")); err == nil {
+		t.Errorf("expected an error for an invalid controls file")
+	}
+	if _, err := validateControls(filepath.Join(dir, "missing.yaml")); err == nil {
+		t.Errorf("expected an error for a missing controls file")
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+	"github.com/fsnotify/fsnotify"
+	"github.com/golang/glog"
+)
+
+// watchedTarget is a target whose checks are run again when the files
+// they audit change, with --watch.
+type watchedTarget struct {
+	controls *check.Controls
+	runner   check.Runner
+	files    []string
+}
+
+// driftEvent is a check whose state changed after a file it audits changed.
+type driftEvent struct {
+	Time     time.Time      `json:"time"`
+	Type     check.NodeType `json:"node_type"`
+	ID       string         `json:"test_number"`
+	Text     string         `json:"test_desc"`
+	File     string         `json:"file"`
+	Previous check.State    `json:"previous_status"`
+	State    check.State    `json:"status"`
+}
+
+// watchedTargets are the targets of this run when --watch is given.
+var watchedTargets []*watchedTarget
+
+// addToWatch adds the results of a target to the ones watched for drift,
+// along with the files found for its components.
+func addToWatch(controls *check.Controls, runner check.Runner, filemaps ...map[string]string) {
+	if !watch || mockMode != "" {
+		return
+	}
+
+	seen := map[string]bool{}
+	var files []string
+	for _, m := range filemaps {
+		for _, file := range m {
+			file = hostPath(file)
+			if filepath.IsAbs(file) && !seen[file] {
+				seen[file] = true
+				files = append(files, file)
+			}
+		}
+	}
+	sort.Strings(files)
+	watchedTargets = append(watchedTargets, &watchedTarget{controls: controls, runner: runner, files: files})
+}
+
+// watchForDrift watches the files found for the components of the targets
+// run, and runs the checks auditing a file again when it changes, reporting
+// the checks whose state changed. It only returns on error.
+func watchForDrift() error {
+	if !watch || len(watchedTargets) == 0 {
+		return nil
+	}
+
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("failed to watch files: %v", err)
+	}
+	defer watcher.Close()
+
+	// Directories are watched rather than files, since files are often
+	// replaced rather than written to, e.g. by editors.
+	dirs := map[string]bool{}
+	for _, t := range watchedTargets {
+		for _, file := range t.files {
+			dir := filepath.Dir(file)
+			if dirs[dir] {
+				continue
+			}
+			dirs[dir] = true
+			if err := watcher.Add(dir); err != nil {
+				glog.V(1).Infof("Not watching %s: %v", dir, err)
+				continue
+			}
+			glog.V(2).Infof("Watching %s", dir)
+		}
+	}
+
+	for {
+		select {
+		case event := <-watcher.Events:
+			for _, d := range checkDrift(event.Name, time.Now()) {
+				printDrift(os.Stdout, d)
+			}
+		case err := <-watcher.Errors:
+			return fmt.Errorf("failed to watch files: %v", err)
+		}
+	}
+}
+
+// checkDrift runs again the checks of the watched targets auditing a file
+// that changed, and returns the ones whose state changed.
+func checkDrift(file string, now time.Time) []driftEvent {
+	var events []driftEvent
+	for _, t := range watchedTargets {
+		if !watchesFile(t, file) {
+			continue
+		}
+		for _, g := range t.controls.Groups {
+			for _, c := range g.Checks {
+				if !strings.Contains(c.Audit, file) && !strings.Contains(c.AuditConfig, file) {
+					continue
+				}
+				glog.V(2).Infof("%s changed, running check %s again", file, c.ID)
+				if prev := t.controls.RerunCheck(t.runner, c); prev != c.State {
+					events = append(events, driftEvent{
+						Time:     now.UTC(),
+						Type:     t.controls.Type,
+						ID:       c.ID,
+						Text:     c.Text,
+						File:     file,
+						Previous: prev,
+						State:    c.State,
+					})
+				}
+			}
+		}
+	}
+	return events
+}
+
+func watchesFile(t *watchedTarget, file string) bool {
+	for _, f := range t.files {
+		if f == file {
+			return true
+		}
+	}
+	return false
+}
+
+// printDrift reports a drift event, as a line of JSON with --json.
+func printDrift(w io.Writer, d driftEvent) {
+	if jsonFmt {
+		data, err := json.Marshal(d)
+		if err != nil {
+			exitWithError(fmt.Errorf("failed to output in JSON format: %v", err))
+		}
+		fmt.Fprintln(w, string(data))
+		return
+	}
+	fmt.Fprintf(w, "%s [DRIFT] %s %s: %s -> %s (%s changed)\n", d.Time.Format(time.RFC3339), d.ID, d.Text, d.Previous, d.State, d.File)
+}
@@ -0,0 +1,83 @@
+// Copyright © 2017-2020 Aqua Security Software Ltd. <info@aquasec.com>
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cmd
+
+import (
+	"bytes"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/aquasecurity/kube-bench/check"
+)
+
+// stateRunner gives the checks the states it's set up with.
+type stateRunner map[string]check.State
+
+func (r stateRunner) Run(c *check.Check) check.State {
+	c.State = r[c.ID]
+	return c.State
+}
+
+func TestCheckDrift(t *testing.T) {
+	defer func(w bool, targets []*watchedTarget) { watch, watchedTargets = w, targets }(watch, watchedTargets)
+	watch, watchedTargets = true, nil
+
+	controls := &check.Controls{Type: check.MASTER, Groups: []*check.Group{{ID: "1.1", Checks: []*check.Check{
+		{ID: "1.1.1", Text: "Ensure that the API server pod specification file permissions are set to 644 or more restrictive", Audit: "stat -c %a /etc/kubernetes/manifests/kube-apiserver.yaml"},
+		{ID: "1.1.2", Text: "Ensure that the API server pod specification file ownership is set to root:root", Audit: "stat -c %U:%G /etc/kubernetes/manifests/kube-apiserver.yaml"},
+		{ID: "1.1.3", Text: "Ensure that the controller manager pod specification file permissions are set to 644 or more restrictive", Audit: "stat -c %a /etc/kubernetes/manifests/kube-controller-manager.yaml"},
+	}}}}
+	runner := stateRunner{"1.1.1": check.PASS, "1.1.2": check.PASS, "1.1.3": check.PASS}
+	controls.RunChecks(runner, func(*check.Group, *check.Check) bool { return true })
+	addToWatch(controls, runner,
+		map[string]string{"apiserver": "/etc/kubernetes/manifests/kube-apiserver.yaml", "controllermanager": "/etc/kubernetes/manifests/kube-controller-manager.yaml"},
+		map[string]string{"apiserver": "/etc/kubernetes/manifests/kube-apiserver.yaml", "scheduler": "scheduler"})
+
+	expectedFiles := []string{"/etc/kubernetes/manifests/kube-apiserver.yaml", "/etc/kubernetes/manifests/kube-controller-manager.yaml"}
+	if files := watchedTargets[0].files; !reflect.DeepEqual(files, expectedFiles) {
+		t.Errorf("expected files %v, got %v", expectedFiles, files)
+	}
+
+	// The permissions of the API server manifest change.
+	runner["1.1.1"] = check.FAIL
+	now := time.Date(2020, 3, 9, 10, 0, 0, 0, time.UTC)
+	events := checkDrift("/etc/kubernetes/manifests/kube-apiserver.yaml", now)
+	expected := []driftEvent{{
+		Time:     now,
+		Type:     check.MASTER,
+		ID:       "1.1.1",
+		Text:     "Ensure that the API server pod specification file permissions are set to 644 or more restrictive",
+		File:     "/etc/kubernetes/manifests/kube-apiserver.yaml",
+		Previous: check.PASS,
+		State:    check.FAIL,
+	}}
+	if !reflect.DeepEqual(events, expected) {
+		t.Errorf("expected %+v, got %+v", expected, events)
+	}
+	if controls.Pass != 2 || controls.Fail != 1 {
+		t.Errorf("expected the summary to be updated, got %+v", controls.Summary)
+	}
+
+	if events := checkDrift("/etc/kubernetes/manifests/kube-scheduler.yaml", now); len(events) != 0 {
+		t.Errorf("expected no drift for an unwatched file, got %+v", events)
+	}
+
+	var out bytes.Buffer
+	printDrift(&out, expected[0])
+	if s := out.String(); s != "2020-03-09T10:00:00Z [DRIFT] 1.1.1 Ensure that the API server pod specification file permissions are set to 644 or more restrictive: PASS -> FAIL (/etc/kubernetes/manifests/kube-apiserver.yaml changed)\n" {
+		t.Errorf("unexpected drift report %q", s)
+	}
+}